@@ -26,6 +26,12 @@ type FieldSelector string
 type Regexp string
 type Base8 string
 
+// Validator is implemented by plugin configs which check their values beyond tags, e.g. compile expressions.
+// Parse calls Validate after all fields are parsed, so a wrong config makes Parse fail instead of the plugin start.
+type Validator interface {
+	Validate() error
+}
+
 type PipelineConfig struct {
 	Raw *simplejson.Json
 }
@@ -115,6 +121,10 @@ func Parse(ptr interface{}, values map[string]int) error {
 		}
 	}
 
+	if validator, ok := ptr.(Validator); ok {
+		return validator.Validate()
+	}
+
 	return nil
 }
 
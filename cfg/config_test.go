@@ -1,6 +1,7 @@
 package cfg
 
 import (
+	"regexp"
 	"testing"
 	"time"
 
@@ -59,6 +60,20 @@ type sliceStruct struct {
 	Childs []sliceChild `default:"" slice:"true"`
 }
 
+type strValidated struct {
+	T  string `default:"a+"`
+	T_ *regexp.Regexp
+}
+
+func (s *strValidated) Validate() error {
+	re, err := regexp.Compile(s.T)
+	if err != nil {
+		return err
+	}
+	s.T_ = re
+	return nil
+}
+
 type strBase8 struct {
 	T  string `default:"0666" parse:"base8"`
 	T_ int64
@@ -200,3 +215,14 @@ func TestBase8(t *testing.T) {
 	assert.Nil(t, err, "shouldn't be an error")
 	assert.Equal(t, int64(511), s.T_)
 }
+
+func TestValidate(t *testing.T) {
+	s := &strValidated{}
+	err := Parse(s, nil)
+	assert.Nil(t, err, "shouldn't be an error")
+	assert.True(t, s.T_.MatchString("aa"), "validate should be called after defaults are set")
+
+	s = &strValidated{T: "a("}
+	err = Parse(s, nil)
+	assert.NotNil(t, err, "should be an error")
+}
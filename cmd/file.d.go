@@ -14,20 +14,82 @@ import (
 	insaneJSON "github.com/vitkovskii/insane-json"
 	"go.uber.org/automaxprocs/maxprocs"
 
+	_ "github.com/ozonru/file.d/plugin/action/adaptive_sample"
+	_ "github.com/ozonru/file.d/plugin/action/add_cgroup_info"
 	_ "github.com/ozonru/file.d/plugin/action/add_host"
+	_ "github.com/ozonru/file.d/plugin/action/anonymize"
+	_ "github.com/ozonru/file.d/plugin/action/byte_throttle"
 	_ "github.com/ozonru/file.d/plugin/action/convert_date"
 	_ "github.com/ozonru/file.d/plugin/action/debug"
+	_ "github.com/ozonru/file.d/plugin/action/decode"
+	_ "github.com/ozonru/file.d/plugin/action/dedup_bucket"
+	_ "github.com/ozonru/file.d/plugin/action/derive_severity"
+	_ "github.com/ozonru/file.d/plugin/action/detect_truncation"
 	_ "github.com/ozonru/file.d/plugin/action/discard"
+	_ "github.com/ozonru/file.d/plugin/action/doc_id"
+	_ "github.com/ozonru/file.d/plugin/action/drop_healthchecks"
+	_ "github.com/ozonru/file.d/plugin/action/drop_large_fields"
+	_ "github.com/ozonru/file.d/plugin/action/ensure_fields"
 	_ "github.com/ozonru/file.d/plugin/action/flatten"
+	_ "github.com/ozonru/file.d/plugin/action/geohash"
+	_ "github.com/ozonru/file.d/plugin/action/head_tail"
+	_ "github.com/ozonru/file.d/plugin/action/hmac_chain"
+	_ "github.com/ozonru/file.d/plugin/action/jmespath"
 	_ "github.com/ozonru/file.d/plugin/action/join"
 	_ "github.com/ozonru/file.d/plugin/action/json_decode"
 	_ "github.com/ozonru/file.d/plugin/action/keep_fields"
+	_ "github.com/ozonru/file.d/plugin/action/kv_array_to_object"
+	_ "github.com/ozonru/file.d/plugin/action/limit_array"
+	_ "github.com/ozonru/file.d/plugin/action/lowercase_values"
+	_ "github.com/ozonru/file.d/plugin/action/mask"
 	_ "github.com/ozonru/file.d/plugin/action/modify"
+	_ "github.com/ozonru/file.d/plugin/action/normalize_phone"
+	_ "github.com/ozonru/file.d/plugin/action/parse_alb"
+	_ "github.com/ozonru/file.d/plugin/action/parse_alertmanager"
+	_ "github.com/ozonru/file.d/plugin/action/parse_cloudtrail"
+	_ "github.com/ozonru/file.d/plugin/action/parse_dnslog"
 	_ "github.com/ozonru/file.d/plugin/action/parse_es"
+	_ "github.com/ozonru/file.d/plugin/action/parse_gclog"
+	_ "github.com/ozonru/file.d/plugin/action/parse_haproxy"
+	_ "github.com/ozonru/file.d/plugin/action/parse_istio_meta"
+	_ "github.com/ozonru/file.d/plugin/action/parse_ja3"
+	_ "github.com/ozonru/file.d/plugin/action/parse_k8s_audit"
+	_ "github.com/ozonru/file.d/plugin/action/parse_k8s_filename"
+	_ "github.com/ozonru/file.d/plugin/action/parse_keyvalue"
+	_ "github.com/ozonru/file.d/plugin/action/parse_nginx_error"
+	_ "github.com/ozonru/file.d/plugin/action/parse_otlp_log"
+	_ "github.com/ozonru/file.d/plugin/action/parse_pg_csvlog"
 	_ "github.com/ozonru/file.d/plugin/action/parse_re2"
+	_ "github.com/ozonru/file.d/plugin/action/parse_redis_slowlog"
+	_ "github.com/ozonru/file.d/plugin/action/parse_slog"
+	_ "github.com/ozonru/file.d/plugin/action/parse_toml"
+	_ "github.com/ozonru/file.d/plugin/action/parse_traefik"
+	_ "github.com/ozonru/file.d/plugin/action/parse_yaml"
+	_ "github.com/ozonru/file.d/plugin/action/per_key_limit"
+	_ "github.com/ozonru/file.d/plugin/action/quantile_metric"
 	_ "github.com/ozonru/file.d/plugin/action/remove_fields"
 	_ "github.com/ozonru/file.d/plugin/action/rename"
+	_ "github.com/ozonru/file.d/plugin/action/reorder_fields"
+	_ "github.com/ozonru/file.d/plugin/action/repair_json"
+	_ "github.com/ozonru/file.d/plugin/action/resplit"
+	_ "github.com/ozonru/file.d/plugin/action/reverse_geo"
+	_ "github.com/ozonru/file.d/plugin/action/rps_metric"
+	_ "github.com/ozonru/file.d/plugin/action/ruleset"
+	_ "github.com/ozonru/file.d/plugin/action/sample"
+	_ "github.com/ozonru/file.d/plugin/action/score"
+	_ "github.com/ozonru/file.d/plugin/action/seen_before"
+	_ "github.com/ozonru/file.d/plugin/action/sessionize"
+	_ "github.com/ozonru/file.d/plugin/action/shard_field"
+	_ "github.com/ozonru/file.d/plugin/action/split"
+	_ "github.com/ozonru/file.d/plugin/action/split_reqresp"
+	_ "github.com/ozonru/file.d/plugin/action/tail_sample"
 	_ "github.com/ozonru/file.d/plugin/action/throttle"
+	_ "github.com/ozonru/file.d/plugin/action/time_window_tag"
+	_ "github.com/ozonru/file.d/plugin/action/transcode"
+	_ "github.com/ozonru/file.d/plugin/action/type_guard"
+	_ "github.com/ozonru/file.d/plugin/action/unflatten"
+	_ "github.com/ozonru/file.d/plugin/action/url_template"
+	_ "github.com/ozonru/file.d/plugin/action/zscore"
 	_ "github.com/ozonru/file.d/plugin/input/dmesg"
 	_ "github.com/ozonru/file.d/plugin/input/fake"
 	_ "github.com/ozonru/file.d/plugin/input/file"
@@ -41,6 +103,7 @@ import (
 	_ "github.com/ozonru/file.d/plugin/output/kafka"
 	_ "github.com/ozonru/file.d/plugin/output/stdout"
 	_ "github.com/ozonru/file.d/plugin/output/file"
+	_ "github.com/ozonru/file.d/plugin/output/weighted"
 )
 
 var (
@@ -151,7 +151,8 @@ func (f *FileD) setupAction(p *pipeline.Pipeline, index int, t string, actionJSO
 	if err != nil {
 		logger.Fatalf("can't extract conditions for action %d/%s in pipeline %q: %s", index, t, p.Name, err.Error())
 	}
-	metricName, metricLabels := extractMetrics(actionJSON)
+	matchCacheField, matchCacheSize := extractMatchCache(actionJSON)
+	metricName, metricLabels, metricMaxLabelValues, metricMaxLabelLength, metricDefaultValue := extractMetrics(actionJSON)
 	configJSON := makeActionJSON(actionJSON)
 
 	_, config := info.Factory()
@@ -170,12 +171,17 @@ func (f *FileD) setupAction(p *pipeline.Pipeline, index int, t string, actionJSO
 	infoCopy.Type = t
 
 	p.AddAction(&pipeline.ActionPluginStaticInfo{
-		PluginStaticInfo: &infoCopy,
-		MatchConditions:  conditions,
-		MatchMode:        matchMode,
-		MetricName:       metricName,
-		MetricLabels:     metricLabels,
-		MatchInvert:      matchInvert,
+		PluginStaticInfo:     &infoCopy,
+		MatchConditions:      conditions,
+		MatchMode:            matchMode,
+		MetricName:           metricName,
+		MetricLabels:         metricLabels,
+		MetricMaxLabelValues: metricMaxLabelValues,
+		MetricMaxLabelLength: metricMaxLabelLength,
+		MetricDefaultValue:   metricDefaultValue,
+		MatchInvert:          matchInvert,
+		MatchCacheField:      matchCacheField,
+		MatchCacheSize:       matchCacheSize,
 	})
 }
 
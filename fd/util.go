@@ -5,10 +5,8 @@ import (
 	"time"
 
 	"github.com/bitly/go-simplejson"
-	"github.com/ozonru/file.d/cfg"
 	"github.com/ozonru/file.d/logger"
 	"github.com/ozonru/file.d/pipeline"
-	"github.com/pkg/errors"
 )
 
 func extractPipelineParams(settings *simplejson.Json) *pipeline.Settings {
@@ -19,6 +17,15 @@ func extractPipelineParams(settings *simplejson.Json) *pipeline.Settings {
 	maintenanceInterval := pipeline.DefaultMaintenanceInterval
 	decoder := "auto"
 	isStrict := false
+	stageLatency := false
+	dryRun := false
+	inputErrorEvents := false
+	sourceMetrics := false
+	ingestStamp := false
+	recordFormat := pipeline.RecordFormatSingle
+	rejectDuplicateKeys := false
+	discardAuditFile := ""
+	var metricConstLabels map[string]string
 
 	if settings != nil {
 		val := settings.Get("capacity").MustInt()
@@ -54,6 +61,31 @@ func extractPipelineParams(settings *simplejson.Json) *pipeline.Settings {
 		antispamThreshold *= int(maintenanceInterval / time.Second)
 
 		isStrict = settings.Get("is_strict").MustBool()
+		stageLatency = settings.Get("stage_latency").MustBool()
+		dryRun = settings.Get("dry_run").MustBool()
+		inputErrorEvents = settings.Get("input_error_events").MustBool()
+		sourceMetrics = settings.Get("source_metrics").MustBool()
+		ingestStamp = settings.Get("ingest_stamp").MustBool()
+
+		str = settings.Get("record_format").MustString()
+		if str != "" {
+			recordFormat = str
+		}
+
+		rejectDuplicateKeys = settings.Get("reject_duplicate_keys").MustBool()
+		discardAuditFile = settings.Get("discard_audit_file").MustString()
+
+		labels := settings.Get("metric_const_labels").MustMap()
+		if len(labels) != 0 {
+			metricConstLabels = make(map[string]string, len(labels))
+			for name, value := range labels {
+				str, ok := value.(string)
+				if !ok {
+					logger.Fatalf("value of metric const label %q should be a string", name)
+				}
+				metricConstLabels[name] = str
+			}
+		}
 	}
 
 	return &pipeline.Settings{
@@ -64,19 +96,25 @@ func extractPipelineParams(settings *simplejson.Json) *pipeline.Settings {
 		MaintenanceInterval: maintenanceInterval,
 		StreamField:         streamField,
 		IsStrict:            isStrict,
+		StageLatency:        stageLatency,
+		DryRun:              dryRun,
+		InputErrorEvents:    inputErrorEvents,
+		SourceMetrics:       sourceMetrics,
+		IngestStamp:         ingestStamp,
+		RecordFormat:        recordFormat,
+		RejectDuplicateKeys: rejectDuplicateKeys,
+		DiscardAuditFile:    discardAuditFile,
+		MetricConstLabels:   metricConstLabels,
 	}
 }
 
 func extractMatchMode(actionJSON *simplejson.Json) (pipeline.MatchMode, error) {
 	mm := actionJSON.Get("match_mode").MustString()
-	if mm != "or" && mm != "and" && mm != "" {
-		return pipeline.MatchModeUnknown, fmt.Errorf("unknown match mode %q must be or/and", mm)
+	matchMode := pipeline.MatchModeByName(mm)
+	if matchMode == pipeline.MatchModeUnknown {
+		return pipeline.MatchModeUnknown, fmt.Errorf("unknown match mode %q must be or/and/or_prefix/and_prefix", mm)
 
 	}
-	matchMode := pipeline.MatchModeAnd
-	if mm == "or" {
-		matchMode = pipeline.MatchModeOr
-	}
 	return matchMode, nil
 }
 
@@ -85,37 +123,35 @@ func extractMatchInvert (actionJSON *simplejson.Json) (bool, error){
 	return invertMatchMode, nil
 }
 
+func extractMatchCache(actionJSON *simplejson.Json) (string, int) {
+	return actionJSON.Get("match_cache_field").MustString(), actionJSON.Get("match_cache_size").MustInt()
+}
+
 func extractConditions(condJSON *simplejson.Json) (pipeline.MatchConditions, error) {
-	conditions := make(pipeline.MatchConditions, 0, 0)
+	fields := make(map[string]string)
 	for field := range condJSON.MustMap() {
-		value := condJSON.Get(field).MustString()
-
-		condition := pipeline.MatchCondition{
-			Field: field,
-		}
-
-		if len(value) > 0 && value[0] == '/' {
-			r, err := cfg.CompileRegex(value)
-			if err != nil {
-				return nil, errors.Wrapf(err, "can't compile regexp %s: %s", value, err)
-			}
-			condition.Regexp = r
-		} else {
-			condition.Value = value
-		}
-		conditions = append(conditions, condition)
+		fields[field] = condJSON.Get(field).MustString()
 	}
 
-	return conditions, nil
+	return pipeline.NewMatchConditions(fields)
 }
 
-func extractMetrics(actionJSON *simplejson.Json) (string, []string) {
+func extractMetrics(actionJSON *simplejson.Json) (string, []string, int, int, *string) {
 	metricName := actionJSON.Get("metric_name").MustString()
 	metricLabels := actionJSON.Get("metric_labels").MustStringArray()
 	if metricLabels == nil {
 		metricLabels = []string{}
 	}
-	return metricName, metricLabels
+	metricMaxLabelValues := actionJSON.Get("metric_max_label_values").MustInt()
+	metricMaxLabelLength := actionJSON.Get("metric_max_label_length").MustInt()
+
+	// empty string is a valid default value, so only absence of the param means the pipeline default
+	var metricDefaultValue *string
+	if value, has := actionJSON.CheckGet("metric_default_value"); has {
+		str := value.MustString()
+		metricDefaultValue = &str
+	}
+	return metricName, metricLabels, metricMaxLabelValues, metricMaxLabelLength, metricDefaultValue
 }
 
 func makeActionJSON(actionJSON *simplejson.Json) []byte {
@@ -124,7 +160,12 @@ func makeActionJSON(actionJSON *simplejson.Json) []byte {
 	actionJSON.Del("match_mode")
 	actionJSON.Del("metric_name")
 	actionJSON.Del("metric_labels")
+	actionJSON.Del("metric_max_label_values")
+	actionJSON.Del("metric_max_label_length")
+	actionJSON.Del("metric_default_value")
 	actionJSON.Del("match_invert")
+	actionJSON.Del("match_cache_field")
+	actionJSON.Del("match_cache_size")
 	configJson, err := actionJSON.Encode()
 	if err != nil {
 		logger.Panicf("can't create action json")
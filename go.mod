@@ -1,6 +1,7 @@
 module github.com/ozonru/file.d
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/Shopify/sarama v1.29.1
 	github.com/alecthomas/kingpin v2.2.6+incompatible
 	github.com/alecthomas/units v0.0.0-20190924025748-f65c72e2690d
@@ -14,11 +15,15 @@ require (
 	github.com/googleapis/gnostic v0.3.1 // indirect
 	github.com/hashicorp/golang-lru v0.5.3 // indirect
 	github.com/imdario/mergo v0.3.7 // indirect
+	github.com/jmespath/go-jmespath v0.4.0
+	github.com/klauspost/compress v1.12.2
 	github.com/pkg/errors v0.8.1
 	github.com/prometheus/client_golang v1.3.0
+	github.com/prometheus/client_model v0.1.0
 	github.com/satori/go.uuid v1.2.0
 	github.com/spf13/pflag v1.0.3 // indirect
 	github.com/stretchr/testify v1.7.0
+	github.com/tinylib/msgp v1.1.5
 	github.com/vitkovskii/insane-json v0.1.0
 	go.uber.org/atomic v1.5.0
 	go.uber.org/automaxprocs v1.2.0
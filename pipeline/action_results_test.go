@@ -0,0 +1,44 @@
+package pipeline_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionResults(t *testing.T) {
+	actions := append(
+		test.NewActionPluginStaticInfo(sleepFactory, nil, pipeline.MatchModeAnd, nil, false),
+		test.NewActionPluginStaticInfo(discardFactory, nil, pipeline.MatchModeAnd, nil, false)...,
+	)
+	p, input, output := test.NewPipelineMock(actions)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	output.SetOutFn(func(e *pipeline.Event) {
+		wg.Done()
+	})
+
+	// discarded events go first, so they are processed when passed events reach the output
+	input.In(0, "test.log", 0, []byte(`{"message":"bye","discard":true}`))
+	input.In(0, "test.log", 1, []byte(`{"message":"bye again","discard":true}`))
+	input.In(0, "test.log", 2, []byte(`{"message":"hello"}`))
+	input.In(0, "test.log", 3, []byte(`{"message":"hello again"}`))
+	input.In(0, "test.log", 4, []byte(`{"message":"hi"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	results := p.GetMetricsCtl().RegisterCounter("action_results_total", "")
+
+	assert.Equal(t, float64(5), testutil.ToFloat64(results.WithLabelValues("0_test_plugin", "pass")), "wrong pass results of the first action")
+	assert.Equal(t, float64(0), testutil.ToFloat64(results.WithLabelValues("0_test_plugin", "discard")), "wrong discard results of the first action")
+	assert.Equal(t, float64(3), testutil.ToFloat64(results.WithLabelValues("1_test_plugin", "pass")), "wrong pass results of the second action")
+	assert.Equal(t, float64(2), testutil.ToFloat64(results.WithLabelValues("1_test_plugin", "discard")), "wrong discard results of the second action")
+	assert.Equal(t, float64(0), testutil.ToFloat64(results.WithLabelValues("1_test_plugin", "hold")), "wrong hold results of the second action")
+}
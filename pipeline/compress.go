@@ -0,0 +1,77 @@
+package pipeline
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	CompressNone = "none"
+	CompressZstd = "zstd"
+	CompressGzip = "gzip"
+)
+
+var (
+	// zstd encoder and decoder are safe for concurrent use of EncodeAll/DecodeAll, so they are shared
+	zstdOnce    = &sync.Once{}
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+func initZstd() {
+	zstdOnce.Do(func() {
+		zstdEncoder, _ = zstd.NewWriter(nil)
+		zstdDecoder, _ = zstd.NewReader(nil)
+	})
+}
+
+// AppendCompressed appends the data compressed with the method, which is one of CompressNone, CompressZstd or CompressGzip.
+func AppendCompressed(out []byte, data []byte, method string) ([]byte, error) {
+	switch method {
+	case "", CompressNone:
+		return append(out, data...), nil
+	case CompressZstd:
+		initZstd()
+		return zstdEncoder.EncodeAll(data, out), nil
+	case CompressGzip:
+		buf := bytes.NewBuffer(out)
+		w := gzip.NewWriter(buf)
+		if _, err := w.Write(data); err != nil {
+			return out, err
+		}
+		if err := w.Close(); err != nil {
+			return out, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return out, fmt.Errorf("unknown compression %q", method)
+	}
+}
+
+// AppendDecompressed appends the data decompressed with the method, see AppendCompressed.
+func AppendDecompressed(out []byte, data []byte, method string) ([]byte, error) {
+	switch method {
+	case "", CompressNone:
+		return append(out, data...), nil
+	case CompressZstd:
+		initZstd()
+		return zstdDecoder.DecodeAll(data, out)
+	case CompressGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return out, err
+		}
+		buf := bytes.NewBuffer(out)
+		if _, err := io.Copy(buf, r); err != nil {
+			return out, err
+		}
+		return buf.Bytes(), r.Close()
+	default:
+		return out, fmt.Errorf("unknown compression %q", method)
+	}
+}
@@ -0,0 +1,35 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := []byte(`{"message":"` + strings.Repeat("compressible ", 100) + `"}`)
+
+	for _, method := range []string{CompressNone, CompressZstd, CompressGzip} {
+		compressed, err := AppendCompressed([]byte("prefix"), data, method)
+		assert.NoError(t, err, "can't compress with %s", method)
+		assert.Equal(t, "prefix", string(compressed[:len("prefix")]), "prefix is corrupted by %s", method)
+		if method != CompressNone {
+			assert.True(t, len(compressed) < len(data), "data isn't compressed with %s", method)
+		}
+
+		decompressed, err := AppendDecompressed([]byte("prefix"), compressed[len("prefix"):], method)
+		assert.NoError(t, err, "can't decompress with %s", method)
+		assert.Equal(t, "prefix"+string(data), string(decompressed), "wrong decompressed data with %s", method)
+	}
+}
+
+func TestDecompressWrongData(t *testing.T) {
+	for _, method := range []string{CompressZstd, CompressGzip} {
+		_, err := AppendDecompressed(nil, []byte(`{"message":"not compressed"}`), method)
+		assert.Error(t, err, "wrong data is decompressed with %s", method)
+	}
+
+	_, err := AppendCompressed(nil, []byte("data"), "lz4")
+	assert.Error(t, err, "unknown compression is accepted")
+}
@@ -0,0 +1,23 @@
+package pipeline
+
+// EventDestination returns the value of the destination field of the event, e.g. a topic or an index name.
+// If the field isn't configured, is absent or is empty or isn't a string or a number, the default destination is returned.
+// The returned string points to the event memory, so outputs should copy it if it's used after the event is released.
+// The destination field is supported by kafka, elasticsearch and file outputs, gelf, stdout and devnull outputs have the only destination.
+func EventDestination(event *Event, field []string, defaultDestination string) string {
+	if len(field) == 0 {
+		return defaultDestination
+	}
+
+	node := event.Root.Dig(field...)
+	if node == nil || !(node.IsString() || node.IsNumber()) {
+		return defaultDestination
+	}
+
+	value := node.AsString()
+	if value == "" {
+		return defaultDestination
+	}
+
+	return value
+}
@@ -0,0 +1,159 @@
+package pipeline
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"hash/fnv"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	discardReasonAction        = "action"
+	discardReasonDuplicateKeys = "duplicate_keys"
+
+	discardAuditQueueSize = 4096
+)
+
+// discardAuditor appends a record for each discarded event to the audit file, one JSON per line:
+// {"time":"2006-01-02T15:04:05.999999999Z","pipeline":"name","reason":"action","action":"1_discard","hash":"af63bd4c8601b7df"}
+// The hash is FNV-1a of the encoded event, so the dropped event can be matched with its source without storing it.
+// Processors only hash the event and queue the record, records are written by the separate goroutine,
+// so the slow disk stalls processors only when the queue is full.
+// The audit output is the file set by `discard_audit_file` pipeline setting rather than an output plugin:
+// the pipeline has the only output, which is the destination of the events themselves,
+// and records emitted into the pipeline could be discarded by the same actions.
+// To ship records elsewhere, read the file by a separate pipeline with the file input.
+type discardAuditor struct {
+	pipelineName string
+	path         string
+	logger       *zap.SugaredLogger
+
+	records chan *discardRecord
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+	bufPool *sync.Pool
+
+	file   *os.File
+	writer *bufio.Writer
+}
+
+type discardRecord struct {
+	Time     string `json:"time"`
+	Pipeline string `json:"pipeline"`
+	Reason   string `json:"reason"`
+	Action   string `json:"action,omitempty"`
+	Hash     string `json:"hash"`
+}
+
+func newDiscardAuditor(pipelineName string, path string, logger *zap.SugaredLogger) *discardAuditor {
+	return &discardAuditor{
+		pipelineName: pipelineName,
+		path:         path,
+		logger:       logger,
+		records:      make(chan *discardRecord, discardAuditQueueSize),
+		stopCh:       make(chan struct{}),
+		doneCh:       make(chan struct{}),
+		bufPool: &sync.Pool{
+			New: func() interface{} {
+				return make([]byte, 0, DefaultAvgLogSize)
+			},
+		},
+	}
+}
+
+func (a *discardAuditor) start() {
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		a.logger.Fatalf("can't open discard audit file %s: %s", a.path, err.Error())
+	}
+
+	a.file = file
+	a.writer = bufio.NewWriter(file)
+
+	go a.write()
+}
+
+// stop writes queued records and closes the file, records of events discarded after the stop are dropped
+func (a *discardAuditor) stop() {
+	close(a.stopCh)
+	<-a.doneCh
+
+	if err := a.file.Close(); err != nil {
+		a.logger.Errorf("can't close discard audit file %s: %s", a.path, err.Error())
+	}
+}
+
+// audit is called by processors concurrently, action is empty if the event is discarded before actions
+func (a *discardAuditor) audit(event *Event, reason string, action string) {
+	buf := a.bufPool.Get().([]byte)
+	buf = event.Root.Encode(buf[:0])
+	hash := fnv.New64a()
+	_, _ = hash.Write(buf)
+	a.bufPool.Put(buf)
+
+	record := &discardRecord{
+		Time:     time.Now().UTC().Format(time.RFC3339Nano),
+		Pipeline: a.pipelineName,
+		Reason:   reason,
+		Action:   action,
+		Hash:     hex.EncodeToString(hash.Sum(nil)),
+	}
+
+	select {
+	case a.records <- record:
+	case <-a.stopCh:
+	}
+}
+
+func (a *discardAuditor) write() {
+	defer close(a.doneCh)
+
+	for {
+		select {
+		case record := <-a.records:
+			a.writeRecord(record)
+		case <-a.stopCh:
+			a.writeQueued()
+			a.flush()
+			return
+		}
+
+		// the buffer is flushed once the queue is empty, so records don't wait for new discarded events
+		if len(a.records) == 0 {
+			a.flush()
+		}
+	}
+}
+
+func (a *discardAuditor) writeQueued() {
+	for {
+		select {
+		case record := <-a.records:
+			a.writeRecord(record)
+		default:
+			return
+		}
+	}
+}
+
+func (a *discardAuditor) writeRecord(record *discardRecord) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		a.logger.Panicf("can't encode discard audit record: %s", err.Error())
+	}
+
+	if _, err := a.writer.Write(append(line, '\n')); err != nil {
+		a.logger.Errorf("can't write discard audit record to %s: %s", a.path, err.Error())
+	}
+}
+
+func (a *discardAuditor) flush() {
+	if err := a.writer.Flush(); err != nil {
+		a.logger.Errorf("can't write discard audit records to %s: %s", a.path, err.Error())
+	}
+}
@@ -0,0 +1,133 @@
+package pipeline_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func auditRecords(t *testing.T, file string) []map[string]string {
+	content, err := ioutil.ReadFile(file)
+	assert.NoError(t, err, "can't read audit file")
+
+	records := make([]map[string]string, 0)
+	for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		record := map[string]string{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &record), "wrong audit record %s", line)
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func eventHash(event string) string {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(event))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func TestDiscardAudit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "discard_audit")
+	assert.NoError(t, err, "can't create temp dir")
+	defer os.RemoveAll(dir)
+
+	settings := test.NewSettings()
+	settings.DiscardAuditFile = filepath.Join(dir, "audit.log")
+	p, input, output := test.NewPipelineMockWithSettings(test.NewActionPluginStaticInfo(discardFactory, nil, pipeline.MatchModeAnd, nil, false), settings)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	output.SetOutFn(func(e *pipeline.Event) {
+		wg.Done()
+	})
+
+	// discarded events go first, so they are processed when passed events reach the output
+	input.In(0, "test.log", 0, []byte(`{"message":"bye","discard":true}`))
+	input.In(0, "test.log", 1, []byte(`{"message":"hello"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	records := auditRecords(t, settings.DiscardAuditFile)
+	assert.Equal(t, 1, len(records), "wrong audit records count")
+	assert.Equal(t, "test_pipeline", records[0]["pipeline"], "wrong pipeline")
+	assert.Equal(t, "action", records[0]["reason"], "wrong reason")
+	assert.Equal(t, "0_test_plugin", records[0]["action"], "wrong action")
+	assert.Equal(t, eventHash(`{"message":"bye","discard":true}`), records[0]["hash"], "wrong hash")
+	assert.NotEmpty(t, records[0]["time"], "no time")
+}
+
+func TestDiscardAuditDuplicateKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "discard_audit")
+	assert.NoError(t, err, "can't create temp dir")
+	defer os.RemoveAll(dir)
+
+	settings := test.NewSettings()
+	settings.RejectDuplicateKeys = true
+	settings.DiscardAuditFile = filepath.Join(dir, "audit.log")
+	p, input, output := test.NewPipelineMockWithSettings(nil, settings)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	output.SetOutFn(func(e *pipeline.Event) {
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"a":1,"a":2}`))
+	input.In(0, "test.log", 1, []byte(`{"message":"hello"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	records := auditRecords(t, settings.DiscardAuditFile)
+	assert.Equal(t, 1, len(records), "wrong audit records count")
+	assert.Equal(t, "duplicate_keys", records[0]["reason"], "wrong reason")
+	assert.Equal(t, "", records[0]["action"], "action shouldn't be set")
+	assert.Equal(t, eventHash(`{"a":1,"a":2}`), records[0]["hash"], "wrong hash")
+}
+
+func TestDiscardAuditQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "discard_audit")
+	assert.NoError(t, err, "can't create temp dir")
+	defer os.RemoveAll(dir)
+
+	settings := test.NewSettings()
+	settings.DiscardAuditFile = filepath.Join(dir, "audit.log")
+	p, input, output := test.NewPipelineMockWithSettings(test.NewActionPluginStaticInfo(discardFactory, nil, pipeline.MatchModeAnd, nil, false), settings)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	output.SetOutFn(func(e *pipeline.Event) {
+		wg.Done()
+	})
+
+	// there are more records than the queue holds, so processors wait for the writer
+	count := 10000
+	for i := 0; i < count; i++ {
+		input.In(0, "test.log", int64(i), []byte(`{"message":"bye","discard":true}`))
+	}
+	input.In(0, "test.log", int64(count), []byte(`{"message":"hello"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	// queued records are written on the stop
+	records := auditRecords(t, settings.DiscardAuditFile)
+	assert.Equal(t, count, len(records), "wrong audit records count")
+}
@@ -0,0 +1,26 @@
+package pipeline
+
+import (
+	"go.uber.org/zap"
+)
+
+// dryRunOutput replaces the pipeline output in dry run mode.
+// It logs events instead of sending them and commits them right away.
+type dryRunOutput struct {
+	outputType string
+	controller OutputPluginController
+	logger     *zap.SugaredLogger
+}
+
+func (o *dryRunOutput) Start(_ AnyConfig, params *OutputPluginParams) {
+	o.controller = params.Controller
+	o.logger = params.Logger
+}
+
+func (o *dryRunOutput) Stop() {
+}
+
+func (o *dryRunOutput) Out(event *Event) {
+	o.logger.Infof("dry run: event would be sent to %q output: %s", o.outputType, event.Root.EncodeToString())
+	o.controller.Commit(event)
+}
@@ -0,0 +1,77 @@
+package pipeline_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ozonru/file.d/logger"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type discardPlugin struct {
+}
+
+func (p *discardPlugin) Start(_ pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+}
+
+func (p *discardPlugin) Stop() {
+}
+
+func (p *discardPlugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	if event.Root.Dig("discard") != nil {
+		return pipeline.ActionDiscard
+	}
+	return pipeline.ActionPass
+}
+
+func discardFactory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &discardPlugin{}, nil
+}
+
+func TestDryRun(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	originalLogger := logger.Instance
+	logger.Instance = zap.New(core).Sugar()
+	defer func() {
+		logger.Instance = originalLogger
+	}()
+
+	settings := test.NewSettings()
+	settings.DryRun = true
+	p, input, output := test.NewPipelineMockWithSettings(test.NewActionPluginStaticInfo(discardFactory, nil, pipeline.MatchModeAnd, nil, false), settings)
+
+	outCalls := atomic.NewInt32(0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outCalls.Inc()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"message":"hello"}`))
+	input.In(0, "test.log", 1, []byte(`{"message":"bye","discard":true}`))
+	input.In(0, "test.log", 2, []byte(`{"message":"hello again"}`))
+
+	deadline := time.Now().Add(time.Second * 10)
+	for p.GetEventsTotal() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	p.Stop()
+
+	assert.Equal(t, int32(0), outCalls.Load(), "real output shouldn't be called")
+	assert.Equal(t, 2, p.GetEventsTotal(), "events aren't committed")
+
+	sent := logs.FilterMessageSnippet(`event would be sent to "devnull" output`).All()
+	assert.Equal(t, 2, len(sent), "wrong sent events count in log")
+	assert.True(t, strings.Contains(sent[0].Message, `{"message":"hello"}`), "wrong logged event")
+	assert.True(t, strings.Contains(sent[1].Message, `{"message":"hello again"}`), "wrong logged event")
+
+	assert.Equal(t, 2, logs.FilterMessageSnippet(`action #0 "test_plugin" passed event`).Len(), "wrong passed action effects count in log")
+	discarded := logs.FilterMessageSnippet(`action #0 "test_plugin" discarded event`).All()
+	assert.Equal(t, 1, len(discarded), "wrong discarded action effects count in log")
+	assert.True(t, strings.Contains(discarded[0].Message, `{"message":"bye","discard":true}`), "wrong logged event")
+}
@@ -0,0 +1,64 @@
+package pipeline_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRejectDuplicateKeys(t *testing.T) {
+	settings := test.NewSettings()
+	settings.RejectDuplicateKeys = true
+	settings.InputErrorEvents = true
+	p, input, output := test.NewPipelineMockWithSettings(nil, settings)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(4) // two clean events and two input errors
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"level":"info","message":"hello","level":"error"}`))
+	input.In(0, "test.log", 1, []byte(`{"a":1,"b":2,"c":3,"c":4}`))
+	input.In(0, "test.log", 2, []byte(`{"message":"clean","nested":{"a":1},"a":2}`))
+	input.In(0, "test.log", 3, []byte(`{"message":"clean","nested":{"a":1,"a":2}}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 4, len(outEvents), "wrong out events count")
+	assert.Contains(t, outEvents, `{"message":"clean","nested":{"a":1},"a":2}`, "clean event should be passed")
+	assert.Contains(t, outEvents, `{"message":"clean","nested":{"a":1,"a":2}}`, "only root keys should be checked")
+	assert.Contains(t, outEvents, `{"_event_type":"input_error","pipeline":"test_pipeline","input":"fake","error":"event has duplicate key \"level\", offset=0, source=0:test.log"}`, "no input error for duplicate key")
+	assert.Contains(t, outEvents, `{"_event_type":"input_error","pipeline":"test_pipeline","input":"fake","error":"event has duplicate key \"c\", offset=1, source=0:test.log"}`, "no input error for duplicate key")
+
+	counter := p.GetMetricsCtl().RegisterCounter("duplicate_keys_events_total", "").WithLabelValues()
+	assert.Equal(t, float64(2), testutil.ToFloat64(counter), "wrong rejected events count")
+}
+
+func TestDuplicateKeysAllowed(t *testing.T) {
+	p, input, output := test.NewPipelineMock(nil)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"level":"info","level":"error"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{`{"level":"info","level":"error"}`}, outEvents, "event with duplicate keys should be passed")
+}
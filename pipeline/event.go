@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"runtime"
 	"sync"
+	"time"
 
 	"github.com/ozonru/file.d/logger"
 	insaneJSON "github.com/vitkovskii/insane-json"
@@ -24,6 +25,10 @@ type Event struct {
 	SourceName string
 	streamName StreamName
 	Size       int // last known event size, it may not be actual
+	createdAt  time.Time
+	synthetic  bool // event is generated by the pipeline itself, so input shouldn't be notified about its commit
+	spawned    bool // event is created by an action from the other event, its offset is committed by the parent
+	partial    bool // event isn't the last one of the split record, its offset is committed by the last one
 
 	action int
 	next   *Event
@@ -103,6 +108,8 @@ func (e *Event) reset() {
 	e.next = nil
 	e.action = 0
 	e.stream = nil
+	e.synthetic = false
+	e.partial = false
 	e.kind.Swap(eventKindRegular)
 }
 
@@ -138,6 +145,14 @@ func (e *Event) IsTimeoutKind() bool {
 	return e.kind.Load() == eventKindTimeout
 }
 
+func (e *Event) IsSynthetic() bool {
+	return e.synthetic
+}
+
+func (e *Event) IsSpawned() bool {
+	return e.spawned
+}
+
 func (e *Event) parseJSON(json []byte) error {
 	return e.Root.DecodeBytes(json)
 }
@@ -154,6 +169,15 @@ func (e *Event) Encode(outBuf []byte) ([]byte, int) {
 	return outBuf, l
 }
 
+// EncodeMsgpack is the same as Encode, but it appends the event in MessagePack format.
+func (e *Event) EncodeMsgpack(outBuf []byte) ([]byte, int) {
+	l := len(outBuf)
+	outBuf = appendMsgpack(outBuf, e.Root.Node)
+	e.Size = len(outBuf) - l
+
+	return outBuf, l
+}
+
 func (e *Event) stageStr() string {
 	switch e.stage {
 	case eventStagePool:
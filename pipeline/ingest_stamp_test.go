@@ -0,0 +1,71 @@
+package pipeline_test
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngestStamp(t *testing.T) {
+	settings := test.NewSettings()
+	settings.IngestStamp = true
+	p, input, output := test.NewPipelineMockWithSettings(nil, settings)
+
+	workers := 8
+	eventsPerWorker := 200
+
+	wg := &sync.WaitGroup{}
+	wg.Add(workers * eventsPerWorker)
+
+	mu := &sync.Mutex{}
+	seqs := make(map[int][]int) // worker => sequence numbers in the order of ingestion by the worker
+	badTimes := 0
+	output.SetOutFn(func(e *pipeline.Event) {
+		_, err := time.Parse(time.RFC3339Nano, e.Root.Dig(pipeline.IngestTimeField).AsString())
+
+		mu.Lock()
+		if err != nil {
+			badTimes++
+		}
+		worker := e.Root.Dig("worker").AsInt()
+		if seqs[worker] == nil {
+			seqs[worker] = make([]int, eventsPerWorker)
+		}
+		seqs[worker][e.Root.Dig("i").AsInt()] = e.Root.Dig(pipeline.IngestSeqField).AsInt()
+		mu.Unlock()
+
+		wg.Done()
+	})
+
+	for w := 0; w < workers; w++ {
+		go func(worker int) {
+			for i := 0; i < eventsPerWorker; i++ {
+				input.In(pipeline.SourceID(worker), "test.log", int64(i), []byte(fmt.Sprintf(`{"worker":%d,"i":%d}`, worker, i)))
+			}
+		}(w)
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 0, badTimes, "wrong ingestion time")
+
+	all := make([]int, 0, workers*eventsPerWorker)
+	for worker, workerSeqs := range seqs {
+		for i := 1; i < len(workerSeqs); i++ {
+			assert.True(t, workerSeqs[i] > workerSeqs[i-1], "sequence isn't increasing for worker %d", worker)
+		}
+		all = append(all, workerSeqs...)
+	}
+
+	sort.Ints(all)
+	for i, seq := range all {
+		assert.Equal(t, i+1, seq, "sequence numbers should be unique and have no gaps")
+	}
+}
@@ -0,0 +1,63 @@
+package pipeline_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInputErrorEvents(t *testing.T) {
+	settings := test.NewSettings()
+	settings.InputErrorEvents = true
+	p, input, output := test.NewPipelineMockWithSettings(nil, settings)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3) // two events and commit of the regular one
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	committed := make([]string, 0)
+	input.SetCommitFn(func(e *pipeline.Event) {
+		committed = append(committed, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"message":"hello"}`))
+	input.Error("connection lost")
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 2, len(outEvents), "wrong out events count")
+	assert.Contains(t, outEvents, `{"message":"hello"}`, "no regular event")
+	assert.Contains(t, outEvents, `{"_event_type":"input_error","pipeline":"test_pipeline","input":"fake","error":"connection lost"}`, "no synthetic event")
+	assert.Equal(t, []string{`{"message":"hello"}`}, committed, "input shouldn't be notified about synthetic events")
+}
+
+func TestInputErrorEventsDisabled(t *testing.T) {
+	p, input, output := test.NewPipelineMock(nil)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.Error("connection lost")
+	input.In(0, "test.log", 0, []byte(`{"message":"hello"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{`{"message":"hello"}`}, outEvents, "wrong out events")
+}
@@ -0,0 +1,93 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+// NewMatchCondition parses the value of the condition:
+// `/regexp/` matches values by the regexp, any other value matches equal values
+// or values starting with it in prefix match modes.
+func NewMatchCondition(field string, value string) (MatchCondition, error) {
+	condition := MatchCondition{
+		Field: field,
+	}
+
+	switch {
+	case len(value) > 0 && value[0] == '/':
+		r, err := cfg.CompileRegex(value)
+		if err != nil {
+			return condition, fmt.Errorf("can't compile regexp %s: %s", value, err.Error())
+		}
+		condition.Regexp = r
+	default:
+		condition.Value = value
+	}
+
+	return condition, nil
+}
+
+// NewMatchConditions parses conditions from the map of field names to condition values, see NewMatchCondition.
+func NewMatchConditions(fields map[string]string) (MatchConditions, error) {
+	conditions := make(MatchConditions, 0, len(fields))
+	for field, value := range fields {
+		condition, err := NewMatchCondition(field, value)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return conditions, nil
+}
+
+// MatchModeByName returns the match mode by its name: and, or, and_prefix or or_prefix,
+// empty name means and mode, MatchModeUnknown is returned for other names.
+func MatchModeByName(name string) MatchMode {
+	switch name {
+	case "", "and":
+		return MatchModeAnd
+	case "or":
+		return MatchModeOr
+	case "and_prefix":
+		return MatchModeAndPrefix
+	case "or_prefix":
+		return MatchModeOrPrefix
+	default:
+		return MatchModeUnknown
+	}
+}
+
+// Match checks the conditions against the object with the mode,
+// the condition of the absent field doesn't match.
+func (c MatchConditions) Match(root *insaneJSON.Node, mode MatchMode) bool {
+	or := mode == MatchModeOr || mode == MatchModeOrPrefix
+	for i := range c {
+		node := root.Dig(c[i].Field)
+		match := node != nil && c[i].MatchValue(node.AsString(), mode)
+		if or && match {
+			return true
+		}
+		if !or && !match {
+			return false
+		}
+	}
+
+	return !or
+}
+
+// MatchValue checks the value against the condition, only the prefix of the value is checked in prefix modes.
+func (c *MatchCondition) MatchValue(value string, mode MatchMode) bool {
+	if c.Regexp != nil {
+		return c.Regexp.MatchString(value)
+	}
+
+	if mode == MatchModeAndPrefix || mode == MatchModeOrPrefix {
+		return strings.HasPrefix(value, c.Value)
+	}
+
+	return value == c.Value
+}
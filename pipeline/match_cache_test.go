@@ -0,0 +1,74 @@
+package pipeline_test
+
+import (
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func matchCacheInfo(size int) []*pipeline.ActionPluginStaticInfo {
+	info := test.NewActionPluginStaticInfo(discardFactory, nil, pipeline.MatchModeAnd, pipeline.MatchConditions{
+		{Field: "message", Regexp: regexp.MustCompile("error")},
+	}, false)
+	info[0].MatchCacheField = "service"
+	info[0].MatchCacheSize = size
+
+	return info
+}
+
+func TestMatchCache(t *testing.T) {
+	p, input, output := test.NewPipelineMock(matchCacheInfo(0))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	// match result is cached for "api", so the second event is discarded without checking the message
+	input.In(0, "test.log", 0, []byte(`{"service":"api","message":"error","discard":true}`))
+	input.In(0, "test.log", 0, []byte(`{"service":"api","message":"ok","discard":true}`))
+	input.In(0, "test.log", 0, []byte(`{"service":"web","message":"ok","discard":true}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"error","discard":true}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"ok","discard":true}`))
+	input.In(0, "test.log", 0, []byte(`{"service":"web","message":"error","discard":true}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"service":"web","message":"ok","discard":true}`,
+		`{"message":"ok","discard":true}`,
+		`{"service":"web","message":"error","discard":true}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestMatchCacheSize(t *testing.T) {
+	p, input, output := test.NewPipelineMock(matchCacheInfo(1))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	// the cache is cleared by "web", so "api" is checked again
+	input.In(0, "test.log", 0, []byte(`{"service":"api","message":"error","discard":true}`))
+	input.In(0, "test.log", 0, []byte(`{"service":"web","message":"error","discard":true}`))
+	input.In(0, "test.log", 0, []byte(`{"service":"api","message":"ok","discard":true}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{`{"service":"api","message":"ok","discard":true}`}, outEvents, "wrong out events")
+}
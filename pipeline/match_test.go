@@ -0,0 +1,116 @@
+package pipeline_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+func TestMatchConditions(t *testing.T) {
+	conds, err := pipeline.NewMatchConditions(map[string]string{
+		"service": "payments",
+		"level":   "/^(error|warn)$/",
+		"host":    "api.",
+	})
+	assert.NoError(t, err, "wrong conditions")
+
+	type testCase struct {
+		json      string
+		and       bool
+		or        bool
+		andPrefix bool
+		orPrefix  bool
+	}
+	cases := []testCase{
+		{json: `{"service":"payments","level":"warn","host":"api."}`, and: true, or: true, andPrefix: true, orPrefix: true},
+		{json: `{"service":"payments","level":"error","host":"api.example.com"}`, and: false, or: true, andPrefix: true, orPrefix: true},
+		{json: `{"service":"payments","level":"info","host":"api.example.com"}`, and: false, or: true, andPrefix: false, orPrefix: true},
+		{json: `{"service":"payments-v2","level":"info","host":"example.com"}`, and: false, or: false, andPrefix: false, orPrefix: true},
+		{json: `{"service":"web","level":"warn","host":"api."}`, and: false, or: true, andPrefix: false, orPrefix: true},
+		{json: `{"level":"error","host":"api.example.com"}`, and: false, or: true, andPrefix: false, orPrefix: true},
+		{json: `{"message":"no fields"}`, and: false, or: false, andPrefix: false, orPrefix: false},
+	}
+
+	for _, c := range cases {
+		root, err := insaneJSON.DecodeString(c.json)
+		assert.NoError(t, err, "wrong json")
+
+		assert.Equal(t, c.and, conds.Match(root.Node, pipeline.MatchModeAnd), "wrong and match for %s", c.json)
+		assert.Equal(t, c.or, conds.Match(root.Node, pipeline.MatchModeOr), "wrong or match for %s", c.json)
+		assert.Equal(t, c.andPrefix, conds.Match(root.Node, pipeline.MatchModeAndPrefix), "wrong and_prefix match for %s", c.json)
+		assert.Equal(t, c.orPrefix, conds.Match(root.Node, pipeline.MatchModeOrPrefix), "wrong or_prefix match for %s", c.json)
+
+		insaneJSON.Release(root)
+	}
+}
+
+func TestMatchConditionsEmpty(t *testing.T) {
+	root, err := insaneJSON.DecodeString(`{"service":"payments"}`)
+	assert.NoError(t, err, "wrong json")
+	defer insaneJSON.Release(root)
+
+	conds := pipeline.MatchConditions{}
+	assert.True(t, conds.Match(root.Node, pipeline.MatchModeAnd), "empty and conditions should match")
+	assert.False(t, conds.Match(root.Node, pipeline.MatchModeOr), "empty or conditions shouldn't match")
+}
+
+func TestNewMatchConditionWrongRegexp(t *testing.T) {
+	_, err := pipeline.NewMatchCondition("level", "/(error/")
+	assert.Error(t, err, "wrong regexp is accepted")
+}
+
+func TestMatchConditionsAsterisk(t *testing.T) {
+	conds, err := pipeline.NewMatchConditions(map[string]string{"service": "pay*"})
+	assert.NoError(t, err, "wrong conditions")
+
+	for json, match := range map[string]bool{`{"service":"pay*"}`: true, `{"service":"payments"}`: false} {
+		root, err := insaneJSON.DecodeString(json)
+		assert.NoError(t, err, "wrong json")
+
+		assert.Equal(t, match, conds.Match(root.Node, pipeline.MatchModeAnd), "asterisk should match literally for %s", json)
+
+		insaneJSON.Release(root)
+	}
+}
+
+func TestMatchModeByName(t *testing.T) {
+	assert.Equal(t, pipeline.MatchModeAnd, pipeline.MatchModeByName(""), "wrong match mode")
+	assert.Equal(t, pipeline.MatchModeAnd, pipeline.MatchModeByName("and"), "wrong match mode")
+	assert.Equal(t, pipeline.MatchModeOr, pipeline.MatchModeByName("or"), "wrong match mode")
+	assert.Equal(t, pipeline.MatchModeAndPrefix, pipeline.MatchModeByName("and_prefix"), "wrong match mode")
+	assert.Equal(t, pipeline.MatchModeOrPrefix, pipeline.MatchModeByName("or_prefix"), "wrong match mode")
+	assert.Equal(t, pipeline.MatchModeUnknown, pipeline.MatchModeByName("prefix"), "wrong match mode")
+}
+
+func TestMatchFieldsPrefix(t *testing.T) {
+	conds, err := pipeline.NewMatchConditions(map[string]string{"service": "pay"})
+	assert.NoError(t, err, "wrong conditions")
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(discardFactory, nil, pipeline.MatchModeAndPrefix, conds, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	// the action is skipped for events which don't match, so they aren't discarded
+	input.In(0, "test.log", 0, []byte(`{"service":"payments","discard":true}`))
+	input.In(0, "test.log", 0, []byte(`{"service":"web","discard":true}`))
+	input.In(0, "test.log", 0, []byte(`{"service":"pay","discard":true}`))
+	input.In(0, "test.log", 0, []byte(`{"discard":true}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"service":"web","discard":true}`,
+		`{"discard":true}`,
+	}, outEvents, "wrong out events")
+}
@@ -0,0 +1,109 @@
+package pipeline
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsCtl creates metrics for plugins of the pipeline.
+// Action plugins are instantiated for each processor, so metrics with the same name are shared between instances.
+type MetricsCtl struct {
+	subsystem   string
+	registry    *prometheus.Registry
+	constLabels prometheus.Labels
+
+	mu        *sync.Mutex
+	counters  map[string]*prometheus.CounterVec
+	summaries map[string]*prometheus.SummaryVec
+	gauges    map[string]*prometheus.GaugeVec
+}
+
+func NewMetricsCtl(pipelineName string, registry *prometheus.Registry) *MetricsCtl {
+	return NewMetricsCtlWithLabels(pipelineName, registry, nil)
+}
+
+// NewMetricsCtlWithLabels is the same as NewMetricsCtl, but the const labels are added to all created metrics.
+func NewMetricsCtlWithLabels(pipelineName string, registry *prometheus.Registry, constLabels map[string]string) *MetricsCtl {
+	return &MetricsCtl{
+		subsystem:   "pipeline_" + pipelineName,
+		registry:    registry,
+		constLabels: constLabels,
+
+		mu:        &sync.Mutex{},
+		counters:  make(map[string]*prometheus.CounterVec),
+		summaries: make(map[string]*prometheus.SummaryVec),
+		gauges:    make(map[string]*prometheus.GaugeVec),
+	}
+}
+
+// RegisterCounter returns the counter with the name, the counter is created if it doesn't exist.
+func (mc *MetricsCtl) RegisterCounter(name string, help string, labels ...string) *prometheus.CounterVec {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if counter, has := mc.counters[name]; has {
+		return counter
+	}
+
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "file_d",
+		Subsystem:   mc.subsystem,
+		Name:        name,
+		Help:        help,
+		ConstLabels: mc.constLabels,
+	}, labels)
+
+	mc.counters[name] = counter
+	mc.registry.MustRegister(counter)
+
+	return counter
+}
+
+// RegisterSummary returns the summary with the name, the summary is created if it doesn't exist.
+// Objectives are the map of `quantile => absolute error`, they are ignored if the summary exists.
+func (mc *MetricsCtl) RegisterSummary(name string, help string, objectives map[float64]float64, labels ...string) *prometheus.SummaryVec {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if summary, has := mc.summaries[name]; has {
+		return summary
+	}
+
+	summary := prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Namespace:   "file_d",
+		Subsystem:   mc.subsystem,
+		Name:        name,
+		Help:        help,
+		ConstLabels: mc.constLabels,
+		Objectives:  objectives,
+	}, labels)
+
+	mc.summaries[name] = summary
+	mc.registry.MustRegister(summary)
+
+	return summary
+}
+
+// RegisterGauge returns the gauge with the name, the gauge is created if it doesn't exist.
+func (mc *MetricsCtl) RegisterGauge(name string, help string, labels ...string) *prometheus.GaugeVec {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if gauge, has := mc.gauges[name]; has {
+		return gauge
+	}
+
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   "file_d",
+		Subsystem:   mc.subsystem,
+		Name:        name,
+		Help:        help,
+		ConstLabels: mc.constLabels,
+	}, labels)
+
+	mc.gauges[name] = gauge
+	mc.registry.MustRegister(gauge)
+
+	return gauge
+}
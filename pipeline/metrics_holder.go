@@ -5,10 +5,20 @@ import (
 	"strconv"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+const (
+	defaultMaxLabelLength = 128
+	truncatedLabelMarker  = "..."
+)
+
+// durationBuckets are from 10µs to ~2.6s, actions usually process an event in microseconds
+var durationBuckets = prometheus.ExponentialBuckets(0.00001, 4, 10)
+
 type metricsHolder struct {
 	pipelineName       string
 	metricsGen         int // generation is used to drop unused metrics from counters
@@ -16,16 +26,22 @@ type metricsHolder struct {
 	metricsGenInterval time.Duration
 	metrics            []*metrics
 	registry           *prometheus.Registry
+	overflows          *prometheus.CounterVec
+	constLabels        map[string]string // added to the generation label of all metrics
 }
 
 type counter struct {
-	count *prometheus.CounterVec
-	size  *prometheus.CounterVec
+	count    *prometheus.CounterVec
+	size     *prometheus.CounterVec
+	duration *prometheus.HistogramVec
 }
 
 type metrics struct {
-	name   string
-	labels []string
+	name           string
+	labels         []string
+	maxLabelValues int // zero means no limit
+	maxLabelLength int
+	defaultValue   string // label value for absent fields
 
 	root *mNode
 
@@ -39,10 +55,11 @@ type mNode struct {
 	self   string
 }
 
-func newMetricsHolder(pipelineName string, registry *prometheus.Registry, metricsGenInterval time.Duration) *metricsHolder {
+func newMetricsHolder(pipelineName string, registry *prometheus.Registry, metricsGenInterval time.Duration, constLabels map[string]string) *metricsHolder {
 	return &metricsHolder{
 		pipelineName: pipelineName,
 		registry:     registry,
+		constLabels:  constLabels,
 
 		metrics:            make([]*metrics, 0),
 		metricsGenInterval: metricsGenInterval,
@@ -50,16 +67,23 @@ func newMetricsHolder(pipelineName string, registry *prometheus.Registry, metric
 
 }
 
-func (m *metricsHolder) AddAction(metricName string, metricLabels []string) {
+func (m *metricsHolder) AddAction(metricName string, metricLabels []string, maxLabelValues int, maxLabelLength int, defaultValue string) {
+	if maxLabelLength <= 0 {
+		maxLabelLength = defaultMaxLabelLength
+	}
+
 	m.metrics = append(m.metrics, &metrics{
-		name:   metricName,
-		labels: metricLabels,
+		name:           metricName,
+		labels:         metricLabels,
+		maxLabelValues: maxLabelValues,
+		maxLabelLength: maxLabelLength,
+		defaultValue:   defaultValue,
 		root: &mNode{
 			childs: make(map[string]*mNode),
 			mu:     &sync.RWMutex{},
 		},
-		current:  counter{nil, nil},
-		previous: counter{nil, nil},
+		current:  counter{nil, nil, nil},
+		previous: counter{nil, nil, nil},
 	})
 }
 
@@ -70,15 +94,21 @@ func (m *metricsHolder) start() {
 func (c *counter) register(registry *prometheus.Registry) {
 	registry.MustRegister(c.count)
 	registry.MustRegister(c.size)
+	registry.MustRegister(c.duration)
 }
 
 func (c *counter) unregister(registry *prometheus.Registry) {
 	registry.Unregister(c.count)
 	registry.Unregister(c.size)
+	registry.Unregister(c.duration)
 }
 
 func (m *metricsHolder) nextMetricsGen() {
-	metricsGen := strconv.Itoa(m.metricsGen)
+	constLabels := prometheus.Labels{"gen": strconv.Itoa(m.metricsGen)}
+	for name, value := range m.constLabels {
+		constLabels[name] = value
+	}
+
 	for index, metrics := range m.metrics {
 		if metrics.name == "" {
 			continue
@@ -90,7 +120,7 @@ func (m *metricsHolder) nextMetricsGen() {
 			Subsystem:   "pipeline_" + m.pipelineName,
 			Name:        metrics.name + "_events_count_total",
 			Help:        fmt.Sprintf("how many events processed by pipeline %q and #%d action", m.pipelineName, index),
-			ConstLabels: map[string]string{"gen": metricsGen},
+			ConstLabels: constLabels,
 		}
 		cnt.count = prometheus.NewCounterVec(opts, append([]string{"status"}, metrics.labels...))
 
@@ -99,10 +129,19 @@ func (m *metricsHolder) nextMetricsGen() {
 			Subsystem:   "pipeline_" + m.pipelineName,
 			Name:        metrics.name + "_events_size_total",
 			Help:        fmt.Sprintf("total size of events processed by pipeline %q and #%d action", m.pipelineName, index),
-			ConstLabels: map[string]string{"gen": metricsGen},
+			ConstLabels: constLabels,
 		}
 		cnt.size = prometheus.NewCounterVec(opts, append([]string{"status"}, metrics.labels...))
 
+		cnt.duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   "file_d",
+			Subsystem:   "pipeline_" + m.pipelineName,
+			Name:        metrics.name + "_process_duration_seconds",
+			Help:        fmt.Sprintf("time spent by #%d action of pipeline %q to process an event", index, m.pipelineName),
+			ConstLabels: constLabels,
+			Buckets:     durationBuckets,
+		}, append([]string{"status"}, metrics.labels...))
+
 		obsolete := metrics.previous
 
 		metrics.previous = metrics.current
@@ -119,25 +158,22 @@ func (m *metricsHolder) nextMetricsGen() {
 }
 
 func (m *metricsHolder) count(event *Event, actionIndex int, eventStatus eventStatus, valuesBuf []string) []string {
-	if len(m.metrics) == 0 {
+	if !m.isCounted(actionIndex) {
 		return valuesBuf
 	}
 
 	metrics := m.metrics[actionIndex]
-	if metrics.name == "" {
-		return valuesBuf
-	}
 
 	valuesBuf = valuesBuf[:0]
 	valuesBuf = append(valuesBuf, string(eventStatus))
 
 	mn := metrics.root
 	for _, field := range metrics.labels {
-		val := DefaultFieldValue
+		val := metrics.defaultValue
 
 		node := event.Root.Dig(field)
 		if node != nil {
-			val = node.AsString()
+			val = labelValue(node.AsString(), metrics.maxLabelLength)
 		}
 
 		mn.mu.RLock()
@@ -147,10 +183,16 @@ func (m *metricsHolder) count(event *Event, actionIndex int, eventStatus eventSt
 		if !has {
 			mn.mu.Lock()
 			nextMN, has = mn.childs[val]
+			if !has && metrics.maxLabelValues > 0 && len(mn.childs) >= metrics.maxLabelValues {
+				// too many values, so they are collapsed into a single one
+				m.overflows.WithLabelValues(metrics.name).Inc()
+				val = OverflowFieldValue
+				nextMN, has = mn.childs[val]
+			}
 			if !has {
-				key := DefaultFieldValue
-				if node != nil {
-					key = string(node.AsBytes()) // make string from []byte to make map string keys works good
+				key := val
+				if node != nil && val != OverflowFieldValue {
+					key = string([]byte(val)) // the value may point to the event memory, so it's copied for the map key
 				}
 
 				nextMN = &mNode{
@@ -173,8 +215,59 @@ func (m *metricsHolder) count(event *Event, actionIndex int, eventStatus eventSt
 	return valuesBuf
 }
 
+// labelValue truncates the value to maxLength bytes and replaces non-printable characters and invalid UTF-8 bytes with `_`.
+// The value is returned as is if it doesn't need changes.
+func labelValue(value string, maxLength int) string {
+	if len(value) <= maxLength && isPrintableASCII(value) {
+		return value
+	}
+
+	out := make([]byte, 0, maxLength+len(truncatedLabelMarker))
+	for i := 0; i < len(value); {
+		r, size := utf8.DecodeRuneInString(value[i:])
+		char := value[i : i+size]
+		if (r == utf8.RuneError && size == 1) || !unicode.IsPrint(r) {
+			char = "_"
+		}
+
+		if len(out)+len(char) > maxLength {
+			out = append(out, truncatedLabelMarker...)
+			break
+		}
+
+		out = append(out, char...)
+		i += size
+	}
+
+	return string(out)
+}
+
+func isPrintableASCII(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] < ' ' || value[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isCounted returns true if metrics are enabled for the action
+func (m *metricsHolder) isCounted(actionIndex int) bool {
+	return len(m.metrics) != 0 && m.metrics[actionIndex].name != ""
+}
+
+// countDuration observes time spent by the action, values should be the label values returned by count for the event
+func (m *metricsHolder) countDuration(actionIndex int, duration time.Duration, values []string) {
+	if !m.isCounted(actionIndex) {
+		return
+	}
+
+	m.metrics[actionIndex].current.duration.WithLabelValues(values...).Observe(duration.Seconds())
+}
+
 func (m *metricsHolder) maintenance() {
-	if time.Since(m.metricsGenTime) < metricsGenInterval {
+	if time.Since(m.metricsGenTime) < m.metricsGenInterval {
 		return
 	}
 
@@ -0,0 +1,272 @@
+package pipeline
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+func TestMetricsHolderMaxLabelValues(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	holder := newMetricsHolder("test", registry, time.Hour, nil)
+	holder.overflows = NewMetricsCtl("test", registry).RegisterCounter("metric_label_overflows_total", "", "metric")
+	holder.AddAction("requests", []string{"service", "request_id"}, 2, 0, DefaultFieldValue)
+	holder.start()
+
+	events := []string{
+		`{"service":"api","request_id":"1"}`,
+		`{"service":"api","request_id":"2"}`,
+		`{"service":"api","request_id":"3"}`,
+		`{"service":"api","request_id":"4"}`,
+		`{"service":"api","request_id":"1"}`,
+		`{"service":"web","request_id":"5"}`,
+		`{"service":"db"}`,
+	}
+
+	valuesBuf := make([]string, 0)
+	for _, json := range events {
+		root, err := insaneJSON.DecodeString(json)
+		assert.NoError(t, err, "wrong json")
+		valuesBuf = holder.count(&Event{Root: root, Size: len(json)}, 0, eventStatusPassed, valuesBuf)
+		insaneJSON.Release(root)
+	}
+
+	count := holder.metrics[0].current.count
+	assert.Equal(t, float64(2), testutil.ToFloat64(count.WithLabelValues("passed", "api", "1")), "wrong count")
+	assert.Equal(t, float64(1), testutil.ToFloat64(count.WithLabelValues("passed", "api", "2")), "wrong count")
+	assert.Equal(t, float64(2), testutil.ToFloat64(count.WithLabelValues("passed", "api", OverflowFieldValue)), "wrong overflow count")
+	assert.Equal(t, float64(1), testutil.ToFloat64(count.WithLabelValues("passed", "web", "5")), "wrong count")
+	assert.Equal(t, float64(1), testutil.ToFloat64(count.WithLabelValues("passed", OverflowFieldValue, DefaultFieldValue)), "wrong overflow count")
+	assert.Equal(t, float64(3), testutil.ToFloat64(holder.overflows.WithLabelValues("requests")), "wrong overflows")
+}
+
+func TestMetricsHolderNoLabelValuesLimit(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	holder := newMetricsHolder("test", registry, time.Hour, nil)
+	holder.overflows = NewMetricsCtl("test", registry).RegisterCounter("metric_label_overflows_total", "", "metric")
+	holder.AddAction("requests", []string{"request_id"}, 0, 0, DefaultFieldValue)
+	holder.start()
+
+	valuesBuf := make([]string, 0)
+	for _, json := range []string{`{"request_id":"1"}`, `{"request_id":"2"}`, `{"request_id":"3"}`} {
+		root, err := insaneJSON.DecodeString(json)
+		assert.NoError(t, err, "wrong json")
+		valuesBuf = holder.count(&Event{Root: root, Size: len(json)}, 0, eventStatusPassed, valuesBuf)
+		insaneJSON.Release(root)
+	}
+
+	assert.Equal(t, 3, len(holder.metrics[0].root.childs), "wrong label values count")
+	assert.Equal(t, float64(0), testutil.ToFloat64(holder.overflows.WithLabelValues("requests")), "wrong overflows")
+}
+
+func TestMetricsHolderLabelValues(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	holder := newMetricsHolder("test", registry, time.Hour, nil)
+	holder.overflows = NewMetricsCtl("test", registry).RegisterCounter("metric_label_overflows_total", "", "metric")
+	holder.AddAction("errors", []string{"error"}, 0, 16, DefaultFieldValue)
+	holder.start()
+
+	events := []string{
+		`{"error":"timeout"}`,
+		`{"error":"panic: runtime error\n\tat main.go:10\n\tat main.go:20"}`,
+		`{"error":"ошибка: не найдено"}`,
+		"{\"error\":\"bad \xff\xfe bytes\"}",
+		`{"error":"tab\tseparated"}`,
+	}
+
+	valuesBuf := make([]string, 0)
+	for _, json := range events {
+		root, err := insaneJSON.DecodeString(json)
+		assert.NoError(t, err, "wrong json")
+		valuesBuf = holder.count(&Event{Root: root, Size: len(json)}, 0, eventStatusPassed, valuesBuf)
+		insaneJSON.Release(root)
+	}
+
+	values := make([]string, 0)
+	for value := range holder.metrics[0].root.childs {
+		values = append(values, value)
+	}
+	assert.ElementsMatch(t, []string{
+		"timeout",
+		"panic: runtime e...",
+		"ошибка: н...",
+		"bad __ bytes",
+		"tab_separated",
+	}, values, "wrong label values")
+
+	count := holder.metrics[0].current.count
+	assert.Equal(t, float64(1), testutil.ToFloat64(count.WithLabelValues("passed", "panic: runtime e...")), "wrong count")
+	assert.Equal(t, float64(1), testutil.ToFloat64(count.WithLabelValues("passed", "bad __ bytes")), "wrong count")
+}
+
+func TestLabelValue(t *testing.T) {
+	assert.Equal(t, "short", labelValue("short", 128), "wrong label value")
+	assert.Equal(t, "ab...", labelValue("abc", 2), "wrong label value")
+	assert.Equal(t, "ж...", labelValue("жж", 3), "multibyte characters shouldn't be split")
+	assert.Equal(t, "a_b", labelValue("a\x00b", 128), "wrong label value")
+	assert.Equal(t, "_", labelValue("\x80", 128), "wrong label value")
+}
+
+func TestMetricsHolderDuration(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	holder := newMetricsHolder("test", registry, time.Hour, nil)
+	holder.overflows = NewMetricsCtl("test", registry).RegisterCounter("metric_label_overflows_total", "", "metric")
+	holder.AddAction("", nil, 0, 0, DefaultFieldValue)
+	holder.AddAction("requests", []string{"service"}, 0, 0, DefaultFieldValue)
+	holder.start()
+
+	root, err := insaneJSON.DecodeString(`{"service":"api"}`)
+	assert.NoError(t, err, "wrong json")
+	defer insaneJSON.Release(root)
+	event := &Event{Root: root}
+
+	assert.False(t, holder.isCounted(0), "action without metric name shouldn't be counted")
+	assert.True(t, holder.isCounted(1), "action with metric name should be counted")
+
+	valuesBuf := make([]string, 0)
+	valuesBuf = holder.count(event, 1, eventStatusPassed, valuesBuf)
+	holder.countDuration(1, time.Millisecond, valuesBuf)
+	valuesBuf = holder.count(event, 1, eventStatusPassed, valuesBuf)
+	holder.countDuration(1, time.Millisecond*3, valuesBuf)
+	valuesBuf = holder.count(event, 1, eventStatusDiscarded, valuesBuf)
+	holder.countDuration(1, time.Second, valuesBuf)
+	holder.countDuration(0, time.Second, valuesBuf)
+
+	metric := &dto.Metric{}
+	err = holder.metrics[1].current.duration.WithLabelValues("passed", "api").(prometheus.Metric).Write(metric)
+	assert.NoError(t, err, "can't write metric")
+	assert.Equal(t, uint64(2), metric.GetHistogram().GetSampleCount(), "wrong samples count")
+	assert.InDelta(t, 0.004, metric.GetHistogram().GetSampleSum(), 0.000001, "wrong samples sum")
+
+	metric = &dto.Metric{}
+	err = holder.metrics[1].current.duration.WithLabelValues("discarded", "api").(prometheus.Metric).Write(metric)
+	assert.NoError(t, err, "can't write metric")
+	assert.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount(), "wrong samples count")
+
+	// duration metric is rotated along with the others, so the first generation is unregistered
+	holder.nextMetricsGen()
+	holder.nextMetricsGen()
+	valuesBuf = holder.count(event, 1, eventStatusPassed, valuesBuf)
+	holder.countDuration(1, time.Millisecond, valuesBuf)
+
+	families, err := registry.Gather()
+	assert.NoError(t, err, "can't gather metrics")
+
+	gens := make(map[string][]string)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "gen" {
+					gens[family.GetName()] = append(gens[family.GetName()], label.GetValue())
+				}
+			}
+		}
+	}
+	assert.Equal(t, []string{"2"}, gens["file_d_pipeline_test_requests_process_duration_seconds"], "wrong duration metric generations")
+	assert.Equal(t, []string{"2"}, gens["file_d_pipeline_test_requests_events_count_total"], "wrong count metric generations")
+}
+
+func TestMetricsHolderDefaultValue(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	holder := newMetricsHolder("test", registry, time.Hour, nil)
+	holder.overflows = NewMetricsCtl("test", registry).RegisterCounter("metric_label_overflows_total", "", "metric")
+	holder.AddAction("first", []string{"service"}, 0, 0, "unknown")
+	holder.AddAction("second", []string{"service"}, 0, 0, "")
+	holder.start()
+
+	valuesBuf := make([]string, 0)
+	for _, json := range []string{`{"message":"absent"}`, `{"service":""}`, `{"message":"absent again"}`} {
+		root, err := insaneJSON.DecodeString(json)
+		assert.NoError(t, err, "wrong json")
+		event := &Event{Root: root, Size: len(json)}
+		valuesBuf = holder.count(event, 0, eventStatusPassed, valuesBuf)
+		valuesBuf = holder.count(event, 1, eventStatusPassed, valuesBuf)
+		insaneJSON.Release(root)
+	}
+
+	first := holder.metrics[0].current.count
+	assert.Equal(t, float64(2), testutil.ToFloat64(first.WithLabelValues("passed", "unknown")), "wrong count for absent field")
+	assert.Equal(t, float64(1), testutil.ToFloat64(first.WithLabelValues("passed", "")), "wrong count for empty field")
+
+	second := holder.metrics[1].current.count
+	assert.Equal(t, float64(3), testutil.ToFloat64(second.WithLabelValues("passed", "")), "wrong count for absent and empty fields")
+	assert.Equal(t, float64(0), testutil.ToFloat64(second.WithLabelValues("passed", "unknown")), "default value of other action shouldn't be used")
+	assert.Equal(t, float64(0), testutil.ToFloat64(second.WithLabelValues("passed", DefaultFieldValue)), "pipeline default value shouldn't be used")
+}
+
+func TestMetricsHolderGenInterval(t *testing.T) {
+	newHolder := func(interval time.Duration) *metricsHolder {
+		registry := prometheus.NewRegistry()
+		holder := newMetricsHolder("test", registry, interval, nil)
+		holder.overflows = NewMetricsCtl("test", registry).RegisterCounter("metric_label_overflows_total", "", "metric")
+		holder.AddAction("requests", []string{"service"}, 0, 0, DefaultFieldValue)
+		holder.start()
+		return holder
+	}
+
+	fast := newHolder(time.Millisecond * 10)
+	slow := newHolder(time.Hour)
+	fastGen, slowGen := fast.metricsGen, slow.metricsGen
+
+	time.Sleep(time.Millisecond * 20)
+	fast.maintenance()
+	slow.maintenance()
+
+	assert.Equal(t, fastGen+1, fast.metricsGen, "holder with short interval should rotate metrics")
+	assert.Equal(t, slowGen, slow.metricsGen, "holder with long interval shouldn't rotate metrics")
+
+	// the interval is counted from the last rotation
+	fast.maintenance()
+	assert.Equal(t, fastGen+1, fast.metricsGen, "metrics shouldn't be rotated before the interval passes")
+}
+
+func TestMetricConstLabels(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	settings := &Settings{
+		Capacity:          16,
+		Decoder:           "json",
+		MetricConstLabels: map[string]string{"tenant": "payments", "cluster": "dc1"},
+	}
+	p := New("test", settings, registry, &http.ServeMux{})
+	p.metricsHolder.AddAction("requests", []string{"service"}, 0, 0, DefaultFieldValue)
+	p.metricsHolder.start()
+
+	root, err := insaneJSON.DecodeString(`{"service":"api"}`)
+	assert.NoError(t, err, "wrong json")
+	defer insaneJSON.Release(root)
+
+	valuesBuf := p.metricsHolder.count(&Event{Root: root, Size: 17}, 0, eventStatusPassed, nil)
+	p.metricsHolder.countDuration(0, time.Millisecond, valuesBuf)
+	p.actionResults.WithLabelValues("test", "pass").Inc()
+	p.GetMetricsCtl().RegisterGauge("queue_size", "").WithLabelValues().Set(1)
+
+	families, err := registry.Gather()
+	assert.NoError(t, err, "can't gather metrics")
+
+	names := make([]string, 0)
+	for _, family := range families {
+		names = append(names, family.GetName())
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			assert.Equal(t, "payments", labels["tenant"], "wrong tenant label of %s", family.GetName())
+			assert.Equal(t, "dc1", labels["cluster"], "wrong cluster label of %s", family.GetName())
+		}
+	}
+
+	assert.Subset(t, names, []string{
+		"file_d_pipeline_test_requests_events_count_total",
+		"file_d_pipeline_test_requests_events_size_total",
+		"file_d_pipeline_test_requests_process_duration_seconds",
+		"file_d_pipeline_test_action_results_total",
+		"file_d_pipeline_test_queue_size",
+	}, "metrics aren't scraped")
+}
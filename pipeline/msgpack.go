@@ -0,0 +1,57 @@
+package pipeline
+
+import (
+	"strconv"
+
+	"github.com/tinylib/msgp/msgp"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+const (
+	FormatJSON    = "json"
+	FormatMsgpack = "msgpack"
+)
+
+// EncodeFormat appends the event to the buffer in the format, which is one of FormatJSON or FormatMsgpack.
+func (e *Event) EncodeFormat(outBuf []byte, format string) ([]byte, int) {
+	if format == FormatMsgpack {
+		return e.EncodeMsgpack(outBuf)
+	}
+
+	return e.Encode(outBuf)
+}
+
+func appendMsgpack(out []byte, node *insaneJSON.Node) []byte {
+	switch {
+	case node.IsObject():
+		fields := node.AsFields()
+		out = msgp.AppendMapHeader(out, uint32(len(fields)))
+		for _, field := range fields {
+			out = msgp.AppendString(out, field.AsString())
+			out = appendMsgpack(out, field.AsFieldValue())
+		}
+	case node.IsArray():
+		elements := node.AsArray()
+		out = msgp.AppendArrayHeader(out, uint32(len(elements)))
+		for _, element := range elements {
+			out = appendMsgpack(out, element)
+		}
+	case node.IsString():
+		out = msgp.AppendString(out, node.AsString())
+	case node.IsNumber():
+		str := node.AsString()
+		if x, err := strconv.ParseInt(str, 10, 64); err == nil {
+			out = msgp.AppendInt64(out, x)
+		} else {
+			out = msgp.AppendFloat64(out, node.AsFloat())
+		}
+	case node.IsTrue():
+		out = msgp.AppendBool(out, true)
+	case node.IsFalse():
+		out = msgp.AppendBool(out, false)
+	default:
+		out = msgp.AppendNil(out)
+	}
+
+	return out
+}
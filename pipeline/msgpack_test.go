@@ -0,0 +1,64 @@
+package pipeline
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tinylib/msgp/msgp"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+func TestEncodeMsgpack(t *testing.T) {
+	events := []string{
+		`{}`,
+		`{"message":"hello","level":"info"}`,
+		`{"int":42,"negative":-7,"float":0.25,"big":12345678901234,"exp":1e3}`,
+		`{"ok":true,"failed":false,"empty":null}`,
+		`{"escaped":"quote \" and newline \n and unicode Ж","k\"ey":"value"}`,
+		`{"nested":{"object":{"deep":[1,"two",{"three":3},[],{}]}},"list":["a","b"]}`,
+	}
+
+	for _, json := range events {
+		root, err := insaneJSON.DecodeString(json)
+		assert.NoError(t, err, "wrong json")
+		event := &Event{Root: root}
+
+		buf := []byte("prefix")
+		buf, start := event.EncodeMsgpack(buf)
+		assert.Equal(t, len("prefix"), start, "wrong start")
+		assert.Equal(t, len(buf)-start, event.Size, "wrong event size")
+
+		decoded := &bytes.Buffer{}
+		rest, err := msgp.UnmarshalAsJSON(decoded, buf[start:])
+		assert.NoError(t, err, "can't decode msgpack for %s", json)
+		assert.Equal(t, 0, len(rest), "extra bytes after event %s", json)
+		assert.JSONEq(t, json, decoded.String(), "wrong decoded event")
+
+		insaneJSON.Release(root)
+	}
+}
+
+func TestEncodeMsgpackTypes(t *testing.T) {
+	root, err := insaneJSON.DecodeString(`{"int":42,"float":1.5,"str":"42","bool":true,"null":null}`)
+	assert.NoError(t, err, "wrong json")
+	defer insaneJSON.Release(root)
+
+	buf, _ := (&Event{Root: root}).EncodeFormat(nil, FormatMsgpack)
+
+	size, buf, err := msgp.ReadMapHeaderBytes(buf)
+	assert.NoError(t, err, "wrong map header")
+	assert.Equal(t, uint32(5), size, "wrong fields count")
+
+	types := make([]msgp.Type, 0)
+	for i := uint32(0); i < size; i++ {
+		_, buf, err = msgp.ReadStringZC(buf)
+		assert.NoError(t, err, "wrong key")
+
+		types = append(types, msgp.NextType(buf))
+		buf, err = msgp.Skip(buf)
+		assert.NoError(t, err, "wrong value")
+	}
+
+	assert.Equal(t, []msgp.Type{msgp.IntType, msgp.Float64Type, msgp.StrType, msgp.BoolType, msgp.NilType}, types, "wrong value types")
+}
@@ -0,0 +1,37 @@
+package pipeline
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	parseResultSuccess = "success"
+	parseResultFailure = "failure"
+)
+
+// ParseResult counts events parsed by a parse action, counters of all parse actions of the pipeline are in
+// the shared `parse_result_total` metric labeled by the action type and the result, so failing parsers can be spotted.
+// Events without the field to parse aren't counted. Actions which check the event root if the field isn't set,
+// e.g. parse_slog, parse_cloudtrail or parse_traefik, don't count events of other formats then.
+// parse_keyvalue parses any string, so it counts only successes.
+type ParseResult struct {
+	success prometheus.Counter
+	failure prometheus.Counter
+}
+
+func NewParseResult(metricsCtl *MetricsCtl, actionType string) *ParseResult {
+	results := metricsCtl.RegisterCounter("parse_result_total", "Events parsed by parse actions", "action", "result")
+
+	return &ParseResult{
+		success: results.WithLabelValues(actionType, parseResultSuccess),
+		failure: results.WithLabelValues(actionType, parseResultFailure),
+	}
+}
+
+func (r *ParseResult) Success() {
+	r.success.Inc()
+}
+
+func (r *ParseResult) Failure() {
+	r.failure.Inc()
+}
@@ -1,6 +1,7 @@
 package pipeline
 
 import (
+	"fmt"
 	"math/rand"
 	"net/http"
 	"runtime"
@@ -11,6 +12,7 @@ import (
 	"github.com/ozonru/file.d/decoder"
 	"github.com/ozonru/file.d/logger"
 	"github.com/prometheus/client_golang/prometheus"
+	insaneJSON "github.com/vitkovskii/insane-json"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
 )
@@ -22,10 +24,22 @@ const (
 	DefaultJSONNodePoolSize    = 1024
 	DefaultMaintenanceInterval = time.Second * 5
 	DefaultFieldValue          = "not_set"
+	OverflowFieldValue         = "<overflow>"
 	DefaultStreamName          = StreamName("not_set")
 
+	EventTypeField      = "_event_type"
+	EventTypeInputError = "input_error"
+
+	// ingestion stamp fields, they are set before actions if it's enabled in the pipeline settings
+	IngestSeqField  = "_ingest_seq"
+	IngestTimeField = "_ingest_time"
+
 	antispamUnbanIterations = 4
 	metricsGenInterval      = time.Hour
+
+	// synthetic events don't belong to any input source, so they use its own stream
+	syntheticSourceID   = SourceID(1<<64 - 1)
+	syntheticStreamName = StreamName("synthetic")
 )
 
 type finalizeFn = func(event *Event, notifyInput bool, backEvent bool)
@@ -34,11 +48,14 @@ type InputPluginController interface {
 	In(sourceID SourceID, sourceName string, offset int64, data []byte, isNewSource bool) uint64
 	DisableStreams()                      // don't use stream field and spread all events across all processors
 	SuggestDecoder(t decoder.DecoderType) // set decoder if pipeline uses "auto" value for decoder
+	InputError(err string)                // report input failure, pipeline may emit a synthetic event for it
 }
 
 type ActionPluginController interface {
-	Commit(event *Event)    // commit offset of held event and skip further processing
-	Propagate(event *Event) // throw held event back to pipeline
+	Commit(event *Event)                    // commit offset of held event and skip further processing
+	Propagate(event *Event)                 // throw held event back to pipeline
+	Spawn(parent *Event, json []byte) error // create new event of parent's source and pass it through next actions after the parent
+	Emit(json []byte) error                 // create new synthetic event and pass it through next actions, it may be called outside of Do
 }
 
 type OutputPluginController interface {
@@ -78,6 +95,21 @@ type Pipeline struct {
 
 	metricsHolder *metricsHolder
 
+	// actionResults counts values returned by actions, the action label is "<index>_<type>"
+	actionResults *prometheus.CounterVec
+
+	// per source volume metrics, they are nil if source metrics are disabled
+	sourceBytes *prometheus.CounterVec
+	sourceLines *prometheus.CounterVec
+
+	// duplicateKeys counts events rejected because of duplicate root keys, it's nil if the rejection is disabled
+	duplicateKeys prometheus.Counter
+
+	// discardAuditor records discarded events, it's nil if the audit is disabled
+	discardAuditor *discardAuditor
+
+	ingestSeq atomic.Uint64
+
 	// some debugging shit
 	logger          *zap.SugaredLogger
 	eventLogEnabled bool
@@ -98,6 +130,15 @@ type Settings struct {
 	AvgLogSize          int
 	StreamField         string
 	IsStrict            bool
+	StageLatency        bool
+	DryRun              bool
+	InputErrorEvents    bool
+	SourceMetrics       bool
+	IngestStamp         bool
+	RecordFormat        string
+	RejectDuplicateKeys bool
+	DiscardAuditFile    string            // file to append audit records of discarded events to, the audit is disabled if it's empty
+	MetricConstLabels   map[string]string // labels added to all metrics of the pipeline, e.g. tenant or cluster
 }
 
 func New(name string, settings *Settings, registry *prometheus.Registry, mux *http.ServeMux) *Pipeline {
@@ -109,9 +150,10 @@ func New(name string, settings *Settings, registry *prometheus.Registry, mux *ht
 		actionParams: &PluginDefaultParams{
 			PipelineName:     name,
 			PipelineSettings: settings,
+			MetricsCtl:       NewMetricsCtlWithLabels(name, registry, settings.MetricConstLabels),
 		},
 
-		metricsHolder: newMetricsHolder(name, registry, metricsGenInterval),
+		metricsHolder: newMetricsHolder(name, registry, metricsGenInterval, settings.MetricConstLabels),
 		streamer:      newStreamer(),
 		eventPool:     newEventPool(settings.Capacity),
 		antispamer:    newAntispamer(settings.AntispamThreshold, antispamUnbanIterations, settings.MaintenanceInterval),
@@ -135,6 +177,33 @@ func New(name string, settings *Settings, registry *prometheus.Registry, mux *ht
 		pipeline.logger.Fatalf("unknown decoder %q for pipeline %q", settings.Decoder, name)
 	}
 
+	if _, has := settings.MetricConstLabels["gen"]; has {
+		pipeline.logger.Fatalf("metric const label \"gen\" is reserved for pipeline %q", name)
+	}
+
+	metricsCtl := pipeline.actionParams.MetricsCtl
+	pipeline.actionResults = metricsCtl.RegisterCounter("action_results_total", "Results returned by actions", "action", "result")
+	pipeline.metricsHolder.overflows = metricsCtl.RegisterCounter("metric_label_overflows_total", "Label values replaced with the overflow value because of metric_max_label_values", "metric")
+
+	switch settings.RecordFormat {
+	case "", RecordFormatSingle, RecordFormatBatchArray, RecordFormatNDJSON:
+	default:
+		pipeline.logger.Fatalf("unknown record format %q for pipeline %q", settings.RecordFormat, name)
+	}
+
+	if settings.SourceMetrics {
+		pipeline.sourceBytes = metricsCtl.RegisterCounter("bytes_total", "Bytes read by the input per source", "input", "source")
+		pipeline.sourceLines = metricsCtl.RegisterCounter("lines_total", "Lines read by the input per source", "input", "source")
+	}
+
+	if settings.RejectDuplicateKeys {
+		pipeline.duplicateKeys = metricsCtl.RegisterCounter("duplicate_keys_events_total", "Events rejected because of duplicate root keys").WithLabelValues()
+	}
+
+	if settings.DiscardAuditFile != "" {
+		pipeline.discardAuditor = newDiscardAuditor(name, settings.DiscardAuditFile, pipeline.logger)
+	}
+
 	mux.HandleFunc("/pipelines/"+name, pipeline.servePipeline)
 
 	return pipeline
@@ -148,6 +217,15 @@ func (p *Pipeline) Start() {
 		p.logger.Panicf("output isn't set for pipeline %q", p.Name)
 	}
 
+	if p.settings.DryRun {
+		p.logger.Warnf("pipeline %q is in dry run mode, events will be logged instead of sending to %q output", p.Name, p.outputInfo.Type)
+		p.output = &dryRunOutput{outputType: p.outputInfo.Type}
+	}
+
+	if p.discardAuditor != nil {
+		p.discardAuditor.start()
+	}
+
 	p.initProcs()
 	p.metricsHolder.start()
 
@@ -194,6 +272,10 @@ func (p *Pipeline) Stop() {
 	p.logger.Infof("stopping %q output", p.Name)
 	p.output.Stop()
 
+	if p.discardAuditor != nil {
+		p.discardAuditor.stop()
+	}
+
 	p.shouldStop = true
 }
 
@@ -211,11 +293,32 @@ func (p *Pipeline) SetOutput(info *OutputPluginInfo) {
 	p.output = info.Plugin.(OutputPlugin)
 }
 
+// GetOutput returns the output plugin from the config, even if the pipeline is in dry run mode.
 func (p *Pipeline) GetOutput() OutputPlugin {
-	return p.output
+	return p.outputInfo.Plugin.(OutputPlugin)
 }
 
 func (p *Pipeline) In(sourceID SourceID, sourceName string, offset int64, bytes []byte, isNewSource bool) uint64 {
+	var records [][]byte
+	switch p.settings.RecordFormat {
+	case RecordFormatBatchArray:
+		records = splitJSONArray(bytes)
+	case RecordFormatNDJSON:
+		records = splitNDJSON(bytes)
+	default:
+		return p.in(sourceID, sourceName, offset, bytes, isNewSource, false)
+	}
+
+	// all events of the record have the same offset, so the record is committed only with its last event
+	seqID := uint64(0)
+	for i, record := range records {
+		seqID = p.in(sourceID, sourceName, offset, record, isNewSource && i == 0, i < len(records)-1)
+	}
+
+	return seqID
+}
+
+func (p *Pipeline) in(sourceID SourceID, sourceName string, offset int64, bytes []byte, isNewSource bool, partial bool) uint64 {
 	length := len(bytes)
 
 	// don't process shit
@@ -244,9 +347,24 @@ func (p *Pipeline) In(sourceID SourceID, sourceName string, offset int64, bytes
 			p.logger.Fatalf("wrong json format offset=%d, length=%d, err=%s, source=%d:%s, json=%s", offset, length, err.Error(), sourceID, sourceName, bytes)
 			return 0
 		}
+		if p.settings.RejectDuplicateKeys {
+			if key := duplicateKey(event.Root.Node); key != "" {
+				p.duplicateKeys.Inc()
+				if p.discardAuditor != nil {
+					p.discardAuditor.audit(event, discardReasonDuplicateKeys, "")
+				}
+				p.eventPool.back(event)
+				p.InputError(fmt.Sprintf("event has duplicate key %q, offset=%d, source=%d:%s", key, offset, sourceID, sourceName))
+				return 0
+			}
+		}
 	case decoder.RAW:
 		_ = event.Root.DecodeString("{}")
-		event.Root.AddFieldNoAlloc(event.Root, "message").MutateToBytesCopy(event.Root, bytes[:len(bytes)-1])
+		message := bytes
+		if message[len(message)-1] == '\n' {
+			message = message[:len(message)-1]
+		}
+		event.Root.AddFieldNoAlloc(event.Root, "message").MutateToBytesCopy(event.Root, message)
 	case decoder.CRI:
 		_ = event.Root.DecodeString("{}")
 		err := decoder.DecodeCRI(event.Root, bytes)
@@ -266,10 +384,23 @@ func (p *Pipeline) In(sourceID SourceID, sourceName string, offset int64, bytes
 	}
 
 	event.Offset = offset
+	event.partial = partial
 	event.SourceID = sourceID
 	event.SourceName = sourceName
 	event.streamName = DefaultStreamName
 	event.Size = len(bytes)
+	if p.settings.StageLatency {
+		event.createdAt = time.Now()
+	}
+
+	if p.settings.IngestStamp {
+		p.stampIngestion(event)
+	}
+
+	if p.settings.SourceMetrics {
+		p.sourceBytes.WithLabelValues(p.inputInfo.Type, sourceName).Add(float64(length))
+		p.sourceLines.WithLabelValues(p.inputInfo.Type, sourceName).Inc()
+	}
 
 	if len(p.inSample) == 0 {
 		p.inSample = event.Root.Encode(p.inSample)
@@ -278,6 +409,32 @@ func (p *Pipeline) In(sourceID SourceID, sourceName string, offset int64, bytes
 	return p.streamEvent(event)
 }
 
+// duplicateKey returns the first root key which occurs in the event more than once,
+// decoder keeps all fields, so duplicates are found by comparing field names
+func duplicateKey(root *insaneJSON.Node) string {
+	fields := root.AsFields()
+	// events usually have few fields, so comparing all pairs is cheaper than allocating a set
+	for i := 1; i < len(fields); i++ {
+		name := fields[i].AsString()
+		for j := 0; j < i; j++ {
+			if fields[j].AsString() == name {
+				return name
+			}
+		}
+	}
+
+	return ""
+}
+
+// stampIngestion sets the monotonic sequence number and the time of the event ingestion
+func (p *Pipeline) stampIngestion(event *Event) {
+	event.Root.AddFieldNoAlloc(event.Root, IngestSeqField).MutateToInt(int(p.ingestSeq.Inc()))
+
+	l := len(event.Buf)
+	event.Buf = time.Now().AppendFormat(event.Buf, time.RFC3339Nano)
+	event.Root.AddFieldNoAlloc(event.Root, IngestTimeField).MutateToBytes(event.Buf[l:])
+}
+
 func (p *Pipeline) streamEvent(event *Event) uint64 {
 	// spread events across all processors
 	if !p.useStreams {
@@ -294,6 +451,33 @@ func (p *Pipeline) streamEvent(event *Event) uint64 {
 	return p.streamer.putEvent(event.SourceID, event.streamName, event)
 }
 
+// InputError logs input failure and emits the synthetic event if it's enabled in the pipeline settings:
+// {"_event_type":"input_error","pipeline":"name","input":"type","error":"text"}
+func (p *Pipeline) InputError(err string) {
+	p.logger.Errorf("input %q error: %s", p.inputInfo.Type, err)
+
+	if !p.settings.InputErrorEvents {
+		return
+	}
+
+	event := p.eventPool.get()
+	_ = event.Root.DecodeString("{}")
+	event.Root.AddFieldNoAlloc(event.Root, EventTypeField).MutateToString(EventTypeInputError)
+	event.Root.AddFieldNoAlloc(event.Root, "pipeline").MutateToString(p.Name)
+	event.Root.AddFieldNoAlloc(event.Root, "input").MutateToString(p.inputInfo.Type)
+	event.Root.AddFieldNoAlloc(event.Root, "error").MutateToString(err)
+
+	event.synthetic = true
+	event.SourceID = syntheticSourceID
+	event.SourceName = EventTypeInputError
+	event.streamName = syntheticStreamName
+	if p.settings.StageLatency {
+		event.createdAt = time.Now()
+	}
+
+	p.streamer.putEvent(syntheticSourceID, syntheticStreamName, event)
+}
+
 func (p *Pipeline) Commit(event *Event) {
 	p.finalize(event, true, true)
 }
@@ -312,7 +496,9 @@ func (p *Pipeline) finalize(event *Event, notifyInput bool, backEvent bool) {
 	}
 
 	if notifyInput {
-		p.input.Commit(event)
+		if !event.synthetic && !event.spawned && !event.partial {
+			p.input.Commit(event)
+		}
 
 		p.totalCommitted.Inc()
 		p.totalSize.Add(int64(event.Size))
@@ -339,12 +525,21 @@ func (p *Pipeline) finalize(event *Event, notifyInput bool, backEvent bool) {
 		p.eventLogMu.Unlock()
 	}
 
+	// spawned events don't belong to the pool
+	if event.spawned {
+		return
+	}
+
 	p.eventPool.back(event)
 }
 
 func (p *Pipeline) AddAction(info *ActionPluginStaticInfo) {
 	p.actionInfos = append(p.actionInfos, info)
-	p.metricsHolder.AddAction(info.MetricName, info.MetricLabels)
+	defaultValue := DefaultFieldValue
+	if info.MetricDefaultValue != nil {
+		defaultValue = *info.MetricDefaultValue
+	}
+	p.metricsHolder.AddAction(info.MetricName, info.MetricLabels, info.MetricMaxLabelValues, info.MetricMaxLabelLength, defaultValue)
 }
 
 func (p *Pipeline) initProcs() {
@@ -365,7 +560,7 @@ func (p *Pipeline) initProcs() {
 }
 
 func (p *Pipeline) newProc() *processor {
-	proc := NewProcessor(p.metricsHolder, p.activeProcs, p.output, p.streamer, p.finalize)
+	proc := NewProcessor(p.metricsHolder, p.actionResults, p.discardAuditor, p.activeProcs, p.output, p.streamer, p.finalize)
 	for j, info := range p.actionInfos {
 		plugin, _ := info.Factory()
 		proc.AddActionPlugin(&ActionPluginInfo{
@@ -475,6 +670,10 @@ func (p *Pipeline) DisableParallelism() {
 	p.singleProc = true
 }
 
+func (p *Pipeline) GetMetricsCtl() *MetricsCtl {
+	return p.actionParams.MetricsCtl
+}
+
 func (p *Pipeline) GetEventsTotal() int {
 	return int(p.totalCommitted.Load())
 }
@@ -35,6 +35,7 @@ type OutputPlugin interface {
 type PluginDefaultParams struct {
 	PipelineName     string
 	PipelineSettings *Settings
+	MetricsCtl       *MetricsCtl
 }
 
 type ActionPluginParams struct {
@@ -80,6 +81,20 @@ type ActionPluginStaticInfo struct {
 	MatchConditions MatchConditions
 	MatchMode       MatchMode
 	MatchInvert     bool
+
+	// MatchCacheField is the event field which value is the key of cached match results,
+	// so conditions are checked once per key, the value should determine values of the match fields
+	MatchCacheField string
+	// MatchCacheSize is the maximum number of cached keys, the cache is cleared when it's reached
+	MatchCacheSize int
+
+	// MetricMaxLabelValues limits distinct values of each metric label, other values are replaced with OverflowFieldValue,
+	// zero means no limit
+	MetricMaxLabelValues int
+	// MetricMaxLabelLength is the maximum length of metric label values in bytes, longer values are truncated
+	MetricMaxLabelLength int
+	// MetricDefaultValue is the metric label value for absent fields, DefaultFieldValue is used if it's nil
+	MetricDefaultValue *string
 }
 
 type ActionPluginInfo struct {
@@ -112,4 +127,7 @@ const (
 	MatchModeAnd     MatchMode = 0
 	MatchModeOr      MatchMode = 1
 	MatchModeUnknown MatchMode = 2
+	// prefix modes are the same as and/or, but condition values match values starting with them
+	MatchModeAndPrefix MatchMode = 3
+	MatchModeOrPrefix  MatchMode = 4
 )
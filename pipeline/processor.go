@@ -1,7 +1,11 @@
 package pipeline
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/ozonru/file.d/logger"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
 )
@@ -23,6 +27,14 @@ const (
 	ActionHold ActionResult = 3
 )
 
+// actionResultNames are values of the result label of the action results metric, indexed by ActionResult
+var actionResultNames = []string{
+	ActionPass:     "pass",
+	ActionDiscard:  "discard",
+	ActionCollapse: "collapse",
+	ActionHold:     "hold",
+}
+
 type eventStatus string
 
 const (
@@ -34,6 +46,11 @@ const (
 	eventStatusHold       eventStatus = "held"
 )
 
+// stageLatencyField is an object which collects milliseconds passed from the event ingestion to the end of each action
+const stageLatencyField = "_stage_latency_ms"
+
+const defaultMatchCacheSize = 10000
+
 // processor is a goroutine which doing pipeline actions
 type processor struct {
 	id            int
@@ -49,19 +66,42 @@ type processor struct {
 	busyActions      []bool
 	busyActionsTotal int
 
+	// matchCaches keep match results by the value of MatchCacheField, it's nil for actions without the cache
+	matchCaches []map[string]bool
+
+	// spawned are events created by Spawn, they are processed after the parent as next events of the stream
+	spawned []*Event
+
 	heartbeatCh   chan *stream
 	metricsValues []string
+	// actionDuration is the time spent by the last action, it's measured only if metrics are enabled for the action
+	actionDuration time.Duration
+
+	// actionResults counts values returned by Do of each action, counters are resolved once per action and result
+	actionResults      *prometheus.CounterVec
+	actionResultsCount [][]prometheus.Counter
+
+	// discardAuditor records events discarded by actions, it's nil if the audit is disabled
+	discardAuditor *discardAuditor
+
+	stageLatency bool
+	stageNames   []string
+
+	dryRun bool
+	logger *zap.SugaredLogger
 }
 
 var id = 0
 
-func NewProcessor(metricsHolder *metricsHolder, activeCounter *atomic.Int32, output OutputPlugin, streamer *streamer, finalizeFn finalizeFn) *processor {
+func NewProcessor(metricsHolder *metricsHolder, actionResults *prometheus.CounterVec, discardAuditor *discardAuditor, activeCounter *atomic.Int32, output OutputPlugin, streamer *streamer, finalizeFn finalizeFn) *processor {
 	processor := &processor{
-		id:            id,
-		streamer:      streamer,
-		metricsHolder: metricsHolder,
-		output:        output,
-		finalize:      finalizeFn,
+		id:             id,
+		streamer:       streamer,
+		metricsHolder:  metricsHolder,
+		actionResults:  actionResults,
+		discardAuditor: discardAuditor,
+		output:         output,
+		finalize:       finalizeFn,
 
 		activeCounter: activeCounter,
 
@@ -74,11 +114,15 @@ func NewProcessor(metricsHolder *metricsHolder, activeCounter *atomic.Int32, out
 }
 
 func (p *processor) start(params *PluginDefaultParams, logger *zap.SugaredLogger) {
+	p.stageLatency = params.PipelineSettings.StageLatency
+	p.dryRun = params.PipelineSettings.DryRun
+	p.logger = logger
+
 	for i, action := range p.actions {
 		actionInfo := p.actionInfos[i]
 		action.Start(actionInfo.PluginStaticInfo.Config, &ActionPluginParams{
 			PluginDefaultParams: params,
-			Controller:          p,
+			Controller:          &actionController{processor: p, index: i},
 			Logger:              logger.Named("action").Named(actionInfo.Type),
 		})
 	}
@@ -101,17 +145,35 @@ func (p *processor) process() {
 
 func (p *processor) dischargeStream(st *stream) {
 	for {
-		event := st.instantGet()
+		event := p.popSpawned()
+		if event == nil {
+			event = st.instantGet()
+		}
 		// if event is nil then stream is over, so let's attach to a new stream
 		if event == nil {
 			return
 		}
 		if !p.processSequence(event) {
+			// processor is unlocked, so spawned events are lost along with the rest of the stream
+			p.spawned = p.spawned[:0]
 			return
 		}
 	}
 }
 
+func (p *processor) popSpawned() *Event {
+	if len(p.spawned) == 0 {
+		return nil
+	}
+
+	event := p.spawned[0]
+	copy(p.spawned, p.spawned[1:])
+	p.spawned[len(p.spawned)-1] = nil
+	p.spawned = p.spawned[:len(p.spawned)-1]
+
+	return event
+}
+
 func (p *processor) processSequence(event *Event) bool {
 	isSuccess := false
 	isPassed := false
@@ -145,9 +207,12 @@ func (p *processor) processEvent(event *Event) (isSuccess bool, isPassed bool, e
 			return true, false, nil
 		}
 
-		// there is busy action, waiting for next sequential event
+		// there is busy action, waiting for next sequential event, spawned events are the next ones
 		action := event.action
-		event = stream.blockGet()
+		event = p.popSpawned()
+		if event == nil {
+			event = stream.blockGet()
+		}
 		if event.IsTimeoutKind() {
 			// pass timeout directly to plugin which requested next sequential event
 			event.action = action
@@ -172,24 +237,41 @@ func (p *processor) doActions(event *Event) (isPassed bool) {
 			continue
 		}
 
-		switch action.Do(event) {
+		// time is measured only if metrics are enabled for the action
+		var start time.Time
+		if p.metricsHolder.isCounted(index) {
+			start = time.Now()
+		}
+		result := action.Do(event)
+		if !start.IsZero() {
+			p.actionDuration = time.Since(start)
+		}
+		p.countResult(index, result)
+
+		switch result {
 		case ActionPass:
-			p.countEvent(event, index, eventStatusPassed)
+			p.countDone(event, index, eventStatusPassed)
 			p.tryResetBusy(index)
+			if p.stageLatency {
+				p.stampLatency(event, index)
+			}
 		case ActionDiscard:
-			p.countEvent(event, index, eventStatusDiscarded)
+			p.countDone(event, index, eventStatusDiscarded)
 			p.tryResetBusy(index)
+			if p.discardAuditor != nil {
+				p.discardAuditor.audit(event, discardReasonAction, p.stageNames[index])
+			}
 			// can't notify input here, because previous events may delay and we'll get offset sequence corruption
 			p.finalize(event, false, true)
 			return false
 		case ActionCollapse:
-			p.countEvent(event, index, eventStatusCollapse)
+			p.countDone(event, index, eventStatusCollapse)
 			p.tryMarkBusy(index)
 			// can't notify input here, because previous events may delay and we'll get offset sequence corruption
 			p.finalize(event, false, true)
 			return false
 		case ActionHold:
-			p.countEvent(event, index, eventStatusHold)
+			p.countDone(event, index, eventStatusHold)
 			p.tryMarkBusy(index)
 
 			p.finalize(event, false, false)
@@ -224,10 +306,40 @@ func (p *processor) tryResetBusy(index int) {
 	}
 }
 
+func (p *processor) stampLatency(event *Event, actionIndex int) {
+	if !event.IsRegularKind() {
+		return
+	}
+
+	node := event.Root.Dig(stageLatencyField)
+	if node == nil {
+		node = event.Root.AddFieldNoAlloc(event.Root, stageLatencyField).MutateToObject()
+	}
+
+	latency := float64(time.Since(event.createdAt)) / float64(time.Millisecond)
+	node.AddFieldNoAlloc(event.Root, p.stageNames[actionIndex]).MutateToFloat(latency)
+}
+
 func (p *processor) countEvent(event *Event, actionIndex int, status eventStatus) {
+	if p.dryRun && status != eventStatusReceived && event.IsRegularKind() {
+		p.logger.Infof("dry run: action #%d %q %s event: %s", actionIndex, p.actionInfos[actionIndex].Type, status, event.Root.EncodeToString())
+	}
 	p.metricsValues = p.metricsHolder.count(event, actionIndex, status, p.metricsValues)
 }
 
+// countDone counts the event processed by the action along with the time spent by the action
+func (p *processor) countDone(event *Event, actionIndex int, status eventStatus) {
+	p.countEvent(event, actionIndex, status)
+	p.metricsHolder.countDuration(actionIndex, p.actionDuration, p.metricsValues)
+}
+
+func (p *processor) countResult(actionIndex int, result ActionResult) {
+	if int(result) >= len(actionResultNames) {
+		return
+	}
+	p.actionResultsCount[actionIndex][result].Inc()
+}
+
 func (p *processor) isMatch(index int, event *Event) bool {
 	if event.IsTimeoutKind() {
 		return true
@@ -238,59 +350,39 @@ func (p *processor) isMatch(index int, event *Event) bool {
 	}
 
 	info := p.actionInfos[index]
-	conds := info.MatchConditions
-	mode := info.MatchMode
-
-	if mode == MatchModeOr {
-		return p.isMatchOr(conds, event)
-	} else {
-		return p.isMatchAnd(conds, event)
+	cache := p.matchCaches[index]
+	if cache == nil {
+		return p.isMatchConds(info, event)
 	}
-}
-
-func (p *processor) isMatchOr(conds MatchConditions, event *Event) bool {
-	for _, cond := range conds {
-		node := event.Root.Dig(cond.Field)
-		if node == nil {
-			continue
-		}
-		value := node.AsString()
-		match := false
-		if cond.Regexp != nil {
-			match = cond.Regexp.MatchString(value)
-		} else {
-			match = value == cond.Value
-		}
 
-		if match {
-			return true
-		}
+	// events without the key aren't cached
+	node := event.Root.Dig(info.MatchCacheField)
+	if node == nil {
+		return p.isMatchConds(info, event)
 	}
 
-	return false
-}
-
-func (p *processor) isMatchAnd(conds MatchConditions, event *Event) bool {
-	for _, cond := range conds {
-		node := event.Root.Dig(cond.Field)
-		if node == nil {
-			return false
-		}
-		value := node.AsString()
+	if match, has := cache[node.AsString()]; has {
+		return match
+	}
 
-		match := false
-		if cond.Regexp != nil {
-			match = cond.Regexp.MatchString(value)
-		} else {
-			match = value == cond.Value
-		}
+	match := p.isMatchConds(info, event)
 
-		if !match {
-			return false
-		}
+	size := info.MatchCacheSize
+	if size <= 0 {
+		size = defaultMatchCacheSize
+	}
+	if len(cache) >= size {
+		cache = make(map[string]bool)
+		p.matchCaches[index] = cache
 	}
+	// the value points to the event memory, so it's copied
+	cache[string([]byte(node.AsString()))] = match
 
-	return true
+	return match
+}
+
+func (p *processor) isMatchConds(info *ActionPluginStaticInfo, event *Event) bool {
+	return info.MatchConditions.Match(event.Root.Node, info.MatchMode)
 }
 
 func (p *processor) stop() {
@@ -305,6 +397,20 @@ func (p *processor) AddActionPlugin(info *ActionPluginInfo) {
 	p.actions = append(p.actions, info.Plugin.(ActionPlugin))
 	p.actionInfos = append(p.actionInfos, info.ActionPluginStaticInfo)
 	p.busyActions = append(p.busyActions, false)
+
+	var cache map[string]bool
+	if info.MatchCacheField != "" {
+		cache = make(map[string]bool)
+	}
+	p.matchCaches = append(p.matchCaches, cache)
+
+	p.stageNames = append(p.stageNames, strconv.Itoa(len(p.stageNames))+"_"+info.Type)
+
+	counters := make([]prometheus.Counter, len(actionResultNames))
+	for result, name := range actionResultNames {
+		counters[result] = p.actionResults.WithLabelValues(p.stageNames[len(p.stageNames)-1], name)
+	}
+	p.actionResultsCount = append(p.actionResultsCount, counters)
 }
 
 func (p *processor) Commit(event *Event) {
@@ -315,3 +421,62 @@ func (p *processor) Propagate(event *Event) {
 	event.action++
 	p.processSequence(event)
 }
+
+// Spawn creates the event from the json, it's passed through the actions following the current action of the parent
+// once the parent is processed, so the spawned event goes to the output after the parent. Spawned events aren't processed
+// inside Do of the parent, so actions may hold or collapse them as any other events of the stream.
+// It has the source and the offset of the parent, but the input isn't notified about its commit,
+// because the offset is committed along with the parent.
+func (p *processor) Spawn(parent *Event, json []byte) error {
+	event := newEvent()
+	if err := event.parseJSON(json); err != nil {
+		return err
+	}
+
+	event.spawned = true
+	event.stage = eventStageProcessor
+	event.Offset = parent.Offset
+	event.SourceID = parent.SourceID
+	event.SourceName = parent.SourceName
+	event.streamName = parent.streamName
+	event.stream = parent.stream
+	event.createdAt = parent.createdAt
+	event.Size = len(json)
+	event.action = parent.action + 1
+
+	p.spawned = append(p.spawned, event)
+
+	return nil
+}
+
+// actionController is the controller which is passed to the action, it knows the index of the action,
+// so events emitted by the action go through the actions following it
+type actionController struct {
+	*processor
+	index int
+}
+
+// Emit creates the synthetic event from the json and passes it to the actions following the action through the synthetic stream,
+// so unlike Spawn it doesn't need the parent event and may be called outside of Do, e.g. by the timer of the action.
+func (c *actionController) Emit(json []byte) error {
+	event := newEvent()
+	if err := event.parseJSON(json); err != nil {
+		return err
+	}
+
+	// emitted events don't belong to the pool too
+	event.spawned = true
+	event.synthetic = true
+	event.action = c.index + 1
+	event.SourceID = syntheticSourceID
+	event.SourceName = "emitted"
+	event.streamName = syntheticStreamName
+	event.Size = len(json)
+	if c.stageLatency {
+		event.createdAt = time.Now()
+	}
+
+	c.streamer.putEvent(syntheticSourceID, syntheticStreamName, event)
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package pipeline_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+type sleepPlugin struct {
+}
+
+func (p *sleepPlugin) Start(_ pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+}
+
+func (p *sleepPlugin) Stop() {
+}
+
+func (p *sleepPlugin) Do(_ *pipeline.Event) pipeline.ActionResult {
+	time.Sleep(time.Millisecond * 5)
+	return pipeline.ActionPass
+}
+
+func sleepFactory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &sleepPlugin{}, nil
+}
+
+func TestStageLatency(t *testing.T) {
+	actions := append(
+		test.NewActionPluginStaticInfo(sleepFactory, nil, pipeline.MatchModeAnd, nil, false),
+		test.NewActionPluginStaticInfo(sleepFactory, nil, pipeline.MatchModeAnd, nil, false)...,
+	)
+
+	settings := test.NewSettings()
+	settings.StageLatency = true
+	p, input, output := test.NewPipelineMockWithSettings(actions, settings)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"message":"hello"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 1, len(outEvents), "wrong out events count")
+
+	first := outEvents[0].Root.Dig("_stage_latency_ms", "0_test_plugin")
+	second := outEvents[0].Root.Dig("_stage_latency_ms", "1_test_plugin")
+	assert.NotNil(t, first, "no latency for the first stage")
+	assert.NotNil(t, second, "no latency for the second stage")
+	assert.True(t, first.AsFloat() >= 5, "wrong latency for the first stage")
+	assert.True(t, second.AsFloat() >= first.AsFloat()+5, "latency doesn't grow across stages")
+}
+
+func TestStageLatencyDisabled(t *testing.T) {
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(sleepFactory, nil, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"message":"hello"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, `{"message":"hello"}`, outEvents[0].Root.EncodeToString(), "wrong out event")
+}
@@ -0,0 +1,107 @@
+package pipeline
+
+const (
+	// RecordFormatSingle means that the input record is a single event
+	RecordFormatSingle = "single"
+	// RecordFormatBatchArray means that the input record is a JSON array of events
+	RecordFormatBatchArray = "batch_array"
+	// RecordFormatNDJSON means that the input record is newline delimited events
+	RecordFormatNDJSON = "ndjson"
+)
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func trimSpace(bytes []byte) []byte {
+	for len(bytes) > 0 && isSpace(bytes[0]) {
+		bytes = bytes[1:]
+	}
+	for len(bytes) > 0 && isSpace(bytes[len(bytes)-1]) {
+		bytes = bytes[:len(bytes)-1]
+	}
+
+	return bytes
+}
+
+// splitJSONArray returns top level elements of the JSON array,
+// the record is returned as is if it isn't an array or the array is malformed,
+// so the decoder reports the error
+func splitJSONArray(record []byte) [][]byte {
+	trimmed := trimSpace(record)
+	if len(trimmed) < 2 || trimmed[0] != '[' || trimmed[len(trimmed)-1] != ']' {
+		return [][]byte{record}
+	}
+
+	parts := make([][]byte, 0)
+	depth := 0
+	inString := false
+	start := 1
+	for i := 1; i < len(trimmed)-1; i++ {
+		c := trimmed[i]
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, trimSpace(trimmed[start:i]))
+				start = i + 1
+			}
+		}
+	}
+
+	if depth != 0 || inString {
+		return [][]byte{record}
+	}
+
+	last := trimSpace(trimmed[start : len(trimmed)-1])
+	if len(last) == 0 && len(parts) == 0 {
+		// empty array
+		return parts
+	}
+	parts = append(parts, last)
+
+	for _, part := range parts {
+		if len(part) == 0 {
+			return [][]byte{record}
+		}
+	}
+
+	return parts
+}
+
+// splitNDJSON returns lines of the record with trailing newlines, blank lines are skipped
+func splitNDJSON(record []byte) [][]byte {
+	parts := make([][]byte, 0)
+	start := 0
+	for i := 0; i <= len(record); i++ {
+		if i < len(record) && record[i] != '\n' {
+			continue
+		}
+
+		end := i
+		if i < len(record) {
+			end++
+		}
+		if len(trimSpace(record[start:end])) != 0 {
+			parts = append(parts, record[start:end])
+		}
+		start = i + 1
+	}
+
+	return parts
+}
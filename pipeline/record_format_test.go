@@ -0,0 +1,86 @@
+package pipeline_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func runRecordFormat(t *testing.T, format string, records []string, count int) []string {
+	return runRecordFormatDecoder(t, format, "json", records, count)
+}
+
+func runRecordFormatDecoder(t *testing.T, format string, decoder string, records []string, count int) []string {
+	settings := test.NewSettings()
+	settings.RecordFormat = format
+	settings.Decoder = decoder
+	p, input, output := test.NewPipelineMockWithSettings(nil, settings)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(count)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, record := range records {
+		input.In(0, "test.log", 0, []byte(record))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+func TestRecordFormatSingle(t *testing.T) {
+	outEvents := runRecordFormat(t, pipeline.RecordFormatSingle, []string{`{"message":"a"}`, `{"message":"b"}`}, 2)
+
+	assert.Equal(t, []string{`{"message":"a"}`, `{"message":"b"}`}, outEvents, "wrong out events")
+}
+
+func TestRecordFormatBatchArray(t *testing.T) {
+	records := []string{
+		`[{"message":"a"},{"message":"b"}]`,
+		"  [\n\t{\"message\":\"c, [d]\"} ,\r\n  {\"nested\":{\"list\":[1,2,{\"x\":\"}\"}]}}\n]\n",
+		`[]`,
+		` [ ] `,
+		`{"message":"not an array"}`,
+		`[{"message":"escaped \" quote, ]"}]`,
+	}
+	outEvents := runRecordFormat(t, pipeline.RecordFormatBatchArray, records, 6)
+
+	assert.Equal(t, []string{
+		`{"message":"a"}`,
+		`{"message":"b"}`,
+		`{"message":"c, [d]"}`,
+		`{"nested":{"list":[1,2,{"x":"}"}]}}`,
+		`{"message":"not an array"}`,
+		`{"message":"escaped \" quote, ]"}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestRecordFormatNDJSON(t *testing.T) {
+	records := []string{
+		"{\"message\":\"a\"}\n{\"message\":\"b\"}\n",
+		"\n  \n{\"message\":\"c\"}\r\n\t\n{\"message\":\"d\"}",
+		" \n ",
+	}
+	outEvents := runRecordFormat(t, pipeline.RecordFormatNDJSON, records, 4)
+
+	assert.Equal(t, []string{`{"message":"a"}`, `{"message":"b"}`, `{"message":"c"}`, `{"message":"d"}`}, outEvents, "wrong out events")
+}
+
+func TestRecordFormatNDJSONRaw(t *testing.T) {
+	records := []string{
+		"first\nsecond\nthird",
+	}
+	outEvents := runRecordFormatDecoder(t, pipeline.RecordFormatNDJSON, "raw", records, 3)
+
+	assert.Equal(t, []string{`{"message":"first"}`, `{"message":"second"}`, `{"message":"third"}`}, outEvents, "wrong out events")
+}
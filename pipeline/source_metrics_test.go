@@ -0,0 +1,44 @@
+package pipeline_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceMetrics(t *testing.T) {
+	settings := test.NewSettings()
+	settings.SourceMetrics = true
+	p, input, output := test.NewPipelineMockWithSettings(nil, settings)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	output.SetOutFn(func(e *pipeline.Event) {
+		wg.Done()
+	})
+
+	first := []byte(`{"message":"hello"}` + "\n")
+	second := []byte(`{"message":"hello again"}` + "\n")
+	third := []byte(`{"message":"hi"}` + "\n")
+
+	input.In(0, "first.log", 0, first)
+	input.In(0, "first.log", int64(len(first)), second)
+	input.In(1, "second.log", 0, third)
+
+	wg.Wait()
+	p.Stop()
+
+	metricsCtl := p.GetMetricsCtl()
+	bytes := metricsCtl.RegisterCounter("bytes_total", "")
+	lines := metricsCtl.RegisterCounter("lines_total", "")
+
+	assert.Equal(t, float64(len(first)+len(second)), testutil.ToFloat64(bytes.WithLabelValues("fake", "first.log")), "wrong bytes metric")
+	assert.Equal(t, float64(len(third)), testutil.ToFloat64(bytes.WithLabelValues("fake", "second.log")), "wrong bytes metric")
+	assert.Equal(t, float64(2), testutil.ToFloat64(lines.WithLabelValues("fake", "first.log")), "wrong lines metric")
+	assert.Equal(t, float64(1), testutil.ToFloat64(lines.WithLabelValues("fake", "second.log")), "wrong lines metric")
+}
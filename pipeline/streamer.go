@@ -93,15 +93,16 @@ func (s *streamer) makeCharged(stream *stream) {
 	s.chargedMu.Unlock()
 }
 
-// nil means that streamer is stopping
+// nil means that streamer is stopping, charged streams are joined even while stopping,
+// because events may be put while processors are stopping, e.g. by actions on their stop
 func (s *streamer) joinStream() *stream {
 	s.chargedMu.Lock()
 	for len(s.charged) == 0 {
-		s.chargedCond.Wait()
 		if s.shouldStop {
 			s.chargedMu.Unlock()
 			return nil
 		}
+		s.chargedCond.Wait()
 	}
 	l := len(s.charged)
 	stream := s.charged[l-1]
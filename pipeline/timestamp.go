@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimestampToNanos replaces the value of the timestamp field of the event with int64 nanoseconds since the Unix epoch.
+// Strings are parsed as RFC3339 with an optional fractional part, numbers and numeric strings are treated as epoch seconds,
+// the fractional part is allowed too. False is returned and the field isn't changed if it's absent or can't be converted.
+func TimestampToNanos(event *Event, field []string) bool {
+	if len(field) == 0 {
+		return false
+	}
+
+	node := event.Root.Dig(field...)
+	if node == nil || !(node.IsString() || node.IsNumber()) {
+		return false
+	}
+
+	nanos, ok := parseNanos(node.AsString())
+	if !ok {
+		return false
+	}
+	node.MutateToInt(int(nanos))
+
+	return true
+}
+
+func parseNanos(value string) (int64, bool) {
+	if nanos, ok := parseEpochNanos(value); ok {
+		return nanos, true
+	}
+
+	// numbers with exponent, the precision is lower than the precision of decimal numbers
+	if x, err := strconv.ParseFloat(value, 64); err == nil {
+		x *= float64(time.Second)
+		if math.IsNaN(x) || x > math.MaxInt64 || x < math.MinInt64 {
+			return 0, false
+		}
+		return int64(math.Round(x)), true
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return 0, false
+	}
+
+	return t.UnixNano(), true
+}
+
+// parseEpochNanos parses decimal epoch seconds without float conversion, since float64 can't keep nanoseconds of current time
+func parseEpochNanos(value string) (int64, bool) {
+	whole, fraction := value, ""
+	if i := strings.IndexByte(value, '.'); i != -1 {
+		whole, fraction = value[:i], value[i+1:]
+	}
+
+	// the bounds are exclusive, since the fractional part is added to the seconds
+	seconds, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || seconds >= math.MaxInt64/int64(time.Second) || seconds <= math.MinInt64/int64(time.Second) {
+		return 0, false
+	}
+
+	for i := 0; i < len(fraction); i++ {
+		if fraction[i] < '0' || fraction[i] > '9' {
+			return 0, false
+		}
+	}
+
+	// digits after nanoseconds are truncated
+	nanos := int64(0)
+	for i := 0; i < 9; i++ {
+		nanos *= 10
+		if i < len(fraction) {
+			nanos += int64(fraction[i] - '0')
+		}
+	}
+
+	if strings.HasPrefix(whole, "-") {
+		nanos = -nanos
+	}
+
+	return seconds*int64(time.Second) + nanos, true
+}
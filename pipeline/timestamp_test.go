@@ -0,0 +1,40 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+func TestTimestampToNanos(t *testing.T) {
+	cases := []struct {
+		json     string
+		expected string
+		ok       bool
+	}{
+		{`{"ts":"2021-01-01T00:00:00Z"}`, `{"ts":1609459200000000000}`, true},
+		{`{"ts":"2021-01-01T03:00:00.123456789+03:00"}`, `{"ts":1609459200123456789}`, true},
+		{`{"ts":1609459200}`, `{"ts":1609459200000000000}`, true},
+		{`{"ts":1609459200.123456789}`, `{"ts":1609459200123456789}`, true},
+		{`{"ts":"1609459200.5"}`, `{"ts":1609459200500000000}`, true},
+		{`{"ts":-1.5}`, `{"ts":-1500000000}`, true},
+		{`{"ts":1.6094592e9}`, `{"ts":1609459200000000000}`, true},
+		{`{"ts":"yesterday"}`, `{"ts":"yesterday"}`, false},
+		{`{"ts":"1609459200.5x"}`, `{"ts":"1609459200.5x"}`, false},
+		{`{"ts":1e300}`, `{"ts":1e300}`, false},
+		{`{"ts":{"seconds":1}}`, `{"ts":{"seconds":1}}`, false},
+		{`{"time":"2021-01-01T00:00:00Z"}`, `{"time":"2021-01-01T00:00:00Z"}`, false},
+	}
+
+	for _, c := range cases {
+		root, err := insaneJSON.DecodeString(c.json)
+		assert.NoError(t, err, "wrong json")
+
+		ok := TimestampToNanos(&Event{Root: root}, []string{"ts"})
+		assert.Equal(t, c.ok, ok, "wrong result for %s", c.json)
+		assert.Equal(t, c.expected, root.EncodeToString(), "wrong event for %s", c.json)
+
+		insaneJSON.Release(root)
+	}
+}
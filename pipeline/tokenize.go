@@ -0,0 +1,99 @@
+package pipeline
+
+import (
+	"strconv"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+// ValueKind is the type of the token value of a space separated log line, e.g. an access log
+type ValueKind int
+
+const (
+	ValueString ValueKind = iota
+	ValueInt
+	ValueFloat
+)
+
+// MutateToValue mutates the node to the value of the kind, `-` means there is no value for numbers.
+// Malformed numbers are kept as strings, rather than losing them.
+func MutateToValue(node *insaneJSON.Node, kind ValueKind, value []byte) {
+	if kind == ValueString {
+		node.MutateToBytes(value)
+		return
+	}
+
+	if len(value) == 1 && value[0] == '-' {
+		node.MutateToNull()
+		return
+	}
+
+	s := ByteToStringUnsafe(value)
+	if kind == ValueInt {
+		if x, err := strconv.Atoi(s); err == nil {
+			node.MutateToInt(x)
+			return
+		}
+	} else {
+		if x, err := strconv.ParseFloat(s, 64); err == nil {
+			node.MutateToFloat(x)
+			return
+		}
+	}
+
+	node.MutateToBytes(value)
+}
+
+// Tokenize appends tokens of the line to the slice, tokens are split by spaces which aren't inside quotes,
+// or inside square brackets if brackets is true.
+// Token which is a single quoted or bracketed string is returned without quotes or brackets.
+// It returns false if the line has unterminated quote or bracket.
+func Tokenize(tokens [][]byte, line []byte, brackets bool) ([][]byte, bool) {
+	i := 0
+	for i < len(line) {
+		if line[i] == ' ' {
+			i++
+			continue
+		}
+
+		start := i
+		if brackets && line[i] == '[' {
+			for i < len(line) && line[i] != ']' {
+				i++
+			}
+			if i >= len(line) {
+				return tokens, false
+			}
+
+			tokens = append(tokens, line[start+1:i])
+			i++
+			continue
+		}
+
+		quoteEnd := -1
+		inQuote := false
+		for i < len(line) && (inQuote || line[i] != ' ') {
+			switch {
+			case inQuote && line[i] == '\\':
+				i++
+			case line[i] == '"':
+				inQuote = !inQuote
+				if !inQuote && quoteEnd == -1 {
+					quoteEnd = i
+				}
+			}
+			i++
+		}
+		if inQuote {
+			return tokens, false
+		}
+
+		if line[start] == '"' && quoteEnd == i-1 {
+			tokens = append(tokens, line[start+1:i-1])
+		} else {
+			tokens = append(tokens, line[start:i])
+		}
+	}
+
+	return tokens, true
+}
@@ -0,0 +1,63 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+func TestTokenize(t *testing.T) {
+	cases := []struct {
+		line     string
+		brackets bool
+		expected []string
+		ok       bool
+	}{
+		{`a  b c`, false, []string{"a", "b", "c"}, true},
+		{`a "b c" "d \"e\" f"`, false, []string{"a", "b c", `d \"e\" f`}, true},
+		{`a x="b c" d`, false, []string{"a", `x="b c"`, "d"}, true},
+		{`a [b c] d`, false, []string{"a", "[b", "c]", "d"}, true},
+		{`a [b c] "d" -`, true, []string{"a", "b c", "d", "-"}, true},
+		{`a "b c`, false, []string{"a"}, false},
+		{`a [b c`, true, []string{"a"}, false},
+		{``, false, []string{}, true},
+	}
+
+	for _, c := range cases {
+		tokens, ok := Tokenize(nil, []byte(c.line), c.brackets)
+		assert.Equal(t, c.ok, ok, "wrong result for %s", c.line)
+
+		actual := make([]string, 0, len(tokens))
+		for _, token := range tokens {
+			actual = append(actual, string(token))
+		}
+		assert.Equal(t, c.expected, actual, "wrong tokens for %s", c.line)
+	}
+}
+
+func TestMutateToValue(t *testing.T) {
+	cases := []struct {
+		kind     ValueKind
+		value    string
+		expected string
+	}{
+		{ValueString, "-", `{"v":"-"}`},
+		{ValueInt, "200", `{"v":200}`},
+		{ValueInt, "-", `{"v":null}`},
+		{ValueInt, "2xx", `{"v":"2xx"}`},
+		{ValueFloat, "0.5", `{"v":0.5}`},
+		{ValueFloat, "-", `{"v":null}`},
+		{ValueFloat, "fast", `{"v":"fast"}`},
+	}
+
+	for _, c := range cases {
+		root, err := insaneJSON.DecodeString(`{"v":""}`)
+		assert.NoError(t, err, "wrong json")
+
+		MutateToValue(root.Dig("v"), c.kind, []byte(c.value))
+		assert.Equal(t, c.expected, root.EncodeToString(), "wrong value for %s", c.value)
+
+		insaneJSON.Release(root)
+	}
+}
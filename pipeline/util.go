@@ -6,6 +6,8 @@ import (
 	"strings"
 	"time"
 	"unsafe"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
 )
 
 func ByteToStringUnsafe(b []byte) string {
@@ -86,3 +88,17 @@ func ParseLevel(level string) int {
 func TrimSpaceFunc(r rune) bool {
 	return byte(r) == ' '
 }
+
+// CreateNestedField returns the node of the field which is found by the path, creating missing objects on the way.
+// Non-object nodes on the way are replaced with objects. Path names aren't copied, so they should outlive the event.
+func CreateNestedField(root *insaneJSON.Root, path []string) *insaneJSON.Node {
+	curr := root.Node
+	for i, name := range path {
+		curr = curr.AddFieldNoAlloc(root, name)
+		if i != len(path)-1 && !curr.IsObject() {
+			curr.MutateToObject()
+		}
+	}
+
+	return curr
+}
@@ -0,0 +1,201 @@
+package adaptive_sample
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/atomic"
+)
+
+const (
+	defaultSmoothing = 0.3
+)
+
+var (
+	// samplers should be shared across processors of the action, all of them receive the same config, so let's have a map by config
+	samplers   = map[*Config]*sampler{}
+	samplersMu = &sync.Mutex{}
+
+	// seeds of random generators should differ for processors started at the same time
+	seedCounter = atomic.NewInt64(0)
+)
+
+/*{ introduction
+It randomly discards events to keep the rate of passed events near `target_rate` events per second.
+The incoming rate is measured every `interval` and smoothed by the exponential moving average with `smoothing` factor,
+the keep probability is `target_rate / smoothed rate`, so all events are kept while the incoming rate is below the target.
+The smoothing damps short bursts, so the probability doesn't oscillate, but it follows the rate change a few intervals later.
+All events are kept during the first interval since there is no measured rate yet.
+
+The sampler is shared across processors of the pipeline. The smoothed incoming rate and the keep probability are exposed
+by `adaptive_sample_incoming_rate` and `adaptive_sample_keep_probability` gauges of the pipeline.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: adaptive_sample
+      match_fields:
+        level: debug
+      target_rate: 1000
+    ...
+```
+}*/
+type Plugin struct {
+	config  *Config
+	sampler *sampler
+	rnd     *rand.Rand
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The desired rate of passed events per second.
+	TargetRate float64 `json:"target_rate" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> How often the incoming rate is measured and the keep probability is adjusted.
+	Interval  cfg.Duration `json:"interval" parse:"duration" default:"1s"` //*
+	Interval_ time.Duration
+
+	//> @3@4@5@6
+	//>
+	//> The weight of the last measured rate in the smoothed rate, it should be in `(0, 1]`, `1` disables smoothing. `0.3` if not set.
+	Smoothing float64 `json:"smoothing"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "adaptive_sample",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+
+	if p.config.TargetRate <= 0 {
+		params.Logger.Fatalf("target_rate should be positive, got=%f", p.config.TargetRate)
+	}
+	if p.config.Interval_ <= 0 {
+		params.Logger.Fatalf("interval should be positive, got=%s", p.config.Interval)
+	}
+	if p.config.Smoothing == 0 {
+		p.config.Smoothing = defaultSmoothing
+	}
+	if p.config.Smoothing < 0 || p.config.Smoothing > 1 {
+		params.Logger.Fatalf("smoothing should be in (0, 1], got=%f", p.config.Smoothing)
+	}
+
+	incomingRate := params.MetricsCtl.RegisterGauge("adaptive_sample_incoming_rate", "smoothed incoming events per second of adaptive_sample action").WithLabelValues()
+	keepProbability := params.MetricsCtl.RegisterGauge("adaptive_sample_keep_probability", "probability to keep the event of adaptive_sample action").WithLabelValues()
+
+	samplersMu.Lock()
+	s, has := samplers[p.config]
+	if !has {
+		s = newSampler(p.config, incomingRate, keepProbability, time.Now)
+		samplers[p.config] = s
+	}
+	samplersMu.Unlock()
+
+	p.sampler = s
+	p.rnd = rand.New(rand.NewSource(time.Now().UnixNano() + seedCounter.Inc()))
+}
+
+func (p *Plugin) Stop() {
+	samplersMu.Lock()
+	delete(samplers, p.config)
+	samplersMu.Unlock()
+}
+
+func (p *Plugin) Do(_ *pipeline.Event) pipeline.ActionResult {
+	if p.sampler.keep(p.rnd.Float64()) {
+		return pipeline.ActionPass
+	}
+
+	return pipeline.ActionDiscard
+}
+
+type sampler struct {
+	mu         *sync.Mutex
+	targetRate float64
+	interval   time.Duration
+	smoothing  float64
+	nowFn      func() time.Time
+
+	incomingRate    prometheus.Gauge
+	keepProbability prometheus.Gauge
+
+	intervalStart time.Time
+	count         int64
+	rate          float64
+	measured      bool
+	probability   float64
+}
+
+func newSampler(config *Config, incomingRate, keepProbability prometheus.Gauge, nowFn func() time.Time) *sampler {
+	keepProbability.Set(1)
+
+	return &sampler{
+		mu:         &sync.Mutex{},
+		targetRate: config.TargetRate,
+		interval:   config.Interval_,
+		smoothing:  config.Smoothing,
+		nowFn:      nowFn,
+
+		incomingRate:    incomingRate,
+		keepProbability: keepProbability,
+
+		intervalStart: nowFn(),
+		probability:   1,
+	}
+}
+
+// keep counts the event and returns whether it's kept, x is a random number in [0, 1)
+func (s *sampler) keep(x float64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := s.nowFn(); now.Sub(s.intervalStart) >= s.interval {
+		s.adjust(now)
+	}
+	s.count++
+
+	return x < s.probability
+}
+
+// adjust updates the smoothed rate by the rate of the elapsed interval and recalculates the keep probability
+func (s *sampler) adjust(now time.Time) {
+	measured := float64(s.count) / now.Sub(s.intervalStart).Seconds()
+	if s.measured {
+		s.rate = s.smoothing*measured + (1-s.smoothing)*s.rate
+	} else {
+		s.rate = measured
+		s.measured = true
+	}
+
+	s.probability = 1
+	if s.rate > s.targetRate {
+		s.probability = s.targetRate / s.rate
+	}
+
+	s.intervalStart = now
+	s.count = 0
+
+	s.incomingRate.Set(s.rate)
+	s.keepProbability.Set(s.probability)
+}
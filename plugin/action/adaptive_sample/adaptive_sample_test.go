@@ -0,0 +1,133 @@
+package adaptive_sample
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ozonru/file.d/logger"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveSampleConverges(t *testing.T) {
+	const target = 500
+
+	now := time.Now()
+	incomingRate := prometheus.NewGauge(prometheus.GaugeOpts{Name: "incoming_rate"})
+	keepProbability := prometheus.NewGauge(prometheus.GaugeOpts{Name: "keep_probability"})
+	s := newSampler(&Config{TargetRate: target, Interval_: time.Second, Smoothing: defaultSmoothing}, incomingRate, keepProbability, func() time.Time { return now })
+	rnd := rand.New(rand.NewSource(0))
+
+	phases := []struct {
+		rate     int
+		expected float64
+	}{
+		{rate: 100, expected: 100},
+		{rate: 2000, expected: target},
+		{rate: 10000, expected: target},
+		{rate: 1000, expected: target},
+		{rate: 300, expected: 300},
+	}
+
+	const seconds = 30
+	const measuredSeconds = 10
+	for _, phase := range phases {
+		kept := 0
+		step := time.Second / time.Duration(phase.rate)
+		for i := 0; i < phase.rate*seconds; i++ {
+			now = now.Add(step)
+			// the rate is measured when the smoothed rate is settled
+			if s.keep(rnd.Float64()) && i >= phase.rate*(seconds-measuredSeconds) {
+				kept++
+			}
+		}
+
+		outRate := float64(kept) / measuredSeconds
+		assert.InEpsilon(t, phase.expected, outRate, 0.1, "output rate doesn't converge for incoming rate %d", phase.rate)
+		assert.InEpsilon(t, float64(phase.rate), testutil.ToFloat64(incomingRate), 0.05, "wrong incoming rate gauge for incoming rate %d", phase.rate)
+	}
+}
+
+func TestAdaptiveSampleSmoothing(t *testing.T) {
+	now := time.Now()
+	gauge := func() prometheus.Gauge { return prometheus.NewGauge(prometheus.GaugeOpts{Name: "gauge"}) }
+	s := newSampler(&Config{TargetRate: 100, Interval_: time.Second, Smoothing: 0.5}, gauge(), gauge(), func() time.Time { return now })
+
+	for i := 0; i < 200; i++ {
+		now = now.Add(time.Millisecond)
+		assert.True(t, s.keep(0.99), "events should be kept before the first interval is elapsed")
+	}
+
+	now = now.Add(800 * time.Millisecond)
+	s.adjust(now)
+	assert.InEpsilon(t, 200, s.rate, 0.01, "the first measured rate should be used as is")
+	assert.InEpsilon(t, 0.5, s.probability, 0.01, "wrong probability")
+
+	s.count = 400
+	now = now.Add(time.Second)
+	s.adjust(now)
+	assert.InEpsilon(t, 300, s.rate, 0.01, "the measured rate isn't smoothed")
+	assert.InEpsilon(t, 1.0/3, s.probability, 0.01, "wrong probability")
+
+	// the silence lowers the rate
+	s.count = 10
+	now = now.Add(10 * time.Second)
+	s.adjust(now)
+	assert.InEpsilon(t, 150.5, s.rate, 0.01, "the silence isn't taken into account")
+
+	s.count = 0
+	now = now.Add(time.Second)
+	s.adjust(now)
+	assert.Equal(t, float64(1), s.probability, "events below the target rate should be kept")
+}
+
+func TestAdaptiveSamplePipeline(t *testing.T) {
+	config := test.NewConfig(&Config{TargetRate: 1000000}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(10)
+
+	output.SetOutFn(func(e *pipeline.Event) {
+		wg.Done()
+	})
+
+	for i := 0; i < 10; i++ {
+		input.In(0, "test.log", 0, []byte(`{"message":"event"}`))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	keepProbability := p.GetMetricsCtl().RegisterGauge("adaptive_sample_keep_probability", "")
+	assert.Equal(t, float64(1), testutil.ToFloat64(keepProbability.WithLabelValues()), "events below the target rate should be kept")
+}
+
+func TestAdaptiveSampleSamplerPerAction(t *testing.T) {
+	start := func(config *Config) *Plugin {
+		plugin := &Plugin{}
+		plugin.Start(config, &pipeline.ActionPluginParams{PluginDefaultParams: test.NewEmptyPluginDefaultParams(), Logger: logger.Instance})
+		return plugin
+	}
+
+	config := test.NewConfig(&Config{TargetRate: 1000}, nil).(*Config)
+	first := start(config)
+	second := start(config)
+	other := start(test.NewConfig(&Config{TargetRate: 10}, nil).(*Config))
+
+	assert.True(t, first.sampler == second.sampler, "processors of the action should share the sampler")
+	assert.True(t, first.sampler != other.sampler, "actions of the pipeline shouldn't share the sampler")
+
+	first.Stop()
+	second.Stop()
+	other.Stop()
+
+	restarted := start(config)
+	assert.True(t, first.sampler != restarted.sampler, "sampler shouldn't survive the action stop")
+	restarted.Stop()
+}
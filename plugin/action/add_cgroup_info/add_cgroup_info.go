@@ -0,0 +1,228 @@
+package add_cgroup_info
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"go.uber.org/zap"
+)
+
+// cgroup v1 reports a huge page aligned number instead of the absent memory limit
+const memoryUnlimitedThreshold = 1 << 62
+
+/*{ introduction
+It adds CPU and memory limits of the file.d process cgroup to an event.
+Limits are read once on the start, both cgroup v1 and v2 are supported:
+cgroup v2 is detected by `cgroup.controllers` file in the cgroup root.
+
+The following fields are added:
+* `{prefix}version` – cgroup version, `1` or `2`;
+* `{prefix}cpu_limit` – CPU limit in cores, e.g. `1.5`;
+* `{prefix}memory_limit` – memory limit in bytes.
+
+A limit isn't added if it isn't set or can't be read.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: add_cgroup_info
+    ...
+```
+It transforms `{"message":"hello"}` into `{"message":"hello","cgroup_version":2,"cgroup_cpu_limit":2,"cgroup_memory_limit":536870912}`.
+}*/
+type Plugin struct {
+	config *Config
+	logger *zap.SugaredLogger
+
+	info *cgroupInfo
+
+	versionField     string
+	cpuLimitField    string
+	memoryLimitField string
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The path where the cgroup filesystem is mounted.
+	CgroupRoot string `json:"cgroup_root" default:"/sys/fs/cgroup"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The file which contains cgroups of the process.
+	ProcCgroup string `json:"proc_cgroup" default:"/proc/self/cgroup"` //*
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to the fields.
+	Prefix string `json:"prefix" default:"cgroup_"` //*
+}
+
+type cgroupInfo struct {
+	version     int
+	cpuLimit    float64 // cores, zero if isn't set
+	memoryLimit int64   // bytes, zero if isn't set
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "add_cgroup_info",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger
+
+	p.versionField = p.config.Prefix + "version"
+	p.cpuLimitField = p.config.Prefix + "cpu_limit"
+	p.memoryLimitField = p.config.Prefix + "memory_limit"
+
+	info, err := readCgroupInfo(p.config.CgroupRoot, p.config.ProcCgroup)
+	if err != nil {
+		p.logger.Warnf("can't read cgroup info, cgroup fields won't be added: %s", err.Error())
+		return
+	}
+	p.logger.Infof("cgroup info is read: %s", info)
+	p.info = info
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	if p.info == nil {
+		return pipeline.ActionPass
+	}
+
+	event.Root.AddFieldNoAlloc(event.Root, p.versionField).MutateToInt(p.info.version)
+	if p.info.cpuLimit > 0 {
+		event.Root.AddFieldNoAlloc(event.Root, p.cpuLimitField).MutateToFloat(p.info.cpuLimit)
+	}
+	if p.info.memoryLimit > 0 {
+		event.Root.AddFieldNoAlloc(event.Root, p.memoryLimitField).MutateToInt(int(p.info.memoryLimit))
+	}
+
+	return pipeline.ActionPass
+}
+
+func readCgroupInfo(root string, procCgroup string) (*cgroupInfo, error) {
+	paths, err := readProcCgroup(procCgroup)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		return readCgroupV2(root, paths[""]), nil
+	}
+
+	return readCgroupV1(root, paths), nil
+}
+
+// readProcCgroup returns cgroup paths of the process by controllers, cgroup v2 path has an empty controller
+func readProcCgroup(procCgroup string) (map[string]string, error) {
+	file, err := os.Open(procCgroup)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	paths := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
+	}
+
+	return paths, scanner.Err()
+}
+
+func readCgroupV2(root string, path string) *cgroupInfo {
+	info := &cgroupInfo{version: 2}
+
+	// cpu.max contains "$MAX $PERIOD", $MAX is "max" if the limit isn't set
+	if fields := strings.Fields(readCgroupFile(root, path, "cpu.max")); len(fields) == 2 {
+		info.cpuLimit = cpuLimit(fields[0], fields[1])
+	}
+
+	value := readCgroupFile(root, path, "memory.max")
+	if limit, err := strconv.ParseInt(value, 10, 64); err == nil {
+		info.memoryLimit = limit
+	}
+
+	return info
+}
+
+func readCgroupV1(root string, paths map[string]string) *cgroupInfo {
+	info := &cgroupInfo{version: 1}
+
+	cpuRoot := filepath.Join(root, "cpu")
+	info.cpuLimit = cpuLimit(
+		readCgroupFile(cpuRoot, paths["cpu"], "cpu.cfs_quota_us"),
+		readCgroupFile(cpuRoot, paths["cpu"], "cpu.cfs_period_us"),
+	)
+
+	value := readCgroupFile(filepath.Join(root, "memory"), paths["memory"], "memory.limit_in_bytes")
+	if limit, err := strconv.ParseInt(value, 10, 64); err == nil && limit < memoryUnlimitedThreshold {
+		info.memoryLimit = limit
+	}
+
+	return info
+}
+
+// readCgroupFile reads the file of the process cgroup, the file in the root is used if it isn't found,
+// since cgroup namespace of a container may hide the parent path
+func readCgroupFile(root string, path string, name string) string {
+	data, err := ioutil.ReadFile(filepath.Join(root, path, name))
+	if err != nil {
+		data, err = ioutil.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			return ""
+		}
+	}
+
+	return strings.TrimSpace(string(data))
+}
+
+// cpuLimit returns the number of cores, it returns zero for a negative or non-numeric quota which means no limit
+func cpuLimit(quota string, period string) float64 {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0
+	}
+
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+
+	return q / p
+}
+
+func (i *cgroupInfo) String() string {
+	return fmt.Sprintf("version=%d, cpu limit=%g, memory limit=%d", i.version, i.cpuLimit, i.memoryLimit)
+}
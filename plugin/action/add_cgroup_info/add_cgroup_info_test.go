@@ -0,0 +1,112 @@
+package add_cgroup_info
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755), "can't create dir")
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644), "can't write file")
+	}
+}
+
+func runAddCgroupInfo(config *Config) string {
+	test.NewConfig(config, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvent := ""
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvent = e.Root.EncodeToString()
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"message":"hello"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvent
+}
+
+func TestAddCgroupInfoV2(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup_v2")
+	assert.NoError(t, err, "can't create temp dir")
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"proc/self/cgroup":                           "0::/kubepods/pod1/container1\n",
+		"cgroup/cgroup.controllers":                  "cpu memory\n",
+		"cgroup/kubepods/pod1/container1/cpu.max":    "150000 100000\n",
+		"cgroup/kubepods/pod1/container1/memory.max": "536870912\n",
+	})
+
+	outEvent := runAddCgroupInfo(&Config{CgroupRoot: filepath.Join(dir, "cgroup"), ProcCgroup: filepath.Join(dir, "proc/self/cgroup")})
+	assert.Equal(t, `{"message":"hello","cgroup_version":2,"cgroup_cpu_limit":1.5,"cgroup_memory_limit":536870912}`, outEvent, "wrong out event")
+}
+
+func TestAddCgroupInfoV2Unlimited(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup_v2")
+	assert.NoError(t, err, "can't create temp dir")
+	defer os.RemoveAll(dir)
+
+	// the process cgroup path is hidden by cgroup namespace, so files of the root are used
+	writeFiles(t, dir, map[string]string{
+		"proc/self/cgroup":          "0::/../container1\n",
+		"cgroup/cgroup.controllers": "cpu memory\n",
+		"cgroup/cpu.max":            "max 100000\n",
+		"cgroup/memory.max":         "max\n",
+	})
+
+	outEvent := runAddCgroupInfo(&Config{CgroupRoot: filepath.Join(dir, "cgroup"), ProcCgroup: filepath.Join(dir, "proc/self/cgroup"), Prefix: "limits_"})
+	assert.Equal(t, `{"message":"hello","limits_version":2}`, outEvent, "wrong out event")
+}
+
+func TestAddCgroupInfoV1(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup_v1")
+	assert.NoError(t, err, "can't create temp dir")
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"proc/self/cgroup":                               "12:memory:/docker/abc\n11:cpu,cpuacct:/docker/abc\n1:name=systemd:/docker/abc\n",
+		"cgroup/cpu/docker/abc/cpu.cfs_quota_us":         "200000\n",
+		"cgroup/cpu/docker/abc/cpu.cfs_period_us":        "100000\n",
+		"cgroup/memory/docker/abc/memory.limit_in_bytes": "1073741824\n",
+	})
+
+	outEvent := runAddCgroupInfo(&Config{CgroupRoot: filepath.Join(dir, "cgroup"), ProcCgroup: filepath.Join(dir, "proc/self/cgroup")})
+	assert.Equal(t, `{"message":"hello","cgroup_version":1,"cgroup_cpu_limit":2,"cgroup_memory_limit":1073741824}`, outEvent, "wrong out event")
+}
+
+func TestAddCgroupInfoV1Unlimited(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup_v1")
+	assert.NoError(t, err, "can't create temp dir")
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"proc/self/cgroup":                    "12:memory:/\n11:cpu,cpuacct:/\n",
+		"cgroup/cpu/cpu.cfs_quota_us":         "-1\n",
+		"cgroup/cpu/cpu.cfs_period_us":        "100000\n",
+		"cgroup/memory/memory.limit_in_bytes": "9223372036854771712\n",
+	})
+
+	outEvent := runAddCgroupInfo(&Config{CgroupRoot: filepath.Join(dir, "cgroup"), ProcCgroup: filepath.Join(dir, "proc/self/cgroup")})
+	assert.Equal(t, `{"message":"hello","cgroup_version":1}`, outEvent, "wrong out event")
+}
+
+func TestAddCgroupInfoNoCgroup(t *testing.T) {
+	outEvent := runAddCgroupInfo(&Config{CgroupRoot: "/not/exists", ProcCgroup: "/not/exists/cgroup"})
+	assert.Equal(t, `{"message":"hello"}`, outEvent, "wrong out event")
+}
@@ -0,0 +1,150 @@
+package anonymize
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"unicode/utf8"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It pseudonymizes identifiers like emails and user IDs: the value of each field is replaced with the digest of the value followed by `salt`.
+The digest is deterministic, so anonymized events still can be grouped and joined by the field, but the original value can't be read.
+Absent fields, objects and arrays are skipped, other values are hashed as strings.
+
+If `keep_prefix_bytes` is set, the first bytes of the value are kept readable before the digest, e.g. `joh` + digest for `john@example.com`.
+The prefix is cut on UTF-8 character boundary and it isn't kept for values which aren't longer than the prefix, since they would be revealed completely.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: anonymize
+      fields:
+        - user.email
+        - user.id
+      salt: my-salt
+    ...
+```
+}*/
+type Plugin struct {
+	config *Config
+	fields [][]string
+	hash   hash.Hash
+	sum    []byte
+	buf    []byte
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The list of the fields to anonymize. Nested fields can be used.
+	Fields []cfg.FieldSelector `json:"fields" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The hashing algorithm.
+	Algorithm string `json:"algorithm" default:"sha256" options:"sha256|sha1"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The salt which is appended to the value before hashing. Digests of the same value are different for different salts.
+	Salt string `json:"salt"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The encoding of the digest.
+	Encoding string `json:"encoding" default:"hex" options:"hex|base64"` //*
+
+	//> @3@4@5@6
+	//>
+	//> How many first bytes of the value to keep readable before the digest.
+	KeepPrefixBytes int `json:"keep_prefix_bytes"` //*
+}
+
+const (
+	algorithmSHA1  = "sha1"
+	encodingBase64 = "base64"
+)
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "anonymize",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+
+	if p.config.KeepPrefixBytes < 0 {
+		params.Logger.Fatalf("keep_prefix_bytes should be non-negative, got=%d", p.config.KeepPrefixBytes)
+	}
+
+	p.fields = make([][]string, 0, len(p.config.Fields))
+	for _, field := range p.config.Fields {
+		p.fields = append(p.fields, cfg.ParseFieldSelector(string(field)))
+	}
+
+	p.hash = sha256.New()
+	if p.config.Algorithm == algorithmSHA1 {
+		p.hash = sha1.New()
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	for _, field := range p.fields {
+		node := event.Root.Dig(field...)
+		if node == nil || node.IsObject() || node.IsArray() {
+			continue
+		}
+
+		node.MutateToBytesCopy(event.Root, p.anonymize(node.AsString()))
+	}
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) anonymize(value string) []byte {
+	p.hash.Reset()
+	_, _ = p.hash.Write([]byte(value))
+	_, _ = p.hash.Write([]byte(p.config.Salt))
+	p.sum = p.hash.Sum(p.sum[:0])
+
+	p.buf = p.buf[:0]
+	if n := p.config.KeepPrefixBytes; n > 0 && len(value) > n {
+		// don't cut UTF-8 sequence
+		for n > 0 && !utf8.RuneStart(value[n]) {
+			n--
+		}
+		p.buf = append(p.buf, value[:n]...)
+	}
+
+	l := len(p.buf)
+	if p.config.Encoding == encodingBase64 {
+		p.buf = append(p.buf, make([]byte, base64.StdEncoding.EncodedLen(len(p.sum)))...)
+		base64.StdEncoding.Encode(p.buf[l:], p.sum)
+	} else {
+		p.buf = append(p.buf, make([]byte, hex.EncodedLen(len(p.sum)))...)
+		hex.Encode(p.buf[l:], p.sum)
+	}
+
+	return p.buf
+}
@@ -0,0 +1,91 @@
+package anonymize
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func runEvents(config *Config, events []string) []string {
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, test.NewConfig(config, nil), pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(events))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, event := range events {
+		input.In(0, "test.log", 0, []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+func sha256Hex(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func base64SHA1(value string) string {
+	sum := sha1.Sum([]byte(value))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestAnonymize(t *testing.T) {
+	config := &Config{Fields: []cfg.FieldSelector{"user.email", "user.id", "missing"}, Salt: "salt"}
+	outEvents := runEvents(config, []string{
+		`{"user":{"email":"john@example.com","id":42},"message":"login"}`,
+		`{"user":{"email":{"primary":"john@example.com"}},"message":"login"}`,
+		`{"message":"no user"}`,
+	})
+
+	assert.Equal(t, []string{
+		`{"user":{"email":"` + sha256Hex("john@example.comsalt") + `","id":"` + sha256Hex("42salt") + `"},"message":"login"}`,
+		`{"user":{"email":{"primary":"john@example.com"}},"message":"login"}`,
+		`{"message":"no user"}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestAnonymizeSalt(t *testing.T) {
+	event := `{"email":"john@example.com"}`
+	config := func(salt string) *Config {
+		return &Config{Fields: []cfg.FieldSelector{"email"}, Salt: salt}
+	}
+
+	first := runEvents(config("first"), []string{event, event})
+	assert.Equal(t, first[0], first[1], "digest should be deterministic for the same salt")
+	assert.Equal(t, first, runEvents(config("first"), []string{event, event}), "digest should be deterministic for the same salt")
+	assert.NotEqual(t, first[0], runEvents(config("second"), []string{event})[0], "digests should differ for different salts")
+}
+
+func TestAnonymizeOptions(t *testing.T) {
+	outEvents := runEvents(&Config{
+		Fields:          []cfg.FieldSelector{"email", "name"},
+		Algorithm:       "sha1",
+		Encoding:        "base64",
+		KeepPrefixBytes: 4,
+	}, []string{`{"email":"john@example.com","name":"Жора"}`})
+
+	// the prefix is cut on the character boundary, which is 4 bytes of the name
+	assert.Equal(t, []string{
+		`{"email":"john` + base64SHA1("john@example.com") + `","name":"Жо` + base64SHA1("Жора") + `"}`,
+	}, outEvents, "wrong out events")
+
+	outEvents = runEvents(&Config{Fields: []cfg.FieldSelector{"id"}, KeepPrefixBytes: 4}, []string{`{"id":"1234"}`})
+	assert.Equal(t, []string{`{"id":"` + sha256Hex("1234") + `"}`}, outEvents, "short value shouldn't be kept")
+}
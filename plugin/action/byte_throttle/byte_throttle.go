@@ -0,0 +1,193 @@
+package byte_throttle
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// buckets should be shared across processors of the action, all of them receive the same config, so let's have a map by config
+	buckets   = map[*Config]*bucket{}
+	bucketsMu = &sync.Mutex{}
+)
+
+/*{ introduction
+It limits the byte throughput of the pipeline to `bytes_per_sec` using the token bucket over event sizes.
+The bucket holds up to `burst` bytes, so short spikes are passed.
+The event which doesn't fit into the bucket is either discarded or delayed until the bucket is refilled, depending on `mode`.
+Delaying blocks the processor, so it slows down the inputs rather than loses events.
+
+The event which is bigger than `burst` is passed when the bucket is full, so it doesn't block the pipeline forever.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: byte_throttle
+      bytes_per_sec: 1048576
+      mode: delay
+    ...
+```
+}*/
+type Plugin struct {
+	config *Config
+	bucket *bucket
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The maximum average rate of bytes per second. Must be positive.
+	BytesPerSec int `json:"bytes_per_sec" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The size of the bucket in bytes, `bytes_per_sec` if not set.
+	Burst int `json:"burst"` //*
+
+	//> @3@4@5@6
+	//>
+	//> What to do with the events exceeding the rate: `drop` discards them, `delay` waits until the bucket is refilled.
+	Mode string `json:"mode" default:"drop" options:"drop|delay"` //*
+}
+
+const modeDelay = "delay"
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "byte_throttle",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	if p.config.BytesPerSec <= 0 {
+		params.Logger.Fatalf("bytes_per_sec should be positive, got=%d", p.config.BytesPerSec)
+	}
+
+	dropped := params.MetricsCtl.RegisterCounter("byte_throttle_dropped_bytes_total", "how many bytes are discarded by byte_throttle action")
+
+	bucketsMu.Lock()
+	b, has := buckets[p.config]
+	if !has {
+		b = newBucket(p.config, dropped, time.Now)
+		buckets[p.config] = b
+	}
+	bucketsMu.Unlock()
+
+	p.bucket = b
+}
+
+func (p *Plugin) Stop() {
+	bucketsMu.Lock()
+	delete(buckets, p.config)
+	bucketsMu.Unlock()
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	if p.config.Mode == modeDelay {
+		if wait := p.bucket.reserve(event.Size); wait > 0 {
+			time.Sleep(wait)
+		}
+		return pipeline.ActionPass
+	}
+
+	if !p.bucket.take(event.Size) {
+		return pipeline.ActionDiscard
+	}
+
+	return pipeline.ActionPass
+}
+
+type bucket struct {
+	mu *sync.Mutex
+
+	rate  float64
+	burst float64
+	nowFn func() time.Time
+
+	// tokens may be negative if reserved bytes exceed the bucket
+	tokens  float64
+	updated time.Time
+
+	dropped *prometheus.CounterVec
+}
+
+// newBucket resolves the default burst into the bucket, the config isn't changed since it identifies the shared bucket
+func newBucket(config *Config, dropped *prometheus.CounterVec, nowFn func() time.Time) *bucket {
+	burst := config.Burst
+	if burst <= 0 {
+		burst = config.BytesPerSec
+	}
+
+	return &bucket{
+		mu: &sync.Mutex{},
+
+		rate:  float64(config.BytesPerSec),
+		burst: float64(burst),
+		nowFn: nowFn,
+
+		tokens:  float64(burst),
+		updated: nowFn(),
+
+		dropped: dropped,
+	}
+}
+
+// take consumes size tokens if the bucket has them or if it's full
+func (b *bucket) take(size int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+
+	n := float64(size)
+	if b.tokens < n && b.tokens < b.burst {
+		b.dropped.WithLabelValues().Add(n)
+		return false
+	}
+
+	b.tokens -= n
+	return true
+}
+
+// reserve consumes size tokens and returns the time to wait until the bucket has them
+func (b *bucket) reserve(size int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	b.tokens -= float64(size)
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens * float64(time.Second) / b.rate)
+}
+
+func (b *bucket) refill() {
+	now := b.nowFn()
+	elapsed := now.Sub(b.updated)
+	if elapsed <= 0 {
+		return
+	}
+	b.updated = now
+
+	b.tokens += elapsed.Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
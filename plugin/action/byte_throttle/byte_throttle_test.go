@@ -0,0 +1,137 @@
+package byte_throttle
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ozonru/file.d/logger"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+)
+
+const eventSize = 100
+
+// event returns the event of eventSize bytes
+func event() []byte {
+	prefix := `{"message":"`
+	suffix := `"}`
+	return []byte(prefix + strings.Repeat("a", eventSize-len(prefix)-len(suffix)) + suffix)
+}
+
+func runThrottle(t *testing.T, config *Config, eventsCount int) (int, float64, time.Duration) {
+	test.NewConfig(config, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	passed := atomic.NewInt32(0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		passed.Inc()
+	})
+
+	dropped := p.GetMetricsCtl().RegisterCounter("byte_throttle_dropped_bytes_total", "").WithLabelValues()
+
+	start := time.Now()
+	for i := 0; i < eventsCount; i++ {
+		input.In(0, "test.log", 0, event())
+	}
+
+	deadline := time.Now().Add(time.Second * 10)
+	for int(passed.Load())+int(testutil.ToFloat64(dropped))/eventSize < eventsCount && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	elapsed := time.Since(start)
+	p.Stop()
+
+	assert.Equal(t, eventsCount, int(passed.Load())+int(testutil.ToFloat64(dropped))/eventSize, "events aren't processed")
+
+	return int(passed.Load()), testutil.ToFloat64(dropped), elapsed
+}
+
+func TestByteThrottleBelowLimit(t *testing.T) {
+	passed, dropped, _ := runThrottle(t, &Config{BytesPerSec: 10000}, 50)
+
+	assert.Equal(t, 50, passed, "wrong passed events count")
+	assert.Equal(t, float64(0), dropped, "wrong dropped bytes")
+}
+
+func TestByteThrottleAboveLimit(t *testing.T) {
+	passed, dropped, elapsed := runThrottle(t, &Config{BytesPerSec: 10000}, 300)
+
+	// the bucket holds 100 events, the rest is what is refilled while events are sent
+	refilled := int(elapsed.Seconds()*10000)/eventSize + 1
+	assert.True(t, passed >= 100 && passed <= 100+refilled, "wrong passed events count: %d", passed)
+	assert.Equal(t, float64((300-passed)*eventSize), dropped, "wrong dropped bytes")
+}
+
+func TestByteThrottleDelay(t *testing.T) {
+	passed, dropped, elapsed := runThrottle(t, &Config{BytesPerSec: 10000, Burst: 1000, Mode: "delay"}, 30)
+
+	assert.Equal(t, 30, passed, "wrong passed events count")
+	assert.Equal(t, float64(0), dropped, "wrong dropped bytes")
+	// 3000 bytes are sent, 1000 bytes are in the bucket, 2000 bytes are refilled in 0.2s
+	assert.True(t, elapsed >= time.Millisecond*190, "events aren't delayed: %s", elapsed)
+}
+
+func TestBucket(t *testing.T) {
+	now := time.Now()
+	dropped := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "dropped"}, []string{})
+	b := newBucket(&Config{BytesPerSec: 1000, Burst: 500}, dropped, func() time.Time { return now })
+
+	assert.True(t, b.take(300), "bytes should be taken")
+	assert.True(t, b.take(200), "bytes should be taken")
+	assert.False(t, b.take(1), "bucket should be empty")
+
+	now = now.Add(time.Millisecond * 100)
+	assert.False(t, b.take(101), "bucket should have 100 bytes")
+	assert.True(t, b.take(100), "bucket should have 100 bytes")
+
+	now = now.Add(time.Hour)
+	assert.True(t, b.take(2000), "event bigger than burst should be taken from the full bucket")
+	assert.False(t, b.take(1), "bucket should be in debt")
+
+	now = now.Add(time.Second * 2)
+	assert.True(t, b.take(500), "debt should be paid off")
+	assert.Equal(t, float64(103), testutil.ToFloat64(dropped.WithLabelValues()), "wrong dropped bytes")
+
+	assert.Equal(t, time.Duration(0), b.reserve(0), "wrong wait time")
+	assert.Equal(t, time.Millisecond*300, b.reserve(300), "wrong wait time")
+	now = now.Add(time.Millisecond * 300)
+	assert.Equal(t, time.Millisecond*100, b.reserve(100), "wrong wait time")
+}
+
+func TestByteThrottleBucketPerAction(t *testing.T) {
+	start := func(config *Config) *Plugin {
+		plugin := &Plugin{}
+		plugin.Start(config, &pipeline.ActionPluginParams{PluginDefaultParams: test.NewEmptyPluginDefaultParams(), Logger: logger.Instance})
+		return plugin
+	}
+
+	config := test.NewConfig(&Config{BytesPerSec: 10000}, nil).(*Config)
+	first := start(config)
+	second := start(config)
+	other := start(test.NewConfig(&Config{BytesPerSec: 20000}, nil).(*Config))
+
+	assert.True(t, first.bucket == second.bucket, "processors of the action should share the bucket")
+	assert.True(t, first.bucket != other.bucket, "actions of the pipeline shouldn't share the bucket")
+
+	first.Stop()
+	second.Stop()
+	other.Stop()
+
+	restarted := start(config)
+	assert.True(t, first.bucket != restarted.bucket, "bucket shouldn't survive the action stop")
+	restarted.Stop()
+}
+
+func TestBucketDefaultBurst(t *testing.T) {
+	config := &Config{BytesPerSec: 1000}
+	dropped := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "dropped"}, []string{})
+	b := newBucket(config, dropped, time.Now)
+
+	assert.Equal(t, float64(1000), b.burst, "wrong burst")
+	assert.Equal(t, 0, config.Burst, "shared config shouldn't be changed")
+}
@@ -1,6 +1,8 @@
 package convert_date
 
 import (
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/ozonru/file.d/cfg"
@@ -10,11 +12,35 @@ import (
 
 /*{ introduction
 It converts field date/time data to different format.
+Source formats are tried in the listed order until one of them parses the value.
+Layouts without timezone are treated as UTC.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: convert_date
+      field: ts
+      source_formats: [unixmilli, "2006-01-02 15:04:05"]
+      target_field: time
+      target_format: rfc3339nano
+      error_field: time_invalid
+    ...
+```
+It transforms `{"ts":1609459200123}` into `{"ts":1609459200123,"time":"2021-01-01T00:00:00.123Z"}`.
 }*/
 type Plugin struct {
 	config *Config
 }
 
+const (
+	formatTimestamp = "timestamp"
+	formatUnix      = "unix"
+	formatUnixMilli = "unixmilli"
+)
+
 //! config-params
 //^ config-params
 type Config struct {
@@ -26,13 +52,20 @@ type Config struct {
 
 	//> @3@4@5@6
 	//>
-	//> List of date formats to parse a field. Available list items should be one of `ansic|unixdate|rubydate|rfc822|rfc822z|rfc850|rfc1123|rfc1123z|rfc3339|rfc3339nano|kitchen|stamp|stampmilli|stampmicro|stampnano`.
-	SourceFormats  []string `json:"source_formats" default:"rfc3339nano,rfc3339"` //*
+	//> List of date formats to parse a field. Available list items should be one of `ansic|unixdate|rubydate|rfc822|rfc822z|rfc850|rfc1123|rfc1123z|rfc3339|rfc3339nano|kitchen|stamp|stampmilli|stampmicro|stampnano`
+	//> or Go time layout. Use `unix` and `unixmilli` for epoch seconds and milliseconds, they can be numbers or strings.
+	SourceFormats  []string `json:"source_formats" default:"rfc3339nano rfc3339"` //*
 	SourceFormats_ []string
 
 	//> @3@4@5@6
 	//>
-	//> Date format to convert to.
+	//> The event field to put the converted date to. Nested fields can be used. The source field is overwritten if it isn't set.
+	TargetField  cfg.FieldSelector `json:"target_field" parse:"selector"` //*
+	TargetField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> Date format to convert to. Any of source formats can be used, `timestamp` is the same as `unix`.
 	TargetFormat  string `json:"target_format" default:"timestamp"` //*
 	TargetFormat_ string
 
@@ -40,6 +73,11 @@ type Config struct {
 	//>
 	//> Remove field if conversion fails.
 	RemoveOnFail bool `json:"remove_on_fail" default:"false"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field which is set to `true` if conversion fails. The marker isn't set if `error_field` is empty.
+	ErrorField string `json:"error_field"` //*
 }
 
 func init() {
@@ -56,20 +94,17 @@ func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
 func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
 	p.config = config.(*Config)
 
+	// the config is shared between processors, so the list is built from scratch
+	sourceFormats := make([]string, 0, len(p.config.SourceFormats))
 	for _, formatName := range p.config.SourceFormats {
-		format, err := pipeline.ParseFormatName(formatName)
-		if err != nil {
-			format = formatName
-		}
-		p.config.SourceFormats_ = append(p.config.SourceFormats_, format)
+		sourceFormats = append(sourceFormats, parseFormat(formatName))
 	}
+	p.config.SourceFormats_ = sourceFormats
 
-	format, err := pipeline.ParseFormatName(p.config.TargetFormat)
-	if err != nil {
-		format = p.config.TargetFormat
+	p.config.TargetFormat_ = parseFormat(p.config.TargetFormat)
+	if p.config.TargetFormat_ == formatTimestamp {
+		p.config.TargetFormat_ = formatUnix
 	}
-
-	p.config.TargetFormat_ = format
 }
 
 func (p *Plugin) Stop() {
@@ -84,16 +119,26 @@ func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
 	if isValidType {
 		date := dateNode.AsString()
 		for _, format := range p.config.SourceFormats_ {
-			t, err := time.Parse(format, date)
-			if err == nil {
-				if p.config.TargetFormat_ == "timestamp" {
-					dateNode.MutateToInt(int(t.Unix()))
-				} else {
-					dateNode.MutateToString(t.Format(p.config.TargetFormat_))
-				}
-
-				return pipeline.ActionPass // successful conversion
+			t, ok := parseTime(format, date)
+			if !ok {
+				continue
+			}
+
+			targetNode := dateNode
+			if len(p.config.TargetField_) != 0 {
+				targetNode = pipeline.CreateNestedField(event.Root, p.config.TargetField_)
+			}
+
+			switch p.config.TargetFormat_ {
+			case formatUnix:
+				targetNode.MutateToInt(int(t.Unix()))
+			case formatUnixMilli:
+				targetNode.MutateToInt(int(t.UnixNano() / int64(time.Millisecond)))
+			default:
+				targetNode.MutateToString(t.Format(p.config.TargetFormat_))
 			}
+
+			return pipeline.ActionPass // successful conversion
 		}
 	}
 
@@ -101,6 +146,48 @@ func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
 	if p.config.RemoveOnFail {
 		dateNode.Suicide()
 	}
+	if p.config.ErrorField != "" {
+		event.Root.AddFieldNoAlloc(event.Root, p.config.ErrorField).MutateToBool(true)
+	}
 
 	return pipeline.ActionPass
 }
+
+func parseFormat(formatName string) string {
+	switch formatName {
+	case formatUnix, formatUnixMilli, formatTimestamp:
+		return formatName
+	}
+
+	format, err := pipeline.ParseFormatName(formatName)
+	if err != nil {
+		return formatName
+	}
+
+	return format
+}
+
+func parseTime(format string, date string) (time.Time, bool) {
+	switch format {
+	case formatUnix, formatUnixMilli:
+		unit := time.Second
+		if format == formatUnixMilli {
+			unit = time.Millisecond
+		}
+
+		if x, err := strconv.ParseInt(date, 10, 64); err == nil {
+			return time.Unix(0, x*int64(unit)).UTC(), true
+		}
+
+		x, err := strconv.ParseFloat(date, 64)
+		if err != nil || math.IsNaN(x) || math.IsInf(x, 0) {
+			return time.Time{}, false
+		}
+		// the integer part is converted separately to keep the precision of the fractional part
+		whole := math.Floor(x)
+		return time.Unix(0, int64(whole)*int64(unit)+int64(math.Round((x-whole)*float64(unit)))).UTC(), true
+	default:
+		t, err := time.Parse(format, date)
+		return t, err == nil
+	}
+}
@@ -76,3 +76,88 @@ func TestConvertFail(t *testing.T) {
 	assert.Equal(t, 1, len(outEvents), "wrong out events count")
 	assert.Equal(t, `{}`, outEvents[0].Root.EncodeToString(), "wrong out event")
 }
+
+func TestConvertFormats(t *testing.T) {
+	config := test.NewConfig(&Config{
+		Field:         "ts",
+		SourceFormats: []string{"unixmilli", "2006-01-02 15:04:05", "rfc3339"},
+		TargetField:   "meta.time",
+		TargetFormat:  "rfc3339nano",
+		ErrorField:    "time_invalid",
+	}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+	wg := &sync.WaitGroup{}
+	wg.Add(6)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"ts":1609459200123}`))
+	input.In(0, "test.log", 0, []byte(`{"ts":"1609459200123"}`))
+	input.In(0, "test.log", 0, []byte(`{"ts":"2021-01-01 03:04:05"}`))
+	input.In(0, "test.log", 0, []byte(`{"ts":"2021-01-01T03:04:05+03:00"}`))
+	input.In(0, "test.log", 0, []byte(`{"ts":"yesterday"}`))
+	input.In(0, "test.log", 0, []byte(`{"ts":{"nested":1}}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"ts":1609459200123,"meta":{"time":"2021-01-01T00:00:00.123Z"}}`,
+		`{"ts":"1609459200123","meta":{"time":"2021-01-01T00:00:00.123Z"}}`,
+		`{"ts":"2021-01-01 03:04:05","meta":{"time":"2021-01-01T03:04:05Z"}}`,
+		`{"ts":"2021-01-01T03:04:05+03:00","meta":{"time":"2021-01-01T03:04:05+03:00"}}`,
+		`{"ts":"yesterday","time_invalid":true}`,
+		`{"ts":{"nested":1},"time_invalid":true}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestConvertUnix(t *testing.T) {
+	config := test.NewConfig(&Config{SourceFormats: []string{"unix"}, TargetFormat: "unixmilli"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"time":1609459200}`))
+	input.In(0, "test.log", 0, []byte(`{"time":1609459200.25}`))
+	input.In(0, "test.log", 0, []byte(`{"time":"2021-01-01T00:00:00Z"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"time":1609459200000}`,
+		`{"time":1609459200250}`,
+		`{"time":"2021-01-01T00:00:00Z"}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestConvertDefaultFormats(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"time":"2021-01-01T00:00:00.5Z"}`))
+	input.In(0, "test.log", 0, []byte(`{"time":"2021-01-01T00:00:01Z"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{`{"time":1609459200}`, `{"time":1609459201}`}, outEvents, "wrong out events")
+}
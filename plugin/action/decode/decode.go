@@ -0,0 +1,131 @@
+package decode
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It decodes the base64, base64url or hex encoded value of the event field in place.
+If `gunzip` is set, the decoded bytes are gunzipped too, so gzipped payloads are unpacked by the single action.
+The event is left untouched if the value can't be decoded or gunzipped. The field isn't parsed, so
+use `json_decode` after this action to parse the decoded JSON.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: decode
+      field: payload
+      encoding: base64
+      gunzip: true
+    - type: json_decode
+      field: payload
+    ...
+```
+}*/
+type Plugin struct {
+	config   *Config
+	buf      []byte
+	unzipped []byte
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to decode.
+	Field  cfg.FieldSelector `json:"field" required:"true" parse:"selector"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The encoding of the value. `base64` and `base64url` values should be padded.
+	Encoding string `json:"encoding" default:"base64" options:"base64|base64url|hex"` //*
+
+	//> @3@4@5@6
+	//>
+	//> If set, the decoded bytes are gunzipped.
+	Gunzip bool `json:"gunzip" default:"false"` //*
+}
+
+const (
+	encodingBase64URL = "base64url"
+	encodingHex       = "hex"
+)
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "decode",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil || !node.IsString() {
+		return pipeline.ActionPass
+	}
+
+	var err error
+	p.buf, err = p.decode(p.buf[:0], node.AsBytes())
+	if err != nil {
+		return pipeline.ActionPass
+	}
+
+	value := p.buf
+	if p.config.Gunzip {
+		p.unzipped, err = pipeline.AppendDecompressed(p.unzipped[:0], p.buf, pipeline.CompressGzip)
+		if err != nil {
+			return pipeline.ActionPass
+		}
+		value = p.unzipped
+	}
+
+	node.MutateToBytesCopy(event.Root, value)
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) decode(out []byte, value []byte) ([]byte, error) {
+	var n int
+	var err error
+	switch p.config.Encoding {
+	case encodingHex:
+		out = grow(out, hex.DecodedLen(len(value)))
+		n, err = hex.Decode(out, value)
+	case encodingBase64URL:
+		out = grow(out, base64.URLEncoding.DecodedLen(len(value)))
+		n, err = base64.URLEncoding.Decode(out, value)
+	default:
+		out = grow(out, base64.StdEncoding.DecodedLen(len(value)))
+		n, err = base64.StdEncoding.Decode(out, value)
+	}
+
+	return out[:n], err
+}
+
+func grow(buf []byte, n int) []byte {
+	if cap(buf) < n {
+		return make([]byte, n)
+	}
+	return buf[:n]
+}
@@ -0,0 +1,87 @@
+package decode
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func runEvents(config *Config, events []string) []string {
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, test.NewConfig(config, nil), pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(events))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, event := range events {
+		input.In(0, "test.log", 0, []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		encoding string
+		in       string
+		out      string
+	}{
+		{encoding: "base64", in: `aGVsbG8/Pg==`, out: `hello?>`},
+		{encoding: "base64url", in: `aGVsbG8_Pg==`, out: `hello?>`},
+		{encoding: "hex", in: `68656c6c6f3f3e`, out: `hello?>`},
+		{encoding: "hex", in: `68656C6C6F`, out: `hello`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			outEvents := runEvents(&Config{Field: "data.payload", Encoding: tt.encoding}, []string{
+				`{"data":{"payload":"` + tt.in + `"}}`,
+			})
+
+			assert.Equal(t, []string{`{"data":{"payload":"` + tt.out + `"}}`}, outEvents, "wrong out events")
+		})
+	}
+}
+
+func TestDecodeGunzip(t *testing.T) {
+	outEvents := runEvents(&Config{Field: "payload", Encoding: "base64", Gunzip: true}, []string{
+		`{"payload":"H4sIAAAAAAACA6tWSlSyMqwFAK+sG1YHAAAA"}`,
+		`{"payload":"aGVsbG8/Pg=="}`,
+	})
+
+	assert.Equal(t, []string{
+		`{"payload":"{\"a\":1}"}`,
+		`{"payload":"aGVsbG8/Pg=="}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	events := []string{
+		`{"payload":"aGVsbG8_Pg=="}`,
+		`{"payload":"aGVsbG8"}`,
+		`{"payload":"not base64!"}`,
+		`{"payload":{"a":"aGVsbG8/Pg=="}}`,
+		`{"payload":123}`,
+		`{"message":"aGVsbG8/Pg=="}`,
+	}
+	outEvents := runEvents(&Config{Field: "payload", Encoding: "base64"}, events)
+	assert.Equal(t, events, outEvents, "malformed values should be left untouched")
+
+	events = []string{
+		`{"payload":"6865z"}`,
+		`{"payload":"686"}`,
+	}
+	outEvents = runEvents(&Config{Field: "payload", Encoding: "hex"}, events)
+	assert.Equal(t, events, outEvents, "malformed values should be left untouched")
+}
@@ -0,0 +1,242 @@
+package dedup_bucket
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const defaultMaxKeys = 100000
+
+var (
+	// dedupers should be shared across processors of the action, all of them receive the same config, so let's have a map by config
+	dedupers   = map[*Config]*deduper{}
+	dedupersMu = &sync.Mutex{}
+)
+
+/*{ introduction
+It discards repeated events within fixed time buckets, e.g. per minute, which is useful for idempotent sinks.
+Events are repeated if they have the same values of `fields` and their time belongs to the same bucket: `floor(time / bucket)`.
+Unlike a sliding window, the first event of the next bucket is always passed.
+
+The time is taken from `time_field`, the current time is used if the field is absent or can't be parsed.
+Numeric time is treated as unix timestamp in seconds.
+Keys of the current and the previous buckets are kept, keys of older buckets are removed.
+Discarded events are counted by `dedup_bucket_dropped_events_total` metric of the pipeline.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: dedup_bucket
+      fields:
+      - request_id
+      bucket: 1m
+    ...
+```
+}*/
+type Plugin struct {
+	config  *Config
+	logger  *zap.SugaredLogger
+	deduper *deduper
+
+	fields [][]string
+	keyBuf []byte
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The list of the fields which make the key of the event. Nested fields can be used.
+	Fields []cfg.FieldSelector `json:"fields" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field which defines the time when event was fired.
+	TimeField  cfg.FieldSelector `json:"time_field" parse:"selector" default:"time"` //*
+	TimeField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> It defines how to parse the time field. Any of `ansic|unixdate|rubydate|rfc822|rfc822z|rfc850|rfc1123|rfc1123z|rfc3339|rfc3339nano|kitchen|stamp|stampmilli|stampmicro|stampnano` or Go time layout can be used.
+	TimeFieldFormat  string `json:"time_field_format" default:"rfc3339nano"` //*
+	TimeFieldFormat_ string
+
+	//> @3@4@5@6
+	//>
+	//> The size of the time bucket.
+	Bucket  cfg.Duration `json:"bucket" parse:"duration" default:"1m"` //*
+	Bucket_ time.Duration
+
+	//> @3@4@5@6
+	//>
+	//> The maximum number of keys to keep. When the limit is reached, keys of the oldest bucket are removed. `100000` if not set.
+	MaxKeys int `json:"max_keys"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "dedup_bucket",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger
+
+	if p.config.Bucket_ <= 0 {
+		p.logger.Fatalf("bucket should be positive, got=%s", p.config.Bucket)
+	}
+	if p.config.MaxKeys <= 0 {
+		p.config.MaxKeys = defaultMaxKeys
+	}
+
+	format, err := pipeline.ParseFormatName(p.config.TimeFieldFormat)
+	if err != nil {
+		format = p.config.TimeFieldFormat
+	}
+	p.config.TimeFieldFormat_ = format
+
+	p.fields = make([][]string, 0, len(p.config.Fields))
+	for _, field := range p.config.Fields {
+		p.fields = append(p.fields, cfg.ParseFieldSelector(string(field)))
+	}
+
+	dropped := params.MetricsCtl.RegisterCounter("dedup_bucket_dropped_events_total", "how many events are discarded by dedup_bucket action").WithLabelValues()
+
+	dedupersMu.Lock()
+	d, has := dedupers[p.config]
+	if !has {
+		d = newDeduper(p.config.MaxKeys, dropped)
+		dedupers[p.config] = d
+	}
+	dedupersMu.Unlock()
+
+	p.deduper = d
+}
+
+func (p *Plugin) Stop() {
+	dedupersMu.Lock()
+	delete(dedupers, p.config)
+	dedupersMu.Unlock()
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	p.keyBuf = p.keyBuf[:0]
+	for _, field := range p.fields {
+		p.keyBuf = append(p.keyBuf, event.Root.Dig(field...).AsString()...)
+		// zero byte separates values, so "ab"+"c" and "a"+"bc" keys are different
+		p.keyBuf = append(p.keyBuf, 0)
+	}
+
+	bucket := p.eventTime(event).UnixNano() / int64(p.config.Bucket_)
+	if p.deduper.isDuplicate(bucket, p.keyBuf) {
+		return pipeline.ActionDiscard
+	}
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) eventTime(event *pipeline.Event) time.Time {
+	node := event.Root.Dig(p.config.TimeField_...)
+	if node == nil {
+		return time.Now()
+	}
+
+	if node.IsNumber() {
+		return time.Unix(int64(node.AsInt()), 0)
+	}
+
+	t, err := time.Parse(p.config.TimeFieldFormat_, node.AsString())
+	if err != nil {
+		return time.Now()
+	}
+
+	return t
+}
+
+type deduper struct {
+	mu      *sync.Mutex
+	maxKeys int
+	dropped prometheus.Counter
+
+	buckets   map[int64]map[string]bool
+	newest    int64
+	keysCount int
+}
+
+func newDeduper(maxKeys int, dropped prometheus.Counter) *deduper {
+	return &deduper{
+		mu:      &sync.Mutex{},
+		maxKeys: maxKeys,
+		dropped: dropped,
+		buckets: make(map[int64]map[string]bool),
+	}
+}
+
+func (d *deduper) isDuplicate(bucket int64, key []byte) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if bucket > d.newest {
+		d.newest = bucket
+		d.removeBuckets(func(b int64) bool { return b < bucket-1 })
+	}
+
+	keys, has := d.buckets[bucket]
+	if !has {
+		keys = make(map[string]bool)
+		d.buckets[bucket] = keys
+	}
+
+	if keys[string(key)] {
+		d.dropped.Inc()
+		return true
+	}
+
+	if d.keysCount >= d.maxKeys {
+		d.removeOldestBucket()
+		if _, has := d.buckets[bucket]; !has {
+			keys = make(map[string]bool)
+			d.buckets[bucket] = keys
+		}
+	}
+
+	keys[string(key)] = true
+	d.keysCount++
+
+	return false
+}
+
+func (d *deduper) removeBuckets(shouldRemove func(b int64) bool) {
+	for b, keys := range d.buckets {
+		if shouldRemove(b) {
+			d.keysCount -= len(keys)
+			delete(d.buckets, b)
+		}
+	}
+}
+
+func (d *deduper) removeOldestBucket() {
+	oldest := d.newest
+	for b := range d.buckets {
+		if b < oldest {
+			oldest = b
+		}
+	}
+	d.removeBuckets(func(b int64) bool { return b == oldest })
+}
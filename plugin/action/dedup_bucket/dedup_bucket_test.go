@@ -0,0 +1,104 @@
+package dedup_bucket
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/logger"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupBucket(t *testing.T) {
+	config := test.NewConfig(&Config{Fields: []cfg.FieldSelector{"user", "req.action"}, TimeField: "ts", Bucket: "1m"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	events := []struct {
+		json   string
+		passed bool
+	}{
+		{`{"id":1,"user":"bob","req":{"action":"buy"},"ts":"2021-07-01T10:00:05Z"}`, true},
+		{`{"id":2,"user":"bob","req":{"action":"buy"},"ts":"2021-07-01T10:00:50Z"}`, false}, // same bucket
+		{`{"id":3,"user":"bob","req":{"action":"sell"},"ts":"2021-07-01T10:00:51Z"}`, true}, // other key
+		{`{"id":4,"user":"alice","req":{"action":"buy"},"ts":"2021-07-01T10:00:52Z"}`, true},
+		{`{"id":5,"user":"bob","req":{"action":"buy"},"ts":"2021-07-01T10:01:00Z"}`, true}, // next bucket
+		{`{"id":6,"user":"bob","req":{"action":"buy"},"ts":"2021-07-01T10:01:59.999Z"}`, false},
+		{`{"id":7,"user":"bob","req":{"action":"buy"},"ts":1625133720}`, true}, // 10:02:00 as unix timestamp
+		{`{"id":8,"user":"bob","req":{"action":"buy"},"ts":1625133779}`, false},
+		{`{"id":9,"user":"bo","req":{"action":"bbuy"},"ts":1625133779}`, true},
+	}
+
+	passedCount := 0
+	for _, e := range events {
+		if e.passed {
+			passedCount++
+		}
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(passedCount)
+
+	passed := make([]int, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		passed = append(passed, e.Root.Dig("id").AsInt())
+		wg.Done()
+	})
+
+	for _, e := range events {
+		input.In(0, "test.log", 0, []byte(e.json))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []int{1, 3, 4, 5, 7, 9}, passed, "wrong passed events")
+
+	dropped := p.GetMetricsCtl().RegisterCounter("dedup_bucket_dropped_events_total", "")
+	assert.Equal(t, float64(3), testutil.ToFloat64(dropped.WithLabelValues()), "wrong dropped metric")
+}
+
+func TestDedupBucketKeys(t *testing.T) {
+	d := newDeduper(3, prometheus.NewCounter(prometheus.CounterOpts{Name: "dropped"}))
+
+	assert.False(t, d.isDuplicate(10, []byte("a")), "event isn't duplicate")
+	assert.False(t, d.isDuplicate(11, []byte("a")), "event isn't duplicate")
+	assert.True(t, d.isDuplicate(10, []byte("a")), "event of the previous bucket is duplicate")
+
+	assert.False(t, d.isDuplicate(12, []byte("a")), "event isn't duplicate")
+	assert.Equal(t, 2, len(d.buckets), "old buckets aren't removed")
+	assert.Equal(t, 2, d.keysCount, "wrong keys count")
+
+	assert.False(t, d.isDuplicate(12, []byte("b")), "event isn't duplicate")
+	assert.False(t, d.isDuplicate(12, []byte("c")), "event isn't duplicate")
+	assert.Nil(t, d.buckets[11], "the oldest bucket isn't removed when keys limit is reached")
+	assert.Equal(t, 3, d.keysCount, "wrong keys count")
+	assert.True(t, d.isDuplicate(12, []byte("b")), "event is duplicate")
+}
+
+func TestDedupBucketDeduperPerAction(t *testing.T) {
+	start := func(config *Config) *Plugin {
+		plugin := &Plugin{}
+		plugin.Start(config, &pipeline.ActionPluginParams{PluginDefaultParams: test.NewEmptyPluginDefaultParams(), Logger: logger.Instance})
+		return plugin
+	}
+
+	config := test.NewConfig(&Config{Fields: []cfg.FieldSelector{"user"}, Bucket: "1m"}, nil).(*Config)
+	first := start(config)
+	second := start(config)
+	other := start(test.NewConfig(&Config{Fields: []cfg.FieldSelector{"request"}, Bucket: "1m"}, nil).(*Config))
+
+	assert.True(t, first.deduper == second.deduper, "processors of the action should share the deduper")
+	assert.True(t, first.deduper != other.deduper, "actions of the pipeline shouldn't share the deduper")
+
+	first.Stop()
+	second.Stop()
+	other.Stop()
+
+	restarted := start(config)
+	assert.True(t, first.deduper != restarted.deduper, "deduper shouldn't survive the action stop")
+	restarted.Stop()
+}
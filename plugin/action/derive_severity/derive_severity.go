@@ -0,0 +1,240 @@
+package derive_severity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It sets the severity derived from several event fields, e.g. status code, latency and error flags.
+Rules are checked in the order of definition and the severity of the first matched rule is set.
+If no rule is matched, `default` severity is set.
+
+Each rule has `match_fields` with the same syntax as the action `match_fields`: exact value or `/regexp/`,
+exact values match by prefix in `and_prefix` and `or_prefix` match modes.
+Additionally, numeric comparisons are supported: `>N`, `>=N`, `<N`, `<=N`.
+Field names are handled as `cfg.FieldSelector`, so nested fields can be used.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: derive_severity
+      rules:
+      - match_fields:
+          status: /^5/
+        severity: error
+      - match_fields:
+          error: "true"
+          latency_ms: ">1000"
+        match_mode: or
+        severity: warn
+      default: info
+    ...
+```
+}*/
+type Plugin struct {
+	config *Config
+	logger *zap.SugaredLogger
+	rules  []*rule
+}
+
+type rule struct {
+	conds    []*condition
+	or       bool
+	prefix   bool
+	severity string
+}
+
+type opKind int
+
+const (
+	opMatch opKind = iota
+	opGreater
+	opGreaterOrEqual
+	opLess
+	opLessOrEqual
+)
+
+type condition struct {
+	field  []string
+	op     opKind
+	match  pipeline.MatchCondition
+	number float64
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to which put the severity.
+	Field string `json:"field" default:"severity"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The list of rules. Each item has the following fields:
+	//> * `match_fields` – conditions of the rule.
+	//> * `match_mode` – `and`, `or`, `and_prefix` or `or_prefix`, the way conditions are combined, `and` by default.
+	//> * `severity` – the severity to set if the rule is matched.
+	Rules []RuleConfig `json:"rules" slice:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The severity which is set if no rule is matched.
+	Default string `json:"default" default:"info"` //*
+}
+
+type RuleConfig struct {
+	MatchFields map[string]string `json:"match_fields" required:"true"`
+	MatchMode   string            `json:"match_mode" default:"and" options:"and|or|and_prefix|or_prefix"`
+	Severity    string            `json:"severity" required:"true"`
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "derive_severity",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger
+
+	p.rules = make([]*rule, 0, len(p.config.Rules))
+	for i, ruleConfig := range p.config.Rules {
+		r := &rule{
+			conds:    make([]*condition, 0, len(ruleConfig.MatchFields)),
+			or:       ruleConfig.MatchMode == "or" || ruleConfig.MatchMode == "or_prefix",
+			prefix:   ruleConfig.MatchMode == "and_prefix" || ruleConfig.MatchMode == "or_prefix",
+			severity: ruleConfig.Severity,
+		}
+
+		for field, value := range ruleConfig.MatchFields {
+			cond, err := parseCondition(field, value)
+			if err != nil {
+				p.logger.Fatalf("wrong condition in rule #%d: %s", i, err.Error())
+			}
+			r.conds = append(r.conds, cond)
+		}
+
+		p.rules = append(p.rules, r)
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	severity := p.config.Default
+	for _, r := range p.rules {
+		if r.isMatch(event) {
+			severity = r.severity
+			break
+		}
+	}
+
+	event.Root.AddFieldNoAlloc(event.Root, p.config.Field).MutateToString(severity)
+
+	return pipeline.ActionPass
+}
+
+func parseCondition(field string, value string) (*condition, error) {
+	cond := &condition{
+		field: cfg.ParseFieldSelector(field),
+		op:    opMatch,
+	}
+
+	ops := []struct {
+		prefix string
+		kind   opKind
+	}{
+		// longer prefixes go first
+		{">=", opGreaterOrEqual},
+		{"<=", opLessOrEqual},
+		{">", opGreater},
+		{"<", opLess},
+	}
+	for _, op := range ops {
+		if !strings.HasPrefix(value, op.prefix) {
+			continue
+		}
+
+		number, err := strconv.ParseFloat(strings.TrimSpace(value[len(op.prefix):]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("wrong number in comparison %q for field %s", value, field)
+		}
+		cond.op = op.kind
+		cond.number = number
+		return cond, nil
+	}
+
+	match, err := pipeline.NewMatchCondition(field, value)
+	if err != nil {
+		return nil, err
+	}
+	cond.match = match
+
+	return cond, nil
+}
+
+func (r *rule) isMatch(event *pipeline.Event) bool {
+	for _, cond := range r.conds {
+		match := cond.isMatch(event, r.prefix)
+		if r.or && match {
+			return true
+		}
+		if !r.or && !match {
+			return false
+		}
+	}
+
+	return !r.or
+}
+
+func (c *condition) isMatch(event *pipeline.Event, prefix bool) bool {
+	node := event.Root.Dig(c.field...)
+	if node == nil {
+		return false
+	}
+
+	value := node.AsString()
+	if c.op == opMatch {
+		mode := pipeline.MatchModeAnd
+		if prefix {
+			mode = pipeline.MatchModeAndPrefix
+		}
+		return c.match.MatchValue(value, mode)
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+
+	switch c.op {
+	case opGreater:
+		return number > c.number
+	case opGreaterOrEqual:
+		return number >= c.number
+	case opLess:
+		return number < c.number
+	case opLessOrEqual:
+		return number <= c.number
+	default:
+		return false
+	}
+}
@@ -0,0 +1,112 @@
+package derive_severity
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeriveSeverity(t *testing.T) {
+	config := test.NewConfig(&Config{
+		Rules: []RuleConfig{
+			{MatchFields: map[string]string{"status": "/^5/"}, Severity: "error"},
+			{MatchFields: map[string]string{"error": "true", "timing.latency_ms": ">=1000"}, MatchMode: "or", Severity: "warn"},
+			{MatchFields: map[string]string{"status": "404", "path": "admin"}, Severity: "notice"},
+		},
+	}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(7)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"status":503,"error":true,"timing":{"latency_ms":5000}}`))
+	input.In(0, "test.log", 0, []byte(`{"status":200,"error":true}`))
+	input.In(0, "test.log", 0, []byte(`{"status":200,"error":false,"timing":{"latency_ms":1000}}`))
+	input.In(0, "test.log", 0, []byte(`{"status":200,"error":false,"timing":{"latency_ms":"999.5"}}`))
+	input.In(0, "test.log", 0, []byte(`{"status":"404","path":"admin"}`))
+	input.In(0, "test.log", 0, []byte(`{"status":404,"path":"/"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"no signals"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 7, len(outEvents), "wrong out events count")
+	assert.Equal(t, "error", outEvents[0].Root.Dig("severity").AsString(), "wrong severity")
+	assert.Equal(t, "warn", outEvents[1].Root.Dig("severity").AsString(), "wrong severity")
+	assert.Equal(t, "warn", outEvents[2].Root.Dig("severity").AsString(), "wrong severity")
+	assert.Equal(t, "info", outEvents[3].Root.Dig("severity").AsString(), "wrong severity")
+	assert.Equal(t, "notice", outEvents[4].Root.Dig("severity").AsString(), "wrong severity")
+	assert.Equal(t, "info", outEvents[5].Root.Dig("severity").AsString(), "wrong severity")
+	assert.Equal(t, "info", outEvents[6].Root.Dig("severity").AsString(), "wrong severity")
+}
+
+func TestDeriveSeverityDefault(t *testing.T) {
+	config := test.NewConfig(&Config{
+		Field:   "level",
+		Default: "debug",
+		Rules: []RuleConfig{
+			{MatchFields: map[string]string{"latency_ms": "<10"}, Severity: "trace"},
+		},
+	}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"latency_ms":5}`))
+	input.In(0, "test.log", 0, []byte(`{"latency_ms":50}`))
+	input.In(0, "test.log", 0, []byte(`{"latency_ms":"fast","level":"error"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 3, len(outEvents), "wrong out events count")
+	assert.Equal(t, "trace", outEvents[0].Root.Dig("level").AsString(), "wrong severity")
+	assert.Equal(t, "debug", outEvents[1].Root.Dig("level").AsString(), "wrong severity")
+	assert.Equal(t, "debug", outEvents[2].Root.Dig("level").AsString(), "wrong severity")
+}
+
+func TestDeriveSeverityPrefix(t *testing.T) {
+	config := test.NewConfig(&Config{
+		Rules: []RuleConfig{
+			{MatchFields: map[string]string{"service": "payments", "status": "5"}, MatchMode: "and_prefix", Severity: "critical"},
+		},
+	}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"service":"payments-api","status":502}`))
+	input.In(0, "test.log", 0, []byte(`{"service":"payments-api","status":200}`))
+	input.In(0, "test.log", 0, []byte(`{"status":500}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 3, len(outEvents), "wrong out events count")
+	assert.Equal(t, "critical", outEvents[0].Root.Dig("severity").AsString(), "wrong severity")
+	assert.Equal(t, "info", outEvents[1].Root.Dig("severity").AsString(), "wrong severity")
+	assert.Equal(t, "info", outEvents[2].Root.Dig("severity").AsString(), "wrong severity")
+}
@@ -0,0 +1,112 @@
+package detect_truncation
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It detects truncation markers added by upstream systems at the end of the event field, e.g. `...[truncated]`.
+If the field ends with one of `markers`, `truncated_field` is set to `true`.
+If `strip_marker` is set, the marker and the whitespaces before it are removed from the field.
+Untruncated events, events without the field and events with a non-string field are passed unchanged.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: detect_truncation
+      field: message
+      strip_marker: true
+    ...
+```
+It transforms `{"message":"stacktrace: at main.go ...[truncated]"}` into `{"message":"stacktrace: at main.go","_truncated":true}`.
+}*/
+type Plugin struct {
+	config  *Config
+	markers []string
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to check for the truncation marker.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"message"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The list of the truncation markers. The longest matching marker is used.
+	Markers []string `json:"markers" default:"...[truncated] [truncated] <truncated>"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field which is set to `true` if the truncation marker is found.
+	TruncatedField string `json:"truncated_field" default:"_truncated"` //*
+
+	//> @3@4@5@6
+	//>
+	//> If set, the truncation marker is removed from the field.
+	StripMarker bool `json:"strip_marker" default:"false"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "detect_truncation",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+
+	p.markers = make([]string, 0, len(p.config.Markers))
+	for _, marker := range p.config.Markers {
+		if marker == "" {
+			params.Logger.Fatalf("truncation marker can't be empty")
+		}
+		p.markers = append(p.markers, marker)
+	}
+
+	// longer markers go first, so "...[truncated]" wins over "[truncated]"
+	sort.SliceStable(p.markers, func(i, j int) bool {
+		return len(p.markers[i]) > len(p.markers[j])
+	})
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil || !node.IsString() {
+		return pipeline.ActionPass
+	}
+
+	value := node.AsString()
+	for _, marker := range p.markers {
+		if !strings.HasSuffix(value, marker) {
+			continue
+		}
+
+		if p.config.StripMarker {
+			node.MutateToString(strings.TrimRight(value[:len(value)-len(marker)], " \t"))
+		}
+		event.Root.AddFieldNoAlloc(event.Root, p.config.TruncatedField).MutateToBool(true)
+		break
+	}
+
+	return pipeline.ActionPass
+}
@@ -0,0 +1,71 @@
+package detect_truncation
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func runEvents(t *testing.T, config *Config, events []string) []string {
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, test.NewConfig(config, nil), pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(events))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, event := range events {
+		input.In(0, "test.log", 0, []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+func TestDetectTruncation(t *testing.T) {
+	outEvents := runEvents(t, &Config{}, []string{
+		`{"message":"long stacktrace ...[truncated]"}`,
+		`{"message":"body <truncated>"}`,
+		`{"message":"complete message"}`,
+		`{"message":"[truncated] marker isn't at the end"}`,
+		`{"message":42}`,
+		`{"level":"info"}`,
+	})
+
+	assert.Equal(t, []string{
+		`{"message":"long stacktrace ...[truncated]","_truncated":true}`,
+		`{"message":"body <truncated>","_truncated":true}`,
+		`{"message":"complete message"}`,
+		`{"message":"[truncated] marker isn't at the end"}`,
+		`{"message":42}`,
+		`{"level":"info"}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestDetectTruncationStripMarker(t *testing.T) {
+	outEvents := runEvents(t, &Config{
+		Field:          "log.text",
+		Markers:        []string{"[cut]", "...[cut]"},
+		TruncatedField: "cut",
+		StripMarker:    true,
+	}, []string{
+		`{"log":{"text":"first part ...[cut]"}}`,
+		`{"log":{"text":"second part[cut]"}}`,
+		`{"log":{"text":"whole part"}}`,
+	})
+
+	assert.Equal(t, []string{
+		`{"log":{"text":"first part"},"cut":true}`,
+		`{"log":{"text":"second part"},"cut":true}`,
+		`{"log":{"text":"whole part"}}`,
+	}, outEvents, "wrong out events")
+}
@@ -7,6 +7,7 @@ import (
 
 /*{ introduction
 It drops an event. It is used in a combination with `match_fields`/`match_mode` parameters to filter out the events.
+A value of `match_fields` is an exact value or `/regexp/`, exact values match values starting with them if `match_mode` is `and_prefix` or `or_prefix`.
 
 **An example for discarding informational and debug logs:**
 ```yaml
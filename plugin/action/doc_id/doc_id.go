@@ -0,0 +1,147 @@
+package doc_id
+
+import (
+	"encoding/hex"
+	"hash"
+	"hash/fnv"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It computes a deterministic document ID from the event and puts it into the event field.
+Elasticsearch output uses the field (`_id` by default) as the document `_id`, so resending the same events doesn't create duplicates.
+
+In `hash` mode the ID is hex encoded FNV-1a 128-bit hash of `fields` values, or of the whole event if `fields` are empty.
+In `concat` mode the ID is `fields` values joined with `separator`, missing fields are treated as empty strings.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: doc_id
+      fields:
+        - request_id
+        - time
+    ...
+    output:
+      type: elasticsearch
+      ...
+```
+}*/
+type Plugin struct {
+	config *Config
+	logger *zap.SugaredLogger
+	fields [][]string
+	hash   hash.Hash
+	buf    []byte
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The list of event fields to compute the ID from. The whole event is used if empty, it's allowed only in `hash` mode.
+	Fields []cfg.FieldSelector `json:"fields"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The way the ID is computed.
+	Mode string `json:"mode" default:"hash" options:"hash|concat"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The separator of values in `concat` mode.
+	Separator string `json:"separator" default:"_"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field to put the ID to.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"_id"` //*
+	Field_ []string
+}
+
+const (
+	modeHash   = "hash"
+	modeConcat = "concat"
+)
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "doc_id",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger
+
+	if p.config.Mode == modeConcat && len(p.config.Fields) == 0 {
+		p.logger.Fatalf("fields should be set in %s mode", modeConcat)
+	}
+
+	p.fields = make([][]string, 0, len(p.config.Fields))
+	for _, field := range p.config.Fields {
+		p.fields = append(p.fields, cfg.ParseFieldSelector(string(field)))
+	}
+
+	p.hash = fnv.New128a()
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	p.buf = p.buf[:0]
+	if p.config.Mode == modeConcat {
+		for i, field := range p.fields {
+			if i > 0 {
+				p.buf = append(p.buf, p.config.Separator...)
+			}
+			p.buf = append(p.buf, event.Root.Dig(field...).AsString()...)
+		}
+	} else {
+		p.buf = p.appendHash(p.buf, event)
+	}
+
+	id := string(p.buf)
+	pipeline.CreateNestedField(event.Root, p.config.Field_).MutateToString(id)
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) appendHash(out []byte, event *pipeline.Event) []byte {
+	p.hash.Reset()
+	if len(p.fields) == 0 {
+		// the field itself isn't hashed, so the ID is the same if it's computed twice
+		event.Root.Dig(p.config.Field_...).Suicide()
+		out = event.Root.Encode(out)
+		_, _ = p.hash.Write(out)
+		out = out[:0]
+	}
+
+	for _, field := range p.fields {
+		node := event.Root.Dig(field...)
+		// distinguish missing fields from empty ones and values from their concatenations
+		if node == nil {
+			_, _ = p.hash.Write([]byte{0})
+			continue
+		}
+		_, _ = p.hash.Write([]byte{1})
+		_, _ = p.hash.Write(node.AsBytes())
+		_, _ = p.hash.Write([]byte{0})
+	}
+
+	return append(out, hex.EncodeToString(p.hash.Sum(nil))...)
+}
@@ -0,0 +1,76 @@
+package doc_id
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func runDocID(config *Config, events []string) []string {
+	test.NewConfig(config, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(events))
+
+	ids := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		ids = append(ids, e.Root.Dig(config.Field_...).AsString())
+		wg.Done()
+	})
+
+	for _, event := range events {
+		input.In(0, "test.log", 0, []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return ids
+}
+
+func TestDocIDHash(t *testing.T) {
+	events := []string{
+		`{"request_id":"1","time":"2021-01-01","message":"first"}`,
+		`{"time":"2021-01-01","request_id":"1","message":"second"}`,
+		`{"request_id":"11","time":"0","message":"third"}`,
+		`{"request_id":"110","message":"fourth"}`,
+		`{"request_id":"110","time":"","message":"fifth"}`,
+	}
+	config := &Config{Fields: []cfg.FieldSelector{"request_id", "time"}}
+
+	ids := runDocID(config, events)
+	assert.Equal(t, 5, len(ids), "wrong out events count")
+	assert.Equal(t, 32, len(ids[0]), "wrong id length")
+	assert.Equal(t, ids[0], ids[1], "ids of the same fields should be equal")
+	assert.NotEqual(t, ids[0], ids[2], "ids of different fields should differ")
+	assert.NotEqual(t, ids[2], ids[3], "ids of different fields should differ")
+	assert.NotEqual(t, ids[3], ids[4], "missing and empty fields should differ")
+
+	assert.Equal(t, ids, runDocID(&Config{Fields: []cfg.FieldSelector{"request_id", "time"}}, events), "ids aren't stable")
+}
+
+func TestDocIDWholeEvent(t *testing.T) {
+	ids := runDocID(&Config{}, []string{
+		`{"message":"hello"}`,
+		`{"_id":"old","message":"hello"}`,
+		`{"message":"bye"}`,
+	})
+
+	assert.Equal(t, 3, len(ids), "wrong out events count")
+	assert.Equal(t, ids[0], ids[1], "id field shouldn't affect the id")
+	assert.NotEqual(t, ids[0], ids[2], "ids of different events should differ")
+}
+
+func TestDocIDConcat(t *testing.T) {
+	ids := runDocID(&Config{Fields: []cfg.FieldSelector{"service", "request.id"}, Mode: "concat", Separator: ":"}, []string{
+		`{"service":"api","request":{"id":42}}`,
+		`{"request":{"id":"abc"}}`,
+	})
+
+	assert.Equal(t, []string{"api:42", ":abc"}, ids, "wrong ids")
+}
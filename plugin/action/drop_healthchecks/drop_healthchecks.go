@@ -0,0 +1,128 @@
+package drop_healthchecks
+
+import (
+	"bytes"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It drops access log events produced by load balancer and orchestrator health checks.
+An event is dropped if its path is one of `paths` (query string is ignored) or its user agent contains one of `user_agents`.
+Use `metric_name` to count the dropped events, they are reported with the `discarded` status.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: drop_healthchecks
+      path_field: request_uri
+      metric_name: healthchecks
+    ...
+```
+}*/
+type Plugin struct {
+	config     *Config
+	paths      [][]byte
+	userAgents [][]byte
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field which contains the request path.
+	PathField  cfg.FieldSelector `json:"path_field" parse:"selector" default:"path"` //*
+	PathField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The list of health check paths.
+	Paths []string `json:"paths" default:"/healthz /health /livez /readyz /ready /ping"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field which contains the request user agent.
+	UserAgentField  cfg.FieldSelector `json:"user_agent_field" parse:"selector" default:"user_agent"` //*
+	UserAgentField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The list of user agent substrings which are specific to health checkers.
+	UserAgents []string `json:"user_agents" default:"kube-probe ELB-HealthChecker GoogleHC Consul"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "drop_healthchecks",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+
+	for _, path := range p.config.Paths {
+		p.paths = append(p.paths, []byte(path))
+	}
+	for _, userAgent := range p.config.UserAgents {
+		p.userAgents = append(p.userAgents, []byte(userAgent))
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	if p.isHealthCheckPath(event) || p.isHealthCheckUserAgent(event) {
+		return pipeline.ActionDiscard
+	}
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) isHealthCheckPath(event *pipeline.Event) bool {
+	node := event.Root.Dig(p.config.PathField_...)
+	if node == nil {
+		return false
+	}
+
+	path := node.AsBytes()
+	pos := bytes.IndexByte(path, '?')
+	if pos != -1 {
+		path = path[:pos]
+	}
+
+	for _, healthPath := range p.paths {
+		if bytes.Equal(path, healthPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Plugin) isHealthCheckUserAgent(event *pipeline.Event) bool {
+	node := event.Root.Dig(p.config.UserAgentField_...)
+	if node == nil {
+		return false
+	}
+
+	userAgent := node.AsBytes()
+	for _, healthUserAgent := range p.userAgents {
+		if bytes.Contains(userAgent, healthUserAgent) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,66 @@
+package drop_healthchecks
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDropHealthChecks(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"path":"/healthz","user_agent":"curl/7.64.1"}`))
+	input.In(0, "test.log", 0, []byte(`{"path":"/api/v1/users","user_agent":"curl/7.64.1"}`))
+	input.In(0, "test.log", 0, []byte(`{"path":"/ready?full=1","user_agent":"curl/7.64.1"}`))
+	input.In(0, "test.log", 0, []byte(`{"path":"/","user_agent":"kube-probe/1.18"}`))
+	input.In(0, "test.log", 0, []byte(`{"path":"/","user_agent":"ELB-HealthChecker/2.0"}`))
+	input.In(0, "test.log", 0, []byte(`{"path":"/healthz/details","user_agent":"Mozilla/5.0"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"no request fields"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 3, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"path":"/api/v1/users","user_agent":"curl/7.64.1"}`, outEvents[0].Root.EncodeToString(), "wrong out event")
+	assert.Equal(t, `{"path":"/healthz/details","user_agent":"Mozilla/5.0"}`, outEvents[1].Root.EncodeToString(), "wrong out event")
+	assert.Equal(t, `{"message":"no request fields"}`, outEvents[2].Root.EncodeToString(), "wrong out event")
+}
+
+func TestDropHealthChecksCustom(t *testing.T) {
+	config := test.NewConfig(&Config{PathField: "request.uri", Paths: []string{"/status"}, UserAgents: []string{"Pingdom"}}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"request":{"uri":"/status"}}`))
+	input.In(0, "test.log", 0, []byte(`{"request":{"uri":"/healthz"}}`))
+	input.In(0, "test.log", 0, []byte(`{"request":{"uri":"/"},"user_agent":"Pingdom.com_bot"}`))
+	input.In(0, "test.log", 0, []byte(`{"request":{"uri":"/"},"user_agent":"kube-probe/1.18"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 2, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"request":{"uri":"/healthz"}}`, outEvents[0].Root.EncodeToString(), "wrong out event")
+	assert.Equal(t, `{"request":{"uri":"/"},"user_agent":"kube-probe/1.18"}`, outEvents[1].Root.EncodeToString(), "wrong out event")
+}
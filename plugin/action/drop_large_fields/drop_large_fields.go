@@ -0,0 +1,116 @@
+package drop_large_fields
+
+import (
+	"sort"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ introduction
+It removes optional fields of the event only if the event size exceeds `max_event_size`, otherwise the event is kept as is.
+The largest of the configured fields are removed first, until the estimated event size fits the limit.
+The event size is the size of the event read by the input plugin.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: drop_large_fields
+      max_event_size: 65536
+      fields:
+      - request.body
+      - response.body
+      - stacktrace
+    ...
+```
+}*/
+type Plugin struct {
+	config     *Config
+	fields     [][]string
+	candidates []candidate
+	buf        []byte
+}
+
+type candidate struct {
+	node *insaneJSON.Node
+	size int
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event size in bytes after which the fields are removed.
+	MaxEventSize int `json:"max_event_size" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The list of the optional fields which can be removed. Nested fields can be used, e.g. `request.body`.
+	Fields []string `json:"fields" required:"true"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "drop_large_fields",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+
+	if p.config.MaxEventSize <= 0 {
+		params.Logger.Fatalf("max_event_size should be positive, got=%d", p.config.MaxEventSize)
+	}
+
+	p.fields = make([][]string, 0, len(p.config.Fields))
+	for _, field := range p.config.Fields {
+		p.fields = append(p.fields, cfg.ParseFieldSelector(field))
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	size := event.Size
+	if size <= p.config.MaxEventSize {
+		return pipeline.ActionPass
+	}
+
+	p.candidates = p.candidates[:0]
+	for _, field := range p.fields {
+		node := event.Root.Dig(field...)
+		if node == nil {
+			continue
+		}
+
+		// the field name, quotes, colon and comma are also counted
+		p.buf = node.Encode(p.buf[:0])
+		p.candidates = append(p.candidates, candidate{node: node, size: len(field[len(field)-1]) + len(p.buf) + 4})
+	}
+
+	sort.SliceStable(p.candidates, func(i, j int) bool {
+		return p.candidates[i].size > p.candidates[j].size
+	})
+
+	for _, c := range p.candidates {
+		if size <= p.config.MaxEventSize {
+			break
+		}
+		c.node.Suicide()
+		size -= c.size
+	}
+
+	return pipeline.ActionPass
+}
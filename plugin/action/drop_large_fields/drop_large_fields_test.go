@@ -0,0 +1,42 @@
+package drop_large_fields
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDropLargeFields(t *testing.T) {
+	config := test.NewConfig(&Config{MaxEventSize: 100, Fields: []string{"stacktrace", "request.body", "response"}}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	body := strings.Repeat("b", 80)
+	stacktrace := strings.Repeat("s", 40)
+
+	input.In(0, "test.log", 0, []byte(`{"message":"small","request":{"body":"ok"},"stacktrace":"at main"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"large","request":{"body":"`+body+`"},"stacktrace":"`+stacktrace+`"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"larger","request":{"body":"`+body+`"},"stacktrace":"`+body+stacktrace+`","response":"x"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"`+body+body+`"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 4, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"message":"small","request":{"body":"ok"},"stacktrace":"at main"}`, outEvents[0], "under limit event shouldn't be changed")
+	assert.Equal(t, `{"message":"large","request":{},"stacktrace":"`+stacktrace+`"}`, outEvents[1], "the largest field should be dropped")
+	assert.Equal(t, `{"message":"larger","request":{},"response":"x"}`, outEvents[2], "the largest fields should be dropped")
+	assert.Equal(t, `{"message":"`+body+body+`"}`, outEvents[3], "event without optional fields shouldn't be changed")
+}
@@ -0,0 +1,101 @@
+package ensure_fields
+
+import (
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ introduction
+It sets default values for the fields which are absent or empty, so events missing them aren't lost by the later stages.
+A field is considered empty if it's `null` or an empty string.
+You can provide an unlimited number of config parameters. Each parameter handled as `cfg.FieldSelector`:`default value`.
+Missing objects on the way to the nested field are created.
+If a field on the way exists and isn't an object, the nested field is skipped, so the existing value isn't overwritten.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: ensure_fields
+      level: info
+      k8s.namespace: unknown
+    ...
+```
+It transforms `{"level":"","message":"hello"}` into `{"level":"info","message":"hello","k8s":{"namespace":"unknown"}}`.
+}*/
+type Plugin struct {
+	config *Config
+	fields []field
+}
+
+type field struct {
+	path  []string
+	value string
+}
+
+type Config map[string]string
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "ensure_fields",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+
+	p.fields = make([]field, 0, len(*p.config))
+	for selector, value := range *p.config {
+		path := cfg.ParseFieldSelector(selector)
+		if len(path) == 0 {
+			continue
+		}
+		p.fields = append(p.fields, field{path: path, value: value})
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	for _, f := range p.fields {
+		if !isEmpty(event.Root.Dig(f.path...)) {
+			continue
+		}
+		if !hasObjectPath(event.Root, f.path) {
+			continue
+		}
+
+		pipeline.CreateNestedField(event.Root, f.path).MutateToString(f.value)
+	}
+
+	return pipeline.ActionPass
+}
+
+// hasObjectPath checks that all existing fields on the way to the nested field are objects
+func hasObjectPath(root *insaneJSON.Root, path []string) bool {
+	for i := 1; i < len(path); i++ {
+		node := root.Dig(path[:i]...)
+		if node == nil {
+			return true
+		}
+		if !node.IsObject() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isEmpty(node *insaneJSON.Node) bool {
+	return node == nil || node.IsNull() || (node.IsString() && node.AsString() == "")
+}
@@ -0,0 +1,83 @@
+package ensure_fields
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnsureFields(t *testing.T) {
+	config := test.NewConfig(&Config{"level": "info", "k8s.namespace": "unknown", "service": "default"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(5)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"level":"error","k8s":{"namespace":"prod"},"service":"api"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"hello"}`))
+	input.In(0, "test.log", 0, []byte(`{"level":"","k8s":{"namespace":null,"pod":"api-1"},"service":"api"}`))
+	input.In(0, "test.log", 0, []byte(`{"level":"warn","k8s":"broken","service":0}`))
+	input.In(0, "test.log", 0, []byte(`{"level":{},"k8s":{},"service":[]}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 5, len(outEvents), "wrong out events count")
+
+	assert.Equal(t, "error", outEvents[0].Root.Dig("level").AsString(), "wrong field value")
+	assert.Equal(t, "prod", outEvents[0].Root.Dig("k8s", "namespace").AsString(), "wrong field value")
+	assert.Equal(t, "api", outEvents[0].Root.Dig("service").AsString(), "wrong field value")
+
+	assert.Equal(t, "hello", outEvents[1].Root.Dig("message").AsString(), "wrong field value")
+	assert.Equal(t, "info", outEvents[1].Root.Dig("level").AsString(), "wrong field value")
+	assert.Equal(t, "unknown", outEvents[1].Root.Dig("k8s", "namespace").AsString(), "wrong field value")
+	assert.Equal(t, "default", outEvents[1].Root.Dig("service").AsString(), "wrong field value")
+
+	assert.Equal(t, "info", outEvents[2].Root.Dig("level").AsString(), "wrong field value")
+	assert.Equal(t, "unknown", outEvents[2].Root.Dig("k8s", "namespace").AsString(), "wrong field value")
+	assert.Equal(t, "api-1", outEvents[2].Root.Dig("k8s", "pod").AsString(), "wrong field value")
+	assert.Equal(t, "api", outEvents[2].Root.Dig("service").AsString(), "wrong field value")
+
+	assert.Equal(t, "warn", outEvents[3].Root.Dig("level").AsString(), "wrong field value")
+	assert.Equal(t, "broken", outEvents[3].Root.Dig("k8s").AsString(), "wrong field value")
+	assert.Equal(t, 0, outEvents[3].Root.Dig("service").AsInt(), "wrong field value")
+
+	assert.Equal(t, `{"level":{},"k8s":{"namespace":"unknown"},"service":[]}`, outEvents[4].Root.EncodeToString(), "wrong out event")
+}
+
+func TestEnsureFieldsNonObjectPath(t *testing.T) {
+	config := test.NewConfig(&Config{"k8s.pod.name": "unknown", "level": "info"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"k8s":"broken"}`))
+	input.In(0, "test.log", 0, []byte(`{"k8s":{"pod":["api-1"]}}`))
+	input.In(0, "test.log", 0, []byte(`{"k8s":{"pod":null}}`))
+	input.In(0, "test.log", 0, []byte(`{"k8s":{"namespace":"prod"}}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 4, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"k8s":"broken","level":"info"}`, outEvents[0], "wrong out event")
+	assert.Equal(t, `{"k8s":{"pod":["api-1"]},"level":"info"}`, outEvents[1], "wrong out event")
+	assert.Equal(t, `{"k8s":{"pod":null},"level":"info"}`, outEvents[2], "wrong out event")
+	assert.Equal(t, `{"k8s":{"namespace":"prod","pod":{"name":"unknown"}},"level":"info"}`, outEvents[3], "wrong out event")
+}
@@ -1,9 +1,12 @@
 package flatten
 
 import (
+	"strconv"
+
 	"github.com/ozonru/file.d/cfg"
 	"github.com/ozonru/file.d/fd"
 	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
 )
 
 /*{ introduction
@@ -21,9 +24,36 @@ pipelines:
     ...
 ```
 It transforms `{"animal":{"type":"cat","paws":4}}` into `{"pet_type":"b","pet_paws":"4"}`.
+
+If `separator` is set, nested objects are flattened recursively and nested keys are joined with the separator.
+If `field` isn't set, the whole event is flattened. Empty objects and empty arrays are kept as values,
+other arrays are flattened according to `array_format`. Use `unflatten` plugin to convert the event back.
+In this mode generated keys don't override existing keys of the root, e.g. if the event already has `a.b` field,
+the value of `{"a":{"b":...}}` is dropped. If generated keys collide with each other, the first one wins.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: flatten
+      separator: .
+    ...
+```
+It transforms `{"animal":{"type":"cat","size":{"height":30}},"name":"Tom"}` into `{"animal.type":"cat","animal.size.height":30,"name":"Tom"}`.
+With `array_format: brackets` it transforms `{"pets":[{"type":"cat"},{"type":"dog"}]}` into `{"pets[0].type":"cat","pets[1].type":"dog"}`.
 }*/
+
+const (
+	arrayFormatKeep     = "keep"
+	arrayFormatIndex    = "index"
+	arrayFormatBrackets = "brackets"
+)
 type Plugin struct {
-	config *Config
+	config  *Config
+	nameBuf []byte
+	fields  []*insaneJSON.Node
 }
 
 //! config-params
@@ -31,14 +61,27 @@ type Plugin struct {
 type Config struct {
 	//> @3@4@5@6
 	//>
-	//> Defines the field that should be flattened.
-	Field  cfg.FieldSelector `json:"field" required:"true" parse:"selector"` //*
+	//> Defines the field that should be flattened. If it isn't set, all fields of the event are flattened.
+	Field  cfg.FieldSelector `json:"field" parse:"selector"` //*
 	Field_ []string
 
 	//> @3@4@5@6
 	//>
 	//> Which prefix to use for extracted fields.
 	Prefix string `json:"prefix" default:""` //*
+
+	//> @3@4@5@6
+	//>
+	//> The separator to join nested keys with. If it isn't set, only the first level keys are extracted.
+	Separator string `json:"separator" default:""` //*
+
+	//> @3@4@5@6
+	//>
+	//> How to flatten arrays if `separator` is set:
+	//> * `keep` – arrays are kept as values.
+	//> * `index` – the index of the element is used as a key, e.g. `tags.0`.
+	//> * `brackets` – the index of the element is put into brackets, e.g. `tags[0]`.
+	ArrayFormat string `json:"array_format" default:"keep" options:"keep|index|brackets"` //*
 }
 
 func init() {
@@ -60,6 +103,11 @@ func (p *Plugin) Stop() {
 }
 
 func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	if len(p.config.Field_) == 0 {
+		p.flattenRoot(event)
+		return pipeline.ActionPass
+	}
+
 	node := event.Root.Dig(p.config.Field_...)
 
 	if !node.IsObject() {
@@ -68,6 +116,12 @@ func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
 
 	node.Suicide()
 
+	if p.config.Separator != "" {
+		p.nameBuf = append(p.nameBuf[:0], p.config.Prefix...)
+		p.flattenNested(event, node.AsFields())
+		return pipeline.ActionPass
+	}
+
 	fields := node.AsFields()
 	for _, field := range fields {
 		l := len(event.Buf)
@@ -81,3 +135,75 @@ func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
 
 	return pipeline.ActionPass
 }
+
+func (p *Plugin) flattenRoot(event *pipeline.Event) {
+	if !event.Root.IsObject() {
+		return
+	}
+
+	// root nodes are reused by the new object, so fields should be copied first
+	p.fields = append(p.fields[:0], event.Root.AsFields()...)
+	event.Root.MutateToObject()
+
+	p.nameBuf = append(p.nameBuf[:0], p.config.Prefix...)
+	if p.config.Separator != "" {
+		for _, field := range p.fields {
+			p.nameBuf = append(p.nameBuf[:len(p.config.Prefix)], field.AsString()...)
+			// first level keys are the existing ones, so they override the generated keys
+			p.flattenValue(event, field.AsFieldValue(), true)
+		}
+		return
+	}
+
+	for _, field := range p.fields {
+		p.nameBuf = append(p.nameBuf[:len(p.config.Prefix)], field.AsString()...)
+		p.addField(event, field.AsFieldValue(), true)
+	}
+}
+
+// flattenNested adds all leaves of the fields to the root, name buffer contains the prefix of leaf names
+func (p *Plugin) flattenNested(event *pipeline.Event, fields []*insaneJSON.Node) {
+	prefixEnd := len(p.nameBuf)
+	for _, field := range fields {
+		p.nameBuf = append(p.nameBuf[:prefixEnd], field.AsString()...)
+		p.flattenValue(event, field.AsFieldValue(), false)
+	}
+	p.nameBuf = p.nameBuf[:prefixEnd]
+}
+
+// flattenValue adds all leaves of the value to the root, name buffer contains the name of the value
+func (p *Plugin) flattenValue(event *pipeline.Event, value *insaneJSON.Node, override bool) {
+	switch {
+	case value.IsObject() && len(value.AsFields()) != 0:
+		p.nameBuf = append(p.nameBuf, p.config.Separator...)
+		p.flattenNested(event, value.AsFields())
+	case value.IsArray() && len(value.AsArray()) != 0 && p.config.ArrayFormat != arrayFormatKeep:
+		prefixEnd := len(p.nameBuf)
+		for i, elem := range value.AsArray() {
+			if p.config.ArrayFormat == arrayFormatBrackets {
+				p.nameBuf = append(p.nameBuf[:prefixEnd], '[')
+				p.nameBuf = strconv.AppendInt(p.nameBuf, int64(i), 10)
+				p.nameBuf = append(p.nameBuf, ']')
+			} else {
+				p.nameBuf = append(p.nameBuf[:prefixEnd], p.config.Separator...)
+				p.nameBuf = strconv.AppendInt(p.nameBuf, int64(i), 10)
+			}
+			p.flattenValue(event, elem, false)
+		}
+		p.nameBuf = p.nameBuf[:prefixEnd]
+	default:
+		p.addField(event, value, override)
+	}
+}
+
+func (p *Plugin) addField(event *pipeline.Event, value *insaneJSON.Node, override bool) {
+	l := len(event.Buf)
+	event.Buf = append(event.Buf, p.nameBuf...)
+	name := pipeline.ByteToStringUnsafe(event.Buf[l:])
+
+	if !override && event.Root.Dig(name) != nil {
+		event.Buf = event.Buf[:l]
+		return
+	}
+	event.Root.AddFieldNoAlloc(event.Root, name).MutateToNode(value)
+}
@@ -43,3 +43,142 @@ func TestFlatten(t *testing.T) {
 	assert.Equal(t, 1, len(dumpedEvents), "wrong out events count")
 	assert.Equal(t, `{"flat_a":"b","flat_c":"d"}`, dumpedEvents[0].Root.EncodeToString(), "wrong out events count")
 }
+
+func TestFlattenSeparator(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "complex", Prefix: "flat.", Separator: "."}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"complex":{"a":{"b":{"c":1}},"d":[1,2],"e":{}},"f":"g"}`))
+	input.In(0, "test.log", 0, []byte(`{"complex":"not an object"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 2, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"f":"g","flat.a.b.c":1,"flat.d":[1,2],"flat.e":{}}`, outEvents[0], "wrong out event")
+	assert.Equal(t, `{"complex":"not an object"}`, outEvents[1], "wrong out event")
+}
+
+func TestFlattenRoot(t *testing.T) {
+	config := test.NewConfig(&Config{Separator: "_"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"request":{"method":"GET","headers":{"host":"example.com"}},"status":200}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 1, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"request_method":"GET","request_headers_host":"example.com","status":200}`, outEvents[0], "wrong out event")
+}
+
+func TestFlattenArrays(t *testing.T) {
+	cases := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format:   "keep",
+			expected: `{"a.b.c":1,"a.tags":["x","y"],"a.empty":{},"a.none":[],"pets":[{"type":"cat"},{"type":"dog","toys":["ball"]}]}`,
+		},
+		{
+			format:   "index",
+			expected: `{"a.b.c":1,"a.tags.0":"x","a.tags.1":"y","a.empty":{},"a.none":[],"pets.0.type":"cat","pets.1.type":"dog","pets.1.toys.0":"ball"}`,
+		},
+		{
+			format:   "brackets",
+			expected: `{"a.b.c":1,"a.tags[0]":"x","a.tags[1]":"y","a.empty":{},"a.none":[],"pets[0].type":"cat","pets[1].type":"dog","pets[1].toys[0]":"ball"}`,
+		},
+	}
+
+	for _, c := range cases {
+		config := test.NewConfig(&Config{Separator: ".", ArrayFormat: c.format}, nil)
+		p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		outEvents := make([]string, 0)
+		output.SetOutFn(func(e *pipeline.Event) {
+			outEvents = append(outEvents, e.Root.EncodeToString())
+			wg.Done()
+		})
+
+		input.In(0, "test.log", 0, []byte(`{"a":{"b":{"c":1},"tags":["x","y"],"empty":{},"none":[]},"pets":[{"type":"cat"},{"type":"dog","toys":["ball"]}]}`))
+
+		wg.Wait()
+		p.Stop()
+
+		assert.Equal(t, []string{c.expected}, outEvents, "wrong out event for %s array format", c.format)
+	}
+}
+
+func TestFlattenCollisions(t *testing.T) {
+	config := test.NewConfig(&Config{Separator: ".", ArrayFormat: "index"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	// existing flat key wins regardless of the order
+	input.In(0, "test.log", 0, []byte(`{"a.b":"flat","a":{"b":"nested","c":"kept"}}`))
+	input.In(0, "test.log", 0, []byte(`{"a":{"b":"nested","c":"kept"},"a.b":"flat"}`))
+	// the first generated key wins
+	input.In(0, "test.log", 0, []byte(`{"x":{"y.z":"first","y":{"z":"second"}}}`))
+	input.In(0, "test.log", 0, []byte(`{"tags.0":"flat","tags":["nested","kept"]}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"a.b":"flat","a.c":"kept"}`,
+		`{"a.b":"flat","a.c":"kept"}`,
+		`{"x.y.z":"first"}`,
+		`{"tags.0":"flat","tags.1":"kept"}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestFlattenFieldCollisions(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "complex", Prefix: "complex.", Separator: "."}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"complex.a.b":"existing","complex":{"a":{"b":"nested","c":{"d":{"e":1}}}}}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{`{"complex.a.b":"existing","complex.a.c.d.e":1}`}, outEvents, "wrong out events")
+}
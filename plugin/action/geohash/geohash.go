@@ -0,0 +1,159 @@
+package geohash
+
+import (
+	"strconv"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It computes the geohash of the point from latitude and longitude fields and puts it into the target field.
+Coordinates may be numbers or numeric strings. If a coordinate is missing, isn't a number or is out of range, the event is passed unchanged.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: geohash
+      lat_field: geo.lat
+      lon_field: geo.lon
+      precision: 5
+    ...
+```
+It transforms `{"geo":{"lat":57.64911,"lon":10.40744}}` into `{"geo":{"lat":57.64911,"lon":10.40744},"geohash":"u4pru"}`.
+}*/
+type Plugin struct {
+	config *Config
+	logger *zap.SugaredLogger
+	buf    []byte
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field with the latitude.
+	LatField  cfg.FieldSelector `json:"lat_field" parse:"selector" default:"lat"` //*
+	LatField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The event field with the longitude.
+	LonField  cfg.FieldSelector `json:"lon_field" parse:"selector" default:"lon"` //*
+	LonField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The event field to put the geohash to.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"geohash"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The length of the geohash from `1` to `12`, `12` if not set.
+	Precision int `json:"precision"` //*
+}
+
+const (
+	maxPrecision = 12
+	base32       = "0123456789bcdefghjkmnpqrstuvwxyz"
+)
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "geohash",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger
+
+	if p.config.Precision == 0 {
+		p.config.Precision = maxPrecision
+	}
+	if p.config.Precision < 0 || p.config.Precision > maxPrecision {
+		p.logger.Fatalf("precision should be from 1 to %d, got=%d", maxPrecision, p.config.Precision)
+	}
+
+	p.buf = make([]byte, 0, maxPrecision)
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	lat, ok := coordinate(event, p.config.LatField_, 90)
+	if !ok {
+		return pipeline.ActionPass
+	}
+	lon, ok := coordinate(event, p.config.LonField_, 180)
+	if !ok {
+		return pipeline.ActionPass
+	}
+
+	p.buf = encode(p.buf[:0], lat, lon, p.config.Precision)
+	pipeline.CreateNestedField(event.Root, p.config.Field_).MutateToString(string(p.buf))
+
+	return pipeline.ActionPass
+}
+
+// coordinate returns the field value if it's a number from -limit to limit
+func coordinate(event *pipeline.Event, field []string, limit float64) (float64, bool) {
+	node := event.Root.Dig(field...)
+	if node == nil || !(node.IsNumber() || node.IsString()) {
+		return 0, false
+	}
+
+	value, err := strconv.ParseFloat(node.AsString(), 64)
+	if err != nil || value < -limit || value > limit {
+		return 0, false
+	}
+
+	return value, true
+}
+
+// encode appends the geohash of the point, bits of longitude and latitude are interleaved starting with longitude
+func encode(out []byte, lat float64, lon float64, precision int) []byte {
+	latMin, latMax := -90.0, 90.0
+	lonMin, lonMax := -180.0, 180.0
+
+	isLon := true
+	for i := 0; i < precision; i++ {
+		ch := 0
+		for bit := 4; bit >= 0; bit-- {
+			if isLon {
+				mid := (lonMin + lonMax) / 2
+				if lon >= mid {
+					ch |= 1 << bit
+					lonMin = mid
+				} else {
+					lonMax = mid
+				}
+			} else {
+				mid := (latMin + latMax) / 2
+				if lat >= mid {
+					ch |= 1 << bit
+					latMin = mid
+				} else {
+					latMax = mid
+				}
+			}
+			isLon = !isLon
+		}
+		out = append(out, base32[ch])
+	}
+
+	return out
+}
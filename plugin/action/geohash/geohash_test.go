@@ -0,0 +1,88 @@
+package geohash
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	points := []struct {
+		lat       float64
+		lon       float64
+		precision int
+		hash      string
+	}{
+		{lat: 57.64911, lon: 10.40744, precision: 11, hash: "u4pruydqqvj"},
+		{lat: 57.64911, lon: 10.40744, precision: 5, hash: "u4pru"},
+		{lat: 57.64911, lon: 10.40744, precision: 1, hash: "u"},
+		{lat: 48.8583, lon: 2.2945, precision: 7, hash: "u09tunq"},
+		{lat: -33.8568, lon: 151.2153, precision: 6, hash: "r3gx2u"},
+		{lat: 0, lon: 0, precision: 4, hash: "s000"},
+		{lat: -90, lon: -180, precision: 3, hash: "000"},
+		{lat: 90, lon: 180, precision: 3, hash: "zzz"},
+	}
+
+	for _, point := range points {
+		assert.Equal(t, point.hash, string(encode(nil, point.lat, point.lon, point.precision)), "wrong geohash for %v,%v", point.lat, point.lon)
+	}
+}
+
+func TestGeohash(t *testing.T) {
+	config := test.NewConfig(&Config{LatField: "geo.lat", LonField: "geo.lon", Precision: 5}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(6)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"geo":{"lat":57.64911,"lon":10.40744}}`))
+	input.In(0, "test.log", 0, []byte(`{"geo":{"lat":"48.8583","lon":"2.2945"}}`))
+	input.In(0, "test.log", 0, []byte(`{"geo":{"lat":57.64911}}`))
+	input.In(0, "test.log", 0, []byte(`{"geo":{"lat":"north","lon":10.40744}}`))
+	input.In(0, "test.log", 0, []byte(`{"geo":{"lat":91,"lon":10.40744}}`))
+	input.In(0, "test.log", 0, []byte(`{"geo":{"lat":57.64911,"lon":-180.5}}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"geo":{"lat":57.64911,"lon":10.40744},"geohash":"u4pru"}`,
+		`{"geo":{"lat":"48.8583","lon":"2.2945"},"geohash":"u09tu"}`,
+		`{"geo":{"lat":57.64911}}`,
+		`{"geo":{"lat":"north","lon":10.40744}}`,
+		`{"geo":{"lat":91,"lon":10.40744}}`,
+		`{"geo":{"lat":57.64911,"lon":-180.5}}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestGeohashDefaultPrecision(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"lat":57.64911,"lon":10.40744}`))
+
+	wg.Wait()
+	p.Stop()
+
+	hash := outEvents[0].Root.Dig("geohash").AsString()
+	assert.Equal(t, 12, len(hash), "wrong precision")
+	assert.Equal(t, "u4pruydqqvj", hash[:11], "wrong geohash")
+}
@@ -0,0 +1,120 @@
+package head_tail
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It truncates the multiline string field, e.g. a huge stack trace, to the first `head` and the last `tail` lines.
+Omitted lines are replaced with the `... (N lines omitted) ...` line.
+The field which has no more than `head + tail` lines or isn't a string is left unchanged.
+The trailing new line of the field is kept.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: head_tail
+      field: stacktrace
+      head: 20
+      tail: 5
+    ...
+```
+}*/
+type Plugin struct {
+	config *Config
+	buf    []byte
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to truncate.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"message"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> How many first lines to keep.
+	Head int `json:"head"` //*
+
+	//> @3@4@5@6
+	//>
+	//> How many last lines to keep.
+	Tail int `json:"tail"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "head_tail",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	if p.config.Head < 0 || p.config.Tail < 0 {
+		params.Logger.Fatalf("head and tail shouldn't be negative, got head=%d, tail=%d", p.config.Head, p.config.Tail)
+	}
+	if p.config.Head+p.config.Tail == 0 {
+		params.Logger.Fatalf("head or tail should be positive")
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil || !node.IsString() {
+		return pipeline.ActionPass
+	}
+
+	value := node.AsString()
+	trailingNewLine := strings.HasSuffix(value, "\n")
+	if trailingNewLine {
+		value = value[:len(value)-1]
+	}
+
+	lines := strings.Count(value, "\n") + 1
+	if lines <= p.config.Head+p.config.Tail {
+		return pipeline.ActionPass
+	}
+
+	headEnd := 0
+	for i := 0; i < p.config.Head; i++ {
+		headEnd += strings.IndexByte(value[headEnd:], '\n') + 1
+	}
+	tailStart := len(value)
+	for i := 0; i < p.config.Tail; i++ {
+		tailStart = strings.LastIndexByte(value[:tailStart], '\n')
+	}
+
+	p.buf = append(p.buf[:0], value[:headEnd]...)
+	p.buf = append(p.buf, "... ("...)
+	p.buf = strconv.AppendInt(p.buf, int64(lines-p.config.Head-p.config.Tail), 10)
+	p.buf = append(p.buf, " lines omitted) ..."...)
+	if p.config.Tail > 0 {
+		p.buf = append(p.buf, value[tailStart:]...)
+	}
+	if trailingNewLine {
+		p.buf = append(p.buf, '\n')
+	}
+
+	node.MutateToString(string(p.buf))
+
+	return pipeline.ActionPass
+}
@@ -0,0 +1,102 @@
+package head_tail
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeLines(count int) string {
+	lines := make([]string, 0, count)
+	for i := 1; i <= count; i++ {
+		lines = append(lines, "line "+string(rune('a'+i-1)))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func runHeadTail(t *testing.T, config *Config, values []string) []*pipeline.Event {
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, test.NewConfig(config, nil), pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(values))
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	for _, value := range values {
+		event, _ := json.Marshal(map[string]string{"message": value})
+		input.In(0, "test.log", 0, event)
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+func TestHeadTail(t *testing.T) {
+	values := []string{
+		"single line",
+		makeLines(4),
+		makeLines(5),
+		makeLines(10),
+		makeLines(10) + "\n",
+		"",
+	}
+	outEvents := runHeadTail(t, &Config{Head: 2, Tail: 2}, values)
+
+	expected := []string{
+		"single line",
+		makeLines(4),
+		"line a\nline b\n... (1 lines omitted) ...\nline d\nline e",
+		"line a\nline b\n... (6 lines omitted) ...\nline i\nline j",
+		"line a\nline b\n... (6 lines omitted) ...\nline i\nline j\n",
+		"",
+	}
+	assert.Equal(t, len(expected), len(outEvents), "wrong out events count")
+	for i, e := range outEvents {
+		assert.Equal(t, expected[i], e.Root.Dig("message").AsString(), "wrong message for %q", values[i])
+	}
+}
+
+func TestHeadTailOneSide(t *testing.T) {
+	outEvents := runHeadTail(t, &Config{Head: 1}, []string{makeLines(3)})
+	assert.Equal(t, "line a\n... (2 lines omitted) ...", outEvents[0].Root.Dig("message").AsString(), "wrong head only message")
+
+	outEvents = runHeadTail(t, &Config{Tail: 1}, []string{makeLines(3) + "\n"})
+	assert.Equal(t, "... (2 lines omitted) ...\nline c\n", outEvents[0].Root.Dig("message").AsString(), "wrong tail only message")
+}
+
+func TestHeadTailField(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "error.stack", Head: 1, Tail: 1}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"error":{"stack":"panic: boom\n\tat a\n\tat b\n\tat main"}}`))
+	input.In(0, "test.log", 0, []byte(`{"error":{"stack":["not","a","string"]}}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"error":{"stack":"panic: boom\n... (2 lines omitted) ...\n\tat main"}}`,
+		`{"error":{"stack":["not","a","string"]}}`,
+	}, outEvents, "wrong out events")
+}
@@ -0,0 +1,115 @@
+package hmac_chain
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It links events into HMAC chains for tamper detection of audit logs.
+Each processor of the pipeline has its own chain with a random id, which is put to `chain_id_field`.
+The HMAC-SHA256 is computed with `key` over the HMAC of the previous event of the chain and the JSON of the event,
+then it's put to `hmac_field` as a hex string. The first event of the chain has no previous HMAC.
+
+The HMAC field is added as the last field of the event, so to verify an event downstream:
+remove the HMAC field from the end of the serialized event, compute HMAC-SHA256 over the decoded previous HMAC of the chain
+followed by the rest of the event and compare it with the HMAC field.
+A modified event breaks the link with its HMAC, a missing event breaks the link of the next event of the chain.
+So the action should be the last one which modifies events.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: hmac_chain
+      key: "my-secret-key"
+    ...
+```
+It transforms `{"user":"alice","action":"login"}` into `{"user":"alice","action":"login","_chain_id":"6f1ed002ab559585","_chain_hmac":"4c0e..."}`.
+}*/
+type Plugin struct {
+	config  *Config
+	mac     hash.Hash
+	chainID string
+	prev    []byte
+	buf     []byte
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The secret key of HMAC.
+	Key string `json:"key" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field to put the HMAC to.
+	HMACField string `json:"hmac_field" default:"_chain_hmac"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field to put the chain id to. The field is a part of HMAC input.
+	ChainIDField string `json:"chain_id_field" default:"_chain_id"` //*
+}
+
+const chainIDLen = 8
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "hmac_chain",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+
+	if p.config.Key == "" {
+		params.Logger.Fatalf("key can't be empty")
+	}
+	p.mac = hmac.New(sha256.New, []byte(p.config.Key))
+
+	id := make([]byte, chainIDLen)
+	if _, err := rand.Read(id); err != nil {
+		params.Logger.Fatalf("can't generate chain id: %s", err.Error())
+	}
+	p.chainID = hex.EncodeToString(id)
+	p.prev = make([]byte, 0, sha256.Size)
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	event.Root.AddFieldNoAlloc(event.Root, p.config.ChainIDField).MutateToString(p.chainID)
+	// remove the field of the upstream chain, otherwise it would be the part of HMAC input and wouldn't be the last field
+	event.Root.Dig(p.config.HMACField).Suicide()
+
+	p.buf = event.Root.Encode(p.buf[:0])
+
+	p.mac.Reset()
+	_, _ = p.mac.Write(p.prev)
+	_, _ = p.mac.Write(p.buf)
+	p.prev = p.mac.Sum(p.prev[:0])
+
+	l := len(event.Buf)
+	event.Buf = append(event.Buf, make([]byte, hex.EncodedLen(len(p.prev)))...)
+	hex.Encode(event.Buf[l:], p.prev)
+	event.Root.AddFieldNoAlloc(event.Root, p.config.HMACField).MutateToString(pipeline.ByteToStringUnsafe(event.Buf[l:]))
+
+	return pipeline.ActionPass
+}
@@ -0,0 +1,112 @@
+package hmac_chain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+const testKey = "secret"
+
+func runEvents(config *Config, events []string) []string {
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, test.NewConfig(config, nil), pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(events))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, event := range events {
+		input.In(0, "test.log", 0, []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+// verify returns the index of the first event which breaks the chain or -1 if the chain is valid
+func verify(t *testing.T, key string, hmacField string, events []string) int {
+	prev := make([]byte, 0)
+	for i, event := range events {
+		root, err := insaneJSON.DecodeString(event)
+		assert.NoError(t, err, "wrong json")
+		value := root.Dig(hmacField).AsString()
+		insaneJSON.Release(root)
+
+		suffix := `,"` + hmacField + `":"` + value + `"}`
+		if !strings.HasSuffix(event, suffix) {
+			return i
+		}
+		data := event[:len(event)-len(suffix)] + "}"
+
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write(prev)
+		mac.Write([]byte(data))
+		expected := mac.Sum(nil)
+
+		actual, err := hex.DecodeString(value)
+		if err != nil || !hmac.Equal(expected, actual) {
+			return i
+		}
+		prev = actual
+	}
+
+	return -1
+}
+
+func TestHMACChain(t *testing.T) {
+	outEvents := runEvents(&Config{Key: testKey}, []string{
+		`{"user":"alice","action":"login"}`,
+		`{"user":"bob","action":"delete","target":{"id":1}}`,
+		`{"user":"alice","action":"logout","_chain_hmac":"forged"}`,
+	})
+
+	assert.Equal(t, 3, len(outEvents), "wrong out events count")
+	assert.Equal(t, -1, verify(t, testKey, "_chain_hmac", outEvents), "chain is broken")
+	assert.Equal(t, 0, verify(t, "wrong", "_chain_hmac", outEvents), "chain is valid with wrong key")
+
+	ids := make(map[string]bool)
+	for _, event := range outEvents {
+		root, err := insaneJSON.DecodeString(event)
+		assert.NoError(t, err, "wrong json")
+		id := root.Dig("_chain_id").AsString()
+		assert.Equal(t, chainIDLen*2, len(id), "wrong chain id")
+		ids[string([]byte(id))] = true
+		insaneJSON.Release(root)
+	}
+	assert.Equal(t, 1, len(ids), "events of the same processor have different chain ids")
+	assert.NotContains(t, outEvents[2], "forged", "upstream hmac isn't removed")
+}
+
+func TestHMACChainTampering(t *testing.T) {
+	outEvents := runEvents(&Config{Key: testKey, HMACField: "hmac", ChainIDField: "chain"}, []string{
+		`{"user":"alice","action":"login"}`,
+		`{"user":"bob","action":"delete"}`,
+		`{"user":"alice","action":"logout"}`,
+	})
+	assert.Equal(t, -1, verify(t, testKey, "hmac", outEvents), "chain is broken")
+
+	modified := append([]string{}, outEvents...)
+	modified[1] = strings.Replace(modified[1], `"bob"`, `"eve"`, 1)
+	assert.Equal(t, 1, verify(t, testKey, "hmac", modified), "modified event isn't detected")
+
+	dropped := []string{outEvents[0], outEvents[2]}
+	assert.Equal(t, 1, verify(t, testKey, "hmac", dropped), "dropped event isn't detected")
+
+	reordered := []string{outEvents[1], outEvents[0], outEvents[2]}
+	assert.Equal(t, 0, verify(t, testKey, "hmac", reordered), "reordered events aren't detected")
+}
@@ -0,0 +1,173 @@
+package jmespath
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It evaluates a JMESPath expression over the event root and replaces the root or the target field with the result.
+The expression is compiled once on the start.
+If the expression gives no result, the event is passed as is.
+If the result replaces the root, it must be an object, otherwise the event is passed as is.
+Events which can't be evaluated are passed as is and counted by `jmespath_errors_total` metric with `stage` label.
+Integers which don't fit into float64 exactly are kept as is in the result, but they can't be compared or used in functions.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: jmespath
+      expression: "{service: meta.service, errors: items[?level=='error'].message}"
+    ...
+```
+It transforms `{"meta":{"service":"api"},"items":[{"level":"error","message":"oops"},{"level":"info","message":"ok"}]}`
+into `{"service":"api","errors":["oops"]}`.
+}*/
+type Plugin struct {
+	config *Config
+	logger *zap.SugaredLogger
+	expr   *jmespath.JMESPath
+	buf    []byte
+
+	decodeErrors prometheus.Counter
+	searchErrors prometheus.Counter
+	encodeErrors prometheus.Counter
+}
+
+// maxExactInt is the maximum integer which float64 keeps exactly
+const maxExactInt = 1 << 53
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> JMESPath expression to evaluate.
+	Expression string `json:"expression" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field to put the result to. If it's empty, the event root is replaced with the result.
+	Field  cfg.FieldSelector `json:"field" parse:"selector"` //*
+	Field_ []string
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "jmespath",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger
+
+	expr, err := jmespath.Compile(p.config.Expression)
+	if err != nil {
+		p.logger.Fatalf("can't compile jmespath expression %q: %s", p.config.Expression, err.Error())
+	}
+	p.expr = expr
+
+	errors := params.MetricsCtl.RegisterCounter("jmespath_errors_total", "how many events can't be evaluated by jmespath action", "stage")
+	p.decodeErrors = errors.WithLabelValues("decode")
+	p.searchErrors = errors.WithLabelValues("search")
+	p.encodeErrors = errors.WithLabelValues("encode")
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	p.buf = event.Root.Encode(p.buf[:0])
+
+	var data interface{}
+	decoder := json.NewDecoder(bytes.NewReader(p.buf))
+	decoder.UseNumber()
+	err := decoder.Decode(&data)
+	if err != nil {
+		p.decodeErrors.Inc()
+		return pipeline.ActionPass
+	}
+
+	result, err := p.expr.Search(convertNumbers(data))
+	if err != nil {
+		p.searchErrors.Inc()
+		return pipeline.ActionPass
+	}
+
+	if result == nil {
+		return pipeline.ActionPass
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		p.encodeErrors.Inc()
+		return pipeline.ActionPass
+	}
+
+	node, err := event.SubparseJSON(resultJSON)
+	if err != nil {
+		p.encodeErrors.Inc()
+		return pipeline.ActionPass
+	}
+
+	if len(p.config.Field_) == 0 {
+		if !node.IsObject() {
+			return pipeline.ActionPass
+		}
+
+		event.Root.MutateToNode(node)
+		return pipeline.ActionPass
+	}
+
+	pipeline.CreateNestedField(event.Root, p.config.Field_).MutateToNode(node)
+
+	return pipeline.ActionPass
+}
+
+// convertNumbers turns numbers into float64, which jmespath works with,
+// integers which float64 can't keep exactly remain json.Number to be encoded back as is
+func convertNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = convertNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertNumbers(item)
+		}
+	case json.Number:
+		if !strings.ContainsAny(v.String(), ".eE") {
+			n, err := v.Int64()
+			if err != nil || n > maxExactInt || n < -maxExactInt {
+				return v
+			}
+			return float64(n)
+		}
+
+		f, err := v.Float64()
+		if err != nil {
+			return v
+		}
+		return f
+	}
+
+	return value
+}
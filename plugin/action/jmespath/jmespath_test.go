@@ -0,0 +1,111 @@
+package jmespath
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func runJMESPath(config *Config, events []string) []string {
+	test.NewConfig(config, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(events))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, event := range events {
+		input.In(0, "test.log", 0, []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+func TestJMESPathProjection(t *testing.T) {
+	outEvents := runJMESPath(&Config{Expression: "{service: meta.service, hosts: servers[*].host}"}, []string{
+		`{"meta":{"service":"api","version":2},"servers":[{"host":"a","port":80},{"host":"b","port":81}]}`,
+	})
+
+	assert.Equal(t, 1, len(outEvents), "wrong out events count")
+	assert.JSONEq(t, `{"service":"api","hosts":["a","b"]}`, outEvents[0], "wrong out event")
+}
+
+func TestJMESPathFilter(t *testing.T) {
+	outEvents := runJMESPath(&Config{Expression: "items[?level=='error'].message", Field: "result.errors"}, []string{
+		`{"items":[{"level":"error","message":"oops"},{"level":"info","message":"ok"},{"level":"error","message":"fail"}]}`,
+		`{"items":[{"level":"info","message":"ok"}]}`,
+	})
+
+	assert.Equal(t, 2, len(outEvents), "wrong out events count")
+	assert.JSONEq(t, `{"items":[{"level":"error","message":"oops"},{"level":"info","message":"ok"},{"level":"error","message":"fail"}],"result":{"errors":["oops","fail"]}}`, outEvents[0], "wrong out event")
+	assert.JSONEq(t, `{"items":[{"level":"info","message":"ok"}],"result":{"errors":[]}}`, outEvents[1], "wrong out event")
+}
+
+func TestJMESPathNoResult(t *testing.T) {
+	outEvents := runJMESPath(&Config{Expression: "missing.field"}, []string{
+		`{"message":"hello"}`,
+	})
+
+	assert.Equal(t, 1, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"message":"hello"}`, outEvents[0], "wrong out event")
+}
+
+func TestJMESPathNotObjectRoot(t *testing.T) {
+	outEvents := runJMESPath(&Config{Expression: "message"}, []string{
+		`{"message":"hello"}`,
+	})
+
+	assert.Equal(t, 1, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"message":"hello"}`, outEvents[0], "wrong out event")
+}
+
+func TestJMESPathBigNumbers(t *testing.T) {
+	outEvents := runJMESPath(&Config{Expression: "items[?size > `10`].{id: id, size: size}", Field: "big"}, []string{
+		`{"items":[{"id":9007199254740993,"size":20},{"id":1,"size":5},{"id":-9223372036854775808,"size":12.5}]}`,
+	})
+
+	assert.Equal(t, 1, len(outEvents), "wrong out events count")
+	assert.JSONEq(t, `{"items":[{"id":9007199254740993,"size":20},{"id":1,"size":5},{"id":-9223372036854775808,"size":12.5}],"big":[{"id":9007199254740993,"size":20},{"id":-9223372036854775808,"size":12.5}]}`, outEvents[0], "wrong out event")
+	assert.Contains(t, outEvents[0], `"big":[{"id":9007199254740993,`, "big integer is corrupted")
+}
+
+func TestJMESPathErrors(t *testing.T) {
+	config := &Config{Expression: "abs(message)", Field: "result"}
+	test.NewConfig(config, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"message":"hello"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":-5}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 2, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"message":"hello"}`, outEvents[0], "wrong out event")
+	assert.Equal(t, `{"message":-5,"result":5}`, outEvents[1], "wrong out event")
+
+	errors := p.GetMetricsCtl().RegisterCounter("jmespath_errors_total", "", "stage")
+	assert.Equal(t, float64(1), testutil.ToFloat64(errors.WithLabelValues("search")), "wrong search errors count")
+	assert.Equal(t, float64(0), testutil.ToFloat64(errors.WithLabelValues("decode")), "wrong decode errors count")
+}
@@ -11,7 +11,8 @@ It decodes a JSON string from the event field and merges the result with the eve
 If the decoded JSON isn't an object, the event will be skipped.
 }*/
 type Plugin struct {
-	config *Config
+	config      *Config
+	parseResult *pipeline.ParseResult
 }
 
 //! config-params
@@ -40,8 +41,9 @@ func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
 	return &Plugin{}, &Config{}
 }
 
-func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
 	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "json_decode")
 }
 
 func (p *Plugin) Stop() {
@@ -54,11 +56,8 @@ func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
 	}
 
 	node, err := event.SubparseJSON(jsonNode.AsBytes())
-	if err != nil {
-		return pipeline.ActionPass
-	}
-
-	if !node.IsObject() {
+	if err != nil || !node.IsObject() {
+		p.parseResult.Failure()
 		return pipeline.ActionPass
 	}
 
@@ -76,6 +75,7 @@ func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
 
 	// place decoded object under root
 	event.Root.MergeWith(node)
+	p.parseResult.Success()
 
 	return pipeline.ActionPass
 }
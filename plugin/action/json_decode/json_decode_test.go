@@ -8,6 +8,7 @@ import (
 	"github.com/ozonru/file.d/logger"
 	"github.com/ozonru/file.d/pipeline"
 	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,3 +43,76 @@ func TestDecode(t *testing.T) {
 	assert.Equal(t, 1, len(outEvents), "wrong out events count")
 	assert.Equal(t, `{"prefix.field2":"value2","prefix.field3":"value3"}`, outEvents[0].Root.EncodeToString(), "wrong out event")
 }
+
+func TestDecodeNested(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "message"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"service":"api","message":"{\"request\":{\"method\":\"GET\",\"headers\":[\"a\",\"b\"]},\"status\":200}"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{`{"service":"api","request":{"method":"GET","headers":["a","b"]},"status":200}`}, outEvents, "wrong out events")
+}
+
+func TestDecodeNotJSON(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "message", Prefix: "json_"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+	wg := &sync.WaitGroup{}
+	wg.Add(5)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"message":"plain text"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"{\"broken\":"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"[1,2,3]"}`))
+	input.In(0, "test.log", 0, []byte(`{"level":"info"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"{\"level\":\"error\"}"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"message":"plain text"}`,
+		`{"message":"{\"broken\":"}`,
+		`{"message":"[1,2,3]"}`,
+		`{"level":"info"}`,
+		`{"json_level":"error"}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestDecodeParseResult(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "log"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+
+	output.SetOutFn(func(e *pipeline.Event) {
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"log":"{\"field\":\"value\"}"}`))
+	input.In(0, "test.log", 0, []byte(`{"log":"not a json"}`))
+	input.In(0, "test.log", 0, []byte(`{"log":"[1,2]"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"no field"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	results := p.GetMetricsCtl().RegisterCounter("parse_result_total", "")
+	assert.Equal(t, float64(1), testutil.ToFloat64(results.WithLabelValues("json_decode", "success")), "wrong success count")
+	assert.Equal(t, float64(2), testutil.ToFloat64(results.WithLabelValues("json_decode", "failure")), "wrong failure count")
+}
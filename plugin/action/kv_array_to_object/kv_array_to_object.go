@@ -0,0 +1,141 @@
+package kv_array_to_object
+
+import (
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ introduction
+It converts an array of `{"key":...,"value":...}` entries into an object.
+Entries which aren't objects or don't have a string or numeric key or a value are skipped.
+If the field isn't an array, the event is passed as is.
+
+Duplicate keys are handled according to the `duplicates` param:
+* `last` – the value of the last entry wins;
+* `array` – values of all entries with the key are collected into an array.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: kv_array_to_object
+      field: labels
+    ...
+```
+It transforms `{"labels":[{"key":"app","value":"api"},{"key":"env","value":"prod"}]}`
+into `{"labels":{"app":"api","env":"prod"}}`.
+}*/
+type Plugin struct {
+	config *Config
+
+	groups   []group
+	keyIndex map[string]int
+}
+
+type group struct {
+	key    string
+	values []*insaneJSON.Node
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field which contains the array of entries.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" required:"true"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The name of the entry key field.
+	KeyName string `json:"key_name" default:"key"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The name of the entry value field.
+	ValueName string `json:"value_name" default:"value"` //*
+
+	//> @3@4@5@6
+	//>
+	//> How to handle duplicate keys.
+	Duplicates string `json:"duplicates" default:"last" options:"last|array"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "kv_array_to_object",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.keyIndex = make(map[string]int)
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if !node.IsArray() {
+		return pipeline.ActionPass
+	}
+
+	p.collect(node)
+
+	// entries are collected already, so array nodes can be reused
+	node.MutateToObject()
+	for _, g := range p.groups {
+		field := node.AddFieldNoAlloc(event.Root, g.key)
+		if len(g.values) == 1 || p.config.Duplicates == "last" {
+			field.MutateToNode(g.values[len(g.values)-1])
+			continue
+		}
+
+		field.MutateToJSON(event.Root, "[]")
+		for _, value := range g.values {
+			field.AddElement().MutateToNode(value)
+		}
+	}
+
+	return pipeline.ActionPass
+}
+
+// collect groups values of valid entries by key keeping the order of first key appearance
+func (p *Plugin) collect(node *insaneJSON.Node) {
+	for key := range p.keyIndex {
+		delete(p.keyIndex, key)
+	}
+	p.groups = p.groups[:0]
+
+	for _, entry := range node.AsArray() {
+		if !entry.IsObject() {
+			continue
+		}
+
+		keyNode := entry.Dig(p.config.KeyName)
+		valueNode := entry.Dig(p.config.ValueName)
+		if valueNode == nil || !(keyNode.IsString() || keyNode.IsNumber()) {
+			continue
+		}
+
+		key := keyNode.AsString()
+		index, has := p.keyIndex[key]
+		if !has {
+			index = len(p.groups)
+			p.keyIndex[key] = index
+			p.groups = append(p.groups, group{key: key, values: make([]*insaneJSON.Node, 0, 1)})
+		}
+		p.groups[index].values = append(p.groups[index].values, valueNode)
+	}
+}
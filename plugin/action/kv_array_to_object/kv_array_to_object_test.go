@@ -0,0 +1,66 @@
+package kv_array_to_object
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func runKVArrayToObject(config *Config, events []string) []string {
+	test.NewConfig(config, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(events))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, event := range events {
+		input.In(0, "test.log", 0, []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+func TestKVArrayToObject(t *testing.T) {
+	outEvents := runKVArrayToObject(&Config{Field: "labels"}, []string{
+		`{"labels":[{"key":"app","value":"api"},{"key":"env","value":"prod"},{"key":"replicas","value":3},{"key":"meta","value":{"team":"core"}}]}`,
+		`{"labels":[{"key":"env","value":"dev"},{"key":"env","value":"prod"}]}`,
+		`{"labels":[]}`,
+		`{"labels":"app=api"}`,
+	})
+
+	assert.Equal(t, 4, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"labels":{"app":"api","env":"prod","replicas":3,"meta":{"team":"core"}}}`, outEvents[0], "wrong out event")
+	assert.Equal(t, `{"labels":{"env":"prod"}}`, outEvents[1], "wrong out event")
+	assert.Equal(t, `{"labels":{}}`, outEvents[2], "wrong out event")
+	assert.Equal(t, `{"labels":"app=api"}`, outEvents[3], "wrong out event")
+}
+
+func TestKVArrayToObjectDuplicatesArray(t *testing.T) {
+	outEvents := runKVArrayToObject(&Config{Field: "tags", KeyName: "name", ValueName: "val", Duplicates: "array"}, []string{
+		`{"tags":[{"name":"env","val":"dev"},{"name":"app","val":"api"},{"name":"env","val":"prod"},{"name":"env","val":null}]}`,
+	})
+
+	assert.Equal(t, 1, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"tags":{"env":["dev","prod",null],"app":"api"}}`, outEvents[0], "wrong out event")
+}
+
+func TestKVArrayToObjectMalformed(t *testing.T) {
+	outEvents := runKVArrayToObject(&Config{Field: "labels"}, []string{
+		`{"labels":["app",42,null,{"value":"no key"},{"key":"no value"},{"key":{"nested":true},"value":1},{"key":null,"value":2},{"key":7,"value":"numeric key"},{"key":"ok","value":true}]}`,
+	})
+
+	assert.Equal(t, 1, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"labels":{"7":"numeric key","ok":true}}`, outEvents[0], "wrong out event")
+}
@@ -0,0 +1,108 @@
+package limit_array
+
+import (
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It truncates the array field to the first `max_len` elements.
+If `add_truncated_count` is set, the number of removed elements is put into `<field>_truncated_count` next to the array.
+Events which have no such field or have a non-array value are passed unchanged.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: limit_array
+      field: errors
+      max_len: 2
+      add_truncated_count: true
+    ...
+```
+It transforms `{"errors":["a","b","c","d"]}` into `{"errors":["a","b"],"errors_truncated_count":2}`.
+}*/
+type Plugin struct {
+	config     *Config
+	logger     *zap.SugaredLogger
+	countField string
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The array field to truncate.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" required:"true"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The maximum number of elements to keep. Must be positive.
+	MaxLen int `json:"max_len" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> If set, the number of removed elements is put into `<field>_truncated_count`.
+	AddTruncatedCount bool `json:"add_truncated_count" default:"false"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "limit_array",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger
+
+	if p.config.MaxLen <= 0 {
+		p.logger.Fatalf("max_len should be positive, got=%d", p.config.MaxLen)
+	}
+
+	p.countField = p.config.Field_[len(p.config.Field_)-1] + "_truncated_count"
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil || !node.IsArray() {
+		return pipeline.ActionPass
+	}
+
+	elements := node.AsArray()
+	if len(elements) <= p.config.MaxLen {
+		return pipeline.ActionPass
+	}
+	truncated := len(elements) - p.config.MaxLen
+
+	// elements are moved into a new array, removing them one by one is quadratic
+	kept := make([]*insaneJSON.Node, p.config.MaxLen)
+	copy(kept, elements)
+
+	node.MutateToJSON(event.Root, "[]")
+	for _, element := range kept {
+		node.AddElement().MutateToNode(element)
+	}
+
+	if p.config.AddTruncatedCount {
+		parent := event.Root.Dig(p.config.Field_[:len(p.config.Field_)-1]...)
+		parent.AddFieldNoAlloc(event.Root, p.countField).MutateToInt(truncated)
+	}
+
+	return pipeline.ActionPass
+}
@@ -0,0 +1,68 @@
+package limit_array
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitArray(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "errors", MaxLen: 2}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(5)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"errors":["a","b","c","d"],"message":"over"}`))
+	input.In(0, "test.log", 0, []byte(`{"errors":[{"code":1},[2,3],"c"]}`))
+	input.In(0, "test.log", 0, []byte(`{"errors":["a","b"]}`))
+	input.In(0, "test.log", 0, []byte(`{"errors":["a"]}`))
+	input.In(0, "test.log", 0, []byte(`{"errors":"a,b,c"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"errors":["a","b"],"message":"over"}`,
+		`{"errors":[{"code":1},[2,3]]}`,
+		`{"errors":["a","b"]}`,
+		`{"errors":["a"]}`,
+		`{"errors":"a,b,c"}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestLimitArrayTruncatedCount(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "response.errors", MaxLen: 1, AddTruncatedCount: true}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"response":{"errors":[1,2,3]}}`))
+	input.In(0, "test.log", 0, []byte(`{"response":{"errors":[1]}}`))
+	input.In(0, "test.log", 0, []byte(`{"response":{"errors":{"code":1}}}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"response":{"errors":[1],"errors_truncated_count":2}}`,
+		`{"response":{"errors":[1]}}`,
+		`{"response":{"errors":{"code":1}}}`,
+	}, outEvents, "wrong out events")
+}
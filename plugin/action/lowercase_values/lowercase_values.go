@@ -0,0 +1,82 @@
+package lowercase_values
+
+import (
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It lowercases string values of the configured enum-like fields, e.g. `level` or `method`, so `ERROR` and `error` become the same value.
+Other fields are left untouched, so free text isn't changed. Non-string values are skipped.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: lowercase_values
+      fields:
+      - level
+      - request.method
+    ...
+```
+It transforms `{"level":"ERROR","request":{"method":"Get"},"message":"Request FAILED"}`
+into `{"level":"error","request":{"method":"get"},"message":"Request FAILED"}`.
+}*/
+type Plugin struct {
+	config *Config
+	fields [][]string
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The list of the fields to lowercase. Nested fields can be used.
+	Fields []cfg.FieldSelector `json:"fields" required:"true"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "lowercase_values",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+
+	p.fields = make([][]string, 0, len(p.config.Fields))
+	for _, field := range p.config.Fields {
+		p.fields = append(p.fields, cfg.ParseFieldSelector(string(field)))
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	for _, field := range p.fields {
+		node := event.Root.Dig(field...)
+		if !node.IsString() {
+			continue
+		}
+
+		value := node.AsString()
+		lower := strings.ToLower(value)
+		if lower != value {
+			node.MutateToString(lower)
+		}
+	}
+
+	return pipeline.ActionPass
+}
@@ -0,0 +1,39 @@
+package lowercase_values
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLowercaseValues(t *testing.T) {
+	config := test.NewConfig(&Config{Fields: []cfg.FieldSelector{"level", "request.method", "missing"}}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"level":"ERROR","request":{"method":"Get"},"message":"Request FAILED","service":"API"}`))
+	input.In(0, "test.log", 0, []byte(`{"level":"error","message":"Already Lower Level"}`))
+	input.In(0, "test.log", 0, []byte(`{"level":42,"request":{"method":null}}`))
+	input.In(0, "test.log", 0, []byte(`{"level":"Warn","request":"POST"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 4, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"level":"error","request":{"method":"get"},"message":"Request FAILED","service":"API"}`, outEvents[0], "wrong out event")
+	assert.Equal(t, `{"level":"error","message":"Already Lower Level"}`, outEvents[1], "wrong out event")
+	assert.Equal(t, `{"level":42,"request":{"method":null}}`, outEvents[2], "wrong out event")
+	assert.Equal(t, `{"level":"warn","request":"POST"}`, outEvents[3], "wrong out event")
+}
@@ -0,0 +1,197 @@
+package mask
+
+import (
+	"regexp"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It masks parts of the event field matched by re2 expressions, e.g. to scrub card numbers and emails before shipping logs.
+Unlike `parse_re2` it doesn't extract anything: the selected capture groups are replaced in-place with `fill_char`.
+If `groups` of the mask are empty, the whole match is replaced.
+
+All masks are matched against the original value and the overlapping spans are joined,
+so the result doesn't depend on the order of the masks.
+Events without the field and events with a non-string field are passed unchanged.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: mask
+      field: message
+      masks:
+      - re: '\b(\d{4})[- ]?(\d{4})[- ]?(\d{4})[- ]?(\d{4})\b'
+        groups: [2, 3]
+      - re: '([\w.+-]+)@[\w-]+\.[\w.]+'
+        groups: [1]
+    ...
+```
+It transforms `{"message":"card 4111-1111-1111-1111 of john@example.com"}` into `{"message":"card 4111-****-****-1111 of ****@example.com"}`.
+}*/
+type Plugin struct {
+	config *Config
+	masks  []*mask
+	spans  []span
+	fill   []byte
+}
+
+type mask struct {
+	re     *regexp.Regexp
+	groups []int
+}
+
+type span struct {
+	start int
+	end   int
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to mask. Must be a string.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"message"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The list of masks. Each item has the following fields:
+	//> * `re` – re2 expression to match.
+	//> * `groups` – the list of capture groups to replace, `0` means the whole match. If empty, the whole match is replaced.
+	Masks []MaskConfig `json:"masks" required:"true" slice:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The character to replace the masked text with.
+	FillChar string `json:"fill_char" default:"*"` //*
+
+	//> @3@4@5@6
+	//>
+	//> If set, each masked span is replaced with exactly `mask_length` fill characters to hide the length of the masked text.
+	//> Otherwise the length in characters is preserved.
+	MaskLength int `json:"mask_length"` //*
+}
+
+type MaskConfig struct {
+	Re     string `json:"re" required:"true"`
+	Groups []int  `json:"groups"`
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "mask",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+
+	if utf8.RuneCountInString(p.config.FillChar) != 1 {
+		params.Logger.Fatalf("fill_char should be a single character, got=%q", p.config.FillChar)
+	}
+	if p.config.MaskLength < 0 {
+		params.Logger.Fatalf("mask_length can't be negative, got=%d", p.config.MaskLength)
+	}
+	p.fill = []byte(p.config.FillChar)
+
+	p.masks = make([]*mask, 0, len(p.config.Masks))
+	for i, maskConfig := range p.config.Masks {
+		re, err := regexp.Compile(maskConfig.Re)
+		if err != nil {
+			params.Logger.Fatalf("can't compile re of mask #%d: %s", i, err.Error())
+		}
+
+		groups := maskConfig.Groups
+		if len(groups) == 0 {
+			groups = []int{0}
+		}
+		for _, group := range groups {
+			if group < 0 || group > re.NumSubexp() {
+				params.Logger.Fatalf("mask #%d has no group %d, groups count=%d", i, group, re.NumSubexp())
+			}
+		}
+
+		p.masks = append(p.masks, &mask{re: re, groups: groups})
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil || !node.IsString() {
+		return pipeline.ActionPass
+	}
+
+	value := node.AsBytes()
+	p.spans = p.spans[:0]
+	for _, m := range p.masks {
+		for _, match := range m.re.FindAllSubmatchIndex(value, -1) {
+			for _, group := range m.groups {
+				start, end := match[group*2], match[group*2+1]
+				// group didn't participate in the match
+				if start < 0 || start == end {
+					continue
+				}
+				p.spans = append(p.spans, span{start: start, end: end})
+			}
+		}
+	}
+
+	if len(p.spans) == 0 {
+		return pipeline.ActionPass
+	}
+
+	sort.Slice(p.spans, func(i, j int) bool {
+		return p.spans[i].start < p.spans[j].start
+	})
+
+	l := len(event.Buf)
+	pos := 0
+	for i := 0; i < len(p.spans); {
+		start, end := p.spans[i].start, p.spans[i].end
+		// join overlapping and adjacent spans
+		for i++; i < len(p.spans) && p.spans[i].start <= end; i++ {
+			if p.spans[i].end > end {
+				end = p.spans[i].end
+			}
+		}
+
+		event.Buf = append(event.Buf, value[pos:start]...)
+		event.Buf = p.appendFill(event.Buf, value[start:end])
+		pos = end
+	}
+	event.Buf = append(event.Buf, value[pos:]...)
+
+	node.MutateToBytesCopy(event.Root, event.Buf[l:])
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) appendFill(buf []byte, masked []byte) []byte {
+	n := p.config.MaskLength
+	if n == 0 {
+		n = utf8.RuneCount(masked)
+	}
+
+	for i := 0; i < n; i++ {
+		buf = append(buf, p.fill...)
+	}
+
+	return buf
+}
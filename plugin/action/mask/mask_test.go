@@ -0,0 +1,84 @@
+package mask
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func runEvents(config *Config, events []string) []string {
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, test.NewConfig(config, nil), pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(events))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, event := range events {
+		input.In(0, "test.log", 0, []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+func TestMask(t *testing.T) {
+	outEvents := runEvents(&Config{
+		Masks: []MaskConfig{
+			{Re: `\b(\d{4})[- ]?(\d{4})[- ]?(\d{4})[- ]?(\d{4})\b`, Groups: []int{2, 3}},
+			{Re: `([\pL\d._+-]+)@[\w-]+\.[\w.]+`, Groups: []int{1}},
+			{Re: `token=\w+`},
+		},
+	}, []string{
+		`{"message":"card 4111-1111-1111-1111 of john@example.com"}`,
+		`{"message":"cards 4111111111111111 and 5500 0000 0000 0004, token=secret"}`,
+		`{"message":"письмо от иван.петров@example.com"}`,
+		`{"message":"nothing to mask"}`,
+		`{"message":{"card":"4111111111111111"}}`,
+		`{"level":"info"}`,
+	})
+
+	assert.Equal(t, []string{
+		`{"message":"card 4111-****-****-1111 of ****@example.com"}`,
+		`{"message":"cards 4111********1111 and 5500 **** **** 0004, ************"}`,
+		`{"message":"письмо от ***********@example.com"}`,
+		`{"message":"nothing to mask"}`,
+		`{"message":{"card":"4111111111111111"}}`,
+		`{"level":"info"}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestMaskOverlapping(t *testing.T) {
+	masks := []MaskConfig{
+		{Re: `user=(\w+)`, Groups: []int{1}},
+		{Re: `(\w+)@(\w+)`, Groups: []int{1, 2}},
+		{Re: `secret`},
+	}
+	events := []string{
+		`{"message":"user=alice@corp"}`,
+		`{"message":"user=secretive"}`,
+	}
+
+	outEvents := runEvents(&Config{Masks: masks}, events)
+	assert.Equal(t, []string{
+		`{"message":"user=*****@****"}`,
+		`{"message":"user=*********"}`,
+	}, outEvents, "wrong out events")
+
+	// the result doesn't depend on the order of the masks
+	reversed := []MaskConfig{masks[2], masks[1], masks[0]}
+	outEvents = runEvents(&Config{Masks: reversed, FillChar: "#", MaskLength: 3}, events)
+	assert.Equal(t, []string{
+		`{"message":"user=###@###"}`,
+		`{"message":"user=###"}`,
+	}, outEvents, "wrong out events")
+}
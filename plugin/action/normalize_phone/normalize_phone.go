@@ -0,0 +1,198 @@
+package normalize_phone
+
+import (
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It normalizes the phone number from the event field to the [E.164](https://en.wikipedia.org/wiki/E.164) format, e.g. `+79161234567`.
+Spaces, dashes, dots, slashes and parentheses are ignored.
+
+International numbers start with `+` or with the international call prefix of `default_region`, e.g. `00` in Europe or `011` in the `US`.
+National numbers are parsed using `default_region`: the trunk prefix is stripped and the country code is added.
+If the number can't be parsed, the field is left unchanged and `invalid_field` is set to `true`.
+If the field is absent, the event will be skipped.
+
+Supported regions: `AU`, `BR`, `BY`, `CA`, `CN`, `DE`, `ES`, `FR`, `GB`, `IN`, `JP`, `KZ`, `NL`, `RU`, `UA`, `US`.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: normalize_phone
+      field: user.phone
+      default_region: RU
+    ...
+```
+It transforms `{"user":{"phone":"8 (916) 123-45-67"}}` into `{"user":{"phone":"+79161234567"}}`.
+}*/
+type Plugin struct {
+	config *Config
+	logger *zap.SugaredLogger
+	region *region
+	digits []byte
+}
+
+type region struct {
+	code       string
+	trunk      string
+	intlPrefix string
+	minLen     int
+	maxLen     int
+}
+
+// regions contains calling codes, trunk prefixes and lengths of national significant numbers
+var regions = map[string]*region{
+	"AU": {code: "61", trunk: "0", intlPrefix: "00", minLen: 9, maxLen: 9},
+	"BR": {code: "55", trunk: "0", intlPrefix: "00", minLen: 10, maxLen: 11},
+	"BY": {code: "375", trunk: "8", intlPrefix: "00", minLen: 9, maxLen: 9},
+	"CA": {code: "1", trunk: "1", intlPrefix: "011", minLen: 10, maxLen: 10},
+	"CN": {code: "86", trunk: "0", intlPrefix: "00", minLen: 10, maxLen: 11},
+	"DE": {code: "49", trunk: "0", intlPrefix: "00", minLen: 6, maxLen: 13},
+	"ES": {code: "34", trunk: "", intlPrefix: "00", minLen: 9, maxLen: 9},
+	"FR": {code: "33", trunk: "0", intlPrefix: "00", minLen: 9, maxLen: 9},
+	"GB": {code: "44", trunk: "0", intlPrefix: "00", minLen: 9, maxLen: 10},
+	"IN": {code: "91", trunk: "0", intlPrefix: "00", minLen: 10, maxLen: 10},
+	"JP": {code: "81", trunk: "0", intlPrefix: "010", minLen: 9, maxLen: 10},
+	"KZ": {code: "7", trunk: "8", intlPrefix: "00", minLen: 10, maxLen: 10},
+	"NL": {code: "31", trunk: "0", intlPrefix: "00", minLen: 9, maxLen: 9},
+	"RU": {code: "7", trunk: "8", intlPrefix: "00", minLen: 10, maxLen: 10},
+	"UA": {code: "380", trunk: "0", intlPrefix: "00", minLen: 9, maxLen: 9},
+	"US": {code: "1", trunk: "1", intlPrefix: "011", minLen: 10, maxLen: 10},
+}
+
+const (
+	// E.164 numbers contain up to 15 digits including the country code
+	minE164Len = 8
+	maxE164Len = 15
+)
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field which contains the phone number.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"phone"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The region which is used to parse national numbers, e.g. `RU` or `US`.
+	//> If not set, only international numbers can be parsed.
+	DefaultRegion string `json:"default_region"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field which is set to `true` if the phone number can't be parsed.
+	InvalidField string `json:"invalid_field" default:"phone_invalid"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "normalize_phone",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger
+
+	if p.config.DefaultRegion != "" {
+		r, has := regions[strings.ToUpper(p.config.DefaultRegion)]
+		if !has {
+			p.logger.Fatalf("unknown default_region %q", p.config.DefaultRegion)
+		}
+		p.region = r
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil {
+		return pipeline.ActionPass
+	}
+
+	l := len(event.Buf)
+	buf, ok := p.appendE164(event.Buf, node.AsString())
+	if !ok {
+		event.Root.AddFieldNoAlloc(event.Root, p.config.InvalidField).MutateToBool(true)
+		return pipeline.ActionPass
+	}
+	event.Buf = buf
+
+	node.MutateToString(pipeline.ByteToStringUnsafe(event.Buf[l:]))
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) appendE164(out []byte, phone string) ([]byte, bool) {
+	phone = strings.TrimSpace(phone)
+	isIntl := strings.HasPrefix(phone, "+")
+	if isIntl {
+		phone = phone[1:]
+	}
+
+	digits := p.digits[:0]
+	for i := 0; i < len(phone); i++ {
+		c := phone[i]
+		switch {
+		case c >= '0' && c <= '9':
+			digits = append(digits, c)
+		case c == ' ' || c == '-' || c == '.' || c == '/' || c == '(' || c == ')':
+		default:
+			return out, false
+		}
+	}
+	p.digits = digits
+
+	if !isIntl {
+		prefix := "00"
+		if p.region != nil {
+			prefix = p.region.intlPrefix
+		}
+		if len(digits) > len(prefix) && string(digits[:len(prefix)]) == prefix {
+			isIntl = true
+			digits = digits[len(prefix):]
+		}
+	}
+
+	if isIntl {
+		if len(digits) < minE164Len || len(digits) > maxE164Len || digits[0] == '0' {
+			return out, false
+		}
+		out = append(out, '+')
+		return append(out, digits...), true
+	}
+
+	r := p.region
+	if r == nil {
+		return out, false
+	}
+
+	if r.trunk != "" && len(digits)-len(r.trunk) >= r.minLen && string(digits[:len(r.trunk)]) == r.trunk {
+		digits = digits[len(r.trunk):]
+	}
+	if len(digits) < r.minLen || len(digits) > r.maxLen {
+		return out, false
+	}
+
+	out = append(out, '+')
+	out = append(out, r.code...)
+	return append(out, digits...), true
+}
@@ -0,0 +1,137 @@
+package normalize_phone
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	config := test.NewConfig(&Config{DefaultRegion: "RU"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	phones := map[string]string{
+		"8 (916) 123-45-67":  "+79161234567",
+		"9161234567":         "+79161234567",
+		"+7 916 123 45 67":   "+79161234567",
+		"+1 (202) 555-0123":  "+12025550123",
+		"0044 20 7946 0958":  "+442079460958",
+		"+44 20 7946 0958":   "+442079460958",
+		"+380 44 123 4567":   "+380441234567",
+		"  +49.30.1234567  ": "+49301234567",
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(phones) + 1)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	originals := make([]string, 0, len(phones))
+	for phone := range phones {
+		originals = append(originals, phone)
+		input.In(0, "test.log", 0, []byte(`{"phone":"`+phone+`"}`))
+	}
+	input.In(0, "test.log", 0, []byte(`{"message":"no phone"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, len(phones)+1, len(outEvents), "wrong out events count")
+	for i, phone := range originals {
+		assert.Equal(t, phones[phone], outEvents[i].Root.Dig("phone").AsString(), "wrong phone for %q", phone)
+		assert.Nil(t, outEvents[i].Root.Dig("phone_invalid"), "phone %q shouldn't be invalid", phone)
+	}
+	assert.Equal(t, `{"message":"no phone"}`, outEvents[len(phones)].Root.EncodeToString(), "wrong out event")
+}
+
+func TestNormalizePhoneRegion(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "user.phone", DefaultRegion: "us"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"user":{"phone":"(202) 555-0123"}}`))
+	input.In(0, "test.log", 0, []byte(`{"user":{"phone":"1-202-555-0123"}}`))
+	input.In(0, "test.log", 0, []byte(`{"user":{"phone":"011 33 1 23 45 67 89"}}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 3, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"user":{"phone":"+12025550123"}}`, outEvents[0].Root.EncodeToString(), "wrong out event")
+	assert.Equal(t, `{"user":{"phone":"+12025550123"}}`, outEvents[1].Root.EncodeToString(), "wrong out event")
+	assert.Equal(t, `{"user":{"phone":"+33123456789"}}`, outEvents[2].Root.EncodeToString(), "wrong out event")
+}
+
+func TestNormalizePhoneInvalid(t *testing.T) {
+	config := test.NewConfig(&Config{DefaultRegion: "RU"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	phones := []string{
+		"916 123",
+		"8 916 123 45 67 89",
+		"+7 916 CALL ME",
+		"+123",
+		"+1234567890123456",
+		"",
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(phones))
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	for _, phone := range phones {
+		input.In(0, "test.log", 0, []byte(`{"phone":"`+phone+`"}`))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, len(phones), len(outEvents), "wrong out events count")
+	for i, phone := range phones {
+		assert.Equal(t, `{"phone":"`+phone+`","phone_invalid":true}`, outEvents[i].Root.EncodeToString(), "wrong out event")
+	}
+}
+
+func TestNormalizePhoneNoRegion(t *testing.T) {
+	config := test.NewConfig(&Config{InvalidField: "bad_phone"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"phone":"+7 916 123-45-67"}`))
+	input.In(0, "test.log", 0, []byte(`{"phone":"8 916 123-45-67"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 2, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"phone":"+79161234567"}`, outEvents[0].Root.EncodeToString(), "wrong out event")
+	assert.Equal(t, `{"phone":"8 916 123-45-67","bad_phone":true}`, outEvents[1].Root.EncodeToString(), "wrong out event")
+}
@@ -0,0 +1,199 @@
+package parse_alb
+
+import (
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ introduction
+It parses AWS Application Load Balancer access log line from the event field and merges the result with the event root.
+Network Load Balancer TLS access logs are also supported: they are detected by the `tls` value of the first field.
+Numeric fields like `elb_status_code`, `received_bytes` or `request_processing_time` are converted to numbers,
+`-` value of a numeric field is converted to `null`. If the line can't be parsed, the event is passed unchanged
+and the failure is counted by `parse_result_total` metric of the pipeline.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_alb
+      field: message
+    ...
+```
+It transforms `{"message":"http 2021-07-01T10:00:00.000000Z app/my-lb/50dc6c495c0c9188 10.0.0.1:2817 10.0.0.2:80 0.000 0.001 0.000 200 200 34 366 \"GET http://example.com:80/ HTTP/1.1\" \"curl/7.46.0\" - - ..."}`
+into `{"type":"http","time":"2021-07-01T10:00:00.000000Z",...,"elb_status_code":200,"target_status_code":200,...,"request":"GET http://example.com:80/ HTTP/1.1","user_agent":"curl/7.46.0",...}`.
+}*/
+type Plugin struct {
+	config      *Config
+	parseResult *pipeline.ParseResult
+
+	tokens [][]byte
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to parse. Must be a string.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"message"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to parsed keys.
+	Prefix string `json:"prefix" default:""` //*
+}
+
+const (
+	kindString = pipeline.ValueString
+	kindInt    = pipeline.ValueInt
+	kindFloat  = pipeline.ValueFloat
+)
+
+type field struct {
+	name string
+	kind pipeline.ValueKind
+}
+
+// albFields is the field order of ALB access logs,
+// see https://docs.aws.amazon.com/elasticloadbalancing/latest/application/load-balancer-access-logs.html
+var albFields = []field{
+	{"type", kindString},
+	{"time", kindString},
+	{"elb", kindString},
+	{"client", kindString},
+	{"target", kindString},
+	{"request_processing_time", kindFloat},
+	{"target_processing_time", kindFloat},
+	{"response_processing_time", kindFloat},
+	{"elb_status_code", kindInt},
+	{"target_status_code", kindInt},
+	{"received_bytes", kindInt},
+	{"sent_bytes", kindInt},
+	{"request", kindString},
+	{"user_agent", kindString},
+	{"ssl_cipher", kindString},
+	{"ssl_protocol", kindString},
+	{"target_group_arn", kindString},
+	{"trace_id", kindString},
+	{"domain_name", kindString},
+	{"chosen_cert_arn", kindString},
+	{"matched_rule_priority", kindInt},
+	{"request_creation_time", kindString},
+	{"actions_executed", kindString},
+	{"redirect_url", kindString},
+	{"error_reason", kindString},
+	{"target_port_list", kindString},
+	{"target_status_code_list", kindString},
+	{"classification", kindString},
+	{"classification_reason", kindString},
+}
+
+// nlbFields is the field order of NLB TLS access logs,
+// see https://docs.aws.amazon.com/elasticloadbalancing/latest/network/load-balancer-access-logs.html
+var nlbFields = []field{
+	{"type", kindString},
+	{"version", kindString},
+	{"time", kindString},
+	{"elb", kindString},
+	{"listener", kindString},
+	{"client", kindString},
+	{"destination", kindString},
+	{"connection_time", kindInt},
+	{"tls_handshake_time", kindInt},
+	{"received_bytes", kindInt},
+	{"sent_bytes", kindInt},
+	{"incoming_tls_alert", kindString},
+	{"chosen_cert_arn", kindString},
+	{"chosen_cert_serial", kindString},
+	{"tls_cipher", kindString},
+	{"tls_protocol_version", kindString},
+	{"tls_named_group", kindString},
+	{"domain_name", kindString},
+	{"alpn_fe_protocol", kindString},
+	{"alpn_be_protocol", kindString},
+	{"alpn_client_preference_list", kindString},
+	{"tls_connection_creation_time", kindString},
+}
+
+const (
+	// lines with less fields are considered broken
+	albMinFields = 14 // up to user_agent
+	nlbMinFields = 11 // up to sent_bytes
+)
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_alb",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_alb")
+	p.tokens = make([][]byte, 0, len(albFields))
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	jsonNode := event.Root.Dig(p.config.Field_...)
+	if jsonNode == nil {
+		return pipeline.ActionPass
+	}
+
+	var ok bool
+	p.tokens, ok = pipeline.Tokenize(p.tokens[:0], jsonNode.AsBytes(), false)
+	if !ok || len(p.tokens) == 0 {
+		p.parseResult.Failure()
+		return pipeline.ActionPass
+	}
+
+	fields, minFields := albFields, albMinFields
+	if string(p.tokens[0]) == "tls" {
+		fields, minFields = nlbFields, nlbMinFields
+	}
+
+	if len(p.tokens) < minFields {
+		p.parseResult.Failure()
+		return pipeline.ActionPass
+	}
+
+	jsonNode.Suicide()
+
+	root := insaneJSON.Spawn()
+
+	var bl int
+	for i, token := range p.tokens {
+		// new fields may be appended by AWS at any time, so just skip unknown ones
+		if i >= len(fields) {
+			break
+		}
+
+		bl = len(event.Buf)
+		event.Buf = append(event.Buf, p.config.Prefix...)
+		event.Buf = append(event.Buf, fields[i].name...)
+
+		node := root.AddFieldNoAlloc(root, pipeline.ByteToStringUnsafe(event.Buf[bl:]))
+		pipeline.MutateToValue(node, fields[i].kind, token)
+	}
+
+	event.Root.MergeWith(root.Node)
+
+	insaneJSON.Release(root)
+	p.parseResult.Success()
+
+	return pipeline.ActionPass
+}
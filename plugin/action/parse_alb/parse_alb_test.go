@@ -0,0 +1,93 @@
+package parse_alb
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseALB(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "log"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(5)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"log":"http 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 \"GET http://www.example.com:80/ HTTP/1.1\" \"curl/7.46.0\" - - arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 \"Root=1-58337262-36d228ad5d99923122bbe354\" \"-\" \"-\" 0 2018-07-02T22:22:48.364000Z \"forward\" \"-\" \"-\" \"10.0.0.1:80\" \"200\" \"-\" \"-\""}`))
+	input.In(0, "test.log", 0, []byte(`{"log":"https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 - -1 -1 -1 503 - 34 366 \"GET https://www.example.com:443/api?user=john doe HTTP/1.1\" \"Mozilla/5.0 (Windows NT 10.0; Win64; x64)\" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 - \"Root=1-58337262-36d228ad5d99923122bbe354\" \"www.example.com\" \"arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012\" -1 2018-07-02T22:22:48.364000Z \"forward\" \"-\" \"-\" \"-\" \"-\" \"-\" \"-\" \"extra_field_from_future\""}`))
+	input.In(0, "test.log", 0, []byte(`{"log":"tls 2.0 2018-12-20T02:59:40 net/my-network-loadbalancer/c6e77e28c25b2234 g3d4b5e8bb8464cd 72.21.218.154:51341 172.100.100.185:443 5 2 98 246 - arn:aws:acm:us-east-2:671290407336:certificate/2a108f19-aded-46b0-8493-c63eb1ef4a99 - ECDHE-RSA-AES128-SHA tlsv12 - my-network-loadbalancer-c6e77e28c25b2234.elb.us-east-2.amazonaws.com h2 h2 \"h2\",\"http/1.1\" 2020-04-01T08:51:42"}`))
+	input.In(0, "test.log", 0, []byte(`{"log":"http 2018-07-02T22:23:00.186641Z \"unterminated"}`))
+	input.In(0, "test.log", 0, []byte(`{"log":"not an alb log"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 5, len(outEvents), "wrong out events count")
+
+	root := outEvents[0].Root
+	assert.Nil(t, root.Dig("log"), "field isn't removed")
+	assert.Equal(t, "http", root.Dig("type").AsString(), "wrong field value")
+	assert.Equal(t, "192.168.131.39:2817", root.Dig("client").AsString(), "wrong field value")
+	assert.Equal(t, 0.001, root.Dig("target_processing_time").AsFloat(), "wrong field value")
+	assert.Equal(t, 200, root.Dig("elb_status_code").AsInt(), "wrong field value")
+	assert.Equal(t, 200, root.Dig("target_status_code").AsInt(), "wrong field value")
+	assert.Equal(t, 366, root.Dig("sent_bytes").AsInt(), "wrong field value")
+	assert.Equal(t, "GET http://www.example.com:80/ HTTP/1.1", root.Dig("request").AsString(), "wrong field value")
+	assert.Equal(t, "curl/7.46.0", root.Dig("user_agent").AsString(), "wrong field value")
+	assert.Equal(t, "Root=1-58337262-36d228ad5d99923122bbe354", root.Dig("trace_id").AsString(), "wrong field value")
+	assert.Equal(t, 0, root.Dig("matched_rule_priority").AsInt(), "wrong field value")
+	assert.Equal(t, "forward", root.Dig("actions_executed").AsString(), "wrong field value")
+	assert.Equal(t, "-", root.Dig("classification_reason").AsString(), "wrong field value")
+
+	root = outEvents[1].Root
+	assert.Equal(t, "-", root.Dig("target").AsString(), "wrong field value")
+	assert.Equal(t, -1.0, root.Dig("request_processing_time").AsFloat(), "wrong field value")
+	assert.Equal(t, 503, root.Dig("elb_status_code").AsInt(), "wrong field value")
+	assert.True(t, root.Dig("target_status_code").IsNull(), "wrong field value")
+	assert.Equal(t, "GET https://www.example.com:443/api?user=john doe HTTP/1.1", root.Dig("request").AsString(), "wrong field value")
+	assert.Equal(t, "Mozilla/5.0 (Windows NT 10.0; Win64; x64)", root.Dig("user_agent").AsString(), "wrong field value")
+	assert.Equal(t, "TLSv1.2", root.Dig("ssl_protocol").AsString(), "wrong field value")
+	assert.Equal(t, -1, root.Dig("matched_rule_priority").AsInt(), "wrong field value")
+
+	root = outEvents[2].Root
+	assert.Equal(t, "tls", root.Dig("type").AsString(), "wrong field value")
+	assert.Equal(t, "172.100.100.185:443", root.Dig("destination").AsString(), "wrong field value")
+	assert.Equal(t, 5, root.Dig("connection_time").AsInt(), "wrong field value")
+	assert.Equal(t, 246, root.Dig("sent_bytes").AsInt(), "wrong field value")
+	assert.Equal(t, "h2", root.Dig("alpn_be_protocol").AsString(), "wrong field value")
+	assert.Equal(t, "2020-04-01T08:51:42", root.Dig("tls_connection_creation_time").AsString(), "wrong field value")
+
+	assert.Equal(t, `{"log":"http 2018-07-02T22:23:00.186641Z \"unterminated"}`, outEvents[3].Root.EncodeToString(), "wrong out event")
+	assert.Equal(t, `{"log":"not an alb log"}`, outEvents[4].Root.EncodeToString(), "wrong out event")
+}
+
+func TestParseALBPrefix(t *testing.T) {
+	config := test.NewConfig(&Config{Prefix: "alb_"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"message":"h2 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 10.0.1.252:48160 10.0.0.66:9000 0.000 0.002 0.000 200 200 5 257 \"GET https://10.0.2.105:773/ HTTP/2.0\" \"curl/7.46.0\""}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 1, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"alb_type":"h2","alb_time":"2018-07-02T22:23:00.186641Z","alb_elb":"app/my-loadbalancer/50dc6c495c0c9188","alb_client":"10.0.1.252:48160","alb_target":"10.0.0.66:9000","alb_request_processing_time":0,"alb_target_processing_time":0.002,"alb_response_processing_time":0,"alb_elb_status_code":200,"alb_target_status_code":200,"alb_received_bytes":5,"alb_sent_bytes":257,"alb_request":"GET https://10.0.2.105:773/ HTTP/2.0","alb_user_agent":"curl/7.46.0"}`, outEvents[0].Root.EncodeToString(), "wrong out event")
+}
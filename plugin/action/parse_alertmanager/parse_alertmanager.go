@@ -0,0 +1,213 @@
+package parse_alertmanager
+
+import (
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It splits Prometheus Alertmanager webhook payload into events, one event per alert.
+Each event has the following fields:
+* `status`, `starts_at`, `ends_at`, `generator_url`, `fingerprint` of the alert, `ends_at` is skipped if it's zero time of firing alert.
+* labels and annotations of the alert with `label_prefix` and `annotation_prefix`.
+* `group_status`, `receiver`, `group_key`, `external_url` of the payload.
+
+Other payload fields are removed, since common and group labels are included into labels of each alert.
+Other event fields are copied to each event. Events without `alerts` array are passed unchanged.
+Events of all alerts except the first one are new events, see `resplit` plugin for the details.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_alertmanager
+    ...
+```
+It transforms `{"status":"firing","receiver":"team","alerts":[{"status":"firing","labels":{"alertname":"HighLatency"},"startsAt":"2023-08-04T16:09:59Z"}]}`
+into `{"group_status":"firing","receiver":"team","status":"firing","starts_at":"2023-08-04T16:09:59Z","label_alertname":"HighLatency"}`.
+}*/
+type Plugin struct {
+	config      *Config
+	controller  pipeline.ActionPluginController
+	logger      *zap.SugaredLogger
+	parseResult *pipeline.ParseResult
+	alerts      []*insaneJSON.Node
+	values      []*insaneJSON.Node
+	added       []string
+	buf         []byte
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field which contains the payload. The payload is the event itself if not set.
+	Field  cfg.FieldSelector `json:"field" parse:"selector"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to alert labels.
+	LabelPrefix string `json:"label_prefix" default:"label_"` //*
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to alert annotations.
+	AnnotationPrefix string `json:"annotation_prefix" default:"annotation_"` //*
+}
+
+// zeroTime is the end time of firing alerts
+const zeroTime = "0001-01-01T00:00:00Z"
+
+var (
+	// payloadFields are mapped to the field names of the event
+	payloadFields = []struct {
+		from string
+		to   string
+	}{
+		{"status", "group_status"},
+		{"receiver", "receiver"},
+		{"groupKey", "group_key"},
+		{"externalURL", "external_url"},
+	}
+	alertFields = []struct {
+		from string
+		to   string
+	}{
+		{"status", "status"},
+		{"startsAt", "starts_at"},
+		{"endsAt", "ends_at"},
+		{"generatorURL", "generator_url"},
+		{"fingerprint", "fingerprint"},
+	}
+	droppedFields = []string{"version", "truncatedAlerts", "groupLabels", "commonLabels", "commonAnnotations", "alerts"}
+)
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_alertmanager",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.controller = params.Controller
+	p.logger = params.Logger
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_alertmanager")
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	payload := event.Root.Dig(p.config.Field_...)
+	if payload == nil {
+		return pipeline.ActionPass
+	}
+	// other events of the root aren't counted, since the root is checked for all events
+	if !payload.IsObject() {
+		if len(p.config.Field_) != 0 {
+			p.parseResult.Failure()
+		}
+		return pipeline.ActionPass
+	}
+
+	p.alerts = p.alerts[:0]
+	for _, alert := range payload.Dig("alerts").AsArray() {
+		if alert.IsObject() {
+			p.alerts = append(p.alerts, alert)
+		}
+	}
+	if len(p.alerts) == 0 {
+		if len(p.config.Field_) != 0 {
+			p.parseResult.Failure()
+		}
+		return pipeline.ActionPass
+	}
+
+	p.values = p.values[:0]
+	for _, field := range payloadFields {
+		p.values = append(p.values, payload.Dig(field.from))
+	}
+
+	// payload nodes are still valid after they are removed from the event
+	if len(p.config.Field_) != 0 {
+		payload.Suicide()
+	} else {
+		for _, field := range payloadFields {
+			payload.Dig(field.from).Suicide()
+		}
+		for _, field := range droppedFields {
+			payload.Dig(field).Suicide()
+		}
+	}
+
+	for i, field := range payloadFields {
+		p.addValue(event, field.to, p.values[i])
+	}
+
+	for _, alert := range p.alerts[1:] {
+		p.added = p.added[:0]
+		p.addAlert(event, alert)
+
+		p.buf = event.Root.Encode(p.buf[:0])
+		if err := p.controller.Spawn(event, p.buf); err != nil {
+			p.logger.Errorf("can't spawn event: %s", err.Error())
+		}
+
+		for _, name := range p.added {
+			event.Root.Dig(name).Suicide()
+		}
+	}
+	p.added = p.added[:0]
+	p.addAlert(event, p.alerts[0])
+	p.parseResult.Success()
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) addAlert(event *pipeline.Event, alert *insaneJSON.Node) {
+	for _, field := range alertFields {
+		value := alert.Dig(field.from)
+		if field.from == "endsAt" && value.AsString() == zeroTime {
+			continue
+		}
+		p.addValue(event, field.to, value)
+	}
+
+	p.addObject(event, p.config.LabelPrefix, alert.Dig("labels"))
+	p.addObject(event, p.config.AnnotationPrefix, alert.Dig("annotations"))
+}
+
+func (p *Plugin) addObject(event *pipeline.Event, prefix string, object *insaneJSON.Node) {
+	if !object.IsObject() {
+		return
+	}
+
+	for _, field := range object.AsFields() {
+		l := len(event.Buf)
+		event.Buf = append(event.Buf, prefix...)
+		event.Buf = append(event.Buf, field.AsString()...)
+		p.addValue(event, pipeline.ByteToStringUnsafe(event.Buf[l:]), field.AsFieldValue())
+	}
+}
+
+func (p *Plugin) addValue(event *pipeline.Event, name string, value *insaneJSON.Node) {
+	if value == nil {
+		return
+	}
+
+	event.Root.AddFieldNoAlloc(event.Root, name).MutateToNode(value)
+	p.added = append(p.added, name)
+}
@@ -0,0 +1,107 @@
+package parse_alertmanager
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+)
+
+const webhook = `{
+	"version": "4",
+	"groupKey": "{}:{alertname=\"HighLatency\"}",
+	"truncatedAlerts": 0,
+	"status": "firing",
+	"receiver": "team-api",
+	"groupLabels": {"alertname": "HighLatency"},
+	"commonLabels": {"alertname": "HighLatency", "severity": "warning"},
+	"commonAnnotations": {"summary": "latency is high"},
+	"externalURL": "http://alertmanager:9093",
+	"alerts": [
+		{
+			"status": "firing",
+			"labels": {"alertname": "HighLatency", "severity": "warning", "instance": "api-1"},
+			"annotations": {"summary": "latency is high"},
+			"startsAt": "2023-08-04T16:09:59Z",
+			"endsAt": "0001-01-01T00:00:00Z",
+			"generatorURL": "http://prometheus:9090/graph",
+			"fingerprint": "a1"
+		},
+		{
+			"status": "resolved",
+			"labels": {"alertname": "HighLatency", "severity": "warning", "instance": "api-2"},
+			"annotations": {"summary": "latency is high", "runbook": "http://wiki/latency"},
+			"startsAt": "2023-08-04T15:00:00Z",
+			"endsAt": "2023-08-04T16:00:00Z",
+			"fingerprint": "a2"
+		},
+		"wrong alert",
+		{
+			"status": "firing",
+			"labels": {"alertname": "HighLatency", "instance": "api-3"},
+			"startsAt": "2023-08-04T16:10:00Z",
+			"fingerprint": "a3"
+		}
+	]
+}`
+
+func runEvents(config *Config, events []string, outCount int) ([]string, int) {
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, test.NewConfig(config, nil), pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(outCount)
+
+	commits := atomic.NewInt32(0)
+	input.SetCommitFn(func(e *pipeline.Event) {
+		commits.Inc()
+	})
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for i, event := range events {
+		input.In(0, "test.log", int64(i+1), []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents, int(commits.Load())
+}
+
+func TestParseAlertmanager(t *testing.T) {
+	outEvents, commits := runEvents(&Config{}, []string{webhook}, 3)
+
+	assert.Equal(t, []string{
+		`{"group_status":"firing","receiver":"team-api","group_key":"{}:{alertname=\"HighLatency\"}","external_url":"http://alertmanager:9093",` +
+			`"status":"firing","starts_at":"2023-08-04T16:09:59Z","generator_url":"http://prometheus:9090/graph","fingerprint":"a1",` +
+			`"label_alertname":"HighLatency","label_severity":"warning","label_instance":"api-1","annotation_summary":"latency is high"}`,
+		`{"group_status":"firing","receiver":"team-api","group_key":"{}:{alertname=\"HighLatency\"}","external_url":"http://alertmanager:9093",` +
+			`"status":"resolved","starts_at":"2023-08-04T15:00:00Z","ends_at":"2023-08-04T16:00:00Z","fingerprint":"a2",` +
+			`"label_alertname":"HighLatency","label_severity":"warning","label_instance":"api-2","annotation_summary":"latency is high","annotation_runbook":"http://wiki/latency"}`,
+		`{"group_status":"firing","receiver":"team-api","group_key":"{}:{alertname=\"HighLatency\"}","external_url":"http://alertmanager:9093",` +
+			`"status":"firing","starts_at":"2023-08-04T16:10:00Z","fingerprint":"a3","label_alertname":"HighLatency","label_instance":"api-3"}`,
+	}, outEvents, "wrong out events")
+	assert.Equal(t, 1, commits, "alerts of the payload should be committed once")
+}
+
+func TestParseAlertmanagerField(t *testing.T) {
+	outEvents, _ := runEvents(&Config{Field: "body", LabelPrefix: "l.", AnnotationPrefix: "a."}, []string{
+		`{"source":"http","body":{"status":"resolved","alerts":[{"status":"resolved","labels":{"alertname":"Down"}},{"labels":{"alertname":"Up"}}]}}`,
+		`{"source":"http","body":{"status":"firing","alerts":[]}}`,
+		`{"source":"http","body":"not a payload"}`,
+	}, 4)
+
+	assert.Equal(t, []string{
+		`{"source":"http","group_status":"resolved","status":"resolved","l.alertname":"Down"}`,
+		`{"source":"http","group_status":"resolved","l.alertname":"Up"}`,
+		`{"source":"http","body":{"status":"firing","alerts":[]}}`,
+		`{"source":"http","body":"not a payload"}`,
+	}, outEvents, "wrong out events")
+}
@@ -0,0 +1,163 @@
+package parse_cloudtrail
+
+import (
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ introduction
+It copies key fields of AWS CloudTrail record to the event root under canonical names:
+* `event_name` – `eventName`.
+* `event_source` – `eventSource`.
+* `region` – `awsRegion`.
+* `source_ip` – `sourceIPAddress`.
+* `user_type` – `userIdentity.type`.
+* `user_arn` – `userIdentity.arn`.
+* `account_id` – `userIdentity.accountId` or `recipientAccountId`.
+* `user_name` – `userIdentity.userName` or `userIdentity.sessionContext.sessionIssuer.userName` for assumed roles.
+* `role_arn` – `userIdentity.sessionContext.sessionIssuer.arn`, it's the role ARN for assumed roles.
+* `role_session` – the session name from the assumed role ARN `arn:aws:sts::<account>:assumed-role/<role>/<session>`.
+* `error_code` – `errorCode`.
+* `error_message` – `errorMessage`.
+
+The record is taken from `field`, e.g. `detail` of EventBridge events, or from the event root if it isn't set.
+Absent fields are skipped and the record is kept untouched.
+If the record has neither `eventName` nor `eventSource`, the event is passed unchanged.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_cloudtrail
+      prefix: cloudtrail_
+    ...
+```
+}*/
+type Plugin struct {
+	config      *Config
+	parseResult *pipeline.ParseResult
+	names       map[string]string
+	values      []string
+	found       []bool
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field with the CloudTrail record, the event root is used if it isn't set.
+	Field  cfg.FieldSelector `json:"field" parse:"selector"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to canonical names.
+	Prefix string `json:"prefix" default:""` //*
+}
+
+type mapping struct {
+	name  string
+	paths [][]string
+}
+
+// mappings are in the order of priority, the first found path of the mapping is used
+var mappings = []mapping{
+	{name: "event_name", paths: [][]string{{"eventName"}}},
+	{name: "event_source", paths: [][]string{{"eventSource"}}},
+	{name: "region", paths: [][]string{{"awsRegion"}}},
+	{name: "source_ip", paths: [][]string{{"sourceIPAddress"}}},
+	{name: "user_type", paths: [][]string{{"userIdentity", "type"}}},
+	{name: "user_arn", paths: [][]string{{"userIdentity", "arn"}}},
+	{name: "account_id", paths: [][]string{{"userIdentity", "accountId"}, {"recipientAccountId"}}},
+	{name: "user_name", paths: [][]string{{"userIdentity", "userName"}, {"userIdentity", "sessionContext", "sessionIssuer", "userName"}}},
+	{name: "role_arn", paths: [][]string{{"userIdentity", "sessionContext", "sessionIssuer", "arn"}}},
+	{name: "error_code", paths: [][]string{{"errorCode"}}},
+	{name: "error_message", paths: [][]string{{"errorMessage"}}},
+}
+
+const assumedRole = ":assumed-role/"
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_cloudtrail",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_cloudtrail")
+
+	p.names = make(map[string]string)
+	for _, m := range mappings {
+		p.names[m.name] = p.config.Prefix + m.name
+	}
+	p.names["role_session"] = p.config.Prefix + "role_session"
+
+	p.values = make([]string, len(mappings))
+	p.found = make([]bool, len(mappings))
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	record := event.Root.Dig(p.config.Field_...)
+	if record == nil {
+		return pipeline.ActionPass
+	}
+	// other events of the root aren't counted, since the root is checked for all events
+	if !record.IsObject() || record.Dig("eventName") == nil && record.Dig("eventSource") == nil {
+		if len(p.config.Field_) != 0 {
+			p.parseResult.Failure()
+		}
+		return pipeline.ActionPass
+	}
+
+	// values are collected first, so the record isn't changed while it's read if canonical names collide with it
+	for i, m := range mappings {
+		p.values[i], p.found[i] = lookup(record, m.paths)
+	}
+	session := ""
+	if arn, has := lookup(record, [][]string{{"userIdentity", "arn"}}); has && strings.Contains(arn, assumedRole) {
+		session = arn[strings.LastIndexByte(arn, '/')+1:]
+	}
+
+	for i, m := range mappings {
+		if p.found[i] {
+			event.Root.AddFieldNoAlloc(event.Root, p.names[m.name]).MutateToString(p.values[i])
+		}
+	}
+	if session != "" {
+		event.Root.AddFieldNoAlloc(event.Root, p.names["role_session"]).MutateToString(session)
+	}
+	p.parseResult.Success()
+
+	return pipeline.ActionPass
+}
+
+// lookup returns the value of the first path which has a non-empty string value
+func lookup(record *insaneJSON.Node, paths [][]string) (string, bool) {
+	for _, path := range paths {
+		node := record.Dig(path...)
+		if node == nil || !node.IsString() {
+			continue
+		}
+		if value := node.AsString(); value != "" {
+			return value, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,145 @@
+package parse_cloudtrail
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+const assumedRoleRecord = `{
+  "eventVersion": "1.08",
+  "userIdentity": {
+    "type": "AssumedRole",
+    "principalId": "AROAEXAMPLE:deploy-bot",
+    "arn": "arn:aws:sts::123456789012:assumed-role/CIDeployRole/deploy-bot",
+    "accountId": "123456789012",
+    "accessKeyId": "ASIAEXAMPLE",
+    "sessionContext": {
+      "sessionIssuer": {
+        "type": "Role",
+        "principalId": "AROAEXAMPLE",
+        "arn": "arn:aws:iam::123456789012:role/CIDeployRole",
+        "accountId": "123456789012",
+        "userName": "CIDeployRole"
+      },
+      "attributes": {"creationDate": "2021-07-02T10:00:00Z", "mfaAuthenticated": "false"}
+    }
+  },
+  "eventTime": "2021-07-02T10:05:00Z",
+  "eventSource": "s3.amazonaws.com",
+  "eventName": "PutObject",
+  "awsRegion": "eu-west-1",
+  "sourceIPAddress": "10.0.0.1",
+  "userAgent": "aws-cli/2.2.0",
+  "errorCode": "AccessDenied",
+  "errorMessage": "Access Denied",
+  "requestParameters": {"bucketName": "artifacts", "key": "build.zip"},
+  "responseElements": null,
+  "recipientAccountId": "123456789012"
+}`
+
+const iamUserRecord = `{
+  "eventVersion": "1.08",
+  "userIdentity": {
+    "type": "IAMUser",
+    "principalId": "AIDAEXAMPLE",
+    "arn": "arn:aws:iam::210987654321:user/alice",
+    "accountId": "210987654321",
+    "userName": "alice"
+  },
+  "eventTime": "2021-07-02T11:00:00Z",
+  "eventSource": "ec2.amazonaws.com",
+  "eventName": "DescribeInstances",
+  "awsRegion": "us-east-1",
+  "sourceIPAddress": "203.0.113.7",
+  "responseElements": null
+}`
+
+func TestParseCloudTrail(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(assumedRoleRecord))
+	input.In(0, "test.log", 0, []byte(iamUserRecord))
+	input.In(0, "test.log", 0, []byte(`{"message":"not a cloudtrail record"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 3, len(outEvents), "wrong out events count")
+
+	expected := map[string]string{
+		"event_name":    "PutObject",
+		"event_source":  "s3.amazonaws.com",
+		"region":        "eu-west-1",
+		"source_ip":     "10.0.0.1",
+		"user_type":     "AssumedRole",
+		"user_arn":      "arn:aws:sts::123456789012:assumed-role/CIDeployRole/deploy-bot",
+		"account_id":    "123456789012",
+		"user_name":     "CIDeployRole",
+		"role_arn":      "arn:aws:iam::123456789012:role/CIDeployRole",
+		"role_session":  "deploy-bot",
+		"error_code":    "AccessDenied",
+		"error_message": "Access Denied",
+	}
+	for field, value := range expected {
+		assert.Equal(t, value, outEvents[0].Root.Dig(field).AsString(), "wrong %s field", field)
+	}
+	assert.Equal(t, "CIDeployRole", outEvents[0].Root.Dig("userIdentity", "sessionContext", "sessionIssuer", "userName").AsString(), "record should be kept")
+
+	expected = map[string]string{
+		"event_name":   "DescribeInstances",
+		"event_source": "ec2.amazonaws.com",
+		"region":       "us-east-1",
+		"source_ip":    "203.0.113.7",
+		"user_type":    "IAMUser",
+		"user_arn":     "arn:aws:iam::210987654321:user/alice",
+		"account_id":   "210987654321",
+		"user_name":    "alice",
+	}
+	for field, value := range expected {
+		assert.Equal(t, value, outEvents[1].Root.Dig(field).AsString(), "wrong %s field", field)
+	}
+	for _, field := range []string{"role_arn", "role_session", "error_code", "error_message"} {
+		assert.Nil(t, outEvents[1].Root.Dig(field), "absent %s field shouldn't be added", field)
+	}
+
+	assert.Equal(t, `{"message":"not a cloudtrail record"}`, outEvents[2].Root.EncodeToString(), "wrong out event")
+}
+
+func TestParseCloudTrailField(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "detail", Prefix: "ct_"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"detail-type":"AWS API Call via CloudTrail","detail":`+assumedRoleRecord+`}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 1, len(outEvents), "wrong out events count")
+	e := outEvents[0]
+	assert.Equal(t, "PutObject", e.Root.Dig("ct_event_name").AsString(), "wrong event_name field")
+	assert.Equal(t, "deploy-bot", e.Root.Dig("ct_role_session").AsString(), "wrong role_session field")
+	assert.Nil(t, e.Root.Dig("event_name"), "canonical names should be prefixed")
+}
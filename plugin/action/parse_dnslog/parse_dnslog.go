@@ -0,0 +1,150 @@
+package parse_dnslog
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It parses DNS server log line from the event field and adds `query_name`, `query_type`, `response_code` and `client_ip` fields to the event root.
+The trailing dot of the query name is removed. Fields which the line has no information about aren't added.
+The original field is kept untouched. If the line isn't recognized, the event is passed unchanged.
+
+Supported formats are:
+* BIND query log: `client @0x7f1c 192.168.1.10#53123 (example.com): query: example.com IN A +E(0)K (10.0.0.1)`.
+* Unbound `log-queries` and `log-replies`: `info: 192.168.1.10 example.com. A IN NOERROR 0.000000 0 45`.
+* dnsmasq `log-queries`: `query[A] example.com from 192.168.1.10` and `reply example.com is NXDOMAIN`.
+Replies of dnsmasq don't have the client and the query type, the response code is `NOERROR` if the reply is an address.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_dnslog
+      field: message
+    ...
+```
+It adds `{"client_ip":"192.168.1.10","query_name":"example.com","query_type":"A"}`
+to `{"message":"dnsmasq[1234]: query[A] example.com from 192.168.1.10"}`.
+}*/
+type Plugin struct {
+	config      *Config
+	parseResult *pipeline.ParseResult
+	names       map[string]string
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to parse. Must be a string.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"message"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to parsed keys.
+	Prefix string `json:"prefix" default:""` //*
+}
+
+const ipRe = `(?P<client_ip>[0-9a-fA-F.:]+)`
+
+// dnsmasqCodes are values which dnsmasq replies with instead of an address
+var dnsmasqCodes = map[string]string{
+	"NXDOMAIN":    "NXDOMAIN",
+	"NODATA":      "NODATA",
+	"NODATA-IPv4": "NODATA",
+	"NODATA-IPv6": "NODATA",
+	"SERVFAIL":    "SERVFAIL",
+	"REFUSED":     "REFUSED",
+}
+
+var formats = []*regexp.Regexp{
+	// bind
+	regexp.MustCompile(`client (?:@0x[0-9a-fA-F]+ )?` + ipRe + `#\d+(?: \([^)]*\))?: (?:view \S+: )?query: (?P<query_name>\S+) (?:IN|CH|HS|ANY) (?P<query_type>\S+)`),
+	// unbound
+	regexp.MustCompile(`info: ` + ipRe + ` (?P<query_name>\S+) (?P<query_type>[A-Z0-9]+) IN(?: (?P<response_code>[A-Z]+))?(?:\s|$)`),
+	// dnsmasq query
+	regexp.MustCompile(`query\[(?P<query_type>[A-Z0-9]+)\] (?P<query_name>\S+) from ` + ipRe),
+	// dnsmasq reply
+	regexp.MustCompile(`(?:reply|cached|config) (?P<query_name>\S+) is (?P<answer>\S+)`),
+}
+
+var outFields = []string{"client_ip", "query_name", "query_type", "response_code"}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_dnslog",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_dnslog")
+
+	p.names = make(map[string]string, len(outFields))
+	for _, name := range outFields {
+		p.names[name] = p.config.Prefix + name
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil {
+		return pipeline.ActionPass
+	}
+
+	line := node.AsString()
+	for _, re := range formats {
+		sm := re.FindStringSubmatch(line)
+		if len(sm) == 0 {
+			continue
+		}
+
+		values := make(map[string]string, len(sm))
+		for i, name := range re.SubexpNames() {
+			if name != "" && sm[i] != "" {
+				values[name] = sm[i]
+			}
+		}
+
+		if answer, has := values["answer"]; has {
+			code, has := dnsmasqCodes[answer]
+			if !has {
+				code = "NOERROR"
+			}
+			values["response_code"] = code
+		}
+		if name, has := values["query_name"]; has && len(name) > 1 {
+			values["query_name"] = strings.TrimSuffix(name, ".")
+		}
+
+		for _, name := range outFields {
+			if value, has := values[name]; has {
+				event.Root.AddFieldNoAlloc(event.Root, p.names[name]).MutateToString(value)
+			}
+		}
+
+		p.parseResult.Success()
+
+		return pipeline.ActionPass
+	}
+
+	p.parseResult.Failure()
+	return pipeline.ActionPass
+}
@@ -0,0 +1,78 @@
+package parse_dnslog
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func runParse(t *testing.T, config *Config, lines []string) []map[string]string {
+	test.NewConfig(config, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(lines))
+
+	outEvents := make([]map[string]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		fields := make(map[string]string)
+		for _, field := range e.Root.AsFields() {
+			if field.AsString() != "message" {
+				fields[field.AsString()] = field.AsFieldValue().AsString()
+			}
+		}
+		outEvents = append(outEvents, fields)
+		wg.Done()
+	})
+
+	for _, line := range lines {
+		input.In(0, "test.log", 0, []byte(`{"message":"`+line+`"}`))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, len(lines), len(outEvents), "wrong out events count")
+	return outEvents
+}
+
+func TestParseBind(t *testing.T) {
+	outEvents := runParse(t, &Config{}, []string{
+		`15-Jun-2021 10:00:00.123 queries: info: client @0x7f1c2c0a1b20 192.168.1.10#53123 (example.com): query: example.com IN A +E(0)K (10.0.0.1)`,
+		`15-Jun-2021 10:00:01.456 client 2001:db8::1#40000 (Mail.Example.ORG): view internal: query: Mail.Example.ORG IN AAAA -ED (10.0.0.1)`,
+	})
+
+	assert.Equal(t, map[string]string{"client_ip": "192.168.1.10", "query_name": "example.com", "query_type": "A"}, outEvents[0], "wrong parsed fields")
+	assert.Equal(t, map[string]string{"client_ip": "2001:db8::1", "query_name": "Mail.Example.ORG", "query_type": "AAAA"}, outEvents[1], "wrong parsed fields")
+}
+
+func TestParseUnbound(t *testing.T) {
+	outEvents := runParse(t, &Config{}, []string{
+		`[1623751200] unbound[1234:0] info: 192.168.1.10 example.com. A IN`,
+		`[1623751200] unbound[1234:0] info: 192.168.1.10 missing.example.com. AAAA IN NXDOMAIN 0.012345 0 54`,
+		`[1623751200] unbound[1234:0] info: 10.0.0.5 . NS IN NOERROR 0.000000 1 239`,
+	})
+
+	assert.Equal(t, map[string]string{"client_ip": "192.168.1.10", "query_name": "example.com", "query_type": "A"}, outEvents[0], "wrong parsed fields")
+	assert.Equal(t, map[string]string{"client_ip": "192.168.1.10", "query_name": "missing.example.com", "query_type": "AAAA", "response_code": "NXDOMAIN"}, outEvents[1], "wrong parsed fields")
+	assert.Equal(t, map[string]string{"client_ip": "10.0.0.5", "query_name": ".", "query_type": "NS", "response_code": "NOERROR"}, outEvents[2], "wrong parsed fields")
+}
+
+func TestParseDnsmasq(t *testing.T) {
+	outEvents := runParse(t, &Config{Prefix: "dns_"}, []string{
+		`Jun 15 10:00:00 dnsmasq[1234]: query[A] example.com from 192.168.1.10`,
+		`Jun 15 10:00:00 dnsmasq[1234]: reply example.com is 93.184.216.34`,
+		`Jun 15 10:00:00 dnsmasq[1234]: config missing.lan is NXDOMAIN`,
+		`Jun 15 10:00:00 dnsmasq[1234]: cached example.com is NODATA-IPv6`,
+		`Jun 15 10:00:00 dnsmasq[1234]: forwarded example.com to 8.8.8.8`,
+	})
+
+	assert.Equal(t, map[string]string{"dns_client_ip": "192.168.1.10", "dns_query_name": "example.com", "dns_query_type": "A"}, outEvents[0], "wrong parsed fields")
+	assert.Equal(t, map[string]string{"dns_query_name": "example.com", "dns_response_code": "NOERROR"}, outEvents[1], "wrong parsed fields")
+	assert.Equal(t, map[string]string{"dns_query_name": "missing.lan", "dns_response_code": "NXDOMAIN"}, outEvents[2], "wrong parsed fields")
+	assert.Equal(t, map[string]string{"dns_query_name": "example.com", "dns_response_code": "NODATA"}, outEvents[3], "wrong parsed fields")
+	assert.Equal(t, map[string]string{}, outEvents[4], "line shouldn't be parsed")
+}
@@ -0,0 +1,184 @@
+package parse_gclog
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It parses JVM GC log line from the event field and adds the following fields to the event root:
+* `gc_type` – `young`, `mixed`, `full`, `remark` or `cleanup`.
+* `cause` – the cause of the collection, e.g. `G1 Evacuation Pause`, it's absent if the line has no cause.
+* `pause_ms` – the pause duration in milliseconds.
+* `heap_before`, `heap_after`, `heap_total` – the heap occupancy before and after the collection and the heap size in bytes.
+
+Unified logging pause lines of G1 (JDK 9+) and legacy `-XX:+PrintGC`/`-XX:+PrintGCDetails` lines of G1 and CMS (JDK 8) are supported.
+The original field is kept untouched. If the line isn't recognized, the event is passed unchanged.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_gclog
+      field: message
+    ...
+```
+It adds `{"gc_type":"young","cause":"G1 Evacuation Pause","pause_ms":3.456,"heap_before":25165824,"heap_after":4194304,"heap_total":268435456}`
+to `{"message":"[2021-05-06T10:00:00.123+0000][info][gc] GC(12) Pause Young (Normal) (G1 Evacuation Pause) 24M->4M(256M) 3.456ms"}`.
+}*/
+type Plugin struct {
+	config      *Config
+	parseResult *pipeline.ParseResult
+	names       map[string]string
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to parse. Must be a string.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"message"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to parsed keys.
+	Prefix string `json:"prefix" default:""` //*
+}
+
+const (
+	heapRe  = `(?P<heap_before>\d+(?:\.\d+)?[BKMG])->(?P<heap_after>\d+(?:\.\d+)?[BKMG])\((?P<heap_total>\d+(?:\.\d+)?[BKMG])\)`
+	causeRe = `(?P<cause>[^()]*(?:\(\))?)`
+)
+
+// unifiedRe matches G1 pause lines of JDK 9+ unified logging,
+// e.g. `GC(12) Pause Young (Normal) (G1 Evacuation Pause) 24M->4M(256M) 3.456ms`
+var unifiedRe = regexp.MustCompile(`GC\(\d+\) Pause (?P<kind>Young|Mixed|Full|Remark|Cleanup)` +
+	`(?: \((?P<phase>Normal|Concurrent Start|Prepare Mixed|Mixed|Concurrent End)\))?` +
+	`(?: \(` + causeRe + `\))? ` +
+	heapRe + ` (?P<pause>\d+(?:\.\d+)?)ms`)
+
+// legacyRe matches G1 and CMS lines of JDK 8,
+// e.g. `[GC pause (G1 Evacuation Pause) (young) 24M->4M(256M), 0.0034560 secs]`
+// or `[GC (Allocation Failure) [ParNew: 307K->34K(314K), 0.0123 secs] 1234K->987K(2048K), 0.0124 secs]`
+var legacyRe = regexp.MustCompile(`\[(?P<full>Full )?GC(?: pause)? \(` + causeRe + `\)` +
+	`(?: \((?P<kind>young|mixed)\))?` +
+	// greedy, so the whole heap is matched rather than generations
+	`.* ` +
+	heapRe + `, (?:\[Metaspace: [^\]]*\], )?(?P<pause>\d+\.\d+) secs\]`)
+
+var sizeUnits = map[byte]float64{
+	'B': 1,
+	'K': 1 << 10,
+	'M': 1 << 20,
+	'G': 1 << 30,
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_gclog",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_gclog")
+
+	p.names = make(map[string]string)
+	for _, name := range []string{"gc_type", "cause", "pause_ms", "heap_before", "heap_after", "heap_total"} {
+		p.names[name] = p.config.Prefix + name
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil {
+		return pipeline.ActionPass
+	}
+
+	line := node.AsString()
+
+	if sm := unifiedRe.FindStringSubmatch(line); len(sm) != 0 {
+		groups := submatchMap(unifiedRe, sm)
+
+		gcType := strings.ToLower(groups["kind"])
+		if groups["phase"] == "Mixed" {
+			gcType = "mixed"
+		}
+
+		pause, _ := strconv.ParseFloat(groups["pause"], 64)
+		p.put(event, gcType, groups, pause)
+		p.parseResult.Success()
+
+		return pipeline.ActionPass
+	}
+
+	if sm := legacyRe.FindStringSubmatch(line); len(sm) != 0 {
+		groups := submatchMap(legacyRe, sm)
+
+		gcType := "young"
+		if groups["full"] != "" {
+			gcType = "full"
+		} else if groups["kind"] != "" {
+			gcType = groups["kind"]
+		}
+
+		pause, _ := strconv.ParseFloat(groups["pause"], 64)
+		p.put(event, gcType, groups, pause*1000)
+		p.parseResult.Success()
+
+		return pipeline.ActionPass
+	}
+
+	p.parseResult.Failure()
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) put(event *pipeline.Event, gcType string, groups map[string]string, pauseMs float64) {
+	root := event.Root
+
+	root.AddFieldNoAlloc(root, p.names["gc_type"]).MutateToString(gcType)
+	if cause := groups["cause"]; cause != "" {
+		root.AddFieldNoAlloc(root, p.names["cause"]).MutateToString(cause)
+	}
+	root.AddFieldNoAlloc(root, p.names["pause_ms"]).MutateToFloat(pauseMs)
+
+	for _, name := range []string{"heap_before", "heap_after", "heap_total"} {
+		root.AddFieldNoAlloc(root, p.names[name]).MutateToInt(parseSize(groups[name]))
+	}
+}
+
+func submatchMap(re *regexp.Regexp, sm []string) map[string]string {
+	groups := make(map[string]string, len(sm))
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			groups[name] = sm[i]
+		}
+	}
+
+	return groups
+}
+
+// parseSize converts sizes like `24M` or `1.5G` into bytes
+func parseSize(size string) int {
+	unit := sizeUnits[size[len(size)-1]]
+	value, _ := strconv.ParseFloat(size[:len(size)-1], 64)
+
+	return int(value * unit)
+}
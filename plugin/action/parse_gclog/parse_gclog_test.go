@@ -0,0 +1,94 @@
+package parse_gclog
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGCLog(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(6)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"message":"[2021-05-06T10:00:00.123+0000][info][gc] GC(12) Pause Young (Normal) (G1 Evacuation Pause) 24M->4M(256M) 3.456ms"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"[12.345s][info][gc] GC(13) Pause Young (Mixed) (G1 Evacuation Pause) 120M->60M(256M) 10.5ms"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"[20.001s][info][gc] GC(14) Pause Full (System.gc()) 100M->50M(256M) 45.678ms"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"[21.100s][info][gc] GC(15) Pause Remark 30M->29M(256M) 1.234ms"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"2021-05-06T10:00:00.123+0000: 1.234: [GC pause (G1 Evacuation Pause) (young) 24.0M->4.0M(256.0M), 0.0034560 secs]"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"[info][gc] GC(16) Concurrent Mark Cycle"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 6, len(outEvents), "wrong out events count")
+
+	assert.Equal(t, "young", outEvents[0].Root.Dig("gc_type").AsString(), "wrong gc type")
+	assert.Equal(t, "G1 Evacuation Pause", outEvents[0].Root.Dig("cause").AsString(), "wrong cause")
+	assert.Equal(t, 3.456, outEvents[0].Root.Dig("pause_ms").AsFloat(), "wrong pause")
+	assert.Equal(t, 24<<20, outEvents[0].Root.Dig("heap_before").AsInt(), "wrong heap before")
+	assert.Equal(t, 4<<20, outEvents[0].Root.Dig("heap_after").AsInt(), "wrong heap after")
+	assert.Equal(t, 256<<20, outEvents[0].Root.Dig("heap_total").AsInt(), "wrong heap total")
+	assert.NotNil(t, outEvents[0].Root.Dig("message"), "original field is removed")
+
+	assert.Equal(t, "mixed", outEvents[1].Root.Dig("gc_type").AsString(), "wrong gc type")
+	assert.Equal(t, 10.5, outEvents[1].Root.Dig("pause_ms").AsFloat(), "wrong pause")
+
+	assert.Equal(t, "full", outEvents[2].Root.Dig("gc_type").AsString(), "wrong gc type")
+	assert.Equal(t, "System.gc()", outEvents[2].Root.Dig("cause").AsString(), "wrong cause")
+	assert.Equal(t, 50<<20, outEvents[2].Root.Dig("heap_after").AsInt(), "wrong heap after")
+
+	assert.Equal(t, "remark", outEvents[3].Root.Dig("gc_type").AsString(), "wrong gc type")
+	assert.Nil(t, outEvents[3].Root.Dig("cause"), "cause shouldn't be set")
+
+	assert.Equal(t, "young", outEvents[4].Root.Dig("gc_type").AsString(), "wrong gc type")
+	assert.Equal(t, "G1 Evacuation Pause", outEvents[4].Root.Dig("cause").AsString(), "wrong cause")
+	assert.InDelta(t, 3.456, outEvents[4].Root.Dig("pause_ms").AsFloat(), 1e-9, "wrong pause")
+	assert.Equal(t, 24<<20, outEvents[4].Root.Dig("heap_before").AsInt(), "wrong heap before")
+
+	assert.Equal(t, `{"message":"[info][gc] GC(16) Concurrent Mark Cycle"}`, outEvents[5].Root.EncodeToString(), "wrong out event")
+}
+
+func TestParseGCLogCMS(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "log", Prefix: "gc_"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"log":"1.234: [GC (Allocation Failure) 1.234: [ParNew: 307K->34K(314K), 0.0123 secs] 1234K->987K(2048K), 0.0124 secs] [Times: user=0.01 sys=0.00, real=0.01 secs]"}`))
+	input.In(0, "test.log", 0, []byte(`{"log":"5.678: [Full GC (Allocation Failure) 5.678: [CMS: 1000K->500K(1024K), 0.1000 secs] 1500K->600K(2048K), [Metaspace: 3000K->3000K(1056768K)], 0.1234 secs]"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 2, len(outEvents), "wrong out events count")
+
+	assert.Equal(t, "young", outEvents[0].Root.Dig("gc_gc_type").AsString(), "wrong gc type")
+	assert.Equal(t, "Allocation Failure", outEvents[0].Root.Dig("gc_cause").AsString(), "wrong cause")
+	assert.InDelta(t, 12.4, outEvents[0].Root.Dig("gc_pause_ms").AsFloat(), 1e-9, "wrong pause")
+	assert.Equal(t, 1234<<10, outEvents[0].Root.Dig("gc_heap_before").AsInt(), "wrong heap before")
+	assert.Equal(t, 987<<10, outEvents[0].Root.Dig("gc_heap_after").AsInt(), "wrong heap after")
+
+	assert.Equal(t, "full", outEvents[1].Root.Dig("gc_gc_type").AsString(), "wrong gc type")
+	assert.InDelta(t, 123.4, outEvents[1].Root.Dig("gc_pause_ms").AsFloat(), 1e-9, "wrong pause")
+	assert.Equal(t, 1500<<10, outEvents[1].Root.Dig("gc_heap_before").AsInt(), "wrong heap before")
+	assert.Equal(t, 2048<<10, outEvents[1].Root.Dig("gc_heap_total").AsInt(), "wrong heap total")
+}
@@ -0,0 +1,153 @@
+package parse_haproxy
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ introduction
+It parses HAProxy log line of the default HTTP format from the event field and merges the result with the event root.
+Syslog header before `haproxy[pid]:` is skipped if it's present.
+
+Extracted fields are: `client_ip`, `client_port`, `accept_date`, `frontend`, `backend`, `server`,
+timings `tq`, `tw`, `tc`, `tr`, `tt`, `status`, `bytes_read`, `captured_request_cookie`, `captured_response_cookie`,
+`termination_state`, `actconn`, `feconn`, `beconn`, `srv_conn`, `retries`, `srv_queue`, `backend_queue`,
+`captured_request_headers`, `captured_response_headers` and `request`.
+Ports, timings, status, bytes and counters are converted to numbers. If the line can't be parsed, the event will be skipped.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_haproxy
+      field: message
+    ...
+```
+It transforms `{"message":"haproxy[14389]: 10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 {1wt.eu} {} \"GET /index.html HTTP/1.1\""}`
+into `{"client_ip":"10.0.1.2","client_port":33317,"accept_date":"06/Feb/2009:12:14:14.655","frontend":"http-in","backend":"static","server":"srv1","tq":10,"tw":0,"tc":30,"tr":69,"tt":109,"status":200,...,"request":"GET /index.html HTTP/1.1"}`.
+}*/
+type Plugin struct {
+	config      *Config
+	parseResult *pipeline.ParseResult
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to parse. Must be a string.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"message"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to parsed keys.
+	Prefix string `json:"prefix" default:""` //*
+}
+
+// httpLogRe matches the default HTTP log format,
+// see https://cbonte.github.io/haproxy-dconv/2.4/configuration.html#8.2.3
+var httpLogRe = regexp.MustCompile(`^(?:.*?haproxy\[\d+\]: )?` +
+	`(?P<client_ip>\S+):(?P<client_port>\d+) ` +
+	`\[(?P<accept_date>[^\]]+)\] ` +
+	`(?P<frontend>\S+) (?P<backend>[^/\s]+)/(?P<server>\S+) ` +
+	`(?P<tq>-?\d+)/(?P<tw>-?\d+)/(?P<tc>-?\d+)/(?P<tr>-?\d+)/\+?(?P<tt>-?\d+) ` +
+	`(?P<status>-?\d+) \+?(?P<bytes_read>\d+) ` +
+	`(?P<captured_request_cookie>\S+) (?P<captured_response_cookie>\S+) ` +
+	`(?P<termination_state>\S{4}) ` +
+	`(?P<actconn>\d+)/(?P<feconn>\d+)/(?P<beconn>\d+)/(?P<srv_conn>\d+)/\+?(?P<retries>\d+) ` +
+	`(?P<srv_queue>\d+)/(?P<backend_queue>\d+) ` +
+	`(?:\{(?P<captured_request_headers>[^}]*)\} )?` +
+	`(?:\{(?P<captured_response_headers>[^}]*)\} )?` +
+	`"(?P<request>[^"]*)"?`)
+
+var numericFields = map[string]bool{
+	"client_port":   true,
+	"tq":            true,
+	"tw":            true,
+	"tc":            true,
+	"tr":            true,
+	"tt":            true,
+	"status":        true,
+	"bytes_read":    true,
+	"actconn":       true,
+	"feconn":        true,
+	"beconn":        true,
+	"srv_conn":      true,
+	"retries":       true,
+	"srv_queue":     true,
+	"backend_queue": true,
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_haproxy",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_haproxy")
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	jsonNode := event.Root.Dig(p.config.Field_...)
+	if jsonNode == nil {
+		return pipeline.ActionPass
+	}
+
+	sm := httpLogRe.FindSubmatch(jsonNode.AsBytes())
+	if len(sm) == 0 {
+		p.parseResult.Failure()
+		return pipeline.ActionPass
+	}
+
+	jsonNode.Suicide()
+
+	root := insaneJSON.Spawn()
+
+	fields := httpLogRe.SubexpNames()
+	var bl int
+	for i := 1; i < len(fields); i++ {
+		// optional groups which aren't matched
+		if sm[i] == nil {
+			continue
+		}
+
+		bl = len(event.Buf)
+		event.Buf = append(event.Buf, p.config.Prefix...)
+		event.Buf = append(event.Buf, fields[i]...)
+
+		node := root.AddFieldNoAlloc(root, pipeline.ByteToStringUnsafe(event.Buf[bl:]))
+		if numericFields[fields[i]] {
+			if x, err := strconv.Atoi(pipeline.ByteToStringUnsafe(sm[i])); err == nil {
+				node.MutateToInt(x)
+				continue
+			}
+		}
+		node.MutateToBytes(sm[i])
+	}
+
+	event.Root.MergeWith(root.Node)
+
+	insaneJSON.Release(root)
+	p.parseResult.Success()
+
+	return pipeline.ActionPass
+}
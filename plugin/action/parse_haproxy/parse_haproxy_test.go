@@ -0,0 +1,74 @@
+package parse_haproxy
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHAProxy(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	lines := []string{
+		`haproxy[14389]: 10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 {1wt.eu} {} "GET /index.html HTTP/1.1"`,
+		`Feb  6 12:12:56 localhost haproxy[14387]: 10.0.1.2:33313 [06/Feb/2009:12:12:51.443] fnt bck/<NOSRV> 0/-1/-1/-1/+5002 503 +212 - - SC-- 0/0/0/0/0 0/0 "GET /favicon.ico HTTP/1.1"`,
+		`haproxy[18113]: 127.0.0.1:34549 [15/Oct/2003:15:19:06.103] px-http~ px-http/srv1 -1/-1/-1/-1/8490 -1 0 - - CR-- 2/2/2/0/0 0/0 ""`,
+		`haproxy[18113]: not a HAProxy HTTP line`,
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(lines))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, line := range lines {
+		input.In(0, "test.log", 0, []byte(`{"message":`+strconv.Quote(line)+`}`))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, len(lines), len(outEvents), "wrong out events count")
+	assert.JSONEq(t, `{"client_ip":"10.0.1.2","client_port":33317,"accept_date":"06/Feb/2009:12:14:14.655","frontend":"http-in","backend":"static","server":"srv1","tq":10,"tw":0,"tc":30,"tr":69,"tt":109,"status":200,"bytes_read":2750,"captured_request_cookie":"-","captured_response_cookie":"-","termination_state":"----","actconn":1,"feconn":1,"beconn":1,"srv_conn":1,"retries":0,"srv_queue":0,"backend_queue":0,"captured_request_headers":"1wt.eu","captured_response_headers":"","request":"GET /index.html HTTP/1.1"}`, outEvents[0], "wrong out event")
+	assert.JSONEq(t, `{"client_ip":"10.0.1.2","client_port":33313,"accept_date":"06/Feb/2009:12:12:51.443","frontend":"fnt","backend":"bck","server":"<NOSRV>","tq":0,"tw":-1,"tc":-1,"tr":-1,"tt":5002,"status":503,"bytes_read":212,"captured_request_cookie":"-","captured_response_cookie":"-","termination_state":"SC--","actconn":0,"feconn":0,"beconn":0,"srv_conn":0,"retries":0,"srv_queue":0,"backend_queue":0,"request":"GET /favicon.ico HTTP/1.1"}`, outEvents[1], "wrong out event")
+	assert.JSONEq(t, `{"client_ip":"127.0.0.1","client_port":34549,"accept_date":"15/Oct/2003:15:19:06.103","frontend":"px-http~","backend":"px-http","server":"srv1","tq":-1,"tw":-1,"tc":-1,"tr":-1,"tt":8490,"status":-1,"bytes_read":0,"captured_request_cookie":"-","captured_response_cookie":"-","termination_state":"CR--","actconn":2,"feconn":2,"beconn":2,"srv_conn":0,"retries":0,"srv_queue":0,"backend_queue":0,"request":""}`, outEvents[2], "wrong out event")
+	assert.JSONEq(t, `{"message":`+strconv.Quote(lines[3])+`}`, outEvents[3], "wrong line shouldn't be changed")
+}
+
+func TestParseHAProxyPrefix(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "log", Prefix: "haproxy_"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"host":"lb1","log":"10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1 10/0/30/69/109 404 2750 - - ---- 1/1/1/1/0 0/0 \"GET /missing HTTP/1.1\""}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 1, len(outEvents), "wrong out events count")
+	e := outEvents[0]
+	assert.Equal(t, "lb1", e.Root.Dig("host").AsString(), "wrong field value")
+	assert.Nil(t, e.Root.Dig("log"), "parsed field isn't removed")
+	assert.Equal(t, 404, e.Root.Dig("haproxy_status").AsInt(), "wrong field value")
+	assert.Equal(t, "static", e.Root.Dig("haproxy_backend").AsString(), "wrong field value")
+	assert.Equal(t, 109, e.Root.Dig("haproxy_tt").AsInt(), "wrong field value")
+	assert.Equal(t, "GET /missing HTTP/1.1", e.Root.Dig("haproxy_request").AsString(), "wrong field value")
+	assert.Nil(t, e.Root.Dig("haproxy_captured_request_headers"), "absent field shouldn't be set")
+}
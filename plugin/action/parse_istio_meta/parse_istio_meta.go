@@ -0,0 +1,192 @@
+package parse_istio_meta
+
+import (
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ introduction
+It extracts workload identity from Istio telemetry and access logs into canonical fields of the event root:
+`source_workload`, `source_namespace`, `destination_workload`, `destination_namespace`, `destination_service` and `response_flags`.
+
+Both snake case fields of Istio access log JSON and camel case fields of Mixer `accesslog` log entries, e.g. `sourceWorkload`, are supported.
+If the destination isn't provided explicitly, `destination_service` and `destination_namespace` are taken from
+outbound `upstream_cluster` of Envoy, e.g. `outbound|9080|v1|reviews.default.svc.cluster.local`.
+Placeholders of absent values, `unknown` and `-`, are skipped.
+
+The log entry is taken from `field` or from the event root if it isn't set.
+If the entry has none of the fields, the event is passed unchanged.
+Parsed fields are removed from the entry unless `keep_original` is set, `upstream_cluster` is always kept.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_istio_meta
+      field: istio
+    ...
+```
+It transforms `{"istio":{"sourceWorkload":"productpage-v1","sourceNamespace":"default","destinationWorkload":"reviews-v2","responseFlags":"-"}}`
+into `{"istio":{},"source_workload":"productpage-v1","source_namespace":"default","destination_workload":"reviews-v2"}`.
+}*/
+type Plugin struct {
+	config  *Config
+	sources []*insaneJSON.Node
+	values  []string
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field with the Istio log entry, the event root is used if it isn't set.
+	Field  cfg.FieldSelector `json:"field" parse:"selector"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> If set, the parsed fields of the entry aren't removed.
+	KeepOriginal bool `json:"keep_original" default:"false"` //*
+}
+
+type mapping struct {
+	name    string
+	sources []string
+}
+
+const (
+	destinationNamespace = 3
+	destinationService   = 4
+
+	upstreamClusterField = "upstream_cluster"
+)
+
+// mappings are indexed by constants above, the first present source is used
+var mappings = []mapping{
+	{name: "source_workload", sources: []string{"source_workload", "sourceWorkload"}},
+	{name: "source_namespace", sources: []string{"source_namespace", "sourceNamespace"}},
+	{name: "destination_workload", sources: []string{"destination_workload", "destinationWorkload"}},
+	{name: "destination_namespace", sources: []string{"destination_namespace", "destinationNamespace"}},
+	{name: "destination_service", sources: []string{"destination_service", "destinationService", "destination_service_host"}},
+	{name: "response_flags", sources: []string{"response_flags", "responseFlags"}},
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_istio_meta",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.sources = make([]*insaneJSON.Node, len(mappings))
+	p.values = make([]string, len(mappings))
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	entry := event.Root.Dig(p.config.Field_...)
+	if entry == nil || !entry.IsObject() {
+		return pipeline.ActionPass
+	}
+
+	found := false
+	for i, m := range mappings {
+		p.sources[i] = nil
+		for _, source := range m.sources {
+			if node := entry.Dig(source); node != nil {
+				p.sources[i] = node
+				found = true
+				break
+			}
+		}
+	}
+
+	service, namespace := parseUpstreamCluster(entry.Dig(upstreamClusterField).AsString())
+	if !found && service == "" {
+		return pipeline.ActionPass
+	}
+
+	// values are taken before the cleanup, since a canonical field may be the source itself
+	for i, node := range p.sources {
+		p.values[i] = normalize(node.AsString())
+	}
+	if p.values[destinationService] == "" {
+		p.values[destinationService] = service
+	}
+	if p.values[destinationNamespace] == "" {
+		p.values[destinationNamespace] = namespace
+	}
+
+	if !p.config.KeepOriginal {
+		for _, node := range p.sources {
+			node.Suicide()
+		}
+	}
+
+	for i, m := range mappings {
+		if p.values[i] == "" {
+			continue
+		}
+		event.Root.AddFieldNoAlloc(event.Root, m.name).MutateToString(p.values[i])
+	}
+
+	return pipeline.ActionPass
+}
+
+// normalize skips placeholders which Istio uses for absent values
+func normalize(value string) string {
+	if value == "-" || value == "unknown" {
+		return ""
+	}
+
+	return value
+}
+
+// parseUpstreamCluster returns the service host and the namespace of the outbound Envoy cluster,
+// it has the format of `outbound|port|subset|host`, e.g. `outbound|9080|v1|reviews.default.svc.cluster.local`
+func parseUpstreamCluster(cluster string) (string, string) {
+	const prefix = "outbound|"
+	if !strings.HasPrefix(cluster, prefix) {
+		return "", ""
+	}
+
+	// skip the port and the subset
+	host := cluster[len(prefix):]
+	for i := 0; i < 2; i++ {
+		pos := strings.IndexByte(host, '|')
+		if pos == -1 {
+			return "", ""
+		}
+		host = host[pos+1:]
+	}
+	if host == "" || strings.IndexByte(host, '|') != -1 {
+		return "", ""
+	}
+
+	// short hosts don't contain the namespace
+	pos := strings.IndexByte(host, '.')
+	if pos == -1 {
+		return host, ""
+	}
+	namespace := host[pos+1:]
+	if pos = strings.IndexByte(namespace, '.'); pos != -1 {
+		namespace = namespace[:pos]
+	}
+
+	return host, namespace
+}
@@ -0,0 +1,89 @@
+package parse_istio_meta
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func runEvents(config *Config, events []string) []string {
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, test.NewConfig(config, nil), pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(events))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, event := range events {
+		input.In(0, "test.log", 0, []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+func TestParseIstioMeta(t *testing.T) {
+	outEvents := runEvents(&Config{}, []string{
+		// Mixer accesslog entry
+		`{"level":"info","instance":"accesslog.logentry.istio-system","sourceWorkload":"productpage-v1","sourceNamespace":"default","destinationWorkload":"reviews-v2","destinationNamespace":"default","responseCode":200,"responseFlags":"-"}`,
+		// access log JSON with explicit telemetry fields
+		`{"method":"GET","response_code":503,"response_flags":"UH,UF","source_workload":"istio-ingressgateway","source_namespace":"istio-system","destination_workload":"unknown"}`,
+		// Envoy access log JSON, the destination is taken from the cluster
+		`{"method":"GET","upstream_cluster":"outbound|9080|v1|reviews.default.svc.cluster.local","response_flags":"-"}`,
+		`{"upstream_cluster":"inbound|9080|http|reviews.default.svc.cluster.local","response_flags":"NR"}`,
+		`{"upstream_cluster":"outbound|80||httpbin"}`,
+		`{"message":"not an istio log"}`,
+	})
+
+	assert.Equal(t, []string{
+		`{"level":"info","instance":"accesslog.logentry.istio-system","responseCode":200,"source_workload":"productpage-v1","source_namespace":"default","destination_workload":"reviews-v2","destination_namespace":"default"}`,
+		`{"method":"GET","response_code":503,"source_workload":"istio-ingressgateway","source_namespace":"istio-system","response_flags":"UH,UF"}`,
+		`{"method":"GET","upstream_cluster":"outbound|9080|v1|reviews.default.svc.cluster.local","destination_namespace":"default","destination_service":"reviews.default.svc.cluster.local"}`,
+		`{"upstream_cluster":"inbound|9080|http|reviews.default.svc.cluster.local","response_flags":"NR"}`,
+		`{"upstream_cluster":"outbound|80||httpbin","destination_service":"httpbin"}`,
+		`{"message":"not an istio log"}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestParseIstioMetaField(t *testing.T) {
+	outEvents := runEvents(&Config{Field: "istio", KeepOriginal: true}, []string{
+		`{"istio":{"sourceWorkload":"productpage-v1","destinationWorkload":"reviews-v2","responseFlags":"DC"}}`,
+		`{"istio":"not an object"}`,
+	})
+
+	assert.Equal(t, []string{
+		`{"istio":{"sourceWorkload":"productpage-v1","destinationWorkload":"reviews-v2","responseFlags":"DC"},"source_workload":"productpage-v1","destination_workload":"reviews-v2","response_flags":"DC"}`,
+		`{"istio":"not an object"}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestParseUpstreamCluster(t *testing.T) {
+	cases := []struct {
+		cluster   string
+		service   string
+		namespace string
+	}{
+		{cluster: "outbound|9080|v1|reviews.default.svc.cluster.local", service: "reviews.default.svc.cluster.local", namespace: "default"},
+		{cluster: "outbound|9080||ratings.prod", service: "ratings.prod", namespace: "prod"},
+		{cluster: "outbound|80||httpbin", service: "httpbin", namespace: ""},
+		{cluster: "inbound|9080||", service: "", namespace: ""},
+		{cluster: "outbound|9080|v1|", service: "", namespace: ""},
+		{cluster: "outbound|9080", service: "", namespace: ""},
+		{cluster: "PassthroughCluster", service: "", namespace: ""},
+	}
+
+	for _, c := range cases {
+		service, namespace := parseUpstreamCluster(c.cluster)
+		assert.Equal(t, c.service, service, "wrong service for %s", c.cluster)
+		assert.Equal(t, c.namespace, namespace, "wrong namespace for %s", c.cluster)
+	}
+}
@@ -0,0 +1,124 @@
+package parse_ja3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"regexp"
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It validates and normalizes JA3/JA3S TLS fingerprint in the event field.
+The field may contain either the MD5 hash of the fingerprint or the raw fingerprint string,
+e.g. `771,4865-4866-4867,0-23-65281,29-23-24,0` for JA3 or `771,4865,65281-0` for JA3S.
+The raw string is replaced with its hash, the hash is lowercased.
+If the value is neither a hash nor a fingerprint string, the event is passed unchanged.
+
+If the normalized hash is found in `fingerprints`, the corresponding label is put into `label_field`.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_ja3
+      field: tls.ja3
+      fingerprints:
+        e7d705a3286e19ea42f587b344ee6865: internal_scanner
+    ...
+```
+It transforms `{"tls":{"ja3":"E7D705A3286E19EA42F587B344EE6865"}}` into `{"tls":{"ja3":"e7d705a3286e19ea42f587b344ee6865"},"ja3_label":"internal_scanner"}`.
+}*/
+type Plugin struct {
+	config       *Config
+	fingerprints map[string]string
+	buf          []byte
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field which contains JA3/JA3S hash or fingerprint string.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"ja3"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The map of `hash => label` of known fingerprints. Hashes are case-insensitive.
+	Fingerprints map[string]string `json:"fingerprints"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field to put the label of the known fingerprint to.
+	LabelField  cfg.FieldSelector `json:"label_field" parse:"selector" default:"ja3_label"` //*
+	LabelField_ []string
+}
+
+var (
+	hashRe = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+	// JA3 is `SSLVersion,Cipher,SSLExtension,EllipticCurve,EllipticCurvePointFormat`,
+	// JA3S is `SSLVersion,Cipher,SSLExtension`
+	ja3Re  = regexp.MustCompile(`^\d+,[\d-]*,[\d-]*,[\d-]*,[\d-]*$`)
+	ja3sRe = regexp.MustCompile(`^\d+,\d*,[\d-]*$`)
+)
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_ja3",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+
+	p.fingerprints = make(map[string]string, len(p.config.Fingerprints))
+	for hash, label := range p.config.Fingerprints {
+		p.fingerprints[strings.ToLower(hash)] = label
+	}
+
+	p.buf = make([]byte, 0, hex.EncodedLen(md5.Size))
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil {
+		return pipeline.ActionPass
+	}
+
+	value := node.AsString()
+	var hash string
+	switch {
+	case hashRe.MatchString(value):
+		hash = strings.ToLower(value)
+	case ja3Re.MatchString(value) || ja3sRe.MatchString(value):
+		sum := md5.Sum([]byte(value))
+		p.buf = p.buf[:hex.EncodedLen(md5.Size)]
+		hex.Encode(p.buf, sum[:])
+		hash = string(p.buf)
+	default:
+		return pipeline.ActionPass
+	}
+
+	node.MutateToString(hash)
+
+	if label, has := p.fingerprints[hash]; has {
+		pipeline.CreateNestedField(event.Root, p.config.LabelField_).MutateToString(label)
+	}
+
+	return pipeline.ActionPass
+}
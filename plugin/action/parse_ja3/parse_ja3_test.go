@@ -0,0 +1,74 @@
+package parse_ja3
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJA3(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(5)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"ja3":"E7D705A3286E19EA42F587B344EE6865"}`))
+	input.In(0, "test.log", 0, []byte(`{"ja3":"771,4865-4866-4867,0-23-65281,29-23-24,0"}`))
+	input.In(0, "test.log", 0, []byte(`{"ja3":"771,4865,65281-0"}`))
+	input.In(0, "test.log", 0, []byte(`{"ja3":"not a fingerprint"}`))
+	input.In(0, "test.log", 0, []byte(`{"ja3":"e7d705a3286e19ea42f587b344ee686"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"ja3":"e7d705a3286e19ea42f587b344ee6865"}`,
+		`{"ja3":"650293d7a2ffb5335422221c5d75a9c9"}`,
+		`{"ja3":"7592d12b8dd8cbaa420d26b25577d4c2"}`,
+		`{"ja3":"not a fingerprint"}`,
+		`{"ja3":"e7d705a3286e19ea42f587b344ee686"}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestParseJA3Fingerprints(t *testing.T) {
+	config := test.NewConfig(&Config{
+		Field:      "tls.ja3",
+		LabelField: "tls.client",
+		Fingerprints: map[string]string{
+			"E7D705A3286E19EA42F587B344EE6865": "internal_scanner",
+			"650293d7a2ffb5335422221c5d75a9c9": "browser",
+		},
+	}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"tls":{"ja3":"e7d705a3286e19ea42f587b344ee6865"}}`))
+	input.In(0, "test.log", 0, []byte(`{"tls":{"ja3":"771,4865-4866-4867,0-23-65281,29-23-24,0"}}`))
+	input.In(0, "test.log", 0, []byte(`{"tls":{"ja3":"7592d12b8dd8cbaa420d26b25577d4c2"}}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 3, len(outEvents), "wrong out events count")
+	assert.Equal(t, "internal_scanner", outEvents[0].Root.Dig("tls", "client").AsString(), "wrong label")
+	assert.Equal(t, "browser", outEvents[1].Root.Dig("tls", "client").AsString(), "wrong label")
+	assert.Nil(t, outEvents[2].Root.Dig("tls", "client"), "label shouldn't be set")
+}
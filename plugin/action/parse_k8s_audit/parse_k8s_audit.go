@@ -0,0 +1,151 @@
+package parse_k8s_audit
+
+import (
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ introduction
+It extracts the most useful fields of Kubernetes audit events into flat fields of the event root:
+
+| Audit event field | Event field |
+|-|-|
+| `auditID` | `{prefix}id` |
+| `stage` | `{prefix}stage` |
+| `verb` | `{prefix}verb` |
+| `requestURI` | `{prefix}uri` |
+| `user.username` | `{prefix}user` |
+| `sourceIPs[0]` | `{prefix}source_ip` |
+| `objectRef.resource` | `{prefix}resource` |
+| `objectRef.namespace` | `{prefix}namespace` |
+| `objectRef.name` | `{prefix}name` |
+| `responseStatus.code` | `{prefix}status_code` |
+
+Audit event is detected by `kind: Event` and `apiVersion: audit.k8s.io/*`, other events are passed as is.
+Absent fields are skipped.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_k8s_audit
+    ...
+```
+It transforms `{"kind":"Event","apiVersion":"audit.k8s.io/v1","stage":"ResponseComplete","verb":"delete","user":{"username":"admin"},...}`
+into `{"kind":"Event","apiVersion":"audit.k8s.io/v1","stage":"ResponseComplete","verb":"delete","user":{"username":"admin"},...,"audit_stage":"ResponseComplete","audit_verb":"delete","audit_user":"admin",...}`.
+}*/
+type Plugin struct {
+	config      *Config
+	parseResult *pipeline.ParseResult
+	fields      []auditField
+}
+
+type auditField struct {
+	path []string
+	name string
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field which contains the audit event. If it's empty, the event root is used.
+	//> If the field is a string, it's decoded as JSON.
+	Field  cfg.FieldSelector `json:"field" parse:"selector"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to extracted fields.
+	Prefix string `json:"prefix" default:"audit_"` //*
+}
+
+var auditFields = []auditField{
+	{[]string{"auditID"}, "id"},
+	{[]string{"stage"}, "stage"},
+	{[]string{"verb"}, "verb"},
+	{[]string{"requestURI"}, "uri"},
+	{[]string{"user", "username"}, "user"},
+	{[]string{"sourceIPs", "0"}, "source_ip"},
+	{[]string{"objectRef", "resource"}, "resource"},
+	{[]string{"objectRef", "namespace"}, "namespace"},
+	{[]string{"objectRef", "name"}, "name"},
+	{[]string{"responseStatus", "code"}, "status_code"},
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_k8s_audit",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_k8s_audit")
+
+	p.fields = make([]auditField, 0, len(auditFields))
+	for _, field := range auditFields {
+		p.fields = append(p.fields, auditField{path: field.path, name: p.config.Prefix + field.name})
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	audit := event.Root.Node
+	if len(p.config.Field_) != 0 {
+		audit = event.Root.Dig(p.config.Field_...)
+		if audit == nil {
+			return pipeline.ActionPass
+		}
+		if audit.IsString() {
+			node, err := event.SubparseJSON(audit.AsBytes())
+			if err != nil {
+				p.parseResult.Failure()
+				return pipeline.ActionPass
+			}
+			audit = node
+		}
+	}
+
+	// other events of the root aren't counted, since the root is checked for all events
+	if !isAuditEvent(audit) {
+		if len(p.config.Field_) != 0 {
+			p.parseResult.Failure()
+		}
+		return pipeline.ActionPass
+	}
+
+	for _, field := range p.fields {
+		node := audit.Dig(field.path...)
+		if node == nil || node.IsObject() || node.IsArray() {
+			continue
+		}
+
+		event.Root.AddFieldNoAlloc(event.Root, field.name).MutateToNode(node)
+	}
+	p.parseResult.Success()
+
+	return pipeline.ActionPass
+}
+
+func isAuditEvent(node *insaneJSON.Node) bool {
+	if node.Dig("kind").AsString() != "Event" {
+		return false
+	}
+
+	return strings.HasPrefix(node.Dig("apiVersion").AsString(), "audit.k8s.io/")
+}
@@ -0,0 +1,94 @@
+package parse_k8s_audit
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const auditEvent = `{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"5d8f4e3b-1c2a-4b7e-9f6d-0a1b2c3d4e5f","stage":"ResponseComplete","requestURI":"/api/v1/namespaces/default/pods/nginx","verb":"delete","user":{"username":"system:admin","groups":["system:masters","system:authenticated"]},"sourceIPs":["10.0.0.1","10.0.0.2"],"userAgent":"kubectl/v1.20.0","objectRef":{"resource":"pods","namespace":"default","name":"nginx","apiVersion":"v1"},"responseStatus":{"metadata":{},"code":200},"requestReceivedTimestamp":"2021-03-01T10:00:00.000000Z","stageTimestamp":"2021-03-01T10:00:00.100000Z"}`
+
+func TestParseK8sAudit(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(auditEvent))
+	input.In(0, "test.log", 0, []byte(`{"kind":"Event","apiVersion":"audit.k8s.io/v1","stage":"RequestReceived","verb":"list","user":{"username":"system:anonymous"},"objectRef":{"resource":"namespaces"}}`))
+	input.In(0, "test.log", 0, []byte(`{"kind":"Event","apiVersion":"v1","verb":"get"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 3, len(outEvents), "wrong out events count")
+
+	e := outEvents[0]
+	assert.Equal(t, "5d8f4e3b-1c2a-4b7e-9f6d-0a1b2c3d4e5f", e.Root.Dig("audit_id").AsString(), "wrong field value")
+	assert.Equal(t, "ResponseComplete", e.Root.Dig("audit_stage").AsString(), "wrong field value")
+	assert.Equal(t, "delete", e.Root.Dig("audit_verb").AsString(), "wrong field value")
+	assert.Equal(t, "/api/v1/namespaces/default/pods/nginx", e.Root.Dig("audit_uri").AsString(), "wrong field value")
+	assert.Equal(t, "system:admin", e.Root.Dig("audit_user").AsString(), "wrong field value")
+	assert.Equal(t, "10.0.0.1", e.Root.Dig("audit_source_ip").AsString(), "wrong field value")
+	assert.Equal(t, "pods", e.Root.Dig("audit_resource").AsString(), "wrong field value")
+	assert.Equal(t, "default", e.Root.Dig("audit_namespace").AsString(), "wrong field value")
+	assert.Equal(t, "nginx", e.Root.Dig("audit_name").AsString(), "wrong field value")
+	assert.Equal(t, 200, e.Root.Dig("audit_status_code").AsInt(), "wrong field value")
+	assert.Equal(t, "system:admin", e.Root.Dig("user", "username").AsString(), "original field is changed")
+
+	e = outEvents[1]
+	assert.Equal(t, "list", e.Root.Dig("audit_verb").AsString(), "wrong field value")
+	assert.Equal(t, "system:anonymous", e.Root.Dig("audit_user").AsString(), "wrong field value")
+	assert.Equal(t, "namespaces", e.Root.Dig("audit_resource").AsString(), "wrong field value")
+	assert.Equal(t, "RequestReceived", e.Root.Dig("audit_stage").AsString(), "wrong field value")
+	assert.Nil(t, e.Root.Dig("audit_status_code"), "field shouldn't be set")
+	assert.Nil(t, e.Root.Dig("audit_namespace"), "field shouldn't be set")
+
+	assert.Equal(t, `{"kind":"Event","apiVersion":"v1","verb":"get"}`, outEvents[2].Root.EncodeToString(), "wrong out event")
+}
+
+func TestParseK8sAuditField(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "log", Prefix: "k8s_"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"log":`+strconv.Quote(auditEvent)+`}`))
+	input.In(0, "test.log", 0, []byte(`{"log":{"kind":"Event","apiVersion":"audit.k8s.io/v1beta1","verb":"create","responseStatus":{"code":403}}}`))
+	input.In(0, "test.log", 0, []byte(`{"log":"not an audit event"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"no log"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 4, len(outEvents), "wrong out events count")
+
+	results := p.GetMetricsCtl().RegisterCounter("parse_result_total", "")
+	assert.Equal(t, float64(2), testutil.ToFloat64(results.WithLabelValues("parse_k8s_audit", "success")), "wrong success count")
+	assert.Equal(t, float64(1), testutil.ToFloat64(results.WithLabelValues("parse_k8s_audit", "failure")), "wrong failure count")
+
+	assert.Equal(t, "delete", outEvents[0].Root.Dig("k8s_verb").AsString(), "wrong field value")
+	assert.Equal(t, "pods", outEvents[0].Root.Dig("k8s_resource").AsString(), "wrong field value")
+	assert.Equal(t, 200, outEvents[0].Root.Dig("k8s_status_code").AsInt(), "wrong field value")
+	assert.Equal(t, "create", outEvents[1].Root.Dig("k8s_verb").AsString(), "wrong field value")
+	assert.Equal(t, 403, outEvents[1].Root.Dig("k8s_status_code").AsInt(), "wrong field value")
+}
@@ -0,0 +1,139 @@
+package parse_k8s_filename
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+const containerIDLen = 64
+
+/*{ introduction
+It parses the source filename of the event into Kubernetes metadata fields.
+It's useful when `/var/log/containers` is read by the file input plugin instead of the k8s input plugin.
+The filename should have the following format: `[pod-name]_[namespace]_[container-name]-[container-id].log`.
+E.g. `my-pod-1566485760-trtrq_my-namespace_my-container-4e0301b633eaa2bfdcafdeba59ba0c72a3815911a6a820bf273534b0f32d98e0.log`.
+
+The following fields are added: `{prefix}pod`, `{prefix}namespace`, `{prefix}container`, `{prefix}container_id`.
+Events with other filenames are passed as is.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    input:
+      type: file
+      watching_dir: /var/log/containers
+      filename_pattern: "*.log"
+      ...
+    actions:
+    - type: parse_k8s_filename
+    ...
+```
+}*/
+type Plugin struct {
+	config *Config
+
+	podField         string
+	namespaceField   string
+	containerField   string
+	containerIDField string
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to the fields.
+	Prefix string `json:"prefix" default:"k8s_"` //*
+}
+
+type k8sMeta struct {
+	pod         string
+	namespace   string
+	container   string
+	containerID string
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_k8s_filename",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+
+	p.podField = p.config.Prefix + "pod"
+	p.namespaceField = p.config.Prefix + "namespace"
+	p.containerField = p.config.Prefix + "container"
+	p.containerIDField = p.config.Prefix + "container_id"
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	meta, ok := parseFilename(event.SourceName)
+	if !ok {
+		return pipeline.ActionPass
+	}
+
+	event.Root.AddFieldNoAlloc(event.Root, p.podField).MutateToString(meta.pod)
+	event.Root.AddFieldNoAlloc(event.Root, p.namespaceField).MutateToString(meta.namespace)
+	event.Root.AddFieldNoAlloc(event.Root, p.containerField).MutateToString(meta.container)
+	event.Root.AddFieldNoAlloc(event.Root, p.containerIDField).MutateToString(meta.containerID)
+
+	return pipeline.ActionPass
+}
+
+func parseFilename(fullFilename string) (k8sMeta, bool) {
+	filename := filepath.Base(fullFilename)
+	if !strings.HasSuffix(filename, ".log") {
+		return k8sMeta{}, false
+	}
+	filename = filename[:len(filename)-len(".log")]
+
+	// pod names and namespaces can't contain underscores, so it's safe to split by them
+	parts := strings.Split(filename, "_")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return k8sMeta{}, false
+	}
+
+	rest := parts[2]
+	// container name, dash and container id
+	if len(rest) < containerIDLen+2 || rest[len(rest)-containerIDLen-1] != '-' {
+		return k8sMeta{}, false
+	}
+
+	containerID := rest[len(rest)-containerIDLen:]
+	if !isHex(containerID) {
+		return k8sMeta{}, false
+	}
+
+	return k8sMeta{
+		pod:         parts[0],
+		namespace:   parts[1],
+		container:   rest[:len(rest)-containerIDLen-1],
+		containerID: containerID,
+	}, true
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,73 @@
+package parse_k8s_filename
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+const containerID = "4e0301b633eaa2bfdcafdeba59ba0c72a3815911a6a820bf273534b0f32d98e0"
+
+func TestParseK8sFilename(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "/var/log/containers/my-pod-1566485760-trtrq_my-namespace_my-container-"+containerID+".log", 0, []byte(`{"log":"hello"}`))
+	input.In(1, "/var/log/containers/web_default_nginx-"+containerID+".log", 0, []byte(`{"log":"bye"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 2, len(outEvents), "wrong out events count")
+	assert.Contains(t, outEvents, `{"log":"hello","k8s_pod":"my-pod-1566485760-trtrq","k8s_namespace":"my-namespace","k8s_container":"my-container","k8s_container_id":"`+containerID+`"}`, "wrong out event")
+	assert.Contains(t, outEvents, `{"log":"bye","k8s_pod":"web","k8s_namespace":"default","k8s_container":"nginx","k8s_container_id":"`+containerID+`"}`, "wrong out event")
+}
+
+func TestParseK8sFilenameNotMatching(t *testing.T) {
+	filenames := []string{
+		"/var/log/syslog",
+		"/var/log/containers/my-pod_my-namespace_my-container-" + containerID + ".txt",
+		"/var/log/containers/my-pod_my-namespace_my-container-4e0301b633.log",
+		"/var/log/containers/my-pod_my-namespace_my-container_" + containerID + ".log",
+		"/var/log/containers/my-pod_my-namespace-my-container-" + containerID + ".log",
+		"/var/log/containers/_my-namespace_my-container-" + containerID + ".log",
+		"/var/log/containers/my-pod_my-namespace_" + containerID + ".log",
+		"/var/log/containers/my-pod_my-namespace_my-container-" + containerID[:63] + "z.log",
+	}
+
+	for _, filename := range filenames {
+		_, ok := parseFilename(filename)
+		assert.False(t, ok, "filename %q shouldn't be parsed", filename)
+	}
+
+	config := test.NewConfig(&Config{Prefix: "kube_"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, filenames[0], 0, []byte(`{"log":"hello"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{`{"log":"hello"}`}, outEvents, "wrong out events")
+}
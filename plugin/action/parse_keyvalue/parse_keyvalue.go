@@ -0,0 +1,229 @@
+package parse_keyvalue
+
+import (
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ introduction
+It parses logfmt and other `key=value` strings from the event field and merges the pairs with the event root.
+A value may be double-quoted, so it can contain spaces and separators, `\"`, `\\`, `\n`, `\r` and `\t` escapes are supported in quoted values.
+An unquoted value lasts until the next pair separator, so it may contain the key separator, e.g. `url=/?a=b` gives `/?a=b`.
+All values are strings, if a key occurs several times the last value wins.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_keyvalue
+      field: message
+    ...
+```
+It transforms `{"message":"level=info msg=\"hi there\" dur=3ms cached"}` into `{"level":"info","msg":"hi there","dur":"3ms","cached":""}`.
+}*/
+type Plugin struct {
+	config      *Config
+	parseResult *pipeline.ParseResult
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to parse. Must be a string.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" required:"true"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to parsed keys.
+	Prefix string `json:"prefix" default:""` //*
+
+	//> @3@4@5@6
+	//>
+	//> A string which separates a key from a value.
+	Separator string `json:"separator" default:"="` //*
+
+	//> @3@4@5@6
+	//>
+	//> A string which separates pairs, e.g. `;`. Spaces and tabs around pairs are always skipped.
+	//> Pairs are separated by spaces and tabs if not set.
+	PairSeparator string `json:"pair_separator" default:""` //*
+
+	//> @3@4@5@6
+	//>
+	//> If set, keys without a separator are skipped, otherwise they get an empty string value.
+	SkipBareKeys bool `json:"skip_bare_keys" default:"false"` //*
+
+	//> @3@4@5@6
+	//>
+	//> If set, the parsed field is kept in the event, otherwise it's removed.
+	//> If a key is the same as the parsed field, the parsed value overwrites the field.
+	KeepOriginal bool `json:"keep_original" default:"false"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_keyvalue",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_keyvalue")
+
+	if p.config.Separator == "" {
+		params.Logger.Fatalf("separator can't be empty")
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil || !node.IsString() {
+		return pipeline.ActionPass
+	}
+
+	s := node.AsString()
+
+	if !p.config.KeepOriginal {
+		node.Suicide()
+	}
+
+	root := insaneJSON.Spawn()
+
+	for {
+		s = p.skipPairSeparators(s)
+		if s == "" {
+			break
+		}
+
+		key := s[:p.indexKeyEnd(s)]
+		s = s[len(key):]
+		key = trimBlanks(key)
+
+		hasValue := strings.HasPrefix(s, p.config.Separator)
+		value := ""
+		if hasValue {
+			s = s[len(p.config.Separator):]
+			value, s = p.cutValue(event, s)
+		}
+
+		if key == "" || !hasValue && p.config.SkipBareKeys {
+			continue
+		}
+
+		if p.config.Prefix != "" {
+			l := len(event.Buf)
+			event.Buf = append(event.Buf, p.config.Prefix...)
+			event.Buf = append(event.Buf, key...)
+			key = pipeline.ByteToStringUnsafe(event.Buf[l:])
+		}
+
+		root.AddFieldNoAlloc(root, key).MutateToString(value)
+	}
+
+	event.Root.MergeWith(root.Node)
+
+	insaneJSON.Release(root)
+	// any string is parsed, e.g. an unterminated quoted value lasts until the end, so there are no failures
+	p.parseResult.Success()
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) skipPairSeparators(s string) string {
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if p.config.PairSeparator == "" || !strings.HasPrefix(s, p.config.PairSeparator) {
+			return s
+		}
+		s = s[len(p.config.PairSeparator):]
+	}
+}
+
+// indexKeyEnd returns the position of the key separator or of the end of the pair
+func (p *Plugin) indexKeyEnd(s string) int {
+	end := p.indexPairEnd(s)
+	if i := strings.Index(s[:end], p.config.Separator); i != -1 {
+		return i
+	}
+
+	return end
+}
+
+func (p *Plugin) indexPairEnd(s string) int {
+	var i int
+	if p.config.PairSeparator == "" {
+		i = strings.IndexAny(s, " \t")
+	} else {
+		i = strings.Index(s, p.config.PairSeparator)
+	}
+	if i == -1 {
+		return len(s)
+	}
+
+	return i
+}
+
+// cutValue returns the value at the beginning of the string and the rest of the string
+func (p *Plugin) cutValue(event *pipeline.Event, s string) (string, string) {
+	if !strings.HasPrefix(s, `"`) {
+		end := p.indexPairEnd(s)
+		return trimBlanks(s[:end]), s[end:]
+	}
+
+	s = s[1:]
+	end := strings.IndexAny(s, `"\`)
+	if end != -1 && s[end] == '"' {
+		return s[:end], s[end+1:]
+	}
+
+	// unescape the value into the event buffer, an unterminated value lasts until the end of the string
+	l := len(event.Buf)
+	i := 0
+	for ; i < len(s) && s[i] != '"'; i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) {
+			i++
+			c = unescape(s[i])
+		}
+		event.Buf = append(event.Buf, c)
+	}
+	if i < len(s) {
+		i++
+	}
+
+	return pipeline.ByteToStringUnsafe(event.Buf[l:]), s[i:]
+}
+
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	}
+
+	return c
+}
+
+func trimBlanks(s string) string {
+	return strings.Trim(s, " \t")
+}
@@ -0,0 +1,71 @@
+package parse_keyvalue
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func runEvents(config *Config, events []string) []string {
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, test.NewConfig(config, nil), pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(events))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, event := range events {
+		input.In(0, "test.log", 0, []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+func TestParseKeyValue(t *testing.T) {
+	outEvents := runEvents(&Config{Field: "message"}, []string{
+		`{"message":"level=info msg=\"hi there\" dur=3ms cached"}`,
+		`{"message":"url=/search?q=a&b=c query=\"a=b c=d\"  empty= quoted=\"\""}`,
+		`{"message":"msg=\"say \\\"hi\\\"\\tand\\\\or\\nbye\" status=ok"}`,
+		`{"message":"unterminated=\"till the end"}`,
+		`{"message":"=nokey level=debug level=warn"}`,
+		`{"message":{"level":"info"}}`,
+		`{"level":"info"}`,
+	})
+
+	assert.Equal(t, []string{
+		`{"level":"info","msg":"hi there","dur":"3ms","cached":""}`,
+		`{"url":"/search?q=a&b=c","query":"a=b c=d","empty":"","quoted":""}`,
+		`{"msg":"say \"hi\"\tand\\or\nbye","status":"ok"}`,
+		`{"unterminated":"till the end"}`,
+		`{"level":"warn"}`,
+		`{"message":{"level":"info"}}`,
+		`{"level":"info"}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestParseKeyValueSeparators(t *testing.T) {
+	outEvents := runEvents(&Config{
+		Field:         "log.line",
+		Prefix:        "kv_",
+		Separator:     ":",
+		PairSeparator: ";",
+		SkipBareKeys:  true,
+		KeepOriginal:  true,
+	}, []string{
+		`{"log":{"line":"user: alice; role:\"admin; owner\";;flag; time:12:30"}}`,
+	})
+
+	assert.Equal(t, []string{
+		`{"log":{"line":"user: alice; role:\"admin; owner\";;flag; time:12:30"},"kv_user":"alice","kv_role":"admin; owner","kv_time":"12:30"}`,
+	}, outEvents, "wrong out events")
+}
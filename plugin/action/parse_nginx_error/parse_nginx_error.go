@@ -0,0 +1,197 @@
+package parse_nginx_error
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ introduction
+It parses Nginx error log line from the event field and merges the result with the event root.
+
+Extracted fields are: `timestamp`, `level`, `pid`, `tid`, `connection` and `message`.
+The details which Nginx appends to the messages of HTTP requests are extracted too if they are present:
+`client`, `server`, `request`, `subrequest`, `upstream`, `host` and `referrer`.
+`pid`, `tid` and `connection` are converted to numbers, `timestamp` is left as is, it's in the local time of Nginx,
+so use `convert_date` action to convert it. If the line can't be parsed, the event is passed unchanged.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_nginx_error
+      field: message
+    ...
+```
+It transforms `{"message":"2021/06/22 16:24:27 [error] 31#31: *5 upstream timed out (110: Connection timed out) while reading response header from upstream, client: 10.0.0.1, server: example.com, request: \"GET /api HTTP/1.1\", upstream: \"http://10.0.0.2:8080/api\", host: \"example.com\""}`
+into `{"timestamp":"2021/06/22 16:24:27","level":"error","pid":31,"tid":31,"connection":5,"message":"upstream timed out (110: Connection timed out) while reading response header from upstream","client":"10.0.0.1","server":"example.com","request":"GET /api HTTP/1.1","upstream":"http://10.0.0.2:8080/api","host":"example.com"}`.
+}*/
+type Plugin struct {
+	config      *Config
+	parseResult *pipeline.ParseResult
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to parse. Must be a string.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"message"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to parsed keys.
+	Prefix string `json:"prefix" default:""` //*
+}
+
+// errorLogRe matches the line written by ngx_log_error_core
+var errorLogRe = regexp.MustCompile(`^(?P<timestamp>\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) ` +
+	`\[(?P<level>[a-z]+)\] ` +
+	`(?P<pid>\d+)#(?P<tid>\d+): ` +
+	`(?:\*(?P<connection>\d+) )?` +
+	`(?P<message>(?s).*)$`)
+
+var numericFields = map[string]bool{
+	"pid":        true,
+	"tid":        true,
+	"connection": true,
+}
+
+// details are appended by ngx_http_log_error in this order, client is always the first one
+var (
+	detailsStart = []byte(", client: ")
+	detailsSep   = []byte(", ")
+	keySep       = []byte(": ")
+	quotedEnd    = []byte(`", `)
+
+	detailKeys = map[string]bool{
+		"client":     true,
+		"server":     true,
+		"request":    true,
+		"subrequest": true,
+		"upstream":   true,
+		"host":       true,
+		"referrer":   true,
+	}
+)
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_nginx_error",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_nginx_error")
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	jsonNode := event.Root.Dig(p.config.Field_...)
+	if jsonNode == nil {
+		return pipeline.ActionPass
+	}
+
+	sm := errorLogRe.FindSubmatch(jsonNode.AsBytes())
+	if len(sm) == 0 {
+		p.parseResult.Failure()
+		return pipeline.ActionPass
+	}
+
+	jsonNode.Suicide()
+
+	root := insaneJSON.Spawn()
+
+	var details []byte
+	fields := errorLogRe.SubexpNames()
+	for i := 1; i < len(fields); i++ {
+		// optional groups which aren't matched
+		if sm[i] == nil {
+			continue
+		}
+
+		value := sm[i]
+		if fields[i] == "message" {
+			if pos := bytes.Index(value, detailsStart); pos != -1 {
+				details = value[pos+len(detailsSep):]
+				value = value[:pos]
+			}
+		}
+
+		node := p.addField(event, root, fields[i])
+		if numericFields[fields[i]] {
+			if x, err := strconv.Atoi(pipeline.ByteToStringUnsafe(value)); err == nil {
+				node.MutateToInt(x)
+				continue
+			}
+		}
+		node.MutateToBytes(value)
+	}
+	p.addDetails(event, root, details)
+
+	event.Root.MergeWith(root.Node)
+
+	insaneJSON.Release(root)
+	p.parseResult.Success()
+
+	return pipeline.ActionPass
+}
+
+// addDetails adds `key: value` pairs separated by `, `, values of some keys are quoted
+func (p *Plugin) addDetails(event *pipeline.Event, root *insaneJSON.Root, details []byte) {
+	for len(details) != 0 {
+		pos := bytes.Index(details, keySep)
+		if pos == -1 {
+			return
+		}
+		key := details[:pos]
+		details = details[pos+len(keySep):]
+
+		var value []byte
+		if len(details) != 0 && details[0] == '"' {
+			details = details[1:]
+			pos = bytes.Index(details, quotedEnd)
+			if pos == -1 {
+				value, details = bytes.TrimSuffix(details, []byte(`"`)), nil
+			} else {
+				value, details = details[:pos], details[pos+len(quotedEnd):]
+			}
+		} else {
+			pos = bytes.Index(details, detailsSep)
+			if pos == -1 {
+				value, details = details, nil
+			} else {
+				value, details = details[:pos], details[pos+len(detailsSep):]
+			}
+		}
+
+		if detailKeys[pipeline.ByteToStringUnsafe(key)] {
+			p.addField(event, root, pipeline.ByteToStringUnsafe(key)).MutateToBytes(value)
+		}
+	}
+}
+
+func (p *Plugin) addField(event *pipeline.Event, root *insaneJSON.Root, name string) *insaneJSON.Node {
+	bl := len(event.Buf)
+	event.Buf = append(event.Buf, p.config.Prefix...)
+	event.Buf = append(event.Buf, name...)
+
+	return root.AddFieldNoAlloc(root, pipeline.ByteToStringUnsafe(event.Buf[bl:]))
+}
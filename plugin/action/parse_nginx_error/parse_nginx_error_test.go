@@ -0,0 +1,64 @@
+package parse_nginx_error
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func runLines(config *Config, lines []string) []string {
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, test.NewConfig(config, nil), pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(lines))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, line := range lines {
+		input.In(0, "test.log", 0, []byte(`{"message":`+strconv.Quote(line)+`}`))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+func TestParseNginxError(t *testing.T) {
+	lines := []string{
+		`2021/06/22 16:24:27 [error] 31#31: *5 upstream timed out (110: Connection timed out) while reading response header from upstream, client: 10.0.0.1, server: example.com, request: "GET /api?a=1, b=2 HTTP/1.1", upstream: "http://10.0.0.2:8080/api?a=1, b=2", host: "example.com"`,
+		`2021/06/22 16:25:01 [error] 31#31: *7 connect() failed (111: Connection refused) while connecting to upstream, client: 10.0.0.3, server: _, request: "POST /login HTTP/2.0", upstream: "http://127.0.0.1:9000/login", host: "example.com", referrer: "https://example.com/"`,
+		`2021/06/22 16:26:13 [warn] 32#32: *12 an upstream response is buffered to a temporary file /var/cache/nginx/proxy_temp/1/00/0000000001 while reading upstream, client: 10.0.0.4, server: example.com, request: "GET /file HTTP/1.1", upstream: "http://10.0.0.2:8080/file", host: "example.com"`,
+		`2021/06/22 16:27:00 [notice] 1#1: signal process started`,
+		`2021/06/22 16:28:45 [emerg] 1#1: bind() to 0.0.0.0:80 failed (98: Address already in use)`,
+		`not an nginx error line`,
+	}
+
+	outEvents := runLines(&Config{}, lines)
+
+	assert.Equal(t, len(lines), len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"timestamp":"2021/06/22 16:24:27","level":"error","pid":31,"tid":31,"connection":5,"message":"upstream timed out (110: Connection timed out) while reading response header from upstream","client":"10.0.0.1","server":"example.com","request":"GET /api?a=1, b=2 HTTP/1.1","upstream":"http://10.0.0.2:8080/api?a=1, b=2","host":"example.com"}`, outEvents[0], "wrong out event")
+	assert.Equal(t, `{"timestamp":"2021/06/22 16:25:01","level":"error","pid":31,"tid":31,"connection":7,"message":"connect() failed (111: Connection refused) while connecting to upstream","client":"10.0.0.3","server":"_","request":"POST /login HTTP/2.0","upstream":"http://127.0.0.1:9000/login","host":"example.com","referrer":"https://example.com/"}`, outEvents[1], "wrong out event")
+	assert.Equal(t, `{"timestamp":"2021/06/22 16:26:13","level":"warn","pid":32,"tid":32,"connection":12,"message":"an upstream response is buffered to a temporary file /var/cache/nginx/proxy_temp/1/00/0000000001 while reading upstream","client":"10.0.0.4","server":"example.com","request":"GET /file HTTP/1.1","upstream":"http://10.0.0.2:8080/file","host":"example.com"}`, outEvents[2], "wrong out event")
+	assert.Equal(t, `{"timestamp":"2021/06/22 16:27:00","level":"notice","pid":1,"tid":1,"message":"signal process started"}`, outEvents[3], "wrong out event")
+	assert.Equal(t, `{"timestamp":"2021/06/22 16:28:45","level":"emerg","pid":1,"tid":1,"message":"bind() to 0.0.0.0:80 failed (98: Address already in use)"}`, outEvents[4], "wrong out event")
+	assert.Equal(t, `{"message":`+strconv.Quote(lines[5])+`}`, outEvents[5], "wrong line shouldn't be changed")
+}
+
+func TestParseNginxErrorPrefix(t *testing.T) {
+	outEvents := runLines(&Config{Prefix: "nginx_"}, []string{
+		`2021/06/22 16:24:27 [crit] 31#31: *5 SSL_do_handshake() failed, client: 10.0.0.1, server: 0.0.0.0:443`,
+	})
+
+	assert.Equal(t, []string{
+		`{"nginx_timestamp":"2021/06/22 16:24:27","nginx_level":"crit","nginx_pid":31,"nginx_tid":31,"nginx_connection":5,"nginx_message":"SSL_do_handshake() failed","nginx_client":"10.0.0.1","nginx_server":"0.0.0.0:443"}`,
+	}, outEvents, "wrong out events")
+}
@@ -0,0 +1,303 @@
+package parse_otlp_log
+
+import (
+	"strconv"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ introduction
+It normalizes OpenTelemetry log record in OTLP JSON format into canonical fields of the event root:
+* `message` – `body`.
+* `level` – `severityText`.
+* `trace_id` – `traceId`.
+* `span_id` – `spanId`.
+* `attributes` are flattened into fields named by attribute keys, keys of nested `kvlistValue` attributes are joined with `.`.
+
+OTLP values are converted into JSON values: `stringValue` and `bytesValue` into strings, `intValue` and `doubleValue` into numbers,
+`boolValue` into booleans, `arrayValue` into arrays and `kvlistValue` of the body into an object.
+
+The record is taken from `field`, e.g. one of `logRecords` of the OTLP export request, or from the event root if it isn't set.
+If the record has neither `body` nor `attributes`, the event is passed unchanged.
+Canonical fields take precedence over the attributes with the same names, such attributes are skipped.
+Parsed fields are removed from the record unless `keep_original` is set.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_otlp_log
+      attributes_prefix: attr.
+    ...
+```
+It transforms `{"severityText":"INFO","body":{"stringValue":"hello"},"attributes":[{"key":"http","value":{"kvlistValue":{"values":[{"key":"method","value":{"stringValue":"GET"}}]}}}]}`
+into `{"message":"hello","level":"INFO","attr.http.method":"GET"}`.
+}*/
+type Plugin struct {
+	config      *Config
+	parseResult *pipeline.ParseResult
+	names       []string
+	sources     []*insaneJSON.Node
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field with the OTLP log record, the event root is used if it isn't set.
+	Field  cfg.FieldSelector `json:"field" parse:"selector"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to the names of flattened attributes.
+	AttributesPrefix string `json:"attributes_prefix" default:""` //*
+
+	//> @3@4@5@6
+	//>
+	//> If set, the parsed fields of the record aren't removed.
+	KeepOriginal bool `json:"keep_original" default:"false"` //*
+}
+
+type mapping struct {
+	name   string
+	source string
+}
+
+var mappings = []mapping{
+	{name: "message", source: "body"},
+	{name: "level", source: "severityText"},
+	{name: "trace_id", source: "traceId"},
+	{name: "span_id", source: "spanId"},
+}
+
+const attributesField = "attributes"
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_otlp_log",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_otlp_log")
+	p.sources = make([]*insaneJSON.Node, len(mappings))
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	record := event.Root.Dig(p.config.Field_...)
+	if record == nil {
+		return pipeline.ActionPass
+	}
+	// other events of the root aren't counted, since the root is checked for all events
+	if !record.IsObject() {
+		if len(p.config.Field_) != 0 {
+			p.parseResult.Failure()
+		}
+		return pipeline.ActionPass
+	}
+
+	attributes := record.Dig(attributesField)
+	for i, m := range mappings {
+		p.sources[i] = record.Dig(m.source)
+	}
+	if p.sources[0] == nil && attributes == nil {
+		if len(p.config.Field_) != 0 {
+			p.parseResult.Failure()
+		}
+		return pipeline.ActionPass
+	}
+
+	// detached nodes are still readable, so the record is cleaned up first to avoid collisions with new fields
+	if !p.config.KeepOriginal {
+		attributes.Suicide()
+		for _, node := range p.sources {
+			node.Suicide()
+		}
+	}
+
+	for i, m := range mappings {
+		node := p.sources[i]
+		if node == nil {
+			continue
+		}
+
+		field := event.Root.AddFieldNoAlloc(event.Root, m.name)
+		if m.source == "body" {
+			p.setValue(event, field, node)
+		} else {
+			field.MutateToString(node.AsString())
+		}
+	}
+
+	if attributes != nil && attributes.IsArray() {
+		p.names = p.names[:0]
+		p.names = append(p.names, p.config.AttributesPrefix)
+		p.addAttributes(event, attributes)
+	}
+	p.parseResult.Success()
+
+	return pipeline.ActionPass
+}
+
+// addAttributes flattens `[{"key":"k","value":{...}}]` list into the event root, p.names holds the prefix of field names
+func (p *Plugin) addAttributes(event *pipeline.Event, list *insaneJSON.Node) {
+	for _, kv := range list.AsArray() {
+		key := kv.Dig("key")
+		value := kv.Dig("value")
+		if key == nil || value == nil {
+			continue
+		}
+
+		prefix := p.names[len(p.names)-1]
+		l := len(event.Buf)
+		event.Buf = append(event.Buf, prefix...)
+		event.Buf = append(event.Buf, key.AsString()...)
+		name := pipeline.ByteToStringUnsafe(event.Buf[l:])
+		if isCanonical(name) {
+			continue
+		}
+
+		if nested := value.Dig("kvlistValue", "values"); nested != nil && nested.IsArray() {
+			event.Buf = append(event.Buf, '.')
+			p.names = append(p.names, pipeline.ByteToStringUnsafe(event.Buf[l:]))
+			p.addAttributes(event, nested)
+			p.names = p.names[:len(p.names)-1]
+			continue
+		}
+
+		p.setValue(event, event.Root.AddFieldNoAlloc(event.Root, name), value)
+	}
+}
+
+func isCanonical(name string) bool {
+	for _, m := range mappings {
+		if m.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setValue converts OTLP AnyValue into the JSON value of the field
+func (p *Plugin) setValue(event *pipeline.Event, field *insaneJSON.Node, value *insaneJSON.Node) {
+	if node := value.Dig("stringValue"); node != nil {
+		field.MutateToString(node.AsString())
+		return
+	}
+	if node := value.Dig("boolValue"); node != nil {
+		field.MutateToBool(node.IsTrue())
+		return
+	}
+	if node := value.Dig("intValue"); node != nil {
+		// int64 values are encoded as strings in OTLP JSON
+		if i, err := strconv.Atoi(node.AsString()); err == nil {
+			field.MutateToInt(i)
+		} else {
+			field.MutateToString(node.AsString())
+		}
+		return
+	}
+	if node := value.Dig("doubleValue"); node != nil {
+		field.MutateToFloat(node.AsFloat())
+		return
+	}
+	if node := value.Dig("bytesValue"); node != nil {
+		field.MutateToString(node.AsString())
+		return
+	}
+
+	if value.Dig("arrayValue") == nil && value.Dig("kvlistValue") == nil {
+		if value.IsString() {
+			// body may be a plain string in some exporters
+			field.MutateToString(value.AsString())
+		} else {
+			field.MutateToNull()
+		}
+		return
+	}
+
+	l := len(event.Buf)
+	event.Buf = appendJSON(event.Buf, value)
+	field.MutateToJSON(event.Root, pipeline.ByteToStringUnsafe(event.Buf[l:]))
+}
+
+// appendJSON appends OTLP AnyValue encoded as JSON value
+func appendJSON(out []byte, value *insaneJSON.Node) []byte {
+	if value == nil {
+		return append(out, "null"...)
+	}
+
+	if node := value.Dig("arrayValue", "values"); node != nil && node.IsArray() {
+		out = append(out, '[')
+		for i, element := range node.AsArray() {
+			if i > 0 {
+				out = append(out, ',')
+			}
+			out = appendJSON(out, element)
+		}
+		return append(out, ']')
+	}
+	if value.Dig("arrayValue") != nil {
+		return append(out, "[]"...)
+	}
+
+	if node := value.Dig("kvlistValue", "values"); node != nil && node.IsArray() {
+		out = append(out, '{')
+		i := 0
+		for _, kv := range node.AsArray() {
+			key := kv.Dig("key")
+			if key == nil {
+				continue
+			}
+			if i > 0 {
+				out = append(out, ',')
+			}
+			i++
+			out = key.Encode(out)
+			out = append(out, ':')
+			out = appendJSON(out, kv.Dig("value"))
+		}
+		return append(out, '}')
+	}
+	if value.Dig("kvlistValue") != nil {
+		return append(out, "{}"...)
+	}
+
+	if node := value.Dig("stringValue"); node != nil {
+		return node.Encode(out)
+	}
+	if node := value.Dig("bytesValue"); node != nil {
+		return node.Encode(out)
+	}
+	if node := value.Dig("boolValue"); node != nil {
+		return node.Encode(out)
+	}
+	if node := value.Dig("doubleValue"); node != nil && node.IsNumber() {
+		return node.Encode(out)
+	}
+	if node := value.Dig("intValue"); node != nil {
+		if _, err := strconv.Atoi(node.AsString()); err == nil {
+			return append(out, node.AsString()...)
+		}
+		return node.Encode(out)
+	}
+
+	return append(out, "null"...)
+}
@@ -0,0 +1,85 @@
+package parse_otlp_log
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+const record = `{
+	"timeUnixNano":"1613759389000000000",
+	"severityNumber":9,
+	"severityText":"INFO",
+	"traceId":"5b8efff798038103d269b633813fc60c",
+	"spanId":"eee19b7ec3c1b174",
+	"body":{"stringValue":"user logged in"},
+	"attributes":[
+		{"key":"service.name","value":{"stringValue":"auth"}},
+		{"key":"retries","value":{"intValue":"3"}},
+		{"key":"ratio","value":{"doubleValue":0.5}},
+		{"key":"cached","value":{"boolValue":true}},
+		{"key":"tags","value":{"arrayValue":{"values":[{"stringValue":"a"},{"intValue":"1"}]}}},
+		{"key":"http","value":{"kvlistValue":{"values":[
+			{"key":"method","value":{"stringValue":"GET"}},
+			{"key":"response","value":{"kvlistValue":{"values":[{"key":"status","value":{"intValue":"200"}}]}}}
+		]}}},
+		{"key":"message","value":{"stringValue":"collision"}},
+		{"key":"empty","value":{}}
+	]
+}`
+
+func TestParseOTLPLog(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(record))
+	input.In(0, "test.log", 0, []byte(`{"message":"not otlp"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 2, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"timeUnixNano":"1613759389000000000","severityNumber":9,`+
+		`"message":"user logged in","level":"INFO","trace_id":"5b8efff798038103d269b633813fc60c","span_id":"eee19b7ec3c1b174",`+
+		`"service.name":"auth","retries":3,"ratio":0.5,"cached":true,"tags":["a",1],"http.method":"GET","http.response.status":200,"empty":null}`,
+		outEvents[0], "wrong out event")
+	assert.Equal(t, `{"message":"not otlp"}`, outEvents[1], "wrong out event")
+}
+
+func TestParseOTLPLogField(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "otel", AttributesPrefix: "attr.", KeepOriginal: true}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	body := `{"kvlistValue":{"values":[{"key":"event","value":{"stringValue":"login"}},{"key":"ids","value":{"arrayValue":{"values":[{"intValue":"1"},{"intValue":"2"}]}}}]}}`
+	input.In(0, "test.log", 0, []byte(`{"otel":{"severityText":"WARN","body":`+body+`,"attributes":[{"key":"user","value":{"kvlistValue":{"values":[{"key":"id","value":{"intValue":"42"}}]}}}]}}`))
+	input.In(0, "test.log", 0, []byte(`{"otel":{"body":"plain text"}}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 2, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"otel":{"severityText":"WARN","body":`+body+`,"attributes":[{"key":"user","value":{"kvlistValue":{"values":[{"key":"id","value":{"intValue":"42"}}]}}}]},`+
+		`"message":{"event":"login","ids":[1,2]},"level":"WARN","attr.user.id":42}`, outEvents[0], "wrong out event")
+	assert.Equal(t, `{"otel":{"body":"plain text"},"message":"plain text"}`, outEvents[1], "wrong out event")
+}
@@ -0,0 +1,157 @@
+package parse_pg_csvlog
+
+import (
+	"encoding/csv"
+	"strconv"
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It parses PostgreSQL `csvlog` record from the event field and adds the columns to the event root.
+Columns are named as in the PostgreSQL docs: `log_time`, `user_name`, `database_name`, `process_id`, `connection_from`,
+`session_id`, `session_line_num`, `command_tag`, `session_start_time`, `virtual_transaction_id`, `transaction_id`,
+`error_severity`, `sql_state_code`, `message`, `detail`, `hint`, `internal_query`, `internal_query_pos`, `context`,
+`query`, `query_pos`, `location`, `application_name`, `backend_type`, `leader_pid` and `query_id`.
+Records of older PostgreSQL versions have less columns, so missing columns are skipped, as well as empty ones.
+`process_id`, `session_line_num`, `internal_query_pos`, `query_pos`, `leader_pid` and `query_id` are added as numbers.
+
+Quoted values may contain commas, doubled quotes and new lines, e.g. multi-line SQL statements.
+Records which span several lines should be joined before the action, e.g. by `join` action with `start: /^\d{4}-\d{2}-\d{2} /`.
+Existing fields with the same names are overwritten, e.g. `message` field gets the message column.
+If the record can't be parsed, the event is passed unchanged.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_pg_csvlog
+      field: log
+    ...
+```
+}*/
+type Plugin struct {
+	config      *Config
+	parseResult *pipeline.ParseResult
+	names       []string
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to parse. Must be a string.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"message"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to parsed keys.
+	Prefix string `json:"prefix" default:""` //*
+}
+
+// columns are in the order of PostgreSQL csvlog
+var columns = []string{
+	"log_time",
+	"user_name",
+	"database_name",
+	"process_id",
+	"connection_from",
+	"session_id",
+	"session_line_num",
+	"command_tag",
+	"session_start_time",
+	"virtual_transaction_id",
+	"transaction_id",
+	"error_severity",
+	"sql_state_code",
+	"message",
+	"detail",
+	"hint",
+	"internal_query",
+	"internal_query_pos",
+	"context",
+	"query",
+	"query_pos",
+	"location",
+	"application_name",
+	"backend_type",
+	"leader_pid",
+	"query_id",
+}
+
+var numericColumns = map[string]bool{
+	"process_id":         true,
+	"session_line_num":   true,
+	"internal_query_pos": true,
+	"query_pos":          true,
+	"leader_pid":         true,
+	"query_id":           true,
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_pg_csvlog",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_pg_csvlog")
+
+	p.names = make([]string, 0, len(columns))
+	for _, column := range columns {
+		p.names = append(p.names, p.config.Prefix+column)
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil {
+		return pipeline.ActionPass
+	}
+
+	reader := csv.NewReader(strings.NewReader(node.AsString()))
+	reader.FieldsPerRecord = -1
+	values, err := reader.Read()
+	// csvlog record has at least 22 columns
+	if err != nil || len(values) < 22 {
+		p.parseResult.Failure()
+		return pipeline.ActionPass
+	}
+
+	for i, value := range values {
+		if i >= len(columns) {
+			break
+		}
+		if value == "" {
+			continue
+		}
+
+		field := event.Root.AddFieldNoAlloc(event.Root, p.names[i])
+		if numericColumns[columns[i]] {
+			if number, err := strconv.Atoi(value); err == nil {
+				field.MutateToInt(number)
+				continue
+			}
+		}
+		field.MutateToString(value)
+	}
+	p.parseResult.Success()
+
+	return pipeline.ActionPass
+}
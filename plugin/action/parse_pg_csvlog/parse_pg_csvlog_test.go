@@ -0,0 +1,108 @@
+package parse_pg_csvlog
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeEvent(field string, record string) []byte {
+	event, _ := json.Marshal(map[string]string{field: record})
+	return event
+}
+
+func TestParsePgCSVLog(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	record := `2021-07-02 10:00:00.123 UTC,"postgres","shop",12345,"10.0.0.1:54321",60dee1a0.3039,7,"SELECT",` +
+		`2021-07-02 09:59:00 UTC,3/42,0,ERROR,42P01,"relation ""orders"" does not exist",,,,,,"SELECT *` + "\n" +
+		`FROM ""orders""` + "\n" +
+		`WHERE id IN (1, 2)",15,,"psql","client backend",,-1234567890` + "\n"
+	input.In(0, "test.log", 0, makeEvent("message", record))
+	input.In(0, "test.log", 0, makeEvent("message", "not a csvlog, record"))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 2, len(outEvents), "wrong out events count")
+
+	e := outEvents[0]
+	expected := map[string]string{
+		"log_time":               "2021-07-02 10:00:00.123 UTC",
+		"user_name":              "postgres",
+		"database_name":          "shop",
+		"connection_from":        "10.0.0.1:54321",
+		"session_id":             "60dee1a0.3039",
+		"command_tag":            "SELECT",
+		"session_start_time":     "2021-07-02 09:59:00 UTC",
+		"virtual_transaction_id": "3/42",
+		"transaction_id":         "0",
+		"error_severity":         "ERROR",
+		"sql_state_code":         "42P01",
+		"message":                `relation "orders" does not exist`,
+		"query":                  "SELECT *\nFROM \"orders\"\nWHERE id IN (1, 2)",
+		"application_name":       "psql",
+		"backend_type":           "client backend",
+	}
+	for field, value := range expected {
+		assert.Equal(t, value, e.Root.Dig(field).AsString(), "wrong %s field", field)
+	}
+	assert.Equal(t, 12345, e.Root.Dig("process_id").AsInt(), "wrong process_id field")
+	assert.True(t, e.Root.Dig("process_id").IsNumber(), "process_id should be a number")
+	assert.Equal(t, 7, e.Root.Dig("session_line_num").AsInt(), "wrong session_line_num field")
+	assert.Equal(t, 15, e.Root.Dig("query_pos").AsInt(), "wrong query_pos field")
+	assert.Equal(t, -1234567890, e.Root.Dig("query_id").AsInt(), "wrong query_id field")
+	for _, field := range []string{"detail", "hint", "internal_query", "internal_query_pos", "context", "location", "leader_pid"} {
+		assert.Nil(t, e.Root.Dig(field), "empty %s column shouldn't be added", field)
+	}
+
+	assert.Equal(t, `{"message":"not a csvlog, record"}`, outEvents[1].Root.EncodeToString(), "wrong out event")
+}
+
+func TestParsePgCSVLogPrefix(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "log", Prefix: "pg_"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	// PostgreSQL 12 has no backend_type, leader_pid and query_id columns
+	record := `2021-07-02 10:00:00.123 UTC,"app","shop",42,"[local]",60dee1a0.2a,1,"idle",2021-07-02 09:59:00 UTC,,0,LOG,00000,` +
+		`"statement: UPDATE items` + "\n" + `SET price = 1","details, with comma","a hint",,,,,,,"psql"`
+	input.In(0, "test.log", 0, makeEvent("log", record))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 1, len(outEvents), "wrong out events count")
+	e := outEvents[0]
+	assert.Equal(t, record, e.Root.Dig("log").AsString(), "original field should be kept")
+	assert.Equal(t, "LOG", e.Root.Dig("pg_error_severity").AsString(), "wrong error_severity field")
+	assert.Equal(t, "statement: UPDATE items\nSET price = 1", e.Root.Dig("pg_message").AsString(), "wrong message field")
+	assert.Equal(t, "details, with comma", e.Root.Dig("pg_detail").AsString(), "wrong detail field")
+	assert.Equal(t, "a hint", e.Root.Dig("pg_hint").AsString(), "wrong hint field")
+	assert.Equal(t, "psql", e.Root.Dig("pg_application_name").AsString(), "wrong application_name field")
+	assert.Equal(t, 42, e.Root.Dig("pg_process_id").AsInt(), "wrong process_id field")
+	assert.Nil(t, e.Root.Dig("pg_virtual_transaction_id"), "empty column shouldn't be added")
+	assert.Nil(t, e.Root.Dig("pg_backend_type"), "missing column shouldn't be added")
+}
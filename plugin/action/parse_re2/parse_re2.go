@@ -1,7 +1,12 @@
 package parse_re2
 
 import (
+	"fmt"
+	"math"
 	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/ozonru/file.d/cfg"
 	"github.com/ozonru/file.d/fd"
@@ -11,11 +16,37 @@ import (
 
 /*{ introduction
 It parses string from the event field using re2 expression with named subgroups and merges the result with the event root.
+
+Only the first match is used by default. If `find_all` is set, all matches are used and the result shape depends on `mode`:
+* `arrays` – each subgroup is merged with the root as an array of its values, one value per match.
+* `objects` – `matches_field` is set to the array of objects, one object of subgroups per match.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_re2
+      field: log
+      re2: '(?P<key>\w+)=(?P<value>\w+)'
+      find_all: true
+    ...
+```
+It transforms `{"log":"a=1 b=2"}` into `{"key":["a","b"],"value":["1","2"]}`,
+and into `{"matches":[{"key":"a","value":"1"},{"key":"b","value":"2"}]}` with `mode: objects`.
+
+Subgroup values are strings by default. If `infer_types` is set, integers, floats and `true`/`false` are written as JSON numbers and booleans.
+Integers with leading zeros like `007` and integers which overflow int64 are kept as strings, since they are likely identifiers.
+The type of the subgroup can be set explicitly in `types`, values which can't be converted to it are kept as strings.
 }*/
 type Plugin struct {
-	config *Config
+	config      *Config
+	parseResult *pipeline.ParseResult
 
-	re *regexp.Regexp
+	re    *regexp.Regexp
+	names []string
+	types []string
 }
 
 //! config-params
@@ -30,12 +61,96 @@ type Config struct {
 	//> @3@4@5@6
 	//>
 	//> Re2 expression to use for parsing.
-	Re2 string `json:"re2" default:"" required:"true"` //*
+	Re2  string `json:"re2" required:"true"` //*
+	Re2_ *regexp.Regexp
 
 	//> @3@4@5@6
 	//>
 	//> A prefix to add to decoded object keys.
 	Prefix string `json:"prefix" default:""` //*
+
+	//> @3@4@5@6
+	//>
+	//> If set, the parsed field is kept in the event, otherwise it's removed.
+	//> If a subgroup has the same name as the parsed field, the subgroup value overwrites the field.
+	KeepOriginal bool `json:"keep_original" default:"false"` //*
+
+	//> @3@4@5@6
+	//>
+	//> What to do if the field doesn't match the expression:
+	//> * `pass` – the event is passed unchanged.
+	//> * `discard` – the event is discarded.
+	//> * `error_field` – the event is passed with `error_field` object which has `re2` expression and the `input` truncated to 256 bytes.
+	//>
+	//> Events without the field are always passed unchanged.
+	OnFailure string `json:"on_failure" default:"pass" options:"pass|discard|error_field"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field to put the parse error to if `on_failure` is `error_field`.
+	ErrorField  cfg.FieldSelector `json:"error_field" parse:"selector" default:"_parse_error"` //*
+	ErrorField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> If set, all matches of the expression are used instead of the first one.
+	FindAll bool `json:"find_all" default:"false"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The shape of the result of all matches if `find_all` is set.
+	Mode string `json:"mode" default:"arrays" options:"arrays|objects"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field to put the array of matches to in `objects` mode.
+	MatchesField  cfg.FieldSelector `json:"matches_field" parse:"selector" default:"matches"` //*
+	MatchesField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> If set, the types of numeric and boolean subgroup values are inferred.
+	InferTypes bool `json:"infer_types" default:"false"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The map of `subgroup => type`, the type is one of `string`, `int`, `float` or `bool`. It overrides `infer_types` for the subgroup.
+	//> Subgroup names are used without `prefix`.
+	Types map[string]string `json:"types"` //*
+}
+
+const (
+	onFailureDiscard    = "discard"
+	onFailureErrorField = "error_field"
+
+	modeObjects = "objects"
+
+	typeAuto   = "auto"
+	typeString = "string"
+	typeInt    = "int"
+	typeFloat  = "float"
+	typeBool   = "bool"
+
+	maxErrorInputLen = 256
+)
+
+// Validate compiles the expression and checks types of subgroups, so a wrong config fails the config parsing with an error
+func (c *Config) Validate() error {
+	re, err := regexp.Compile(c.Re2)
+	if err != nil {
+		return fmt.Errorf("can't compile re2 expression: %s", err.Error())
+	}
+	c.Re2_ = re
+
+	for name, kind := range c.Types {
+		switch kind {
+		case typeString, typeInt, typeFloat, typeBool:
+		default:
+			return fmt.Errorf("unknown type %q for subgroup %s", kind, name)
+		}
+	}
+
+	return nil
 }
 
 func init() {
@@ -49,10 +164,31 @@ func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
 	return &Plugin{}, &Config{}
 }
 
-func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
 	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_re2")
+
+	p.re = p.config.Re2_
+
+	// names of all matches are the same, so they are prefixed once
+	fields := p.re.SubexpNames()
+	p.names = make([]string, len(fields))
+	for i := 1; i < len(fields); i++ {
+		if len(fields[i]) != 0 {
+			p.names[i] = p.config.Prefix + fields[i]
+		}
+	}
 
-	p.re = regexp.MustCompile(p.config.Re2)
+	p.types = make([]string, len(fields))
+	for i := 1; i < len(fields); i++ {
+		p.types[i] = typeString
+		if p.config.InferTypes {
+			p.types[i] = typeAuto
+		}
+		if kind, has := p.config.Types[fields[i]]; has {
+			p.types[i] = kind
+		}
+	}
 }
 
 func (p *Plugin) Stop() {
@@ -64,13 +200,20 @@ func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
 		return pipeline.ActionPass
 	}
 
+	if p.config.FindAll {
+		return p.doAll(event, jsonNode)
+	}
+
 	sm := p.re.FindSubmatch(jsonNode.AsBytes())
 
 	if len(sm) == 0 {
-		return pipeline.ActionPass
+		p.parseResult.Failure()
+		return p.onFailure(event, jsonNode.AsString())
 	}
 
-	jsonNode.Suicide()
+	if !p.config.KeepOriginal {
+		jsonNode.Suicide()
+	}
 
 	root := insaneJSON.Spawn()
 
@@ -86,12 +229,153 @@ func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
 		event.Buf = append(event.Buf, p.config.Prefix...)
 		event.Buf = append(event.Buf, fields[i]...)
 
-		root.AddFieldNoAlloc(root, pipeline.ByteToStringUnsafe(event.Buf[bl:len(event.Buf)])).MutateToBytes(sm[i])
+		p.mutateToValue(root.AddFieldNoAlloc(root, pipeline.ByteToStringUnsafe(event.Buf[bl:len(event.Buf)])), i, sm[i])
 	}
 
 	event.Root.MergeWith(root.Node)
 
 	insaneJSON.Release(root)
+	p.parseResult.Success()
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) doAll(event *pipeline.Event, jsonNode *insaneJSON.Node) pipeline.ActionResult {
+	matches := p.re.FindAllSubmatch(jsonNode.AsBytes(), -1)
+
+	if len(matches) == 0 {
+		p.parseResult.Failure()
+		return p.onFailure(event, jsonNode.AsString())
+	}
+
+	if !p.config.KeepOriginal {
+		jsonNode.Suicide()
+	}
+
+	// the result is built right in the event root, since merged arrays and objects would refer to the nodes of the temporary root
+	if p.config.Mode == modeObjects {
+		matchesNode := pipeline.CreateNestedField(event.Root, p.config.MatchesField_)
+		matchesNode.MutateToJSON(event.Root, "[]")
+		for _, sm := range matches {
+			matchNode := matchesNode.AddElement().MutateToObject()
+			for i, name := range p.names {
+				if name != "" {
+					p.mutateToValue(matchNode.AddFieldNoAlloc(event.Root, name), i, sm[i])
+				}
+			}
+		}
+	} else {
+		for i, name := range p.names {
+			if name == "" {
+				continue
+			}
+
+			valuesNode := event.Root.AddFieldNoAlloc(event.Root, name)
+			valuesNode.MutateToJSON(event.Root, "[]")
+			for _, sm := range matches {
+				p.mutateToValue(valuesNode.AddElement(), i, sm[i])
+			}
+		}
+	}
+
+	p.parseResult.Success()
+
+	return pipeline.ActionPass
+}
+
+// mutateToValue sets the node to the value of the subgroup converted to the type of the subgroup
+func (p *Plugin) mutateToValue(node *insaneJSON.Node, subgroup int, value []byte) {
+	s := pipeline.ByteToStringUnsafe(value)
+
+	switch p.types[subgroup] {
+	case typeAuto:
+		if s == "true" || s == "false" {
+			node.MutateToBool(s == "true")
+			return
+		}
+		if !isDecimal(s) {
+			break
+		}
+		if strings.IndexByte(s, '.') == -1 {
+			// int64 overflow is kept as string
+			if x, err := strconv.ParseInt(s, 10, 64); err == nil {
+				node.MutateToInt(int(x))
+				return
+			}
+			break
+		}
+		if x, err := strconv.ParseFloat(s, 64); err == nil {
+			node.MutateToFloat(x)
+			return
+		}
+	case typeInt:
+		if x, err := strconv.ParseInt(s, 10, 64); err == nil {
+			node.MutateToInt(int(x))
+			return
+		}
+	case typeFloat:
+		if x, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(x, 0) && !math.IsNaN(x) {
+			node.MutateToFloat(x)
+			return
+		}
+	case typeBool:
+		if x, err := strconv.ParseBool(s); err == nil {
+			node.MutateToBool(x)
+			return
+		}
+	}
+
+	node.MutateToBytes(value)
+}
+
+// isDecimal returns whether the value is a decimal number with an optional sign and fractional part,
+// but without leading zeros of the integer part
+func isDecimal(s string) bool {
+	if strings.HasPrefix(s, "-") {
+		s = s[1:]
+	}
+
+	whole, fraction := s, ""
+	hasPoint := false
+	if i := strings.IndexByte(s, '.'); i != -1 {
+		whole, fraction, hasPoint = s[:i], s[i+1:], true
+	}
+
+	if whole == "" || (hasPoint && fraction == "") || (len(whole) > 1 && whole[0] == '0') {
+		return false
+	}
+
+	return isDigits(whole) && isDigits(fraction)
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Plugin) onFailure(event *pipeline.Event, input string) pipeline.ActionResult {
+	switch p.config.OnFailure {
+	case onFailureDiscard:
+		return pipeline.ActionDiscard
+	case onFailureErrorField:
+		if len(input) > maxErrorInputLen {
+			n := maxErrorInputLen
+			// don't cut UTF-8 sequence
+			for n > 0 && !utf8.RuneStart(input[n]) {
+				n--
+			}
+			input = input[:n]
+		}
+
+		errorNode := pipeline.CreateNestedField(event.Root, p.config.ErrorField_)
+		errorNode.MutateToObject()
+		errorNode.AddFieldNoAlloc(event.Root, "re2").MutateToString(p.config.Re2)
+		errorNode.AddFieldNoAlloc(event.Root, "input").MutateToString(input)
+	}
 
 	return pipeline.ActionPass
 }
@@ -1,6 +1,9 @@
 package parse_re2
 
 import (
+	"encoding/json"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 
@@ -8,7 +11,9 @@ import (
 	"github.com/ozonru/file.d/logger"
 	"github.com/ozonru/file.d/pipeline"
 	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	insaneJSON "github.com/vitkovskii/insane-json"
 )
 
 func TestDecode(t *testing.T) {
@@ -17,15 +22,15 @@ func TestDecode(t *testing.T) {
 		Prefix: "prefix.",
 		Re2:    "(?P<date>[\\d]{4}-[\\d]{2}-[\\d]{2} [\\d]{2}:[\\d]{2}:[\\d]{2} GMT) \\[(?P<pid>[\\d]+)\\] => \\[(?P<pid_message_number>[\\d-]+)\\] client=(?P<client>[^,]*),db=(?P<db>[^,]*),user=(?P<user>[^,]*) (LOG|HINT):  (?P<message>.+)",
 	}
-	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
 	err := cfg.Parse(config, nil)
 	if err != nil {
 		logger.Panicf("wrong config")
 	}
 
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
 	inEvents := 0
 	input.SetInFn(func() {
 		inEvents++
@@ -46,3 +51,388 @@ func TestDecode(t *testing.T) {
 	assert.Equal(t, 1, len(outEvents), "wrong out events count")
 	assert.Equal(t, `{"prefix.date":"2021-06-22 16:24:27 GMT","prefix.pid":"7291","prefix.pid_message_number":"2-1","prefix.client":"test_client","prefix.db":"test_db","prefix.user":"test_user","prefix.message":"listening on IPv4 address \"0.0.0.0\", port 5432"}`, outEvents[0].Root.EncodeToString(), "wrong out event")
 }
+
+func TestConfigTags(t *testing.T) {
+	// the config is decoded the same way as action configs of the pipeline
+	config := &Config{}
+	err := json.Unmarshal([]byte(`{"field":"log","re2":"(?P<method>[A-Z]+) (?P<path>\\S+)","prefix":"req_"}`), config)
+	assert.NoError(t, err, "wrong json")
+	assert.NoError(t, cfg.Parse(config, nil), "wrong config")
+
+	assert.Equal(t, `(?P<method>[A-Z]+) (?P<path>\S+)`, config.Re2, "re2 should be decoded into Re2 field")
+	assert.Equal(t, "req_", config.Prefix, "prefix should be decoded into Prefix field")
+	assert.Equal(t, []string{"", "method", "path"}, config.Re2_.SubexpNames(), "wrong compiled expression")
+}
+
+func TestInvalidRe2(t *testing.T) {
+	config := &Config{Field: "log", Re2: `(?P<method>[A-Z]+`}
+
+	var err error
+	assert.NotPanics(t, func() {
+		err = cfg.Parse(config, nil)
+	}, "invalid expression shouldn't panic")
+	assert.Error(t, err, "invalid expression should fail config parsing")
+	assert.Contains(t, err.Error(), "can't compile re2 expression", "wrong error")
+}
+
+func TestInvalidType(t *testing.T) {
+	config := &Config{Field: "log", Re2: `(?P<status>\d+)`, Types: map[string]string{"status": "number"}}
+
+	var err error
+	assert.NotPanics(t, func() {
+		err = cfg.Parse(config, nil)
+	}, "invalid type shouldn't panic")
+	assert.Error(t, err, "invalid type should fail config parsing")
+	assert.Contains(t, err.Error(), `unknown type "number" for subgroup status`, "wrong error")
+}
+
+func TestKeepOriginal(t *testing.T) {
+	events := []string{
+		`{"log":"GET /users 200","message":"request"}`,
+		`{"log":"not a request","message":"request"}`,
+	}
+
+	cases := []struct {
+		config   *Config
+		expected []string
+	}{
+		{
+			config: &Config{Field: "log", Re2: `(?P<method>[A-Z]+) (?P<path>\S+) (?P<status>\d+)`},
+			expected: []string{
+				`{"message":"request","method":"GET","path":"/users","status":"200"}`,
+				`{"log":"not a request","message":"request"}`,
+			},
+		},
+		{
+			config: &Config{Field: "log", Re2: `(?P<method>[A-Z]+) (?P<path>\S+) (?P<status>\d+)`, KeepOriginal: true},
+			expected: []string{
+				`{"log":"GET /users 200","message":"request","method":"GET","path":"/users","status":"200"}`,
+				`{"log":"not a request","message":"request"}`,
+			},
+		},
+		{
+			// subgroups which collide with the original field and other fields overwrite them
+			config: &Config{Field: "log", Re2: `(?P<log>[A-Z]+) (?P<message>\S+)`, KeepOriginal: true},
+			expected: []string{
+				`{"log":"GET","message":"/users"}`,
+				`{"log":"not a request","message":"request"}`,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		err := cfg.Parse(c.config, nil)
+		if err != nil {
+			logger.Panicf("wrong config")
+		}
+		p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, c.config, pipeline.MatchModeAnd, nil, false))
+
+		wg := &sync.WaitGroup{}
+		wg.Add(len(events))
+
+		outEvents := make([]string, 0)
+		output.SetOutFn(func(e *pipeline.Event) {
+			outEvents = append(outEvents, e.Root.EncodeToString())
+			wg.Done()
+		})
+
+		for _, e := range events {
+			input.In(0, "test.log", 0, []byte(e))
+		}
+
+		wg.Wait()
+		p.Stop()
+
+		assert.Equal(t, c.expected, outEvents, "wrong out events for keep_original=%t", c.config.KeepOriginal)
+	}
+}
+
+func TestOnFailure(t *testing.T) {
+	long := "a" + strings.Repeat("ж", 200)
+	events := []string{
+		`{"log":"привет, мир"}`,
+		`{"log":"` + long + `"}`,
+		`{"message":"no field"}`,
+		`{"log":"GET /users 200"}`,
+	}
+	re2 := `(?P<method>[A-Z]+) (?P<path>\S+) (?P<status>\d+)`
+	parsed := `{"method":"GET","path":"/users","status":"200"}`
+	// encoder escapes HTML characters
+	encodedRe2 := strings.NewReplacer(`\`, `\\`, "<", `\u003c`, ">", `\u003e`).Replace(re2)
+
+	cases := []struct {
+		config   *Config
+		expected []string
+	}{
+		{
+			config:   &Config{Field: "log", Re2: re2},
+			expected: []string{events[0], events[1], events[2], parsed},
+		},
+		{
+			config:   &Config{Field: "log", Re2: re2, OnFailure: "discard"},
+			expected: []string{events[2], parsed},
+		},
+		{
+			config: &Config{Field: "log", Re2: re2, OnFailure: "error_field", ErrorField: "errors.parse"},
+			expected: []string{
+				`{"log":"привет, мир","errors":{"parse":{"re2":"` + encodedRe2 + `","input":"привет, мир"}}}`,
+				`{"log":"` + long + `","errors":{"parse":{"re2":"` + encodedRe2 + `","input":"` + long[:255] + `"}}}`,
+				events[2],
+				parsed,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		err := cfg.Parse(c.config, nil)
+		if err != nil {
+			logger.Panicf("wrong config")
+		}
+		p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, c.config, pipeline.MatchModeAnd, nil, false))
+
+		wg := &sync.WaitGroup{}
+		wg.Add(len(c.expected))
+
+		outEvents := make([]string, 0)
+		output.SetOutFn(func(e *pipeline.Event) {
+			outEvents = append(outEvents, e.Root.EncodeToString())
+			wg.Done()
+		})
+
+		for _, e := range events {
+			input.In(0, "test.log", 0, []byte(e))
+		}
+
+		wg.Wait()
+		p.Stop()
+
+		assert.Equal(t, c.expected, outEvents, "wrong out events for on_failure=%s", c.config.OnFailure)
+	}
+}
+
+func TestParseResult(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "log", Re2: `^(?P<method>[A-Z]+) (?P<path>\S+)$`}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+
+	output.SetOutFn(func(e *pipeline.Event) {
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"log":"GET /users"}`))
+	input.In(0, "test.log", 0, []byte(`{"log":"POST /users"}`))
+	input.In(0, "test.log", 0, []byte(`{"log":"not a request"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"no field"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	results := p.GetMetricsCtl().RegisterCounter("parse_result_total", "")
+	assert.Equal(t, float64(2), testutil.ToFloat64(results.WithLabelValues("parse_re2", "success")), "wrong success count")
+	assert.Equal(t, float64(1), testutil.ToFloat64(results.WithLabelValues("parse_re2", "failure")), "wrong failure count")
+}
+
+func TestFindAll(t *testing.T) {
+	events := []string{
+		`{"log":"no pairs here"}`,
+		`{"log":"a=1"}`,
+		`{"log":"a=1 b=2 c=","message":"pairs"}`,
+	}
+	re2 := `(?P<key>\w+)=(?P<value>\w*)`
+
+	cases := []struct {
+		config   *Config
+		expected []string
+	}{
+		{
+			config: &Config{Field: "log", Re2: re2, FindAll: true},
+			expected: []string{
+				`{"log":"no pairs here"}`,
+				`{"key":["a"],"value":["1"]}`,
+				`{"message":"pairs","key":["a","b","c"],"value":["1","2",""]}`,
+			},
+		},
+		{
+			config: &Config{Field: "log", Re2: re2, FindAll: true, Mode: "objects", MatchesField: "parsed.pairs", Prefix: "p_"},
+			expected: []string{
+				`{"log":"no pairs here"}`,
+				`{"parsed":{"pairs":[{"p_key":"a","p_value":"1"}]}}`,
+				`{"message":"pairs","parsed":{"pairs":[{"p_key":"a","p_value":"1"},{"p_key":"b","p_value":"2"},{"p_key":"c","p_value":""}]}}`,
+			},
+		},
+		{
+			config: &Config{Field: "log", Re2: re2},
+			expected: []string{
+				`{"log":"no pairs here"}`,
+				`{"key":"a","value":"1"}`,
+				`{"message":"pairs","key":"a","value":"1"}`,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		config := test.NewConfig(c.config, nil)
+		p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+		wg := &sync.WaitGroup{}
+		wg.Add(len(events))
+
+		outEvents := make([]string, 0)
+		output.SetOutFn(func(e *pipeline.Event) {
+			outEvents = append(outEvents, e.Root.EncodeToString())
+			wg.Done()
+		})
+
+		for _, e := range events {
+			input.In(0, "test.log", 0, []byte(e))
+		}
+
+		wg.Wait()
+		p.Stop()
+
+		assert.Equal(t, c.expected, outEvents, "wrong out events for find_all=%t mode=%s", c.config.FindAll, c.config.Mode)
+	}
+}
+
+func nodeType(node *insaneJSON.Node) string {
+	switch {
+	case node == nil:
+		return "absent"
+	case node.IsString():
+		return "string"
+	case node.IsTrue(), node.IsFalse():
+		return "bool"
+	case node.IsNumber() && strings.ContainsAny(node.AsString(), ".eE"):
+		return "float"
+	case node.IsNumber():
+		return "int"
+	}
+	return "other"
+}
+
+func TestInferTypes(t *testing.T) {
+	re2 := `^(?P<status>\S*) (?P<latency>\S*) (?P<cached>\S*) (?P<code>\S*)$`
+	fields := []string{"status", "latency", "cached", "code"}
+	lines := []string{
+		`200 0.25 true 42`,
+		`007 -1.5 false -3`,
+		`9223372036854775808 1e3 True 1.`,
+		`  - 0`,
+		`-0 .5 yes 00.1`,
+	}
+
+	cases := []struct {
+		name     string
+		config   *Config
+		expected [][]string
+		encoded  []string
+	}{
+		{
+			name:   "strings by default",
+			config: &Config{Field: "log", Re2: re2},
+			expected: [][]string{
+				{"string", "string", "string", "string"},
+				{"string", "string", "string", "string"},
+				{"string", "string", "string", "string"},
+				{"string", "string", "string", "string"},
+				{"string", "string", "string", "string"},
+			},
+		},
+		{
+			name:   "infer types",
+			config: &Config{Field: "log", Re2: re2, InferTypes: true},
+			expected: [][]string{
+				{"int", "float", "bool", "int"},
+				// leading zeros are kept
+				{"string", "float", "bool", "int"},
+				// int64 overflow, exponent, non-lowercase bool and point without fraction are kept
+				{"string", "string", "string", "string"},
+				// empty captures are kept
+				{"string", "string", "string", "int"},
+				{"int", "string", "string", "string"},
+			},
+			encoded: []string{
+				`{"status":200,"latency":0.25,"cached":true,"code":42}`,
+				`{"status":"007","latency":-1.5,"cached":false,"code":-3}`,
+				`{"status":"9223372036854775808","latency":"1e3","cached":"True","code":"1."}`,
+				`{"status":"","latency":"","cached":"-","code":0}`,
+				`{"status":0,"latency":".5","cached":"yes","code":"00.1"}`,
+			},
+		},
+		{
+			name:   "explicit types",
+			config: &Config{Field: "log", Re2: re2, InferTypes: true, Types: map[string]string{"status": "int", "latency": "float", "cached": "bool", "code": "string"}},
+			expected: [][]string{
+				{"int", "float", "bool", "string"},
+				{"int", "float", "bool", "string"},
+				// int64 overflow is kept
+				{"string", "int", "bool", "string"},
+				{"string", "string", "string", "string"},
+				{"int", "float", "string", "string"},
+			},
+			encoded: []string{
+				`{"status":200,"latency":0.25,"cached":true,"code":"42"}`,
+				`{"status":7,"latency":-1.5,"cached":false,"code":"-3"}`,
+				`{"status":"9223372036854775808","latency":1000,"cached":true,"code":"1."}`,
+				`{"status":"","latency":"","cached":"-","code":"0"}`,
+				`{"status":0,"latency":0.5,"cached":"yes","code":"00.1"}`,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := test.NewConfig(c.config, nil)
+			p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+			wg := &sync.WaitGroup{}
+			wg.Add(len(lines))
+
+			types := make([][]string, 0)
+			encoded := make([]string, 0)
+			output.SetOutFn(func(e *pipeline.Event) {
+				eventTypes := make([]string, 0, len(fields))
+				for _, field := range fields {
+					eventTypes = append(eventTypes, nodeType(e.Root.Dig(field)))
+				}
+				types = append(types, eventTypes)
+				encoded = append(encoded, e.Root.EncodeToString())
+				wg.Done()
+			})
+
+			for _, line := range lines {
+				input.In(0, "test.log", 0, []byte(`{"log":`+strconv.Quote(line)+`}`))
+			}
+
+			wg.Wait()
+			p.Stop()
+
+			assert.Equal(t, c.expected, types, "wrong node types")
+			if c.encoded != nil {
+				assert.Equal(t, c.encoded, encoded, "wrong out events")
+			}
+		})
+	}
+}
+
+func TestInferTypesFindAll(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "log", Re2: `(?P<key>\w+)=(?P<value>\S+)`, FindAll: true, Mode: "objects", InferTypes: true}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"log":"a=1 b=x c=false"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{`{"matches":[{"key":"a","value":1},{"key":"b","value":"x"},{"key":"c","value":false}]}`}, outEvents, "wrong out events")
+}
@@ -0,0 +1,231 @@
+package parse_redis_slowlog
+
+import (
+	"strconv"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ introduction
+It parses Redis slow log entry from the event field and merges the result with the event root.
+The entry is expected in the format of `redis-cli --csv SLOWLOG GET` output of Redis 4.0+:
+`id,timestamp,duration,"arg",...,"client","client_name"`, one entry per line.
+
+Extracted fields are: `id`, `timestamp`, `duration_us`, `command`, `client` and `client_name`.
+Command arguments are unescaped and joined with spaces, `client_name` is set only if it isn't empty.
+If the line can't be parsed, the event will be skipped.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_redis_slowlog
+      field: message
+    ...
+```
+It transforms `{"message":"14,1309448221,15000,\"SET\",\"user:1\",\"alice\",\"127.0.0.1:58217\",\"worker-123\""}`
+into `{"id":14,"timestamp":1309448221,"duration_us":15000,"command":"SET user:1 alice","client":"127.0.0.1:58217","client_name":"worker-123"}`.
+}*/
+type Plugin struct {
+	config      *Config
+	parseResult *pipeline.ParseResult
+	args        []arg
+}
+
+// arg is the position of the unescaped string in the event buffer
+type arg struct {
+	start int
+	end   int
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to parse. Must be a string.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"message"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to parsed keys.
+	Prefix string `json:"prefix" default:""` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_redis_slowlog",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_redis_slowlog")
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	jsonNode := event.Root.Dig(p.config.Field_...)
+	if jsonNode == nil {
+		return pipeline.ActionPass
+	}
+
+	line := jsonNode.AsString()
+	numbers := [3]int{}
+	for i := range numbers {
+		pos := 0
+		for pos < len(line) && line[pos] != ',' {
+			pos++
+		}
+
+		x, err := strconv.Atoi(line[:pos])
+		if err != nil || pos == len(line) {
+			p.parseResult.Failure()
+			return pipeline.ActionPass
+		}
+		numbers[i] = x
+		line = line[pos+1:]
+	}
+
+	l := len(event.Buf)
+	buf, ok := p.parseArgs(event.Buf, line)
+	// at least the command name, the client and the client name are expected
+	if !ok || len(p.args) < 3 {
+		event.Buf = event.Buf[:l]
+		p.parseResult.Failure()
+		return pipeline.ActionPass
+	}
+	event.Buf = buf
+
+	jsonNode.Suicide()
+
+	root := insaneJSON.Spawn()
+
+	p.addNumber(event, root, "id", numbers[0])
+	p.addNumber(event, root, "timestamp", numbers[1])
+	p.addNumber(event, root, "duration_us", numbers[2])
+
+	commandStart := len(event.Buf)
+	for i, a := range p.args[:len(p.args)-2] {
+		if i != 0 {
+			event.Buf = append(event.Buf, ' ')
+		}
+		event.Buf = append(event.Buf, event.Buf[a.start:a.end]...)
+	}
+	p.addString(event, root, "command", event.Buf[commandStart:])
+
+	client := p.args[len(p.args)-2]
+	p.addString(event, root, "client", event.Buf[client.start:client.end])
+
+	clientName := p.args[len(p.args)-1]
+	if clientName.end > clientName.start {
+		p.addString(event, root, "client_name", event.Buf[clientName.start:clientName.end])
+	}
+
+	event.Root.MergeWith(root.Node)
+
+	insaneJSON.Release(root)
+	p.parseResult.Success()
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) addNumber(event *pipeline.Event, root *insaneJSON.Root, name string, value int) {
+	root.AddFieldNoAlloc(root, p.appendKey(event, name)).MutateToInt(value)
+}
+
+func (p *Plugin) addString(event *pipeline.Event, root *insaneJSON.Root, name string, value []byte) {
+	root.AddFieldNoAlloc(root, p.appendKey(event, name)).MutateToBytes(value)
+}
+
+func (p *Plugin) appendKey(event *pipeline.Event, name string) string {
+	l := len(event.Buf)
+	event.Buf = append(event.Buf, p.config.Prefix...)
+	event.Buf = append(event.Buf, name...)
+
+	return pipeline.ByteToStringUnsafe(event.Buf[l:])
+}
+
+// parseArgs unescapes the comma-separated quoted strings into the buffer,
+// the escaping is the same as redis-cli uses, e.g. `"a\"b\x00"`
+func (p *Plugin) parseArgs(buf []byte, line string) ([]byte, bool) {
+	p.args = p.args[:0]
+	for {
+		if len(line) == 0 || line[0] != '"' {
+			return buf, false
+		}
+
+		start := len(buf)
+		pos := 1
+		closed := false
+		for pos < len(line) {
+			c := line[pos]
+			pos++
+			if c == '"' {
+				closed = true
+				break
+			}
+			if c != '\\' {
+				buf = append(buf, c)
+				continue
+			}
+
+			if pos == len(line) {
+				return buf, false
+			}
+			c = line[pos]
+			pos++
+			switch c {
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'a':
+				buf = append(buf, '\a')
+			case 'b':
+				buf = append(buf, '\b')
+			case 'x':
+				if pos+2 > len(line) {
+					return buf, false
+				}
+				x, err := strconv.ParseUint(line[pos:pos+2], 16, 8)
+				if err != nil {
+					return buf, false
+				}
+				buf = append(buf, byte(x))
+				pos += 2
+			default:
+				buf = append(buf, c)
+			}
+		}
+
+		if !closed {
+			return buf, false
+		}
+		p.args = append(p.args, arg{start: start, end: len(buf)})
+
+		line = line[pos:]
+		if len(line) == 0 {
+			return buf, true
+		}
+		if line[0] != ',' {
+			return buf, false
+		}
+		line = line[1:]
+	}
+}
@@ -0,0 +1,66 @@
+package parse_redis_slowlog
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRedisSlowlog(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(6)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"message":"14,1309448221,15,\"ping\",\"127.0.0.1:58217\",\"worker-123\""}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"15,1309448228,15000,\"SET\",\"user:1\",\"alice\",\"10.0.0.5:6379\",\"\""}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"16,1309448230,2048,\"HSET\",\"session\",\"field one\",\"va\\\"l\\x41\\n\",\"10.0.0.6:40000\",\"api\""}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"17,1309448231,99,\"MSET\",\"k1\",\"v1\",\"... (30 more arguments)\",\"10.0.0.7:1\",\"\""}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"not a slow log entry"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"18,1309448232,5,\"ping\",\"unclosed"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"id":14,"timestamp":1309448221,"duration_us":15,"command":"ping","client":"127.0.0.1:58217","client_name":"worker-123"}`,
+		`{"id":15,"timestamp":1309448228,"duration_us":15000,"command":"SET user:1 alice","client":"10.0.0.5:6379"}`,
+		`{"id":16,"timestamp":1309448230,"duration_us":2048,"command":"HSET session field one va\"lA\n","client":"10.0.0.6:40000","client_name":"api"}`,
+		`{"id":17,"timestamp":1309448231,"duration_us":99,"command":"MSET k1 v1 ... (30 more arguments)","client":"10.0.0.7:1"}`,
+		`{"message":"not a slow log entry"}`,
+		`{"message":"18,1309448232,5,\"ping\",\"unclosed"}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestParseRedisSlowlogPrefix(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "log", Prefix: "redis_"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"host":"cache-1","log":"1,1309448221,120,\"GET\",\"key\",\"127.0.0.1:1\",\"app\""}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"host":"cache-1","redis_id":1,"redis_timestamp":1309448221,"redis_duration_us":120,"redis_command":"GET key","redis_client":"127.0.0.1:1","redis_client_name":"app"}`,
+	}, outEvents, "wrong out events")
+}
@@ -0,0 +1,141 @@
+package parse_slog
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It normalizes records of Go `log/slog` JSON handler. A record is detected by the presence of `level` and `msg` fields,
+other events are passed as is.
+* `msg` field is renamed to `message_field`.
+* `level` field is normalized to one of `debug`, `info`, `warn`, `error`, e.g. `INFO+2` becomes `info` and `WARN+4` becomes `error`.
+* `source` object is flattened into the root with `source_prefix`.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_slog
+    ...
+```
+It transforms `{"time":"2023-08-04T16:09:59.657Z","level":"WARN","source":{"function":"main.main","file":"/app/main.go","line":17},"msg":"disk is almost full","usage":0.93}`
+into `{"time":"2023-08-04T16:09:59.657Z","level":"warn","usage":0.93,"source_function":"main.main","source_file":"/app/main.go","source_line":17,"message":"disk is almost full"}`.
+}*/
+type Plugin struct {
+	config      *Config
+	parseResult *pipeline.ParseResult
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to which put the `msg` value.
+	MessageField string `json:"message_field" default:"message"` //*
+
+	//> @3@4@5@6
+	//>
+	//> Which prefix to use for the fields of the `source` object.
+	SourcePrefix string `json:"source_prefix" default:"source_"` //*
+}
+
+// slog levels are integers: DEBUG=-4, INFO=0, WARN=4, ERROR=8,
+// intermediate levels are printed as an offset from the closest lower one, e.g. INFO+2
+var slogLevels = map[string]int{
+	"debug": -4,
+	"info":  0,
+	"warn":  4,
+	"error": 8,
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_slog",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_slog")
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	level := event.Root.Dig("level")
+	msg := event.Root.Dig("msg")
+	if !level.IsString() || msg == nil {
+		return pipeline.ActionPass
+	}
+
+	level.MutateToString(normalizeLevel(level.AsString()))
+
+	msg.Suicide()
+	event.Root.AddFieldNoAlloc(event.Root, p.config.MessageField).MutateToNode(msg)
+	// events without slog fields aren't slog records, so they aren't counted as failures
+	p.parseResult.Success()
+
+	source := event.Root.Dig("source")
+	if !source.IsObject() {
+		return pipeline.ActionPass
+	}
+
+	source.Suicide()
+
+	fields := source.AsFields()
+	for _, field := range fields {
+		l := len(event.Buf)
+		event.Buf = append(event.Buf, p.config.SourcePrefix...)
+		event.Buf = append(event.Buf, field.AsString()...)
+		field.MutateToField(pipeline.ByteToStringUnsafe(event.Buf[l:]))
+	}
+
+	event.Root.MergeWith(source)
+
+	return pipeline.ActionPass
+}
+
+// normalizeLevel converts slog level name into the lowercase name of the closest lower standard level.
+// Unknown levels are just lowercased.
+func normalizeLevel(level string) string {
+	level = strings.ToLower(level)
+
+	name, offset := level, 0
+	if pos := strings.IndexAny(level, "+-"); pos > 0 {
+		x, err := strconv.Atoi(level[pos:])
+		if err != nil {
+			return level
+		}
+		name, offset = level[:pos], x
+	}
+
+	value, has := slogLevels[name]
+	if !has {
+		return level
+	}
+
+	value += offset
+	switch {
+	case value < slogLevels["info"]:
+		return "debug"
+	case value < slogLevels["warn"]:
+		return "info"
+	case value < slogLevels["error"]:
+		return "warn"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,83 @@
+package parse_slog
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSlog(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"time":"2023-08-04T16:09:59.657014+03:00","level":"INFO","source":{"function":"main.main","file":"/app/main.go","line":17},"msg":"server started","port":8080}`))
+	input.In(0, "test.log", 0, []byte(`{"time":"2023-08-04T16:10:01.000000+03:00","level":"ERROR","msg":"request failed","err":"connection refused","req":{"method":"GET","path":"/api"}}`))
+	input.In(0, "test.log", 0, []byte(`{"time":"2023-08-04T16:10:02.000000+03:00","level":"DEBUG-4","msg":"trace"}`))
+	input.In(0, "test.log", 0, []byte(`{"level":"info","message":"not a slog record"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 4, len(outEvents), "wrong out events count")
+	assert.JSONEq(t, `{"time":"2023-08-04T16:09:59.657014+03:00","level":"info","port":8080,"message":"server started","source_function":"main.main","source_file":"/app/main.go","source_line":17}`, outEvents[0].Root.EncodeToString(), "wrong out event")
+	assert.JSONEq(t, `{"time":"2023-08-04T16:10:01.000000+03:00","level":"error","err":"connection refused","req":{"method":"GET","path":"/api"},"message":"request failed"}`, outEvents[1].Root.EncodeToString(), "wrong out event")
+	assert.JSONEq(t, `{"time":"2023-08-04T16:10:02.000000+03:00","level":"debug","message":"trace"}`, outEvents[2].Root.EncodeToString(), "wrong out event")
+	assert.JSONEq(t, `{"level":"info","message":"not a slog record"}`, outEvents[3].Root.EncodeToString(), "wrong out event")
+}
+
+func TestParseSlogCustomFields(t *testing.T) {
+	config := test.NewConfig(&Config{MessageField: "log", SourcePrefix: "caller."}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"level":"WARN","source":{"file":"main.go","line":3},"msg":"slow"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.JSONEq(t, `{"level":"warn","log":"slow","caller.file":"main.go","caller.line":3}`, outEvents[0].Root.EncodeToString(), "wrong out event")
+}
+
+func TestNormalizeLevel(t *testing.T) {
+	levels := map[string]string{
+		"DEBUG":    "debug",
+		"DEBUG-4":  "debug",
+		"DEBUG+3":  "debug",
+		"DEBUG+4":  "info",
+		"INFO":     "info",
+		"INFO+2":   "info",
+		"INFO-1":   "debug",
+		"WARN":     "warn",
+		"WARN+2":   "warn",
+		"WARN+4":   "error",
+		"ERROR":    "error",
+		"ERROR+8":  "error",
+		"info":     "info",
+		"FATAL":    "fatal",
+		"INFO+abc": "info+abc",
+	}
+
+	for level, expected := range levels {
+		assert.Equal(t, expected, normalizeLevel(level), "wrong level for %q", level)
+	}
+}
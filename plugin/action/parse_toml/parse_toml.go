@@ -0,0 +1,111 @@
+package parse_toml
+
+import (
+	"encoding/json"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It decodes a TOML document from the event field and merges the result with the event root.
+If the field can't be decoded, the event will be skipped
+and the failure will be counted by `parse_result_total` metric of the pipeline.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_toml
+      field: config
+    ...
+```
+It transforms `{"config":"service = \"api\"\n[limits]\nrps = 100"}` into `{"service":"api","limits":{"rps":100}}`.
+}*/
+type Plugin struct {
+	config      *Config
+	parseResult *pipeline.ParseResult
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to decode. Must be a string.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" required:"true"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to decoded object keys.
+	Prefix string `json:"prefix" default:""` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_toml",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_toml")
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	tomlNode := event.Root.Dig(p.config.Field_...)
+	if tomlNode == nil {
+		return pipeline.ActionPass
+	}
+
+	doc := make(map[string]interface{})
+	_, err := toml.Decode(tomlNode.AsString(), &doc)
+	if err != nil {
+		p.parseResult.Failure()
+		return pipeline.ActionPass
+	}
+
+	// TOML document is always a table, so no need to check the type of the decoded node
+	data, err := json.Marshal(doc)
+	if err != nil {
+		p.parseResult.Failure()
+		return pipeline.ActionPass
+	}
+
+	node, err := event.SubparseJSON(data)
+	if err != nil {
+		p.parseResult.Failure()
+		return pipeline.ActionPass
+	}
+
+	tomlNode.Suicide()
+
+	if p.config.Prefix != "" {
+		fields := node.AsFields()
+		for _, field := range fields {
+			l := len(event.Buf)
+			event.Buf = append(event.Buf, p.config.Prefix...)
+			event.Buf = append(event.Buf, field.AsString()...)
+			field.MutateToField(pipeline.ByteToStringUnsafe(event.Buf[l:]))
+		}
+	}
+
+	// place decoded object under root
+	event.Root.MergeWith(node)
+	p.parseResult.Success()
+
+	return pipeline.ActionPass
+}
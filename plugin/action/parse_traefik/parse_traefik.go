@@ -0,0 +1,240 @@
+package parse_traefik
+
+import (
+	"bytes"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ introduction
+It parses Traefik access logs and puts canonical fields into the event root.
+Both CLF and JSON access log formats are supported:
+* if the field is a string starting with `{`, it's decoded as a JSON access log;
+* if the field is another string, it's parsed as a CLF access log and removed;
+* if the field is absent, the event root is considered as a JSON access log.
+
+Canonical fields are: `client_ip`, `user`, `time`, `method`, `path`, `protocol`, `status`, `size`, `referer`, `user_agent`,
+`request_count`, `router`, `service`, `service_url`, `duration_ms`, `origin_duration_ms`, `overhead_ms`.
+`service`, `origin_duration_ms` and `overhead_ms` are available only for JSON format.
+Status, size, request count and durations are converted to numbers, `-` value of a numeric field is converted to `null`.
+If the line can't be parsed, the event is passed unchanged and the failure is counted by `parse_result_total` metric of the pipeline.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_traefik
+      field: message
+    ...
+```
+It transforms `{"message":"10.0.0.1 - - [01/Jul/2021:10:00:00 +0000] \"GET /api HTTP/1.1\" 200 1234 \"-\" \"curl/7.46.0\" 42 \"api@docker\" \"http://10.0.0.5:8080\" 15ms"}`
+into `{"client_ip":"10.0.0.1","user":"-","time":"01/Jul/2021:10:00:00 +0000","method":"GET","path":"/api","protocol":"HTTP/1.1","status":200,"size":1234,...,"router":"api@docker","service_url":"http://10.0.0.5:8080","duration_ms":15}`.
+}*/
+type Plugin struct {
+	config      *Config
+	parseResult *pipeline.ParseResult
+
+	tokens [][]byte
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to parse.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"message"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to canonical fields.
+	Prefix string `json:"prefix" default:""` //*
+}
+
+const (
+	kindString   = pipeline.ValueString
+	kindInt      = pipeline.ValueInt
+	kindDuration = pipeline.ValueFloat // milliseconds with `ms` suffix in CLF, nanoseconds in JSON
+)
+
+type field struct {
+	name string
+	kind pipeline.ValueKind
+}
+
+// clfFields is the field order of CLF access logs after the request is split into method, path and protocol,
+// see https://doc.traefik.io/traefik/observability/access-logs/#clf-common-log-format
+var clfFields = []field{
+	{"client_ip", kindString},
+	{"ident", kindString}, // always `-`, it isn't put to the event
+	{"user", kindString},
+	{"time", kindString},
+	{"request", kindString}, // it's split into method, path and protocol
+	{"status", kindInt},
+	{"size", kindInt},
+	{"referer", kindString},
+	{"user_agent", kindString},
+	{"request_count", kindInt},
+	{"router", kindString},
+	{"service_url", kindString},
+	{"duration_ms", kindDuration},
+}
+
+type jsonField struct {
+	field
+	key string
+}
+
+// jsonFields maps JSON access log fields to canonical ones,
+// see https://doc.traefik.io/traefik/observability/access-logs/#limiting-the-fieldsincluding-headers
+var jsonFields = []jsonField{
+	{field{"client_ip", kindString}, "ClientHost"},
+	{field{"user", kindString}, "ClientUsername"},
+	{field{"time", kindString}, "StartUTC"},
+	{field{"method", kindString}, "RequestMethod"},
+	{field{"path", kindString}, "RequestPath"},
+	{field{"protocol", kindString}, "RequestProtocol"},
+	{field{"status", kindInt}, "DownstreamStatus"},
+	{field{"size", kindInt}, "DownstreamContentSize"},
+	{field{"referer", kindString}, "request_Referer"},
+	{field{"user_agent", kindString}, "request_User-Agent"},
+	{field{"request_count", kindInt}, "RequestCount"},
+	{field{"router", kindString}, "RouterName"},
+	{field{"service", kindString}, "ServiceName"},
+	{field{"service_url", kindString}, "ServiceURL"},
+	{field{"duration_ms", kindDuration}, "Duration"},
+	{field{"origin_duration_ms", kindDuration}, "OriginDuration"},
+	{field{"overhead_ms", kindDuration}, "Overhead"},
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_traefik",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_traefik")
+	p.tokens = make([][]byte, 0, len(clfFields))
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	// the event root is parsed if the field is absent, events which aren't access logs aren't counted then
+	if node == nil {
+		if p.parseJSON(event, event.Root.Node) {
+			p.parseResult.Success()
+		}
+		return pipeline.ActionPass
+	}
+
+	value := node.AsBytes()
+	isParsed := false
+	if len(value) > 0 && value[0] == '{' {
+		jsonNode, err := event.SubparseJSON(value)
+		isParsed = err == nil && p.parseJSON(event, jsonNode)
+	} else {
+		isParsed = p.parseCLF(event, node)
+	}
+
+	if isParsed {
+		p.parseResult.Success()
+	} else {
+		p.parseResult.Failure()
+	}
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) parseJSON(event *pipeline.Event, node *insaneJSON.Node) bool {
+	if !node.IsObject() || node.Dig("DownstreamStatus") == nil {
+		return false
+	}
+
+	root := insaneJSON.Spawn()
+	for _, f := range jsonFields {
+		valueNode := node.Dig(f.key)
+		if valueNode == nil {
+			continue
+		}
+
+		value := p.addField(event, root, f.name)
+		if f.kind == kindDuration {
+			// JSON durations are in nanoseconds
+			value.MutateToFloat(float64(valueNode.AsInt()) / 1e6)
+			continue
+		}
+		pipeline.MutateToValue(value, f.kind, valueNode.AsBytes())
+	}
+
+	event.Root.MergeWith(root.Node)
+	insaneJSON.Release(root)
+
+	return true
+}
+
+func (p *Plugin) parseCLF(event *pipeline.Event, node *insaneJSON.Node) bool {
+	var ok bool
+	p.tokens, ok = pipeline.Tokenize(p.tokens[:0], node.AsBytes(), true)
+	if !ok || len(p.tokens) < len(clfFields) {
+		return false
+	}
+
+	request := bytes.Split(p.tokens[4], []byte(" "))
+	if len(request) != 3 {
+		return false
+	}
+
+	node.Suicide()
+
+	root := insaneJSON.Spawn()
+	for i, f := range clfFields {
+		token := p.tokens[i]
+		switch f.name {
+		case "ident":
+			continue
+		case "request":
+			p.addField(event, root, "method").MutateToBytes(request[0])
+			p.addField(event, root, "path").MutateToBytes(request[1])
+			p.addField(event, root, "protocol").MutateToBytes(request[2])
+			continue
+		}
+
+		if f.kind == kindDuration {
+			token = bytes.TrimSuffix(token, []byte("ms"))
+		}
+		pipeline.MutateToValue(p.addField(event, root, f.name), f.kind, token)
+	}
+
+	event.Root.MergeWith(root.Node)
+	insaneJSON.Release(root)
+
+	return true
+}
+
+func (p *Plugin) addField(event *pipeline.Event, root *insaneJSON.Root, name string) *insaneJSON.Node {
+	if p.config.Prefix == "" {
+		return root.AddFieldNoAlloc(root, name)
+	}
+
+	l := len(event.Buf)
+	event.Buf = append(event.Buf, p.config.Prefix...)
+	event.Buf = append(event.Buf, name...)
+
+	return root.AddFieldNoAlloc(root, pipeline.ByteToStringUnsafe(event.Buf[l:]))
+}
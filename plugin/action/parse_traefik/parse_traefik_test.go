@@ -0,0 +1,96 @@
+package parse_traefik
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraefikCLF(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	lines := []string{
+		`10.0.0.1 - bob [01/Jul/2021:10:00:00 +0000] "GET /api/users?id=1 HTTP/1.1" 200 1234 "https://example.com/" "Mozilla/5.0 (X11; Linux x86_64)" 42 "api@docker" "http://10.0.0.5:8080" 15ms`,
+		`10.0.0.2 - - [01/Jul/2021:10:00:01 +0000] "POST /login HTTP/2.0" 502 - "-" "curl/7.46.0" 43 "auth@file" "http://10.0.0.6:80" 0ms`,
+		`10.0.0.3 - - [01/Jul/2021:10:00:02 +0000] "GET /broken`,
+	}
+	for _, line := range lines {
+		input.In(0, "test.log", 0, []byte(`{"message":`+strconv.Quote(line)+`}`))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 3, len(outEvents), "wrong out events count")
+	assert.JSONEq(t, `{"client_ip":"10.0.0.1","user":"bob","time":"01/Jul/2021:10:00:00 +0000","method":"GET","path":"/api/users?id=1","protocol":"HTTP/1.1","status":200,"size":1234,"referer":"https://example.com/","user_agent":"Mozilla/5.0 (X11; Linux x86_64)","request_count":42,"router":"api@docker","service_url":"http://10.0.0.5:8080","duration_ms":15}`, outEvents[0], "wrong out event")
+	assert.JSONEq(t, `{"client_ip":"10.0.0.2","user":"-","time":"01/Jul/2021:10:00:01 +0000","method":"POST","path":"/login","protocol":"HTTP/2.0","status":502,"size":null,"referer":"-","user_agent":"curl/7.46.0","request_count":43,"router":"auth@file","service_url":"http://10.0.0.6:80","duration_ms":0}`, outEvents[1], "wrong out event")
+	assert.JSONEq(t, `{"message":`+strconv.Quote(lines[2])+`}`, outEvents[2], "broken line shouldn't be changed")
+
+	results := p.GetMetricsCtl().RegisterCounter("parse_result_total", "")
+	assert.Equal(t, float64(2), testutil.ToFloat64(results.WithLabelValues("parse_traefik", "success")), "wrong success count")
+	assert.Equal(t, float64(1), testutil.ToFloat64(results.WithLabelValues("parse_traefik", "failure")), "wrong failure count")
+}
+
+func TestParseTraefikJSON(t *testing.T) {
+	config := test.NewConfig(&Config{Prefix: "traefik_"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	accessLog := `{"ClientHost":"10.0.0.1","DownstreamContentSize":1234,"DownstreamStatus":200,"Duration":15500000,"OriginDuration":14000000,"Overhead":1500000,"RequestCount":42,"RequestMethod":"GET","RequestPath":"/api","RequestProtocol":"HTTP/1.1","RouterName":"api@docker","ServiceName":"api-service@docker","ServiceURL":"http://10.0.0.5:8080","StartUTC":"2021-07-01T10:00:00.000000000Z","request_User-Agent":"curl/7.46.0","level":"info","msg":""}`
+	input.In(0, "test.log", 0, []byte(accessLog))
+	input.In(0, "test.log", 0, []byte(`{"message":`+strconv.Quote(accessLog)+`}`))
+	input.In(0, "test.log", 0, []byte(`{"level":"info","msg":"not an access log"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 3, len(outEvents), "wrong out events count")
+
+	// the root which isn't an access log isn't counted
+	results := p.GetMetricsCtl().RegisterCounter("parse_result_total", "")
+	assert.Equal(t, float64(2), testutil.ToFloat64(results.WithLabelValues("parse_traefik", "success")), "wrong success count")
+	assert.Equal(t, float64(0), testutil.ToFloat64(results.WithLabelValues("parse_traefik", "failure")), "wrong failure count")
+
+	for _, e := range outEvents[:2] {
+		assert.Equal(t, "10.0.0.1", e.Root.Dig("traefik_client_ip").AsString(), "wrong field value")
+		assert.Equal(t, "GET", e.Root.Dig("traefik_method").AsString(), "wrong field value")
+		assert.Equal(t, "/api", e.Root.Dig("traefik_path").AsString(), "wrong field value")
+		assert.Equal(t, 200, e.Root.Dig("traefik_status").AsInt(), "wrong field value")
+		assert.Equal(t, 1234, e.Root.Dig("traefik_size").AsInt(), "wrong field value")
+		assert.Equal(t, 42, e.Root.Dig("traefik_request_count").AsInt(), "wrong field value")
+		assert.Equal(t, "api@docker", e.Root.Dig("traefik_router").AsString(), "wrong field value")
+		assert.Equal(t, "api-service@docker", e.Root.Dig("traefik_service").AsString(), "wrong field value")
+		assert.Equal(t, "http://10.0.0.5:8080", e.Root.Dig("traefik_service_url").AsString(), "wrong field value")
+		assert.Equal(t, "curl/7.46.0", e.Root.Dig("traefik_user_agent").AsString(), "wrong field value")
+		assert.Equal(t, 15.5, e.Root.Dig("traefik_duration_ms").AsFloat(), "wrong field value")
+		assert.Equal(t, 14.0, e.Root.Dig("traefik_origin_duration_ms").AsFloat(), "wrong field value")
+		assert.Equal(t, 1.5, e.Root.Dig("traefik_overhead_ms").AsFloat(), "wrong field value")
+		assert.Nil(t, e.Root.Dig("traefik_referer"), "absent field shouldn't be set")
+	}
+	assert.NotNil(t, outEvents[0].Root.Dig("RouterName"), "original field is removed")
+	assert.NotNil(t, outEvents[1].Root.Dig("message"), "JSON access log field is removed")
+	assert.Equal(t, `{"level":"info","msg":"not an access log"}`, outEvents[2].Root.EncodeToString(), "wrong out event")
+}
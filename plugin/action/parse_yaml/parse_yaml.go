@@ -0,0 +1,101 @@
+package parse_yaml
+
+import (
+	"github.com/ghodss/yaml"
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It decodes a YAML document from the event field and merges the result with the event root.
+If the field can't be decoded or the decoded document isn't an object, the event will be skipped
+and the failure will be counted by `parse_result_total` metric of the pipeline.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_yaml
+      field: config
+    ...
+```
+It transforms `{"config":"service: api\nlimits:\n  rps: 100"}` into `{"service":"api","limits":{"rps":100}}`.
+}*/
+type Plugin struct {
+	config      *Config
+	parseResult *pipeline.ParseResult
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to decode. Must be a string.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" required:"true"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to decoded object keys.
+	Prefix string `json:"prefix" default:""` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "parse_yaml",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.parseResult = pipeline.NewParseResult(params.MetricsCtl, "parse_yaml")
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	yamlNode := event.Root.Dig(p.config.Field_...)
+	if yamlNode == nil {
+		return pipeline.ActionPass
+	}
+
+	json, err := yaml.YAMLToJSON(yamlNode.AsBytes())
+	if err != nil {
+		p.parseResult.Failure()
+		return pipeline.ActionPass
+	}
+
+	node, err := event.SubparseJSON(json)
+	if err != nil || !node.IsObject() {
+		p.parseResult.Failure()
+		return pipeline.ActionPass
+	}
+
+	yamlNode.Suicide()
+
+	if p.config.Prefix != "" {
+		fields := node.AsFields()
+		for _, field := range fields {
+			l := len(event.Buf)
+			event.Buf = append(event.Buf, p.config.Prefix...)
+			event.Buf = append(event.Buf, field.AsString()...)
+			field.MutateToField(pipeline.ByteToStringUnsafe(event.Buf[l:]))
+		}
+	}
+
+	// place decoded object under root
+	event.Root.MergeWith(node)
+	p.parseResult.Success()
+
+	return pipeline.ActionPass
+}
@@ -0,0 +1,88 @@
+package parse_yaml
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseYAML(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "doc"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"doc":"service: api\nreplicas: 3\nlimits:\n  rps: 100\n  burst: 1.5\ntags:\n- a\n- b\nowner:\n  team:\n    name: core\n    oncall: true"}`))
+	input.In(0, "test.log", 0, []byte(`{"doc":"key: value","level":"info"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 2, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"limits":{"burst":1.5,"rps":100},"owner":{"team":{"name":"core","oncall":true}},"replicas":3,"service":"api","tags":["a","b"]}`, outEvents[0].Root.EncodeToString(), "wrong out event")
+	assert.Equal(t, `{"level":"info","key":"value"}`, outEvents[1].Root.EncodeToString(), "wrong out event")
+}
+
+func TestParseYAMLPrefix(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "doc", Prefix: "doc_"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"doc":"a: 1\nb:\n  c: 2"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, `{"doc_a":1,"doc_b":{"c":2}}`, outEvents[0].Root.EncodeToString(), "wrong out event")
+}
+
+func TestParseYAMLMalformed(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "doc"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"doc":"key: [unclosed"}`))
+	input.In(0, "test.log", 0, []byte(`{"doc":"- just\n- a list"}`))
+	input.In(0, "test.log", 0, []byte(`{"doc":"a: 1\n  b: 2"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"no doc"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 4, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"doc":"key: [unclosed"}`, outEvents[0].Root.EncodeToString(), "wrong out event")
+	assert.Equal(t, `{"doc":"- just\n- a list"}`, outEvents[1].Root.EncodeToString(), "wrong out event")
+	assert.Equal(t, `{"doc":"a: 1\n  b: 2"}`, outEvents[2].Root.EncodeToString(), "wrong out event")
+	assert.Equal(t, `{"message":"no doc"}`, outEvents[3].Root.EncodeToString(), "wrong out event")
+
+	results := p.GetMetricsCtl().RegisterCounter("parse_result_total", "")
+	assert.Equal(t, float64(0), testutil.ToFloat64(results.WithLabelValues("parse_yaml", "success")), "wrong success count")
+	assert.Equal(t, float64(3), testutil.ToFloat64(results.WithLabelValues("parse_yaml", "failure")), "wrong failure count")
+}
@@ -0,0 +1,233 @@
+package per_key_limit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultMaxKeys       = 100000
+	defaultMaxMetricKeys = 100
+
+	otherMetricKey = "_other"
+)
+
+var (
+	// limiters should be shared across processors of the action, all of them receive the same config, so let's have a map by config
+	limiters   = map[*Config]*limiter{}
+	limitersMu = &sync.Mutex{}
+)
+
+/*{ introduction
+It limits the rate of events for each value of the key field: no more than `max_rate` events per `interval` are passed for a key,
+the excess is discarded. So a noisy key doesn't affect events of other keys.
+
+Discarded events are counted by `per_key_limit_dropped_events_total` metric of the pipeline with the `key` label.
+To keep the metric cardinality bounded, only first `max_metric_keys` keys get their own label value, others are counted as `_other`.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: per_key_limit
+      key_field: tenant
+      max_rate: 1000
+      interval: 1s
+    ...
+```
+}*/
+type Plugin struct {
+	config  *Config
+	limiter *limiter
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field which value is used as a key. Events without the field have the same empty key.
+	KeyField  cfg.FieldSelector `json:"key_field" parse:"selector" required:"true"` //*
+	KeyField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The maximum number of events of a key which are passed per `interval`.
+	MaxRate int `json:"max_rate" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> Time interval to count the events rate.
+	Interval  cfg.Duration `json:"interval" parse:"duration" default:"1s"` //*
+	Interval_ time.Duration
+
+	//> @3@4@5@6
+	//>
+	//> The maximum number of keys to track. When the limit is reached, keys with expired intervals are removed.
+	//> Active keys are never removed, so events of new keys aren't limited until some keys expire. `100000` if not set.
+	MaxKeys int `json:"max_keys"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The maximum number of distinct `key` label values of the drop metric. `100` if not set.
+	MaxMetricKeys int `json:"max_metric_keys"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "per_key_limit",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	if p.config.MaxRate <= 0 {
+		params.Logger.Fatalf("max_rate should be positive, got=%d", p.config.MaxRate)
+	}
+
+	dropped := params.MetricsCtl.RegisterCounter("per_key_limit_dropped_events_total", "how many events are discarded by per_key_limit action", "key")
+
+	limitersMu.Lock()
+	l, has := limiters[p.config]
+	if !has {
+		l = newLimiter(p.config, dropped, time.Now)
+		limiters[p.config] = l
+	}
+	limitersMu.Unlock()
+
+	p.limiter = l
+}
+
+func (p *Plugin) Stop() {
+	limitersMu.Lock()
+	delete(limiters, p.config)
+	limitersMu.Unlock()
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	if p.limiter.isAllowed(event.Root.Dig(p.config.KeyField_...).AsBytes()) {
+		return pipeline.ActionPass
+	}
+
+	return pipeline.ActionDiscard
+}
+
+type keyState struct {
+	windowStart time.Time
+	count       int
+}
+
+type limiter struct {
+	mu *sync.Mutex
+
+	maxRate       int
+	interval      time.Duration
+	maxKeys       int
+	maxMetricKeys int
+	nowFn         func() time.Time
+
+	keys       map[string]*keyState
+	cleanedAt  time.Time
+	dropped    *prometheus.CounterVec
+	metricKeys map[string]prometheus.Counter
+}
+
+// newLimiter resolves defaults into the limiter, the config isn't changed since it identifies the shared limiter
+func newLimiter(config *Config, dropped *prometheus.CounterVec, nowFn func() time.Time) *limiter {
+	maxKeys := config.MaxKeys
+	if maxKeys <= 0 {
+		maxKeys = defaultMaxKeys
+	}
+	maxMetricKeys := config.MaxMetricKeys
+	if maxMetricKeys <= 0 {
+		maxMetricKeys = defaultMaxMetricKeys
+	}
+
+	return &limiter{
+		mu: &sync.Mutex{},
+
+		maxRate:       config.MaxRate,
+		interval:      config.Interval_,
+		maxKeys:       maxKeys,
+		maxMetricKeys: maxMetricKeys,
+		nowFn:         nowFn,
+
+		keys:       make(map[string]*keyState),
+		dropped:    dropped,
+		metricKeys: make(map[string]prometheus.Counter),
+	}
+}
+
+func (l *limiter) isAllowed(key []byte) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.nowFn()
+	state, has := l.keys[string(key)]
+	if !has {
+		if len(l.keys) >= l.maxKeys {
+			l.removeExpired(now)
+		}
+		// active keys aren't removed to keep limiting them, so events of new keys aren't limited until some keys expire
+		if len(l.keys) >= l.maxKeys {
+			return true
+		}
+		state = &keyState{windowStart: now}
+		l.keys[string(key)] = state
+	}
+
+	if now.Sub(state.windowStart) >= l.interval {
+		state.windowStart = now
+		state.count = 0
+	}
+
+	if state.count >= l.maxRate {
+		l.droppedCounter(key).Inc()
+		return false
+	}
+
+	state.count++
+	return true
+}
+
+// removeExpired removes keys with expired intervals, keys are checked at most once per interval,
+// so a flood of new keys doesn't make each event scan all keys
+func (l *limiter) removeExpired(now time.Time) {
+	if now.Sub(l.cleanedAt) < l.interval {
+		return
+	}
+	l.cleanedAt = now
+
+	for key, state := range l.keys {
+		if now.Sub(state.windowStart) >= l.interval {
+			delete(l.keys, key)
+		}
+	}
+}
+
+func (l *limiter) droppedCounter(key []byte) prometheus.Counter {
+	if counter, has := l.metricKeys[string(key)]; has {
+		return counter
+	}
+
+	if len(l.metricKeys) >= l.maxMetricKeys {
+		return l.dropped.WithLabelValues(otherMetricKey)
+	}
+
+	counter := l.dropped.WithLabelValues(string(key))
+	l.metricKeys[string(key)] = counter
+
+	return counter
+}
@@ -0,0 +1,138 @@
+package per_key_limit
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ozonru/file.d/logger"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPerKeyLimit(t *testing.T) {
+	config := test.NewConfig(&Config{KeyField: "tenant", MaxRate: 10, Interval: "1m"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	quiet := []string{"a", "b", "c", "d"}
+	noisyCount := 100
+	quietCount := 5
+
+	wg := &sync.WaitGroup{}
+	wg.Add(10 + len(quiet)*quietCount)
+
+	passed := make(map[string]int)
+	output.SetOutFn(func(e *pipeline.Event) {
+		passed[e.Root.Dig("tenant").AsString()]++
+		wg.Done()
+	})
+
+	// quiet events go last, so all noisy events are processed when they are passed
+	for i := 0; i < noisyCount; i++ {
+		input.In(0, "test.log", 0, []byte(`{"tenant":"noisy"}`))
+	}
+	for i := 0; i < quietCount; i++ {
+		for _, key := range quiet {
+			input.In(0, "test.log", 0, []byte(`{"tenant":"`+key+`"}`))
+		}
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 10, passed["noisy"], "wrong passed events count for noisy key")
+	for _, key := range quiet {
+		assert.Equal(t, quietCount, passed[key], "wrong passed events count for quiet key %q", key)
+	}
+
+	dropped := p.GetMetricsCtl().RegisterCounter("per_key_limit_dropped_events_total", "", "key")
+	assert.Equal(t, float64(noisyCount-10), testutil.ToFloat64(dropped.WithLabelValues("noisy")), "wrong dropped metric")
+	assert.Equal(t, float64(0), testutil.ToFloat64(dropped.WithLabelValues("a")), "wrong dropped metric")
+}
+
+func TestPerKeyLimitInterval(t *testing.T) {
+	now := time.Now()
+	config := &Config{MaxRate: 2, Interval_: time.Second, MaxKeys: 2, MaxMetricKeys: 1}
+	dropped := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "dropped"}, []string{"key"})
+	l := newLimiter(config, dropped, func() time.Time { return now })
+
+	assert.True(t, l.isAllowed([]byte("a")), "event should be allowed")
+	assert.True(t, l.isAllowed([]byte("a")), "event should be allowed")
+	assert.False(t, l.isAllowed([]byte("a")), "event shouldn't be allowed")
+
+	now = now.Add(time.Second)
+	assert.True(t, l.isAllowed([]byte("a")), "event should be allowed in the next interval")
+
+	assert.True(t, l.isAllowed([]byte("b")), "event should be allowed")
+	assert.True(t, l.isAllowed([]byte("b")), "event should be allowed")
+	assert.False(t, l.isAllowed([]byte("b")), "event shouldn't be allowed")
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(dropped.WithLabelValues("a")), "wrong dropped metric")
+	assert.Equal(t, float64(1), testutil.ToFloat64(dropped.WithLabelValues(otherMetricKey)), "metric keys aren't capped")
+	assert.Equal(t, float64(0), testutil.ToFloat64(dropped.WithLabelValues("b")), "metric keys aren't capped")
+
+	now = now.Add(time.Second)
+	assert.True(t, l.isAllowed([]byte("c")), "event should be allowed")
+	assert.Equal(t, 1, len(l.keys), "expired keys aren't removed")
+}
+
+func TestPerKeyLimitMaxKeys(t *testing.T) {
+	now := time.Now()
+	config := &Config{MaxRate: 2, Interval_: time.Second, MaxKeys: 3, MaxMetricKeys: 1}
+	dropped := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "dropped"}, []string{"key"})
+	l := newLimiter(config, dropped, func() time.Time { return now })
+
+	assert.True(t, l.isAllowed([]byte("noisy")), "event should be allowed")
+	assert.True(t, l.isAllowed([]byte("noisy")), "event should be allowed")
+
+	// quiet keys fill the map, but the noisy key keeps its state
+	for i := 0; i < 10; i++ {
+		assert.True(t, l.isAllowed([]byte(fmt.Sprintf("quiet_%d", i))), "event of a quiet key should be allowed")
+		assert.False(t, l.isAllowed([]byte("noisy")), "noisy key isn't limited after the flood of keys")
+	}
+	assert.Equal(t, 3, len(l.keys), "keys aren't capped")
+
+	// keys are removed once their intervals expire
+	now = now.Add(time.Second)
+	assert.True(t, l.isAllowed([]byte("quiet_new")), "event should be allowed")
+	assert.Equal(t, 1, len(l.keys), "expired keys aren't removed")
+}
+
+func TestPerKeyLimitLimiterPerAction(t *testing.T) {
+	start := func(config *Config) *Plugin {
+		plugin := &Plugin{}
+		plugin.Start(config, &pipeline.ActionPluginParams{PluginDefaultParams: test.NewEmptyPluginDefaultParams(), Logger: logger.Instance})
+		return plugin
+	}
+
+	config := test.NewConfig(&Config{KeyField: "tenant", MaxRate: 10, Interval: "1m"}, nil).(*Config)
+	first := start(config)
+	second := start(config)
+	other := start(test.NewConfig(&Config{KeyField: "service", MaxRate: 10, Interval: "1m"}, nil).(*Config))
+
+	assert.True(t, first.limiter == second.limiter, "processors of the action should share the limiter")
+	assert.True(t, first.limiter != other.limiter, "actions of the pipeline shouldn't share the limiter")
+
+	first.Stop()
+	second.Stop()
+	other.Stop()
+
+	restarted := start(config)
+	assert.True(t, first.limiter != restarted.limiter, "limiter shouldn't survive the action stop")
+	restarted.Stop()
+}
+
+func TestPerKeyLimitDefaults(t *testing.T) {
+	config := &Config{MaxRate: 2, Interval_: time.Second}
+	dropped := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "dropped"}, []string{"key"})
+	l := newLimiter(config, dropped, time.Now)
+
+	assert.Equal(t, defaultMaxKeys, l.maxKeys, "wrong max keys")
+	assert.Equal(t, defaultMaxMetricKeys, l.maxMetricKeys, "wrong max metric keys")
+	assert.Equal(t, 0, config.MaxKeys, "shared config shouldn't be changed")
+	assert.Equal(t, 0, config.MaxMetricKeys, "shared config shouldn't be changed")
+}
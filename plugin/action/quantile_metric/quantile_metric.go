@@ -0,0 +1,146 @@
+package quantile_metric
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It observes the numeric event field in the summary metric, so quantiles of the field can be queried from Prometheus.
+The metric is named `file_d_pipeline_<pipeline>_<metric_name>`, label values are taken from `labels` event fields.
+Label names are field names with `.` replaced by `_`. Events which have no numeric value aren't observed.
+Events are always passed unchanged.
+
+> ⚠ Label values should have low cardinality, each combination of values creates a new time series.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: quantile_metric
+      value_field: latency_ms
+      metric_name: request_latency_ms
+      labels:
+        - service
+        - request.method
+      quantiles: [0.5, 0.95]
+    ...
+```
+It exposes `file_d_pipeline_example_pipeline_request_latency_ms{service="api",request_method="GET",quantile="0.95"}` and so on.
+}*/
+type Plugin struct {
+	config  *Config
+	logger  *zap.SugaredLogger
+	summary *prometheus.SummaryVec
+	labels  [][]string
+	values  []string
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field which value is observed. It may be a number or a numeric string.
+	ValueField  cfg.FieldSelector `json:"value_field" parse:"selector" required:"true"` //*
+	ValueField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The name of the metric.
+	MetricName string `json:"metric_name" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The list of event fields which values are used as label values.
+	Labels []cfg.FieldSelector `json:"labels"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The list of quantiles to compute, `[0.5, 0.95, 0.99]` if not set.
+	Quantiles []float64 `json:"quantiles"` //*
+}
+
+const maxObjectiveError = 0.01
+
+var defaultQuantiles = []float64{0.5, 0.95, 0.99}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "quantile_metric",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger
+
+	if len(p.config.Quantiles) == 0 {
+		p.config.Quantiles = defaultQuantiles
+	}
+
+	objectives := make(map[float64]float64, len(p.config.Quantiles))
+	for _, q := range p.config.Quantiles {
+		if q <= 0 || q >= 1 {
+			p.logger.Fatalf("quantile should be in (0, 1), got=%v", q)
+		}
+		objectives[q] = objectiveError(q)
+	}
+
+	labelNames := make([]string, 0, len(p.config.Labels))
+	p.labels = make([][]string, 0, len(p.config.Labels))
+	for _, label := range p.config.Labels {
+		labelNames = append(labelNames, strings.ReplaceAll(string(label), ".", "_"))
+		p.labels = append(p.labels, cfg.ParseFieldSelector(string(label)))
+	}
+	p.values = make([]string, len(p.labels))
+
+	p.summary = params.MetricsCtl.RegisterSummary(p.config.MetricName, "Quantiles of "+string(p.config.ValueField)+" field", objectives, labelNames...)
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.ValueField_...)
+	if node == nil || !(node.IsNumber() || node.IsString()) {
+		return pipeline.ActionPass
+	}
+
+	value, err := strconv.ParseFloat(node.AsString(), 64)
+	if err != nil {
+		return pipeline.ActionPass
+	}
+
+	for i, label := range p.labels {
+		p.values[i] = event.Root.Dig(label...).AsString()
+	}
+
+	p.summary.WithLabelValues(p.values...).Observe(value)
+
+	return pipeline.ActionPass
+}
+
+// objectiveError returns the absolute error of the quantile, the closer quantile to the edge the more precise it is
+func objectiveError(q float64) float64 {
+	e := q / 10
+	if q > 0.5 {
+		e = (1 - q) / 10
+	}
+
+	return math.Min(e, maxObjectiveError)
+}
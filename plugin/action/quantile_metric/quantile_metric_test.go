@@ -0,0 +1,98 @@
+package quantile_metric
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func getQuantiles(t *testing.T, summary *prometheus.SummaryVec, labels ...string) (uint64, map[float64]float64) {
+	metric := &dto.Metric{}
+	err := summary.WithLabelValues(labels...).(prometheus.Metric).Write(metric)
+	assert.NoError(t, err, "can't write metric")
+
+	quantiles := make(map[float64]float64)
+	for _, q := range metric.GetSummary().GetQuantile() {
+		quantiles[q.GetQuantile()] = q.GetValue()
+	}
+
+	return metric.GetSummary().GetSampleCount(), quantiles
+}
+
+func TestQuantileMetric(t *testing.T) {
+	config := test.NewConfig(&Config{
+		ValueField: "latency_ms",
+		MetricName: "latency_ms",
+		Labels:     []cfg.FieldSelector{"service", "request.method"},
+	}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1000 + 100 + 2)
+
+	output.SetOutFn(func(e *pipeline.Event) {
+		wg.Done()
+	})
+
+	for i := 1; i <= 1000; i++ {
+		input.In(0, "test.log", 0, []byte(`{"service":"api","request":{"method":"GET"},"latency_ms":`+strconv.Itoa(i)+`}`))
+	}
+	for i := 1; i <= 100; i++ {
+		input.In(0, "test.log", 0, []byte(`{"service":"api","request":{"method":"POST"},"latency_ms":"`+strconv.Itoa(i*10)+`"}`))
+	}
+	input.In(0, "test.log", 0, []byte(`{"service":"api","request":{"method":"GET"},"latency_ms":"fast"}`))
+	input.In(0, "test.log", 0, []byte(`{"service":"api","request":{"method":"GET"}}`))
+
+	wg.Wait()
+	p.Stop()
+
+	summary := p.GetMetricsCtl().RegisterSummary("latency_ms", "", nil)
+
+	count, quantiles := getQuantiles(t, summary, "api", "GET")
+	assert.Equal(t, uint64(1000), count, "wrong sample count")
+	assert.Equal(t, 3, len(quantiles), "wrong quantiles count")
+	// the rank error of the summary may be twice as much as the objective error
+	assert.InDelta(t, 500, quantiles[0.5], 1000*0.01*2, "wrong p50")
+	assert.InDelta(t, 950, quantiles[0.95], 1000*0.005*2, "wrong p95")
+	assert.InDelta(t, 990, quantiles[0.99], 1000*0.001*2, "wrong p99")
+
+	count, quantiles = getQuantiles(t, summary, "api", "POST")
+	assert.Equal(t, uint64(100), count, "wrong sample count")
+	assert.InDelta(t, 500, quantiles[0.5], 1000*0.01*2, "wrong p50")
+	assert.InDelta(t, 950, quantiles[0.95], 1000*0.005*2, "wrong p95")
+}
+
+func TestQuantileMetricQuantiles(t *testing.T) {
+	config := test.NewConfig(&Config{
+		ValueField: "size",
+		MetricName: "size",
+		Quantiles:  []float64{0.9},
+	}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(100)
+
+	output.SetOutFn(func(e *pipeline.Event) {
+		wg.Done()
+	})
+
+	for i := 1; i <= 100; i++ {
+		input.In(0, "test.log", 0, []byte(`{"size":`+strconv.Itoa(i)+`}`))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	count, quantiles := getQuantiles(t, p.GetMetricsCtl().RegisterSummary("size", "", nil))
+	assert.Equal(t, uint64(100), count, "wrong sample count")
+	assert.Equal(t, 1, len(quantiles), "wrong quantiles count")
+	assert.InDelta(t, 90, quantiles[0.9], 100*0.01*2, "wrong p90")
+}
@@ -7,8 +7,10 @@ import (
 )
 
 /*{ introduction
-It renames the fields of the event. You can provide an unlimited number of config parameters. Each parameter handled as `cfg.FieldSelector`:`string`.
-When `override` is set to `false`, the field won't be renamed in the case of field name collision.
+It renames the fields of the event. You can provide an unlimited number of config parameters. Each parameter handled as `cfg.FieldSelector`:`cfg.FieldSelector`.
+So fields can be moved to nested destinations, intermediate objects are created if needed. Escape dots with `\` to use them in field names.
+When `override` is set to `false`, the field won't be renamed in the case of field name collision,
+it also includes the case when an intermediate field of the destination exists and isn't an object.
 Sequence of rename operations isn't guaranteed. Use different actions for prioritization.
 
 **Example:**
@@ -20,6 +22,7 @@ pipelines:
     - type: rename
       override: false
       my_object.field.subfield: new_sub_field
+      request_id: meta.request.id
     ...
 ```
 
@@ -28,14 +31,20 @@ The resulting event could look like:
 {
   "my_object": {
     "field": {
-      "new_sub_field":"value"
     }
   },
+  "new_sub_field":"value",
+  "meta": {
+    "request": {
+      "id":"42"
+    }
+  }
+}
 ```
 }*/
 type Plugin struct {
 	paths          [][]string
-	names          []string
+	names          [][]string
 	preserveFields bool
 }
 
@@ -67,7 +76,7 @@ func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams
 	for path, name := range m {
 		selector := cfg.ParseFieldSelector(path)
 		p.paths = append(p.paths, selector)
-		p.names = append(p.names, name)
+		p.names = append(p.names, cfg.ParseFieldSelector(name))
 	}
 }
 
@@ -76,10 +85,8 @@ func (p *Plugin) Stop() {
 
 func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
 	for index, path := range p.paths {
-		if p.preserveFields {
-			if event.Root.Dig(p.names[index]) != nil {
-				continue
-			}
+		if p.preserveFields && isCollision(event, p.names[index]) {
+			continue
 		}
 
 		node := event.Root.Dig(path...)
@@ -88,8 +95,24 @@ func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
 		}
 
 		node.Suicide()
-		event.Root.AddFieldNoAlloc(event.Root, p.names[index]).MutateToNode(node)
+		pipeline.CreateNestedField(event.Root, p.names[index]).MutateToNode(node)
 	}
 
 	return pipeline.ActionPass
 }
+
+// isCollision checks if the destination field exists or can't be created without overriding a non-object field
+func isCollision(event *pipeline.Event, name []string) bool {
+	node := event.Root.Node
+	for _, field := range name {
+		node = node.Dig(field)
+		if node == nil {
+			return false
+		}
+		if !node.IsObject() {
+			return true
+		}
+	}
+
+	return true
+}
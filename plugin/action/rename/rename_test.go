@@ -42,10 +42,79 @@ func TestRename(t *testing.T) {
 	assert.Equal(t, "value_2", outEvents[1].Root.Dig("renamed_field_2").AsString(), "wrong field value")
 	assert.Equal(t, "value_3", outEvents[2].Root.Dig("field_3").AsString(), "wrong field value")
 	assert.Equal(t, "value_5", outEvents[3].Root.Dig("renamed_field_5").AsString(), "wrong field value")
-	assert.Equal(t, "value_6", outEvents[4].Root.Dig("renamed_field.escaped").AsString(), "wrong field value")
+	assert.Equal(t, "value_6", outEvents[4].Root.Dig("renamed_field", "escaped").AsString(), "wrong field value")
 	assert.Nil(t, outEvents[0].Root.Dig("field_1"), "field isn't nil")
 	assert.Nil(t, outEvents[1].Root.Dig("field_2"), "field isn't nil")
 	assert.Nil(t, outEvents[2].Root.Dig("renamed_field_3"), "field isn't nil")
 	assert.Nil(t, outEvents[3].Root.Dig("field_4", "field_5"), "field isn't nil")
 	assert.Nil(t, outEvents[4].Root.Dig("k8s_node_label_topology\\.kubernetes\\.io/zone"), "field isn't nil")
 }
+
+func TestRenameNested(t *testing.T) {
+	config := &Config{
+		"request_id":    "meta.request.id",
+		"user.name":     "meta.user",
+		"source\\.file": "meta.file\\.name",
+		"absent.field":  "meta.absent",
+		"target.value":  "status",
+		"override":      false,
+	}
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"request_id":"42","user":{"name":"alice","id":1}}`))
+	input.In(0, "test.log", 0, []byte(`{"request_id":"43","meta":{"request":{"id":"1"}}}`))
+	input.In(0, "test.log", 0, []byte(`{"request_id":"44","meta":"string","source.file":"app.log"}`))
+	input.In(0, "test.log", 0, []byte(`{"source.file":"app.log","meta":{"host":"h1"},"target":{"value":"ok"}}`))
+
+	wg.Wait()
+	p.Stop()
+
+	// sequence of rename operations isn't guaranteed, so fields order may differ
+	expected := []string{
+		`{"user":{"id":1},"meta":{"request":{"id":"42"},"user":"alice"}}`,
+		`{"request_id":"43","meta":{"request":{"id":"1"}}}`,
+		`{"request_id":"44","meta":"string","source.file":"app.log"}`,
+		`{"meta":{"host":"h1","file.name":"app.log"},"target":{},"status":"ok"}`,
+	}
+	assert.Equal(t, len(expected), len(outEvents), "wrong out events count")
+	for i := range expected {
+		assert.JSONEq(t, expected[i], outEvents[i], "wrong out event")
+	}
+}
+
+func TestRenameNestedOverride(t *testing.T) {
+	config := &Config{
+		"request_id": "meta.request.id",
+		"override":   true,
+	}
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"request_id":"43","meta":{"request":{"id":"1"}}}`))
+	input.In(0, "test.log", 0, []byte(`{"request_id":"44","meta":"string"}`))
+	input.In(0, "test.log", 0, []byte(`{"meta":{"request":{"id":"1"}}}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"meta":{"request":{"id":"43"}}}`,
+		`{"meta":{"request":{"id":"44"}}}`,
+		`{"meta":{"request":{"id":"1"}}}`,
+	}, outEvents, "wrong out events")
+}
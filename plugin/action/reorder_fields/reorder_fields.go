@@ -0,0 +1,116 @@
+package reorder_fields
+
+import (
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ introduction
+It moves the root fields listed in `leading_fields` to the beginning of the event in the listed order.
+The order of the rest fields is preserved. Absent fields are skipped.
+Outputs serialize fields in the event order, so it's useful for human readers and tools which expect e.g. `time` first.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: reorder_fields
+      leading_fields: [time, level, message]
+    ...
+```
+It transforms `{"message":"hello","pod":"api","level":"info","time":"2021-01-01T00:00:00Z"}`
+into `{"time":"2021-01-01T00:00:00Z","level":"info","message":"hello","pod":"api"}`.
+}*/
+type Plugin struct {
+	config  *Config
+	leading map[string]bool
+	fields  []*insaneJSON.Node
+	values  []*insaneJSON.Node
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The list of root fields to put first.
+	LeadingFields []string `json:"leading_fields" required:"true"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "reorder_fields",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+
+	p.leading = make(map[string]bool, len(p.config.LeadingFields))
+	names := make([]string, 0, len(p.config.LeadingFields))
+	for _, name := range p.config.LeadingFields {
+		if p.leading[name] {
+			continue
+		}
+		p.leading[name] = true
+		names = append(names, name)
+	}
+	p.config.LeadingFields = names
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	root := event.Root
+	if !root.IsObject() || p.isOrdered(root) {
+		return pipeline.ActionPass
+	}
+
+	// object nodes are reused by the new object, so fields and values should be copied first
+	p.fields = append(p.fields[:0], root.AsFields()...)
+	p.values = p.values[:0]
+	for _, name := range p.config.LeadingFields {
+		p.values = append(p.values, root.Dig(name))
+	}
+
+	root.MutateToObject()
+	for i, value := range p.values {
+		if value != nil {
+			root.AddFieldNoAlloc(root, p.config.LeadingFields[i]).MutateToNode(value)
+		}
+	}
+	for _, field := range p.fields {
+		if !p.leading[field.AsString()] {
+			// names are the parts of the field nodes, so they outlive the event
+			root.AddFieldNoAlloc(root, field.AsString()).MutateToNode(field.AsFieldValue())
+		}
+	}
+
+	return pipeline.ActionPass
+}
+
+// isOrdered checks whether the present leading fields are already at the beginning in the right order
+func (p *Plugin) isOrdered(root *insaneJSON.Root) bool {
+	fields := root.AsFields()
+	pos := 0
+	for _, name := range p.config.LeadingFields {
+		if root.Dig(name) == nil {
+			continue
+		}
+		if pos >= len(fields) || fields[pos].AsString() != name {
+			return false
+		}
+		pos++
+	}
+
+	return true
+}
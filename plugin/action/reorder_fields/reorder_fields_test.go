@@ -0,0 +1,41 @@
+package reorder_fields
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReorderFields(t *testing.T) {
+	config := test.NewConfig(&Config{LeadingFields: []string{"time", "level", "message"}}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(5)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"message":"hello","pod":"api","level":"info","k8s":{"ns":"default"},"time":"2021-01-01T00:00:00Z","code":1}`))
+	input.In(0, "test.log", 0, []byte(`{"pod":"api","message":"no level","source":"stdout"}`))
+	input.In(0, "test.log", 0, []byte(`{"time":"2021-01-01T00:00:00Z","level":"warn","message":"ordered","pod":"api"}`))
+	input.In(0, "test.log", 0, []byte(`{"message":{"text":"nested"},"time":1}`))
+	input.In(0, "test.log", 0, []byte(`{"pod":"api"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"time":"2021-01-01T00:00:00Z","level":"info","message":"hello","pod":"api","k8s":{"ns":"default"},"code":1}`,
+		`{"message":"no level","pod":"api","source":"stdout"}`,
+		`{"time":"2021-01-01T00:00:00Z","level":"warn","message":"ordered","pod":"api"}`,
+		`{"time":1,"message":{"text":"nested"}}`,
+		`{"pod":"api"}`,
+	}, outEvents, "wrong out events")
+}
@@ -0,0 +1,221 @@
+package repair_json
+
+import (
+	"errors"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It decodes a loose JSON string from the event field, repairs it and merges the result with the event root, like `json_decode` does.
+The following leniencies are repaired:
+* trailing commas in objects and arrays, e.g. `{"a":1,}` or `[1,2,]`.
+* single quoted strings, e.g. `{'a':'it\'s'}`.
+* unquoted keys, e.g. `{a:1}`.
+
+If the JSON can't be repaired or isn't an object, the event is passed unchanged.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: repair_json
+      field: payload
+    ...
+```
+It transforms `{"payload":"{user: 'bob', tags: ['a', 'b',],}"}` into `{"user":"bob","tags":["a","b"]}`.
+}*/
+type Plugin struct {
+	config *Config
+	buf    []byte
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to decode. Must be a string.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" required:"true"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to decoded object keys.
+	Prefix string `json:"prefix" default:""` //*
+}
+
+var errBareWord = errors.New("bare word can't be repaired")
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "repair_json",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	jsonNode := event.Root.Dig(p.config.Field_...)
+	if jsonNode == nil {
+		return pipeline.ActionPass
+	}
+
+	var err error
+	p.buf, err = repair(p.buf[:0], jsonNode.AsBytes())
+	if err != nil {
+		return pipeline.ActionPass
+	}
+
+	// decoded nodes point to the JSON, so the repaired JSON is copied to live as long as the event
+	node, err := event.Root.DecodeStringAdditional(string(p.buf))
+	if err != nil {
+		return pipeline.ActionPass
+	}
+
+	if !node.IsObject() {
+		return pipeline.ActionPass
+	}
+
+	jsonNode.Suicide()
+
+	if p.config.Prefix != "" {
+		fields := node.AsFields()
+		for _, field := range fields {
+			l := len(event.Buf)
+			event.Buf = append(event.Buf, p.config.Prefix...)
+			event.Buf = append(event.Buf, field.AsString()...)
+			field.MutateToField(pipeline.ByteToStringUnsafe(event.Buf[l:]))
+		}
+	}
+
+	// place decoded object under root
+	event.Root.MergeWith(node)
+
+	return pipeline.ActionPass
+}
+
+// repair appends the strict JSON converted from the loose one,
+// it doesn't validate the JSON, errors are left for the decoder
+func repair(out []byte, json []byte) ([]byte, error) {
+	for i := 0; i < len(json); i++ {
+		c := json[i]
+		switch {
+		case c == '"':
+			end := stringEnd(json, i)
+			out = append(out, json[i:end]...)
+			i = end - 1
+		case c == '\'':
+			i = appendSingleQuoted(&out, json, i)
+		case c == ',':
+			next := skipSpaces(json, i+1)
+			if next < len(json) && (json[next] == '}' || json[next] == ']') {
+				// trailing comma
+				continue
+			}
+			out = append(out, c)
+		case isWordChar(c):
+			end := i
+			for end < len(json) && isWordChar(json[end]) {
+				end++
+			}
+			word := json[i:end]
+
+			next := skipSpaces(json, end)
+			switch {
+			case next < len(json) && json[next] == ':':
+				// unquoted key
+				out = append(out, '"')
+				out = append(out, word...)
+				out = append(out, '"')
+			case isLiteral(word):
+				out = append(out, word...)
+			default:
+				return out, errBareWord
+			}
+			i = end - 1
+		default:
+			out = append(out, c)
+		}
+	}
+
+	return out, nil
+}
+
+// stringEnd returns the position after the closing quote of the string started at pos
+func stringEnd(json []byte, pos int) int {
+	for i := pos + 1; i < len(json); i++ {
+		switch json[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+
+	return len(json)
+}
+
+// appendSingleQuoted appends the single quoted string started at pos as the double quoted one
+// and returns the position of the closing quote
+func appendSingleQuoted(out *[]byte, json []byte, pos int) int {
+	*out = append(*out, '"')
+	i := pos + 1
+	for ; i < len(json); i++ {
+		c := json[i]
+		switch {
+		case c == '\'':
+			*out = append(*out, '"')
+			return i
+		case c == '"':
+			*out = append(*out, '\\', '"')
+		case c == '\\' && i+1 < len(json) && json[i+1] == '\'':
+			*out = append(*out, '\'')
+			i++
+		case c == '\\' && i+1 < len(json):
+			*out = append(*out, c, json[i+1])
+			i++
+		default:
+			*out = append(*out, c)
+		}
+	}
+
+	// unterminated string is left for the decoder
+	return i
+}
+
+func skipSpaces(json []byte, pos int) int {
+	for pos < len(json) && (json[pos] == ' ' || json[pos] == '\t' || json[pos] == '\n' || json[pos] == '\r') {
+		pos++
+	}
+
+	return pos
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$' || c == '-' || c == '+' || c == '.'
+}
+
+// isLiteral returns true if the word is a valid JSON value without quotes
+func isLiteral(word []byte) bool {
+	switch string(word) {
+	case "true", "false", "null":
+		return true
+	}
+
+	return word[0] == '-' || word[0] >= '0' && word[0] <= '9'
+}
@@ -0,0 +1,86 @@
+package repair_json
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeEvent(payload string) string {
+	event, _ := json.Marshal(map[string]string{"payload": payload})
+	return string(event)
+}
+
+func TestRepairJSON(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "payload"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	cases := []struct {
+		payload  string
+		expected string
+	}{
+		// trailing commas
+		{payload: `{"a":1,"list":[1,2,],}`, expected: `{"a":1,"list":[1,2]}`},
+		{payload: "{\"a\":[{\"b\":null,\n},\n\t],\r\n}", expected: `{"a":[{"b":null}]}`},
+		// single quotes
+		{payload: `{'a':'it\'s "quoted"','b':'tab\t'}`, expected: `{"a":"it's \"quoted\"","b":"tab\t"}`},
+		// unquoted keys
+		{payload: `{user: "bob", $id: -1.5e3, nested: {is_ok: true, off: false}}`, expected: `{"user":"bob","$id":-1.5e3,"nested":{"is_ok":true,"off":false}}`},
+		// all together
+		{payload: "{user: 'bob',\n tags: ['a', 'b',],\n}", expected: `{"user":"bob","tags":["a","b"]}`},
+		// strict JSON isn't changed
+		{payload: `{"a":"x, } 'y'","b":{"c":[]}}`, expected: `{"a":"x, } 'y'","b":{"c":[]}}`},
+		// irreparable
+		{payload: `{a: bob}`, expected: makeEvent(`{a: bob}`)},
+		{payload: `{'a': 'unterminated}`, expected: makeEvent(`{'a': 'unterminated}`)},
+		{payload: `[1,2,]`, expected: makeEvent(`[1,2,]`)},
+		{payload: `not json`, expected: makeEvent(`not json`)},
+		{payload: `{"a":1`, expected: makeEvent(`{"a":1`)},
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(cases))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, c := range cases {
+		input.In(0, "test.log", 0, []byte(makeEvent(c.payload)))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, len(cases), len(outEvents), "wrong out events count")
+	for i, c := range cases {
+		assert.Equal(t, c.expected, outEvents[i], "wrong out event for %s", c.payload)
+	}
+}
+
+func TestRepairJSONPrefix(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "payload", Prefix: "p_"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"service":"api","payload":"{user: 'bob',}"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{`{"service":"api","p_user":"bob"}`}, outEvents, "wrong out events")
+}
@@ -0,0 +1,111 @@
+package resplit
+
+import (
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It splits the string field containing several lines into several events, one event per line.
+Other fields of the event are copied to each event. A trailing `\r` of a line is removed.
+The original event gets the first line, events of other lines are new events,
+they go through the next actions and to the output after the original event. Events with a single line are passed unchanged.
+
+New events have the same source and offset as the original event, the offset is committed only by the original event.
+So if file.d is stopped after the original event is committed, but before new events are sent, new events are lost.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: resplit
+    ...
+```
+It transforms `{"service":"api","message":"first\nsecond"}` into two events:
+`{"service":"api","message":"first"}` and `{"service":"api","message":"second"}`.
+}*/
+type Plugin struct {
+	config     *Config
+	controller pipeline.ActionPluginController
+	logger     *zap.SugaredLogger
+	lines      []string
+	buf        []byte
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to split. Must be a string.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"message"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> If set, empty lines produce events too, otherwise they are skipped.
+	//> If all lines are empty, the event is passed unchanged.
+	KeepEmpty bool `json:"keep_empty" default:"false"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "resplit",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.controller = params.Controller
+	p.logger = params.Logger
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil || !node.IsString() {
+		return pipeline.ActionPass
+	}
+
+	value := node.AsString()
+	if strings.IndexByte(value, '\n') == -1 {
+		return pipeline.ActionPass
+	}
+
+	p.lines = p.lines[:0]
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" && !p.config.KeepEmpty {
+			continue
+		}
+		p.lines = append(p.lines, line)
+	}
+
+	if len(p.lines) == 0 {
+		return pipeline.ActionPass
+	}
+
+	for _, line := range p.lines[1:] {
+		node.MutateToString(line)
+		p.buf = event.Root.Encode(p.buf[:0])
+		if err := p.controller.Spawn(event, p.buf); err != nil {
+			p.logger.Errorf("can't spawn event: %s", err.Error())
+		}
+	}
+	node.MutateToString(p.lines[0])
+
+	return pipeline.ActionPass
+}
@@ -0,0 +1,134 @@
+package resplit
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/plugin/action/join"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+)
+
+func runEvents(config *Config, events []string, outCount int) ([]string, int) {
+	return runActions(test.NewActionPluginStaticInfo(factory, test.NewConfig(config, nil), pipeline.MatchModeAnd, nil, false), events, outCount)
+}
+
+func runActions(actions []*pipeline.ActionPluginStaticInfo, events []string, outCount int) ([]string, int) {
+	p, input, output := test.NewPipelineMock(actions)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(outCount)
+
+	commits := atomic.NewInt32(0)
+	input.SetCommitFn(func(e *pipeline.Event) {
+		commits.Inc()
+	})
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for i, event := range events {
+		input.In(0, "test.log", int64(i+1), []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents, int(commits.Load())
+}
+
+func TestResplitSingleLine(t *testing.T) {
+	outEvents, commits := runEvents(&Config{}, []string{
+		`{"service":"api","message":"single line"}`,
+		`{"service":"api","message":"trailing newline\n"}`,
+		`{"service":"api","message":{"text":"a\nb"}}`,
+		`{"service":"api","message":"\n\n"}`,
+	}, 4)
+
+	assert.Equal(t, []string{
+		`{"service":"api","message":"single line"}`,
+		`{"service":"api","message":"trailing newline"}`,
+		`{"service":"api","message":{"text":"a\nb"}}`,
+		`{"service":"api","message":"\n\n"}`,
+	}, outEvents, "wrong out events")
+	assert.Equal(t, 4, commits, "wrong commits count")
+}
+
+func TestResplitMultiLine(t *testing.T) {
+	outEvents, commits := runEvents(&Config{}, []string{
+		`{"service":"api","message":"first\r\nsecond\n\nthird","level":"error"}`,
+		`{"service":"web","message":"one\ntwo"}`,
+	}, 5)
+
+	assert.Equal(t, []string{
+		`{"service":"api","message":"first","level":"error"}`,
+		`{"service":"api","message":"second","level":"error"}`,
+		`{"service":"api","message":"third","level":"error"}`,
+		`{"service":"web","message":"one"}`,
+		`{"service":"web","message":"two"}`,
+	}, outEvents, "wrong out events")
+	assert.Equal(t, 2, commits, "spawned events shouldn't be committed to the input")
+}
+
+func TestResplitKeepEmpty(t *testing.T) {
+	outEvents, _ := runEvents(&Config{Field: "log.text", KeepEmpty: true}, []string{
+		`{"log":{"text":"a\n\nb\n"}}`,
+	}, 4)
+
+	assert.Equal(t, []string{
+		`{"log":{"text":"a"}}`,
+		`{"log":{"text":""}}`,
+		`{"log":{"text":"b"}}`,
+		`{"log":{"text":""}}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestResplitJoin(t *testing.T) {
+	joinConfig := test.NewConfig(&join.Config{Field: "message", Start: `/^panic:/`, Continue: `/^\s/`}, nil)
+
+	joinFactory := fd.DefaultPluginRegistry.GetActionByType("join").Factory
+	actions := test.NewActionPluginStaticInfo(factory, test.NewConfig(&Config{}, nil), pipeline.MatchModeAnd, nil, false)
+	actions = append(actions, test.NewActionPluginStaticInfo(joinFactory, joinConfig, pipeline.MatchModeAnd, nil, false)...)
+
+	outEvents, commits := runActions(actions, []string{
+		`{"service":"api","message":"start\npanic: boom\n\tat main.go:1\n\tat main.go:2\ndone"}`,
+		`{"service":"web","message":"next"}`,
+	}, 4)
+
+	assert.Equal(t, []string{
+		`{"service":"api","message":"start"}`,
+		`{"service":"api","message":"panic: boom\tat main.go:1\tat main.go:2"}`,
+		`{"service":"api","message":"done"}`,
+		`{"service":"web","message":"next"}`,
+	}, outEvents, "spawned events should be joined")
+	assert.Equal(t, 2, commits, "spawned events shouldn't be committed to the input")
+}
+
+func TestResplitSpawnedSize(t *testing.T) {
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, test.NewConfig(&Config{}, nil), pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	sizes := make(map[string]int)
+	output.SetOutFn(func(e *pipeline.Event) {
+		sizes[e.Root.EncodeToString()] = e.Size
+		wg.Done()
+	})
+
+	original := `{"message":"first\nsecond\nthird"}`
+	input.In(0, "test.log", 1, []byte(original))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, len(original), sizes[`{"message":"first"}`], "wrong size of the original event")
+	assert.Equal(t, len(`{"message":"second"}`), sizes[`{"message":"second"}`], "wrong size of the spawned event")
+	assert.Equal(t, len(`{"message":"third"}`), sizes[`{"message":"third"}`], "wrong size of the spawned event")
+}
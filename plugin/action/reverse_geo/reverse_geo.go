@@ -0,0 +1,338 @@
+package reverse_geo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"go.uber.org/zap"
+)
+
+var (
+	// datasets are loaded once and shared across processors and pipelines, so let's have a map by file name
+	datasets   = map[string]*dataset{}
+	datasetsMu = &sync.Mutex{}
+)
+
+/*{ introduction
+It finds the region which contains the point from latitude and longitude fields and puts region properties into the event.
+Regions are loaded once from the local GeoJSON file with `FeatureCollection` of `Polygon` or `MultiPolygon` features.
+Polygon holes are respected. If regions overlap, the first one in the file is used.
+
+Coordinates may be numbers or numeric strings.
+If a coordinate is missing, isn't a number, is out of range or the point doesn't belong to any region, e.g. it's in the ocean,
+the event is passed unchanged.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: reverse_geo
+      dataset: /etc/file.d/regions.geojson
+      lat_field: geo.lat
+      lon_field: geo.lon
+      properties:
+        name: geo.region
+        iso_a2: geo.country
+    ...
+```
+}*/
+type Plugin struct {
+	config  *Config
+	logger  *zap.SugaredLogger
+	dataset *dataset
+	fields  []*propertyField
+}
+
+type propertyField struct {
+	property string
+	field    []string
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The path to the GeoJSON file with regions.
+	Dataset string `json:"dataset" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field with the latitude.
+	LatField  cfg.FieldSelector `json:"lat_field" parse:"selector" default:"lat"` //*
+	LatField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The event field with the longitude.
+	LonField  cfg.FieldSelector `json:"lon_field" parse:"selector" default:"lon"` //*
+	LonField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The mapping of feature properties to event fields, `{"name": "region"}` if not set.
+	//> Properties which are absent in the found feature are skipped.
+	Properties map[string]string `json:"properties"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "reverse_geo",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger
+
+	if len(p.config.Properties) == 0 {
+		p.config.Properties = map[string]string{"name": "region"}
+	}
+	p.fields = make([]*propertyField, 0, len(p.config.Properties))
+	for property, field := range p.config.Properties {
+		p.fields = append(p.fields, &propertyField{property: property, field: cfg.ParseFieldSelector(field)})
+	}
+
+	datasetsMu.Lock()
+	defer datasetsMu.Unlock()
+
+	d, has := datasets[p.config.Dataset]
+	if !has {
+		var err error
+		d, err = loadDataset(p.config.Dataset)
+		if err != nil {
+			p.logger.Fatalf("can't load dataset %s: %s", p.config.Dataset, err.Error())
+		}
+		datasets[p.config.Dataset] = d
+	}
+	p.dataset = d
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	lat, ok := coordinate(event, p.config.LatField_, 90)
+	if !ok {
+		return pipeline.ActionPass
+	}
+	lon, ok := coordinate(event, p.config.LonField_, 180)
+	if !ok {
+		return pipeline.ActionPass
+	}
+
+	r := p.dataset.find(lon, lat)
+	if r == nil {
+		return pipeline.ActionPass
+	}
+
+	for _, f := range p.fields {
+		value, has := r.properties[f.property]
+		if !has {
+			continue
+		}
+		pipeline.CreateNestedField(event.Root, f.field).MutateToString(value)
+	}
+
+	return pipeline.ActionPass
+}
+
+// coordinate returns the field value if it's a number from -limit to limit
+func coordinate(event *pipeline.Event, field []string, limit float64) (float64, bool) {
+	node := event.Root.Dig(field...)
+	if node == nil || !(node.IsNumber() || node.IsString()) {
+		return 0, false
+	}
+
+	value, err := strconv.ParseFloat(node.AsString(), 64)
+	if err != nil || value < -limit || value > limit {
+		return 0, false
+	}
+
+	return value, true
+}
+
+type point [2]float64
+
+// ring is a closed line, the first ring of the polygon is the outer one, others are holes
+type ring []point
+
+type polygon []ring
+
+type region struct {
+	properties map[string]string
+	polygons   []polygon
+
+	minLon, minLat, maxLon, maxLat float64
+}
+
+type dataset struct {
+	regions []*region
+}
+
+type geoJSON struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Properties map[string]interface{} `json:"properties"`
+		Geometry   struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"features"`
+}
+
+func loadDataset(fileName string) (*dataset, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := &geoJSON{}
+	if err := json.Unmarshal(data, collection); err != nil {
+		return nil, err
+	}
+	if collection.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("GeoJSON should be a FeatureCollection, got=%q", collection.Type)
+	}
+
+	d := &dataset{regions: make([]*region, 0, len(collection.Features))}
+	for i, feature := range collection.Features {
+		var polygons []polygon
+		switch feature.Geometry.Type {
+		case "Polygon":
+			var coordinates [][][]float64
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &coordinates); err != nil {
+				return nil, fmt.Errorf("wrong coordinates of feature #%d: %s", i, err.Error())
+			}
+			polygons = []polygon{toPolygon(coordinates)}
+		case "MultiPolygon":
+			var coordinates [][][][]float64
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &coordinates); err != nil {
+				return nil, fmt.Errorf("wrong coordinates of feature #%d: %s", i, err.Error())
+			}
+			for _, c := range coordinates {
+				polygons = append(polygons, toPolygon(c))
+			}
+		default:
+			// points and lines can't contain anything
+			continue
+		}
+
+		d.regions = append(d.regions, newRegion(feature.Properties, polygons))
+	}
+
+	return d, nil
+}
+
+func toPolygon(coordinates [][][]float64) polygon {
+	result := make(polygon, 0, len(coordinates))
+	for _, c := range coordinates {
+		r := make(ring, 0, len(c))
+		for _, position := range c {
+			// altitude is ignored
+			if len(position) >= 2 {
+				r = append(r, point{position[0], position[1]})
+			}
+		}
+		result = append(result, r)
+	}
+
+	return result
+}
+
+func newRegion(properties map[string]interface{}, polygons []polygon) *region {
+	r := &region{
+		properties: make(map[string]string, len(properties)),
+		polygons:   polygons,
+		minLon:     180,
+		minLat:     90,
+		maxLon:     -180,
+		maxLat:     -90,
+	}
+
+	for name, value := range properties {
+		switch v := value.(type) {
+		case string:
+			r.properties[name] = v
+		case float64:
+			r.properties[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			r.properties[name] = strconv.FormatBool(v)
+		}
+	}
+
+	for _, poly := range polygons {
+		if len(poly) == 0 {
+			continue
+		}
+		for _, pt := range poly[0] {
+			r.minLon, r.maxLon = math.Min(r.minLon, pt[0]), math.Max(r.maxLon, pt[0])
+			r.minLat, r.maxLat = math.Min(r.minLat, pt[1]), math.Max(r.maxLat, pt[1])
+		}
+	}
+
+	return r
+}
+
+func (d *dataset) find(lon float64, lat float64) *region {
+	for _, r := range d.regions {
+		if r.contains(lon, lat) {
+			return r
+		}
+	}
+
+	return nil
+}
+
+func (r *region) contains(lon float64, lat float64) bool {
+	if lon < r.minLon || lon > r.maxLon || lat < r.minLat || lat > r.maxLat {
+		return false
+	}
+
+	for _, poly := range r.polygons {
+		if len(poly) == 0 || !poly[0].contains(lon, lat) {
+			continue
+		}
+
+		inHole := false
+		for _, hole := range poly[1:] {
+			if hole.contains(lon, lat) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+
+	return false
+}
+
+// contains uses ray casting, the point is inside if the ray crosses the ring odd number of times
+func (r ring) contains(x float64, y float64) bool {
+	inside := false
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		xi, yi := r[i][0], r[i][1]
+		xj, yj := r[j][0], r[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
@@ -0,0 +1,141 @@
+package reverse_geo
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+const regions = `{
+  "type": "FeatureCollection",
+  "features": [
+    {
+      "type": "Feature",
+      "properties": {"name": "Square Land", "country": "AA", "code": 1},
+      "geometry": {"type": "Polygon", "coordinates": [
+        [[0, 0], [10, 0], [10, 10], [0, 10], [0, 0]],
+        [[4, 4], [6, 4], [6, 6], [4, 6], [4, 4]]
+      ]}
+    },
+    {
+      "type": "Feature",
+      "properties": {"name": "Islands", "country": "BB"},
+      "geometry": {"type": "MultiPolygon", "coordinates": [
+        [[[20, 20], [22, 20], [22, 22], [20, 22], [20, 20]]],
+        [[[30, -2], [32, -2], [32, 0], [30, 0], [30, -2]]]
+      ]}
+    },
+    {
+      "type": "Feature",
+      "properties": {"name": "Triangle"},
+      "geometry": {"type": "Polygon", "coordinates": [[[-20, 0, 100], [-10, 0, 100], [-15, 10, 100], [-20, 0, 100]]]}
+    },
+    {
+      "type": "Feature",
+      "properties": {"name": "Lighthouse"},
+      "geometry": {"type": "Point", "coordinates": [50, 50]}
+    }
+  ]
+}`
+
+func writeDataset(t *testing.T) string {
+	file, err := ioutil.TempFile("", "regions*.geojson")
+	assert.NoError(t, err, "can't create dataset")
+	_, err = file.WriteString(regions)
+	assert.NoError(t, err, "can't write dataset")
+	assert.NoError(t, file.Close(), "can't close dataset")
+
+	return file.Name()
+}
+
+func TestReverseGeo(t *testing.T) {
+	dataset := writeDataset(t)
+	defer os.Remove(dataset)
+
+	config := test.NewConfig(&Config{Dataset: dataset, Properties: map[string]string{"name": "geo.region", "country": "geo.country", "code": "geo.code"}}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	events := []struct {
+		json     string
+		region   string
+		country  string
+		expected string
+	}{
+		{json: `{"lat":1,"lon":5}`, region: "Square Land", country: "AA"},
+		{json: `{"lat":"9.5","lon":"0.5"}`, region: "Square Land", country: "AA"},
+		{json: `{"lat":21,"lon":21}`, region: "Islands", country: "BB"},
+		{json: `{"lat":-1,"lon":31}`, region: "Islands", country: "BB"},
+		{json: `{"lat":9,"lon":-15}`, region: "Triangle"},
+		{json: `{"lat":5,"lon":5}`, expected: `{"lat":5,"lon":5}`},         // lake in the hole
+		{json: `{"lat":9,"lon":-17}`, expected: `{"lat":9,"lon":-17}`},     // out of the triangle, but in its bounds
+		{json: `{"lat":-40,"lon":-30}`, expected: `{"lat":-40,"lon":-30}`}, // ocean
+		{json: `{"lat":50,"lon":50}`, expected: `{"lat":50,"lon":50}`},     // points can't contain anything
+		{json: `{"lat":91,"lon":5}`, expected: `{"lat":91,"lon":5}`},
+		{json: `{"lat":1,"lon":-181}`, expected: `{"lat":1,"lon":-181}`},
+		{json: `{"lat":"north","lon":5}`, expected: `{"lat":"north","lon":5}`},
+		{json: `{"lat":1}`, expected: `{"lat":1}`},
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(events))
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	for _, e := range events {
+		input.In(0, "test.log", 0, []byte(e.json))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, len(events), len(outEvents), "wrong out events count")
+	for i, e := range events {
+		out := outEvents[i]
+		if e.expected != "" {
+			assert.Equal(t, e.expected, out.Root.EncodeToString(), "wrong out event")
+			continue
+		}
+
+		assert.Equal(t, e.region, out.Root.Dig("geo", "region").AsString(), "wrong region for %s", e.json)
+		if e.country == "" {
+			assert.Nil(t, out.Root.Dig("geo", "country"), "absent property shouldn't be added for %s", e.json)
+		} else {
+			assert.Equal(t, e.country, out.Root.Dig("geo", "country").AsString(), "wrong country for %s", e.json)
+		}
+	}
+	assert.Equal(t, "1", outEvents[0].Root.Dig("geo", "code").AsString(), "wrong numeric property")
+}
+
+func TestReverseGeoDefaultProperties(t *testing.T) {
+	dataset := writeDataset(t)
+	defer os.Remove(dataset)
+
+	config := test.NewConfig(&Config{Dataset: dataset, LatField: "pos.lat", LonField: "pos.lon"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"pos":{"lat":21.5,"lon":20.5}}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 1, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"pos":{"lat":21.5,"lon":20.5},"region":"Islands"}`, outEvents[0].Root.EncodeToString(), "wrong out event")
+}
@@ -0,0 +1,208 @@
+package rps_metric
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const defaultMaxKeys = 1000
+
+var (
+	// windows should be shared across processors of the pipeline, so let's have a map by pipeline and metric names
+	windows   = map[string]*window{}
+	windowsMu = &sync.Mutex{}
+)
+
+/*{ introduction
+It counts events per value of `key_field` over the rolling `window` and exposes the rate per second as a gauge.
+The gauge is named `file_d_pipeline_<pipeline>_<metric_name>`, the label name is the key field name with `.` replaced by `_`.
+Events without the key field are counted with the empty label value. Events are always passed unchanged.
+
+The window is split into one second buckets. Gauges are updated by events,
+so when the second is changed rates of all keys are recalculated and keys without events in the window are removed.
+If the pipeline has no events at all, gauges keep the last values.
+
+To protect from high cardinality, no more than `max_keys` keys are tracked,
+events of new keys beyond the limit are counted with `overflow_value` label value.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: rps_metric
+      key_field: request.endpoint
+      window: 30s
+    ...
+```
+It exposes `file_d_pipeline_example_pipeline_rps{request_endpoint="/api/v1/users"}` and so on.
+}*/
+type Plugin struct {
+	config *Config
+	window *window
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field which value is the key of the rate, e.g. the endpoint.
+	KeyField  cfg.FieldSelector `json:"key_field" parse:"selector" required:"true"` //*
+	KeyField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The name of the gauge.
+	MetricName string `json:"metric_name" default:"rps"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The size of the rolling window, must be at least one second.
+	Window  cfg.Duration `json:"window" parse:"duration" default:"1m"` //*
+	Window_ time.Duration
+
+	//> @3@4@5@6
+	//>
+	//> The maximum number of keys to track, `1000` if not set.
+	MaxKeys int `json:"max_keys"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The label value for events of keys beyond `max_keys`.
+	OverflowValue string `json:"overflow_value" default:"__overflow__"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "rps_metric",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	if p.config.Window_ < time.Second {
+		params.Logger.Fatalf("window should be at least 1s, got=%s", p.config.Window)
+	}
+	if p.config.MaxKeys <= 0 {
+		p.config.MaxKeys = defaultMaxKeys
+	}
+
+	label := strings.ReplaceAll(string(p.config.KeyField), ".", "_")
+	gauge := params.MetricsCtl.RegisterGauge(p.config.MetricName, "Events per second by "+string(p.config.KeyField)+" field", label)
+
+	windowsMu.Lock()
+	name := params.PipelineName + "_" + p.config.MetricName
+	w, has := windows[name]
+	// metrics are created for each pipeline instance, so a different gauge means that the pipeline is recreated
+	if !has || w.gauge != gauge {
+		w = newWindow(p.config, gauge, time.Now)
+		windows[name] = w
+	}
+	windowsMu.Unlock()
+
+	p.window = w
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	p.window.add(event.Root.Dig(p.config.KeyField_...).AsString())
+
+	return pipeline.ActionPass
+}
+
+type keyCounter struct {
+	buckets []int64
+	sum     int64
+}
+
+type window struct {
+	mu            *sync.Mutex
+	seconds       int64
+	maxKeys       int
+	overflowValue string
+	gauge         *prometheus.GaugeVec
+	nowFn         func() time.Time
+
+	keys    map[string]*keyCounter
+	current int64
+}
+
+func newWindow(config *Config, gauge *prometheus.GaugeVec, nowFn func() time.Time) *window {
+	return &window{
+		mu:            &sync.Mutex{},
+		seconds:       int64(config.Window_ / time.Second),
+		maxKeys:       config.MaxKeys,
+		overflowValue: config.OverflowValue,
+		gauge:         gauge,
+		nowFn:         nowFn,
+
+		keys:    make(map[string]*keyCounter),
+		current: nowFn().Unix(),
+	}
+}
+
+func (w *window) add(key string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// time may go backwards, so such events are counted in the current second
+	if now := w.nowFn().Unix(); now > w.current {
+		w.advance(now)
+	}
+
+	counter, has := w.keys[key]
+	if !has {
+		if len(w.keys) >= w.maxKeys {
+			key = w.overflowValue
+			counter, has = w.keys[key]
+		}
+		if !has {
+			counter = &keyCounter{buckets: make([]int64, w.seconds)}
+			w.keys[key] = counter
+		}
+	}
+
+	counter.buckets[w.current%w.seconds]++
+	counter.sum++
+	w.gauge.WithLabelValues(key).Set(float64(counter.sum) / float64(w.seconds))
+}
+
+// advance moves the window to the second, it clears elapsed buckets and recalculates rates of all keys
+func (w *window) advance(now int64) {
+	elapsed := now - w.current
+	if elapsed > w.seconds {
+		elapsed = w.seconds
+	}
+
+	for key, counter := range w.keys {
+		for i := int64(1); i <= elapsed; i++ {
+			bucket := &counter.buckets[(w.current+i)%w.seconds]
+			counter.sum -= *bucket
+			*bucket = 0
+		}
+
+		if counter.sum == 0 {
+			delete(w.keys, key)
+			w.gauge.DeleteLabelValues(key)
+			continue
+		}
+		w.gauge.WithLabelValues(key).Set(float64(counter.sum) / float64(w.seconds))
+	}
+
+	w.current = now
+}
@@ -0,0 +1,105 @@
+package rps_metric
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRPSMetric(t *testing.T) {
+	config := test.NewConfig(&Config{KeyField: "request.endpoint"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"request":{"endpoint":"/users"}}`))
+	input.In(0, "test.log", 0, []byte(`{"request":{"endpoint":"/users"}}`))
+	input.In(0, "test.log", 0, []byte(`{"request":{"endpoint":"/users"}}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"no endpoint"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 4, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"request":{"endpoint":"/users"}}`, outEvents[0], "event should be unchanged")
+
+	gauge := p.GetMetricsCtl().RegisterGauge("rps", "", "request_endpoint")
+	// the second may change during the test, but events are still in the window
+	assert.Equal(t, 3.0/60, testutil.ToFloat64(gauge.WithLabelValues("/users")), "wrong rate")
+	assert.Equal(t, 1.0/60, testutil.ToFloat64(gauge.WithLabelValues("")), "wrong rate")
+}
+
+func seriesCount(gauge *prometheus.GaugeVec) int {
+	ch := make(chan prometheus.Metric, 100)
+	gauge.Collect(ch)
+	close(ch)
+
+	return len(ch)
+}
+
+func TestWindow(t *testing.T) {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "rps"}, []string{"endpoint"})
+	now := time.Unix(1000, 0)
+	w := newWindow(&Config{Window_: 10 * time.Second, MaxKeys: 2, OverflowValue: "overflow"}, gauge, func() time.Time { return now })
+
+	rate := func(key string) float64 {
+		return testutil.ToFloat64(gauge.WithLabelValues(key))
+	}
+
+	for i := 0; i < 20; i++ {
+		w.add("/a")
+	}
+	assert.Equal(t, 2.0, rate("/a"), "wrong rate of the spike")
+
+	// synthetic load of 5 events per second
+	for s := 0; s < 10; s++ {
+		for i := 0; i < 5; i++ {
+			w.add("/b")
+		}
+		if s < 9 {
+			now = now.Add(time.Second)
+		}
+		assert.Equal(t, float64(5*(s+1))/10, rate("/b"), "wrong rate at second %d", s)
+	}
+	assert.Equal(t, 2.0, rate("/a"), "spike should be in the window")
+
+	// the spike bucket leaves the window, while the load is steady
+	for s := 0; s < 5; s++ {
+		now = now.Add(time.Second)
+		for i := 0; i < 5; i++ {
+			w.add("/b")
+		}
+		assert.Equal(t, 5.0, rate("/b"), "rate should be steady")
+	}
+	assert.Equal(t, 1, seriesCount(gauge), "key without events in the window should be removed")
+
+	w.add("/c")
+	w.add("/d")
+	w.add("/e")
+	assert.Equal(t, 0.1, rate("/c"), "wrong rate")
+	assert.Equal(t, 0.2, rate("overflow"), "keys beyond the limit should be counted as overflow")
+	assert.Equal(t, 3, seriesCount(gauge), "wrong series count")
+
+	now = now.Add(time.Minute)
+	w.add("/b")
+	assert.Equal(t, 0.1, rate("/b"), "window should be cleared after the idle period")
+	assert.Equal(t, 1, seriesCount(gauge), "idle keys should be removed")
+
+	// time goes backwards
+	now = now.Add(-time.Second)
+	w.add("/b")
+	assert.Equal(t, 0.2, rate("/b"), "wrong rate")
+}
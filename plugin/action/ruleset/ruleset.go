@@ -0,0 +1,288 @@
+package ruleset
+
+import (
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It applies the field policy of `keep`, `drop`, `rename` and `mask` rules in a single pass over the top-level fields of the event.
+It's faster than the chain of `keep_fields`, `remove_fields`, `rename` and `mask` actions, since rules are grouped by the top-level field
+at start and each field is looked up once.
+
+The ruleset is declarative, so the order of the rules of different types doesn't matter:
+* `keep` and `drop` rules choose the original fields to leave: if there are `keep` rules, top-level fields which aren't listed are removed,
+fields listed in `drop` rules are removed too.
+* `mask` rules replace matches of `re` in the string values of the left fields with `fill_char`, the whole value is masked if `re` is empty.
+* `rename` rules move the left fields to their destinations, the existing destination fields are overwritten.
+
+Rules refer to the fields of the original event, so a field created by `rename` can't be masked or dropped by another rule.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: ruleset
+      rules:
+      - type: keep
+        fields: [time, message, user, request]
+      - type: drop
+        fields: [user.password]
+      - type: mask
+        fields: [user.email]
+        re: '^[^@]+'
+      - type: rename
+        from: request.id
+        to: request_id
+    ...
+```
+It transforms `{"time":"12:00","message":"ok","user":{"email":"john@example.com","password":"123"},"request":{"id":"42"},"debug":true}`
+into `{"time":"12:00","message":"ok","user":{"email":"****@example.com"},"request":{},"request_id":"42"}`.
+}*/
+type Plugin struct {
+	config *Config
+	logger *zap.SugaredLogger
+
+	keep   map[string]bool
+	fields map[string]*fieldRules
+	fill   []byte
+
+	removed []*insaneJSON.Node
+	moved   []moved
+}
+
+// fieldRules are the rules of the top-level field, paths are relative to the field value
+type fieldRules struct {
+	drop    bool
+	drops   [][]string
+	masks   []*maskRule
+	renames []*renameRule
+}
+
+type maskRule struct {
+	path []string
+	re   *regexp.Regexp
+}
+
+type renameRule struct {
+	path []string
+	to   []string
+}
+
+type moved struct {
+	node *insaneJSON.Node
+	to   []string
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The list of the rules. Each rule has the following fields:
+	//> * `type` – `keep`, `drop`, `rename` or `mask`.
+	//> * `fields` – the list of the fields of `keep`, `drop` and `mask` rules. Nested fields can be used in all rules except `keep`.
+	//> * `from`, `to` – the field to rename and its destination, both can be nested.
+	//> * `re` – re2 expression of `mask` rule.
+	Rules []RuleConfig `json:"rules" required:"true" slice:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The character to replace the masked text with, the length of the text in characters is preserved.
+	FillChar string `json:"fill_char" default:"*"` //*
+}
+
+type RuleConfig struct {
+	Type   string   `json:"type" required:"true" options:"keep|drop|rename|mask"`
+	Fields []string `json:"fields"`
+	From   string   `json:"from"`
+	To     string   `json:"to"`
+	Re     string   `json:"re"`
+}
+
+const (
+	ruleKeep   = "keep"
+	ruleDrop   = "drop"
+	ruleRename = "rename"
+	ruleMask   = "mask"
+)
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "ruleset",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger
+
+	if utf8.RuneCountInString(p.config.FillChar) != 1 {
+		p.logger.Fatalf("fill_char should be a single character, got=%q", p.config.FillChar)
+	}
+	p.fill = []byte(p.config.FillChar)
+
+	p.fields = make(map[string]*fieldRules)
+	for i := range p.config.Rules {
+		p.compile(i, &p.config.Rules[i])
+	}
+}
+
+func (p *Plugin) compile(i int, rule *RuleConfig) {
+	if rule.Type == ruleRename {
+		from := cfg.ParseFieldSelector(rule.From)
+		to := cfg.ParseFieldSelector(rule.To)
+		if len(from) == 0 || len(to) == 0 {
+			p.logger.Fatalf("rename rule #%d should have from and to fields", i)
+		}
+		r := p.fieldRules(from[0])
+		r.renames = append(r.renames, &renameRule{path: from[1:], to: to})
+		return
+	}
+
+	if len(rule.Fields) == 0 {
+		p.logger.Fatalf("%s rule #%d should have fields", rule.Type, i)
+	}
+
+	var re *regexp.Regexp
+	if rule.Type == ruleMask && rule.Re != "" {
+		var err error
+		re, err = regexp.Compile(rule.Re)
+		if err != nil {
+			p.logger.Fatalf("can't compile re of mask rule #%d: %s", i, err.Error())
+		}
+	}
+
+	for _, field := range rule.Fields {
+		path := cfg.ParseFieldSelector(field)
+		if len(path) == 0 {
+			p.logger.Fatalf("%s rule #%d has empty field", rule.Type, i)
+		}
+
+		switch rule.Type {
+		case ruleKeep:
+			if len(path) != 1 {
+				p.logger.Fatalf("keep rule #%d can't have nested field %q", i, field)
+			}
+			if p.keep == nil {
+				p.keep = make(map[string]bool)
+			}
+			p.keep[path[0]] = true
+		case ruleDrop:
+			r := p.fieldRules(path[0])
+			if len(path) == 1 {
+				r.drop = true
+			} else {
+				r.drops = append(r.drops, path[1:])
+			}
+		case ruleMask:
+			r := p.fieldRules(path[0])
+			r.masks = append(r.masks, &maskRule{path: path[1:], re: re})
+		}
+	}
+}
+
+func (p *Plugin) fieldRules(name string) *fieldRules {
+	r, has := p.fields[name]
+	if !has {
+		r = &fieldRules{}
+		p.fields[name] = r
+	}
+	return r
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	if !event.Root.IsObject() {
+		return pipeline.ActionPass
+	}
+
+	p.removed = p.removed[:0]
+	p.moved = p.moved[:0]
+
+	// the root isn't changed while its fields are iterated
+	for _, field := range event.Root.AsFields() {
+		name := field.AsString()
+		value := field.AsFieldValue()
+		r := p.fields[name]
+
+		if (p.keep != nil && !p.keep[name]) || (r != nil && r.drop) {
+			p.removed = append(p.removed, value)
+			continue
+		}
+		if r == nil {
+			continue
+		}
+
+		for _, path := range r.drops {
+			value.Dig(path...).Suicide()
+		}
+		for _, m := range r.masks {
+			p.mask(event, value.Dig(m.path...), m.re)
+		}
+		for _, rn := range r.renames {
+			if node := value.Dig(rn.path...); node != nil {
+				p.moved = append(p.moved, moved{node: node, to: rn.to})
+			}
+		}
+	}
+
+	for _, node := range p.removed {
+		node.Suicide()
+	}
+	for _, m := range p.moved {
+		m.node.Suicide()
+		pipeline.CreateNestedField(event.Root, m.to).MutateToNode(m.node)
+	}
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) mask(event *pipeline.Event, node *insaneJSON.Node, re *regexp.Regexp) {
+	if node == nil || !node.IsString() {
+		return
+	}
+
+	value := node.AsBytes()
+	l := len(event.Buf)
+	if re == nil {
+		event.Buf = p.appendFill(event.Buf, value)
+	} else {
+		matches := re.FindAllIndex(value, -1)
+		if len(matches) == 0 {
+			return
+		}
+
+		pos := 0
+		for _, match := range matches {
+			event.Buf = append(event.Buf, value[pos:match[0]]...)
+			event.Buf = p.appendFill(event.Buf, value[match[0]:match[1]])
+			pos = match[1]
+		}
+		event.Buf = append(event.Buf, value[pos:]...)
+	}
+
+	node.MutateToBytesCopy(event.Root, event.Buf[l:])
+}
+
+func (p *Plugin) appendFill(buf []byte, masked []byte) []byte {
+	for n := utf8.RuneCount(masked); n > 0; n-- {
+		buf = append(buf, p.fill...)
+	}
+	return buf
+}
@@ -0,0 +1,184 @@
+package ruleset
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/logger"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/plugin/action/keep_fields"
+	"github.com/ozonru/file.d/plugin/action/mask"
+	"github.com/ozonru/file.d/plugin/action/remove_fields"
+	"github.com/ozonru/file.d/plugin/action/rename"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+func runEvents(config *Config, events []string) []string {
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, test.NewConfig(config, nil), pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(events))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, event := range events {
+		input.In(0, "test.log", 0, []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+func TestRulesetKeep(t *testing.T) {
+	outEvents := runEvents(&Config{Rules: []RuleConfig{
+		{Type: "keep", Fields: []string{"time", "message"}},
+		{Type: "keep", Fields: []string{"level"}},
+	}}, []string{
+		`{"time":"12:00","message":"ok","level":"info","debug":true,"user":{"id":1}}`,
+		`{"debug":true}`,
+	})
+
+	assert.Equal(t, []string{
+		`{"time":"12:00","message":"ok","level":"info"}`,
+		`{}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestRulesetDrop(t *testing.T) {
+	outEvents := runEvents(&Config{Rules: []RuleConfig{
+		{Type: "drop", Fields: []string{"debug", "user.password", "user.token.value"}},
+	}}, []string{
+		`{"message":"ok","debug":true,"user":{"id":1,"password":"123","token":{"value":"abc","ttl":60}}}`,
+		`{"message":"ok","user":"john"}`,
+	})
+
+	assert.Equal(t, []string{
+		`{"message":"ok","user":{"id":1,"token":{"ttl":60}}}`,
+		`{"message":"ok","user":"john"}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestRulesetRename(t *testing.T) {
+	outEvents := runEvents(&Config{Rules: []RuleConfig{
+		{Type: "rename", From: "msg", To: "message"},
+		{Type: "rename", From: "request.id", To: "meta.request_id"},
+		{Type: "rename", From: "level", To: "severity"},
+	}}, []string{
+		`{"msg":"ok","request":{"id":"42","path":"/"},"severity":"high","level":"info"}`,
+		`{"message":"ok"}`,
+	})
+
+	assert.Equal(t, []string{
+		`{"meta":{"request_id":"42"},"request":{"path":"/"},"severity":"info","message":"ok"}`,
+		`{"message":"ok"}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestRulesetMask(t *testing.T) {
+	outEvents := runEvents(&Config{Rules: []RuleConfig{
+		{Type: "mask", Fields: []string{"user.email"}, Re: `^[^@]+`},
+		{Type: "mask", Fields: []string{"password", "user.id"}},
+	}}, []string{
+		`{"user":{"email":"жора@example.com","id":12},"password":"secret"}`,
+		`{"user":{"email":"not an email"}}`,
+	})
+
+	assert.Equal(t, []string{
+		`{"user":{"email":"****@example.com","id":12},"password":"******"}`,
+		`{"user":{"email":"************"}}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestRulesetAll(t *testing.T) {
+	outEvents := runEvents(&Config{Rules: []RuleConfig{
+		{Type: "rename", From: "request.id", To: "request_id"},
+		{Type: "mask", Fields: []string{"user.email"}, Re: `^[^@]+`},
+		{Type: "keep", Fields: []string{"time", "message", "user", "request"}},
+		{Type: "drop", Fields: []string{"user.password"}},
+	}}, []string{
+		`{"time":"12:00","message":"ok","user":{"email":"john@example.com","password":"123"},"request":{"id":"42"},"debug":true}`,
+	})
+
+	assert.Equal(t, []string{
+		`{"time":"12:00","message":"ok","user":{"email":"****@example.com"},"request":{},"request_id":"42"}`,
+	}, outEvents, "the order of rules of different types shouldn't matter")
+}
+
+// both benchmarks decode the event, so the difference is the cost of the rules
+const benchEvent = `{"time":"2021-01-01T12:00:00Z","level":"info","msg":"request is done","debug":{"trace":"abc"},"host":"web-1",` +
+	`"user":{"id":"42","email":"john@example.com","phone":"+79991234567","password":"123","token":"f00d"},` +
+	`"request":{"id":"1f2e","path":"/api","duration":12,"headers":{"cookie":"a=b","authorization":"Bearer f00d"}}}`
+
+var benchFields = []string{"time", "level", "msg", "user", "request"}
+
+func startPlugin(b *testing.B, factory pipeline.PluginFactory, config pipeline.AnyConfig) pipeline.ActionPlugin {
+	p, _ := factory()
+	plugin := p.(pipeline.ActionPlugin)
+	plugin.Start(config, &pipeline.ActionPluginParams{PluginDefaultParams: test.NewEmptyPluginDefaultParams(), Logger: logger.Instance})
+	b.Cleanup(plugin.Stop)
+
+	return plugin
+}
+
+func benchmarkActions(b *testing.B, actions []pipeline.ActionPlugin) {
+	root := insaneJSON.Spawn()
+	defer insaneJSON.Release(root)
+	event := &pipeline.Event{Root: root}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := root.DecodeString(benchEvent); err != nil {
+			b.Fatal(err)
+		}
+		event.Buf = event.Buf[:0]
+		for _, action := range actions {
+			action.Do(event)
+		}
+	}
+}
+
+func BenchmarkRuleset(b *testing.B) {
+	p := startPlugin(b, factory, test.NewConfig(&Config{Rules: []RuleConfig{
+		{Type: "keep", Fields: benchFields},
+		{Type: "drop", Fields: []string{"user.password", "user.token", "request.headers.cookie", "request.headers.authorization"}},
+		{Type: "rename", From: "msg", To: "message"},
+		{Type: "rename", From: "request.id", To: "request_id"},
+		{Type: "mask", Fields: []string{"user.email"}, Re: `^[^@]+`},
+		{Type: "mask", Fields: []string{"user.phone"}},
+	}}, nil))
+
+	benchmarkActions(b, []pipeline.ActionPlugin{p})
+}
+
+func BenchmarkChainedActions(b *testing.B) {
+	keepFactory := func() (pipeline.AnyPlugin, pipeline.AnyConfig) { return &keep_fields.Plugin{}, nil }
+	removeFactory := func() (pipeline.AnyPlugin, pipeline.AnyConfig) { return &remove_fields.Plugin{}, nil }
+	renameFactory := func() (pipeline.AnyPlugin, pipeline.AnyConfig) { return &rename.Plugin{}, nil }
+	maskFactory := func() (pipeline.AnyPlugin, pipeline.AnyConfig) { return &mask.Plugin{}, nil }
+
+	benchmarkActions(b, []pipeline.ActionPlugin{
+		startPlugin(b, keepFactory, test.NewConfig(&keep_fields.Config{Fields: benchFields}, nil)),
+		startPlugin(b, removeFactory, test.NewConfig(&remove_fields.Config{
+			Fields: []string{"user.password", "user.token", "request.headers.cookie", "request.headers.authorization"},
+		}, nil)),
+		startPlugin(b, renameFactory, &rename.Config{"msg": "message", "request.id": "request_id"}),
+		startPlugin(b, maskFactory, test.NewConfig(&mask.Config{
+			Field: cfg.FieldSelector("user.email"),
+			Masks: []mask.MaskConfig{{Re: `^[^@]+`}},
+		}, nil)),
+		startPlugin(b, maskFactory, test.NewConfig(&mask.Config{
+			Field: cfg.FieldSelector("user.phone"),
+			Masks: []mask.MaskConfig{{Re: `.+`}},
+		}, nil)),
+	})
+}
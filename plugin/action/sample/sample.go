@@ -0,0 +1,124 @@
+package sample
+
+import (
+	"hash"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"go.uber.org/atomic"
+)
+
+/*{ introduction
+It keeps only `rate` fraction of events and discards others.
+If `field` is set, the decision is made by the hash of the field value, so all events with the same value are kept or discarded together,
+e.g. whole traces are kept if the field is a trace id. The decision for the value doesn't depend on file.d restarts and processors,
+and values kept with a lower rate are kept with a higher rate too.
+Events without the field and events of a pipeline without `field` are sampled randomly.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: sample
+      match_fields:
+        level: debug
+      rate: 0.1
+      field: trace_id
+    ...
+```
+}*/
+type Plugin struct {
+	config    *Config
+	threshold uint64
+	keepAll   bool
+	hasher    hash.Hash64
+	rnd       *rand.Rand
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The fraction of events to keep, it should be greater than `0` and not greater than `1`.
+	Rate float64 `json:"rate" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field which value is the sampling key.
+	Field  cfg.FieldSelector `json:"field" parse:"selector"` //*
+	Field_ []string
+}
+
+// seeds of random generators should differ for processors started at the same time
+var seedCounter = atomic.NewInt64(0)
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "sample",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+
+	if p.config.Rate <= 0 || p.config.Rate > 1 {
+		params.Logger.Fatalf("rate should be in (0, 1], got=%f", p.config.Rate)
+	}
+
+	p.keepAll = p.config.Rate == 1
+	if !p.keepAll {
+		p.threshold = uint64(p.config.Rate * math.MaxUint64)
+	}
+	p.hasher = fnv.New64a()
+	p.rnd = rand.New(rand.NewSource(time.Now().UnixNano() + seedCounter.Inc()))
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	if p.keepAll {
+		return pipeline.ActionPass
+	}
+
+	var h uint64
+	node := event.Root.Dig(p.config.Field_...)
+	if len(p.config.Field_) != 0 && node != nil {
+		p.hasher.Reset()
+		_, _ = p.hasher.Write(node.AsBytes())
+		h = mix(p.hasher.Sum64())
+	} else {
+		h = p.rnd.Uint64()
+	}
+
+	if h < p.threshold {
+		return pipeline.ActionPass
+	}
+
+	return pipeline.ActionDiscard
+}
+
+// mix is the finalizer of splitmix64, it spreads FNV hash of similar values over all bits,
+// because the threshold is compared with the high bits
+func mix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+
+	return h
+}
@@ -0,0 +1,115 @@
+package sample
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/logger"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+func newPlugin(config *Config) *Plugin {
+	p, _ := factory()
+	plugin := p.(*Plugin)
+	plugin.Start(test.NewConfig(config, nil), &pipeline.ActionPluginParams{Logger: logger.Instance})
+
+	return plugin
+}
+
+// isKept returns whether the plugin passes the event
+func isKept(t *testing.T, p *Plugin, json string) bool {
+	root, err := insaneJSON.DecodeString(json)
+	assert.NoError(t, err, "wrong json")
+	defer insaneJSON.Release(root)
+
+	return p.Do(&pipeline.Event{Root: root}) == pipeline.ActionPass
+}
+
+func TestSampleRate(t *testing.T) {
+	const count = 20000
+
+	for _, rate := range []float64{0.1, 0.5, 0.9} {
+		for _, field := range []string{"", "trace_id"} {
+			p := newPlugin(&Config{Rate: rate, Field: cfg.FieldSelector(field)})
+
+			kept := 0
+			for i := 0; i < count; i++ {
+				if isKept(t, p, fmt.Sprintf(`{"trace_id":"trace-%d"}`, i)) {
+					kept++
+				}
+			}
+
+			ratio := float64(kept) / count
+			assert.InDelta(t, rate, ratio, 0.02, "wrong keep ratio for rate %f and field %q", rate, field)
+		}
+	}
+}
+
+func TestSampleKeyStable(t *testing.T) {
+	first := newPlugin(&Config{Rate: 0.3, Field: "trace.id"})
+	second := newPlugin(&Config{Rate: 0.3, Field: "trace.id"})
+	higher := newPlugin(&Config{Rate: 0.6, Field: "trace.id"})
+
+	for i := 0; i < 1000; i++ {
+		event := fmt.Sprintf(`{"trace":{"id":"%d"},"message":"span %d"}`, i, i)
+		kept := isKept(t, first, event)
+
+		for j := 0; j < 3; j++ {
+			assert.Equal(t, kept, isKept(t, first, event), "decision is changed for the same key")
+		}
+		assert.Equal(t, kept, isKept(t, second, event), "decision is different for other plugin instance")
+		if kept {
+			assert.True(t, isKept(t, higher, event), "key kept with lower rate is discarded with higher rate")
+		}
+	}
+}
+
+func TestSampleKeepAll(t *testing.T) {
+	p := newPlugin(&Config{Rate: 1, Field: "trace_id"})
+	for i := 0; i < 100; i++ {
+		assert.True(t, isKept(t, p, fmt.Sprintf(`{"trace_id":"%d"}`, i)), "event is discarded with rate 1")
+		assert.True(t, isKept(t, p, `{"message":"no key"}`), "event is discarded with rate 1")
+	}
+}
+
+func TestSamplePipeline(t *testing.T) {
+	config := &Config{Rate: 0.5, Field: "trace_id"}
+	events := make([]string, 0)
+	expected := make([]string, 0)
+	predictor := newPlugin(config)
+	for i := 0; i < 100; i++ {
+		for span := 0; span < 3; span++ {
+			event := fmt.Sprintf(`{"trace_id":"trace-%d","span":%d}`, i, span)
+			events = append(events, event)
+			if isKept(t, predictor, event) {
+				expected = append(expected, event)
+			}
+		}
+	}
+
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, test.NewConfig(&Config{Rate: 0.5, Field: "trace_id"}, nil), pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(expected))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, event := range events {
+		input.In(0, "test.log", 0, []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, expected, outEvents, "wrong out events")
+	assert.Equal(t, 0, len(expected)%3, "spans of the same trace aren't kept together")
+}
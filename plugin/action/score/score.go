@@ -0,0 +1,124 @@
+package score
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It computes a weighted sum of numeric event fields plus a bias and puts the result into the target field:
+`score = bias + weight_1 * field_1 + ... + weight_n * field_n`.
+Numeric strings are also accepted. Missing or non-numeric fields are replaced with `missing_value`.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: score
+      weights:
+        failed_logins: 2
+        geo.distance_km: 0.01
+      bias: 1
+      field: risk
+    ...
+```
+It transforms `{"failed_logins":3,"geo":{"distance_km":500}}` into `{"failed_logins":3,"geo":{"distance_km":500},"risk":12}`.
+}*/
+type Plugin struct {
+	config *Config
+	terms  []*term
+}
+
+type term struct {
+	field  []string
+	weight float64
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The map of `event field => weight`. Nested fields can be used.
+	Weights map[string]float64 `json:"weights" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The value which is added to the weighted sum.
+	Bias float64 `json:"bias"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The value which is used for missing or non-numeric fields.
+	MissingValue float64 `json:"missing_value"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field to put the score to.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"score"` //*
+	Field_ []string
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "score",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+
+	// sort fields to have the same order of additions for all events
+	fields := make([]string, 0, len(p.config.Weights))
+	for field := range p.config.Weights {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	p.terms = make([]*term, 0, len(fields))
+	for _, field := range fields {
+		p.terms = append(p.terms, &term{
+			field:  cfg.ParseFieldSelector(field),
+			weight: p.config.Weights[field],
+		})
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	score := p.config.Bias
+	for _, t := range p.terms {
+		score += t.weight * p.value(event, t.field)
+	}
+
+	pipeline.CreateNestedField(event.Root, p.config.Field_).MutateToFloat(score)
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) value(event *pipeline.Event, field []string) float64 {
+	node := event.Root.Dig(field...)
+	if node == nil || !(node.IsNumber() || node.IsString()) {
+		return p.config.MissingValue
+	}
+
+	value, err := strconv.ParseFloat(node.AsString(), 64)
+	if err != nil {
+		return p.config.MissingValue
+	}
+
+	return value
+}
@@ -0,0 +1,68 @@
+package score
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func runScore(config *Config, events []string) []*pipeline.Event {
+	test.NewConfig(config, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(events))
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	for _, event := range events {
+		input.In(0, "test.log", 0, []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+func TestScore(t *testing.T) {
+	outEvents := runScore(&Config{Weights: map[string]float64{"failed_logins": 2, "geo.distance_km": 0.01, "new_device": -0.5}, Bias: 1}, []string{
+		`{"failed_logins":3,"geo":{"distance_km":500},"new_device":1}`,
+		`{"failed_logins":"4","geo":{"distance_km":0},"new_device":0}`,
+	})
+
+	assert.Equal(t, 2, len(outEvents), "wrong out events count")
+	assert.InDelta(t, 11.5, outEvents[0].Root.Dig("score").AsFloat(), 1e-9, "wrong score")
+	assert.InDelta(t, 9, outEvents[1].Root.Dig("score").AsFloat(), 1e-9, "wrong score")
+}
+
+func TestScoreMissingFields(t *testing.T) {
+	weights := map[string]float64{"a": 1, "b": 10}
+
+	outEvents := runScore(&Config{Weights: weights, Field: "risk.score"}, []string{
+		`{"a":5}`,
+		`{"a":5,"b":"not a number"}`,
+		`{"a":{"nested":1},"b":null}`,
+	})
+
+	assert.Equal(t, 3, len(outEvents), "wrong out events count")
+	assert.Equal(t, 5.0, outEvents[0].Root.Dig("risk", "score").AsFloat(), "wrong score")
+	assert.Equal(t, 5.0, outEvents[1].Root.Dig("risk", "score").AsFloat(), "wrong score")
+	assert.Equal(t, 0.0, outEvents[2].Root.Dig("risk", "score").AsFloat(), "wrong score")
+
+	outEvents = runScore(&Config{Weights: weights, MissingValue: 2, Bias: -1}, []string{
+		`{"a":5}`,
+		`{}`,
+	})
+
+	assert.Equal(t, 2, len(outEvents), "wrong out events count")
+	assert.Equal(t, 24.0, outEvents[0].Root.Dig("score").AsFloat(), "wrong score")
+	assert.Equal(t, 21.0, outEvents[1].Root.Dig("score").AsFloat(), "wrong score")
+}
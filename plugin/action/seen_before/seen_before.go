@@ -0,0 +1,235 @@
+package seen_before
+
+import (
+	"hash"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultCapacity          = 1000000
+	defaultFalsePositiveRate = 0.01
+)
+
+var (
+	separator = []byte{0}
+
+	// filters should be shared across processors of the action, all of them receive the same config, so let's have a map by config
+	filters   = map[*Config]*rotatingFilter{}
+	filtersMu = &sync.Mutex{}
+)
+
+/*{ introduction
+It sets `seen_field` to `true` if the key of the event has probably been seen before, otherwise it's set to `false`.
+It's a cheap approximate deduplication: keys are stored in a Bloom filter, so there are no false negatives,
+but a new key may be reported as seen with `false_positive_rate` probability.
+
+To bound the false positive rate, the filter is rotated every `interval`: keys of the previous interval are still checked,
+keys of older intervals are forgotten. So a key is remembered at least for `interval` since it's seen last time.
+The filter is shared across processors of the pipeline.
+Events which are reported as seen are counted by `seen_before_seen_events_total` metric of the pipeline.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: seen_before
+      fields:
+      - service
+      - message
+      interval: 1h
+      capacity: 100000
+    ...
+```
+}*/
+type Plugin struct {
+	config *Config
+	filter *rotatingFilter
+
+	fields [][]string
+	hasher hash.Hash64
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The list of the fields which make the key of the event. Nested fields can be used.
+	Fields []cfg.FieldSelector `json:"fields" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field to put the flag to.
+	SeenField string `json:"seen_field" default:"seen"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The interval of the filter rotation.
+	Interval  cfg.Duration `json:"interval" parse:"duration" default:"1h"` //*
+	Interval_ time.Duration
+
+	//> @3@4@5@6
+	//>
+	//> The expected number of unique keys per interval, it defines the size of the filter. `1000000` if not set.
+	Capacity int `json:"capacity"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The desired probability of false positives if the number of keys doesn't exceed `capacity`. `0.01` if not set.
+	FalsePositiveRate float64 `json:"false_positive_rate"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "seen_before",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+
+	if p.config.Interval_ <= 0 {
+		params.Logger.Fatalf("interval should be positive, got=%s", p.config.Interval)
+	}
+	if p.config.Capacity <= 0 {
+		p.config.Capacity = defaultCapacity
+	}
+	if p.config.FalsePositiveRate <= 0 {
+		p.config.FalsePositiveRate = defaultFalsePositiveRate
+	}
+	if p.config.FalsePositiveRate >= 1 {
+		params.Logger.Fatalf("false_positive_rate should be less than 1, got=%f", p.config.FalsePositiveRate)
+	}
+
+	p.fields = make([][]string, 0, len(p.config.Fields))
+	for _, field := range p.config.Fields {
+		p.fields = append(p.fields, cfg.ParseFieldSelector(string(field)))
+	}
+	p.hasher = fnv.New64a()
+
+	seen := params.MetricsCtl.RegisterCounter("seen_before_seen_events_total", "how many events are reported as seen by seen_before action").WithLabelValues()
+
+	filtersMu.Lock()
+	f, has := filters[p.config]
+	if !has {
+		f = newRotatingFilter(p.config.Capacity, p.config.FalsePositiveRate, p.config.Interval_, seen)
+		filters[p.config] = f
+	}
+	filtersMu.Unlock()
+
+	p.filter = f
+}
+
+func (p *Plugin) Stop() {
+	filtersMu.Lock()
+	delete(filters, p.config)
+	filtersMu.Unlock()
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	p.hasher.Reset()
+	for _, field := range p.fields {
+		_, _ = p.hasher.Write(event.Root.Dig(field...).AsBytes())
+		// zero byte separates values, so "ab"+"c" and "a"+"bc" keys are different
+		_, _ = p.hasher.Write(separator)
+	}
+
+	seen := p.filter.checkAndAdd(p.hasher.Sum64(), time.Now())
+	event.Root.AddFieldNoAlloc(event.Root, p.config.SeenField).MutateToBool(seen)
+
+	return pipeline.ActionPass
+}
+
+// rotatingFilter keeps Bloom filters of the current and the previous intervals
+type rotatingFilter struct {
+	mu       *sync.Mutex
+	interval time.Duration
+	seen     prometheus.Counter
+
+	bitsCount   uint64
+	hashesCount uint64
+
+	current   []uint64
+	previous  []uint64
+	rotatedAt time.Time
+}
+
+func newRotatingFilter(capacity int, falsePositiveRate float64, interval time.Duration, seen prometheus.Counter) *rotatingFilter {
+	// optimal parameters of the filter: m = -n*ln(p)/ln(2)^2, k = m/n*ln(2)
+	bitsCount := uint64(math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashesCount := uint64(math.Round(float64(bitsCount) / float64(capacity) * math.Ln2))
+	if hashesCount == 0 {
+		hashesCount = 1
+	}
+
+	return &rotatingFilter{
+		mu:       &sync.Mutex{},
+		interval: interval,
+		seen:     seen,
+
+		bitsCount:   bitsCount,
+		hashesCount: hashesCount,
+
+		current:  make([]uint64, (bitsCount+63)/64),
+		previous: make([]uint64, (bitsCount+63)/64),
+	}
+}
+
+// checkAndAdd returns true if the key hash is probably seen before and adds it to the current filter
+func (f *rotatingFilter) checkAndAdd(h uint64, now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.rotatedAt.IsZero() {
+		f.rotatedAt = now
+	}
+	if elapsed := now.Sub(f.rotatedAt); elapsed >= f.interval {
+		f.previous, f.current = f.current, f.previous
+		// both filters are outdated if more than two intervals passed
+		if elapsed >= f.interval*2 {
+			clearBits(f.previous)
+		}
+		clearBits(f.current)
+		f.rotatedAt = now
+	}
+
+	// double hashing is used to get the positions: h1 + i*h2
+	h1, h2 := h&math.MaxUint32, h>>32|1
+	inCurrent, inPrevious := true, true
+	for i := uint64(0); i < f.hashesCount; i++ {
+		pos := (h1 + i*h2) % f.bitsCount
+		word, bit := pos/64, uint64(1)<<(pos%64)
+
+		inCurrent = inCurrent && f.current[word]&bit != 0
+		inPrevious = inPrevious && f.previous[word]&bit != 0
+		f.current[word] |= bit
+	}
+
+	seen := inCurrent || inPrevious
+	if seen {
+		f.seen.Inc()
+	}
+
+	return seen
+}
+
+func clearBits(bits []uint64) {
+	for i := range bits {
+		bits[i] = 0
+	}
+}
@@ -0,0 +1,118 @@
+package seen_before
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/logger"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeenBefore(t *testing.T) {
+	config := test.NewConfig(&Config{Fields: []cfg.FieldSelector{"service", "error.code"}}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(6)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"service":"api","error":{"code":"E1"}}`))
+	input.In(0, "test.log", 0, []byte(`{"service":"api","error":{"code":"E2"}}`))
+	input.In(0, "test.log", 0, []byte(`{"service":"api","error":{"code":"E1"},"message":"again"}`))
+	input.In(0, "test.log", 0, []byte(`{"service":"apiE","error":{"code":"1"}}`))
+	input.In(0, "test.log", 0, []byte(`{"service":"web"}`))
+	input.In(0, "test.log", 0, []byte(`{"service":"web"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	seen := make([]bool, 0)
+	for _, e := range outEvents {
+		seen = append(seen, e.Root.Dig("seen").AsBool())
+	}
+	assert.Equal(t, []bool{false, false, true, false, false, true}, seen, "wrong seen flags")
+}
+
+func TestRotatingFilter(t *testing.T) {
+	f := newRotatingFilter(1000, 0.01, time.Minute, prometheus.NewCounter(prometheus.CounterOpts{Name: "seen"}))
+	now := time.Now()
+
+	assert.False(t, f.checkAndAdd(1, now), "new key shouldn't be seen")
+	assert.True(t, f.checkAndAdd(1, now.Add(time.Second*30)), "repeated key should be seen")
+
+	// the key is in the previous filter after the rotation
+	assert.True(t, f.checkAndAdd(1, now.Add(time.Second*70)), "key of the previous interval should be seen")
+	assert.False(t, f.checkAndAdd(2, now.Add(time.Second*80)), "new key shouldn't be seen")
+
+	// the key is added to the current filter on each check, so it's remembered while it's repeated
+	assert.True(t, f.checkAndAdd(1, now.Add(time.Second*140)), "repeated key should be seen")
+	assert.False(t, f.checkAndAdd(2, now.Add(time.Second*210)), "key of the older interval should be forgotten")
+
+	// both filters are cleared if the key isn't checked for two intervals
+	assert.False(t, f.checkAndAdd(1, now.Add(time.Second*400)), "outdated key shouldn't be seen")
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum64()
+}
+
+func TestRotatingFilterFalsePositives(t *testing.T) {
+	const capacity = 10000
+	f := newRotatingFilter(capacity, 0.01, time.Hour, prometheus.NewCounter(prometheus.CounterOpts{Name: "seen"}))
+	now := time.Now()
+
+	for i := 0; i < capacity; i++ {
+		f.checkAndAdd(hashKey("key_"+strconv.Itoa(i)), now)
+	}
+
+	// new keys are added to the filter too, so check a few of them to keep the filter close to its capacity
+	falsePositives := 0
+	for i := capacity; i < capacity+1000; i++ {
+		if f.checkAndAdd(hashKey("key_"+strconv.Itoa(i)), now) {
+			falsePositives++
+		}
+	}
+	assert.True(t, falsePositives < 30, "too many false positives: %d", falsePositives)
+
+	for i := 0; i < capacity; i++ {
+		assert.True(t, f.checkAndAdd(hashKey("key_"+strconv.Itoa(i)), now), "there should be no false negatives")
+	}
+}
+
+func TestSeenBeforeFilterPerAction(t *testing.T) {
+	start := func(config *Config) *Plugin {
+		plugin := &Plugin{}
+		plugin.Start(config, &pipeline.ActionPluginParams{PluginDefaultParams: test.NewEmptyPluginDefaultParams(), Logger: logger.Instance})
+		return plugin
+	}
+
+	config := test.NewConfig(&Config{Fields: []cfg.FieldSelector{"service"}}, nil).(*Config)
+	first := start(config)
+	second := start(config)
+	other := start(test.NewConfig(&Config{Fields: []cfg.FieldSelector{"host"}}, nil).(*Config))
+
+	assert.True(t, first.filter == second.filter, "processors of the action should share the filter")
+	assert.True(t, first.filter != other.filter, "actions of the pipeline shouldn't share the filter")
+
+	first.Stop()
+	second.Stop()
+	other.Stop()
+
+	restarted := start(config)
+	assert.True(t, first.filter != restarted.filter, "filter shouldn't survive the action stop")
+	restarted.Stop()
+}
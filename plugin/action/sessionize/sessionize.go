@@ -0,0 +1,218 @@
+package sessionize
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultMaxKeys = 100000
+)
+
+var (
+	// trackers should be shared across processors of the action, all of them receive the same config, so let's have a map by config
+	trackers   = map[*Config]*tracker{}
+	trackersMu = &sync.Mutex{}
+)
+
+/*{ introduction
+It groups events of each value of the key field, e.g. user ID, into sessions and sets `session_field` to the ID of the session.
+A new session is started when the gap between the event and the previous event of the key exceeds `session_timeout`.
+
+The session ID is the time of the first event of the session in Unix milliseconds, so the ID increases with each new session of the key.
+The time of the event is taken from `time_field`, the current time is used if the field is absent or can't be parsed.
+Events which are older than the last event of the key belong to the current session.
+Events without the key field are passed unchanged.
+
+Sessions of the keys are shared across processors of the pipeline. No more than `max_keys` keys are tracked:
+when the limit is reached, keys with expired sessions are removed, and if there are no such keys, all keys are removed,
+so the sessions of the removed keys are started again. Started sessions are counted by `sessionize_sessions_total` metric of the pipeline.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: sessionize
+      key_field: user_id
+      session_timeout: 30m
+    ...
+```
+}*/
+type Plugin struct {
+	config  *Config
+	tracker *tracker
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field which value is used as a key.
+	KeyField  cfg.FieldSelector `json:"key_field" parse:"selector" required:"true"` //*
+	KeyField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The maximum gap between consecutive events of the session.
+	SessionTimeout  cfg.Duration `json:"session_timeout" parse:"duration" default:"30m"` //*
+	SessionTimeout_ time.Duration
+
+	//> @3@4@5@6
+	//>
+	//> The event field to put the session ID to.
+	SessionField  cfg.FieldSelector `json:"session_field" parse:"selector" default:"session_id"` //*
+	SessionField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The event field which contains the time of the event.
+	TimeField  cfg.FieldSelector `json:"time_field" parse:"selector" default:"time"` //*
+	TimeField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The format of `time_field`.
+	TimeFieldFormat string `json:"time_field_format" default:"rfc3339nano" options:"ansic|unixdate|rubydate|rfc822|rfc822z|rfc850|rfc1123|rfc1123z|rfc3339|rfc3339nano|kitchen|stamp|stampmilli|stampmicro|stampnano"` //*
+	TimeFieldFormat_ string
+
+	//> @3@4@5@6
+	//>
+	//> The maximum number of keys to track. `100000` if not set.
+	MaxKeys int `json:"max_keys"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "sessionize",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	if p.config.SessionTimeout_ <= 0 {
+		params.Logger.Fatalf("session_timeout should be positive, got=%s", p.config.SessionTimeout)
+	}
+	if p.config.MaxKeys <= 0 {
+		p.config.MaxKeys = defaultMaxKeys
+	}
+
+	format, err := pipeline.ParseFormatName(p.config.TimeFieldFormat)
+	if err != nil {
+		params.Logger.Fatalf("wrong time field format: %s", err.Error())
+	}
+	p.config.TimeFieldFormat_ = format
+
+	started := params.MetricsCtl.RegisterCounter("sessionize_sessions_total", "how many sessions are started by sessionize action").WithLabelValues()
+
+	trackersMu.Lock()
+	t, has := trackers[p.config]
+	if !has {
+		t = newTracker(p.config.SessionTimeout_, p.config.MaxKeys, started)
+		trackers[p.config] = t
+	}
+	trackersMu.Unlock()
+
+	p.tracker = t
+}
+
+func (p *Plugin) Stop() {
+	trackersMu.Lock()
+	delete(trackers, p.config)
+	trackersMu.Unlock()
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	key := event.Root.Dig(p.config.KeyField_...)
+	if key == nil {
+		return pipeline.ActionPass
+	}
+
+	ts := time.Now()
+	if node := event.Root.Dig(p.config.TimeField_...); node != nil {
+		if t, err := time.Parse(p.config.TimeFieldFormat_, node.AsString()); err == nil {
+			ts = t
+		}
+	}
+
+	id := p.tracker.sessionID(key.AsBytes(), ts)
+	pipeline.CreateNestedField(event.Root, p.config.SessionField_).MutateToInt(int(id))
+
+	return pipeline.ActionPass
+}
+
+type session struct {
+	startedAt  time.Time
+	lastSeenAt time.Time
+}
+
+type tracker struct {
+	mu      *sync.Mutex
+	timeout time.Duration
+	maxKeys int
+	started prometheus.Counter
+
+	keys map[string]*session
+}
+
+func newTracker(timeout time.Duration, maxKeys int, started prometheus.Counter) *tracker {
+	return &tracker{
+		mu:      &sync.Mutex{},
+		timeout: timeout,
+		maxKeys: maxKeys,
+		started: started,
+
+		keys: make(map[string]*session),
+	}
+}
+
+// sessionID returns the ID of the session of the key which the event of ts time belongs to
+func (t *tracker) sessionID(key []byte, ts time.Time) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, has := t.keys[string(key)]
+	if !has {
+		if len(t.keys) >= t.maxKeys {
+			t.removeExpired(ts)
+		}
+		s = &session{}
+		t.keys[string(key)] = s
+	}
+
+	if !has || ts.Sub(s.lastSeenAt) > t.timeout {
+		s.startedAt = ts
+		s.lastSeenAt = ts
+		t.started.Inc()
+	}
+	if ts.After(s.lastSeenAt) {
+		s.lastSeenAt = ts
+	}
+
+	return s.startedAt.UnixNano() / int64(time.Millisecond)
+}
+
+// removeExpired removes keys with expired sessions, all keys are removed if there are no such keys
+func (t *tracker) removeExpired(now time.Time) {
+	for key, s := range t.keys {
+		if now.Sub(s.lastSeenAt) > t.timeout {
+			delete(t.keys, key)
+		}
+	}
+
+	if len(t.keys) >= t.maxKeys {
+		t.keys = make(map[string]*session)
+	}
+}
@@ -0,0 +1,111 @@
+package sessionize
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ozonru/file.d/logger"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionize(t *testing.T) {
+	config := test.NewConfig(&Config{KeyField: "user", SessionTimeout: "30m"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	events := []string{
+		`{"user":"a","time":"2021-01-01T10:00:00Z"}`,
+		`{"user":"b","time":"2021-01-01T10:05:00Z"}`,
+		// within the timeout
+		`{"user":"a","time":"2021-01-01T10:20:00Z"}`,
+		`{"user":"a","time":"2021-01-01T10:50:00Z"}`,
+		// older event belongs to the current session
+		`{"user":"a","time":"2021-01-01T10:10:00Z"}`,
+		// the gap exceeds the timeout
+		`{"user":"a","time":"2021-01-01T11:20:01Z"}`,
+		// the gap is equal to the timeout
+		`{"user":"b","time":"2021-01-01T10:35:00Z"}`,
+		`{"user":"b","time":"2021-01-01T11:05:01Z"}`,
+		`{"time":"2021-01-01T10:00:00Z"}`,
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(events))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, event := range events {
+		input.In(0, "test.log", 0, []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"user":"a","time":"2021-01-01T10:00:00Z","session_id":1609495200000}`,
+		`{"user":"b","time":"2021-01-01T10:05:00Z","session_id":1609495500000}`,
+		`{"user":"a","time":"2021-01-01T10:20:00Z","session_id":1609495200000}`,
+		`{"user":"a","time":"2021-01-01T10:50:00Z","session_id":1609495200000}`,
+		`{"user":"a","time":"2021-01-01T10:10:00Z","session_id":1609495200000}`,
+		`{"user":"a","time":"2021-01-01T11:20:01Z","session_id":1609500001000}`,
+		`{"user":"b","time":"2021-01-01T10:35:00Z","session_id":1609495500000}`,
+		`{"user":"b","time":"2021-01-01T11:05:01Z","session_id":1609499101000}`,
+		`{"time":"2021-01-01T10:00:00Z"}`,
+	}, outEvents, "wrong out events")
+
+	started := p.GetMetricsCtl().RegisterCounter("sessionize_sessions_total", "")
+	assert.Equal(t, float64(4), testutil.ToFloat64(started.WithLabelValues()), "wrong started sessions count")
+}
+
+func TestSessionizeMaxKeys(t *testing.T) {
+	started := prometheus.NewCounter(prometheus.CounterOpts{Name: "started"})
+	tr := newTracker(time.Minute, 2, started)
+	now := time.Now()
+
+	idA := tr.sessionID([]byte("a"), now)
+	tr.sessionID([]byte("b"), now.Add(30*time.Second))
+	assert.Equal(t, idA, tr.sessionID([]byte("a"), now.Add(time.Minute)), "session should continue within the timeout")
+
+	// b is expired, so it's removed to make room for c
+	tr.sessionID([]byte("c"), now.Add(time.Minute+31*time.Second))
+	assert.Equal(t, 2, len(tr.keys), "expired keys aren't removed")
+	assert.NotNil(t, tr.keys["a"], "active key is removed")
+	assert.Nil(t, tr.keys["b"], "expired key isn't removed")
+
+	// there are no expired keys, so all keys are removed
+	tr.sessionID([]byte("d"), now.Add(time.Minute+32*time.Second))
+	assert.Equal(t, 1, len(tr.keys), "keys aren't removed")
+	assert.Equal(t, float64(4), testutil.ToFloat64(started), "wrong started sessions count")
+}
+
+func TestSessionizeTrackerPerAction(t *testing.T) {
+	start := func(config *Config) *Plugin {
+		plugin := &Plugin{}
+		plugin.Start(config, &pipeline.ActionPluginParams{PluginDefaultParams: test.NewEmptyPluginDefaultParams(), Logger: logger.Instance})
+		return plugin
+	}
+
+	config := test.NewConfig(&Config{KeyField: "user", SessionTimeout: "30m"}, nil).(*Config)
+	first := start(config)
+	second := start(config)
+	other := start(test.NewConfig(&Config{KeyField: "device", SessionTimeout: "30m"}, nil).(*Config))
+
+	assert.True(t, first.tracker == second.tracker, "processors of the action should share the tracker")
+	assert.True(t, first.tracker != other.tracker, "actions of the pipeline shouldn't share the tracker")
+
+	first.Stop()
+	second.Stop()
+	other.Stop()
+
+	restarted := start(config)
+	assert.True(t, first.tracker != restarted.tracker, "tracker shouldn't survive the action stop")
+	restarted.Stop()
+}
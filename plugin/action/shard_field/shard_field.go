@@ -0,0 +1,94 @@
+package shard_field
+
+import (
+	"hash"
+	"hash/fnv"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It hashes the value of the key field into a shard number in the range `0..shards-1` and puts it into the event.
+The same key always gets the same shard, so events can be consistently fanned out to `shards` destinations.
+Events without the key field are handled as events with an empty key.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: shard_field
+      key_field: user_id
+      shards: 16
+    ...
+```
+It transforms `{"user_id":"42"}` into `{"user_id":"42","shard":3}`.
+}*/
+type Plugin struct {
+	config *Config
+	logger *zap.SugaredLogger
+	hash   hash.Hash32
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field which value is hashed.
+	KeyField  cfg.FieldSelector `json:"key_field" parse:"selector" required:"true"` //*
+	KeyField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The number of shards. Must be positive.
+	Shards int `json:"shards" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field to put the shard number to.
+	Field string `json:"field" default:"shard"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "shard_field",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger
+
+	if p.config.Shards <= 0 {
+		p.logger.Fatalf("shards should be positive, got=%d", p.config.Shards)
+	}
+
+	p.hash = fnv.New32a()
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	shard := p.shard(event.Root.Dig(p.config.KeyField_...).AsBytes())
+	event.Root.AddFieldNoAlloc(event.Root, p.config.Field).MutateToInt(shard)
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) shard(key []byte) int {
+	p.hash.Reset()
+	_, _ = p.hash.Write(key)
+
+	return int(p.hash.Sum32() % uint32(p.config.Shards))
+}
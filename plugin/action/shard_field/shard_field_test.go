@@ -0,0 +1,69 @@
+package shard_field
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardFieldDeterminism(t *testing.T) {
+	config := test.NewConfig(&Config{KeyField: "user.id", Shards: 8}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	keys := []string{"alice", "bob", "carol", "dave"}
+	repeats := 5
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(keys)*repeats + 1)
+
+	shards := make(map[string][]int)
+	output.SetOutFn(func(e *pipeline.Event) {
+		key := e.Root.Dig("user", "id").AsString()
+		shards[key] = append(shards[key], e.Root.Dig("shard").AsInt())
+		wg.Done()
+	})
+
+	for i := 0; i < repeats; i++ {
+		for _, key := range keys {
+			input.In(0, "test.log", 0, []byte(`{"user":{"id":"`+key+`"}}`))
+		}
+	}
+	input.In(0, "test.log", 0, []byte(`{"message":"no key"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	for _, key := range append(keys, "") {
+		assert.Equal(t, 1, len(uniq(shards[key])), "shard isn't consistent for key %q", key)
+		assert.True(t, shards[key][0] >= 0 && shards[key][0] < 8, "shard is out of range for key %q", key)
+	}
+}
+
+func TestShardFieldDistribution(t *testing.T) {
+	plugin := &Plugin{config: &Config{Shards: 10}, hash: fnv.New32a()}
+
+	keys := 100000
+	counts := make([]int, 10)
+	for i := 0; i < keys; i++ {
+		counts[plugin.shard([]byte("key_"+strconv.Itoa(i)))]++
+	}
+
+	expected := keys / 10
+	for shard, count := range counts {
+		assert.InDelta(t, expected, count, float64(expected)*0.05, "shard %d is unbalanced", shard)
+	}
+}
+
+func uniq(values []int) map[int]bool {
+	result := make(map[int]bool)
+	for _, value := range values {
+		result[value] = true
+	}
+
+	return result
+}
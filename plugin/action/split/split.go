@@ -0,0 +1,124 @@
+package split
+
+import (
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It splits the event containing an array into several events, one event per array element: the element becomes the root of the event.
+Fields from `copy_fields` are copied from the original event to each event unless the element has the same field.
+The original event gets the first element, events of other elements are new events,
+they go through the next actions and to the output after the original event. Elements which aren't objects are skipped,
+the event is passed unchanged if the field isn't an array or the array has no object elements.
+
+New events have the same source and offset as the original event, the offset is committed only by the original event.
+So if file.d is stopped after the original event is committed, but before new events are sent, new events are lost.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: split
+      field: records
+      copy_fields:
+      - service
+    ...
+```
+It transforms `{"service":"api","records":[{"id":1},{"id":2}]}` into two events:
+`{"id":1,"service":"api"}` and `{"id":2,"service":"api"}`.
+}*/
+type Plugin struct {
+	config     *Config
+	controller pipeline.ActionPluginController
+	logger     *zap.SugaredLogger
+
+	elements []*insaneJSON.Node
+	copied   []*insaneJSON.Node
+	buf      []byte
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field which contains the array to split.
+	Field  cfg.FieldSelector `json:"field" required:"true" parse:"selector"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The list of the top-level fields of the original event to copy to each event.
+	CopyFields []string `json:"copy_fields"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "split",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.controller = params.Controller
+	p.logger = params.Logger
+	p.copied = make([]*insaneJSON.Node, len(p.config.CopyFields))
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if !node.IsArray() {
+		return pipeline.ActionPass
+	}
+
+	p.elements = p.elements[:0]
+	for _, element := range node.AsArray() {
+		if element.IsObject() {
+			p.elements = append(p.elements, element)
+		}
+	}
+	if len(p.elements) == 0 {
+		return pipeline.ActionPass
+	}
+
+	// nodes are still valid after the root is replaced by an element
+	for i, name := range p.config.CopyFields {
+		p.copied[i] = event.Root.Dig(name)
+	}
+
+	for _, element := range p.elements[1:] {
+		p.mutateToElement(event, element)
+
+		p.buf = event.Root.Encode(p.buf[:0])
+		if err := p.controller.Spawn(event, p.buf); err != nil {
+			p.logger.Errorf("can't spawn event: %s", err.Error())
+		}
+	}
+	p.mutateToElement(event, p.elements[0])
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) mutateToElement(event *pipeline.Event, element *insaneJSON.Node) {
+	event.Root.MutateToNode(element)
+	for i, name := range p.config.CopyFields {
+		if p.copied[i] == nil || event.Root.Dig(name) != nil {
+			continue
+		}
+		event.Root.AddFieldNoAlloc(event.Root, name).MutateToNode(p.copied[i])
+	}
+}
@@ -0,0 +1,86 @@
+package split
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+)
+
+func runEvents(config *Config, events []string, outCount int) ([]string, int) {
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, test.NewConfig(config, nil), pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(outCount)
+
+	commits := atomic.NewInt32(0)
+	input.SetCommitFn(func(e *pipeline.Event) {
+		commits.Inc()
+	})
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for i, event := range events {
+		input.In(0, "test.log", int64(i+1), []byte(event))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents, int(commits.Load())
+}
+
+func TestSplit(t *testing.T) {
+	outEvents, commits := runEvents(&Config{Field: "records", CopyFields: []string{"service", "host", "id"}}, []string{
+		`{"service":"api","id":0,"records":[{"id":1,"message":"first"},"not an object",{"id":2,"message":"second"},{"message":"third"}]}`,
+		`{"service":"web","records":[{"id":4}]}`,
+	}, 4)
+
+	assert.Equal(t, []string{
+		`{"id":1,"message":"first","service":"api"}`,
+		`{"id":2,"message":"second","service":"api"}`,
+		`{"message":"third","service":"api","id":0}`,
+		`{"id":4,"service":"web"}`,
+	}, outEvents, "wrong out events")
+	assert.Equal(t, 2, commits, "spawned events shouldn't be committed to the input")
+}
+
+func TestSplitEmptyArray(t *testing.T) {
+	events := []string{
+		`{"service":"api","records":[]}`,
+		`{"service":"web","records":[{"id":1}]}`,
+	}
+	outEvents, commits := runEvents(&Config{Field: "records"}, events, 2)
+
+	assert.Equal(t, []string{events[0], `{"id":1}`}, outEvents, "events with empty arrays should be passed unchanged")
+	assert.Equal(t, 2, commits, "wrong commits count")
+}
+
+func TestSplitNoObjects(t *testing.T) {
+	events := []string{
+		`{"service":"api","records":[1,"two",[3],null]}`,
+		`{"service":"web","records":[{"id":1}]}`,
+	}
+	outEvents, commits := runEvents(&Config{Field: "records"}, events, 2)
+
+	assert.Equal(t, []string{events[0], `{"id":1}`}, outEvents, "events without object elements should be passed unchanged")
+	assert.Equal(t, 2, commits, "wrong commits count")
+}
+
+func TestSplitNotArray(t *testing.T) {
+	events := []string{
+		`{"service":"api","batch":{"records":{"id":1}}}`,
+		`{"service":"api","batch":{"records":"[{\"id\":1}]"}}`,
+		`{"service":"api"}`,
+	}
+	outEvents, _ := runEvents(&Config{Field: "batch.records"}, events, 3)
+
+	assert.Equal(t, events, outEvents, "events without the array should be passed unchanged")
+}
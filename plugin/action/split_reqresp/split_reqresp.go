@@ -0,0 +1,125 @@
+package split_reqresp
+
+import (
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ introduction
+It flattens request and response objects of the event into prefixed fields of the event root.
+Nested objects are flattened recursively, names of nested keys are joined with `separator`.
+Arrays and empty objects are kept as values. If a half is absent or isn't an object, it's skipped.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: split_reqresp
+    ...
+```
+It transforms `{"request":{"method":"GET","headers":{"host":"example.com"}},"response":{"status":200}}`
+into `{"request_method":"GET","request_headers_host":"example.com","response_status":200}`.
+}*/
+type Plugin struct {
+	config  *Config
+	halves  []*half
+	nameBuf []byte
+}
+
+type half struct {
+	field  []string
+	prefix string
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field which contains the request object.
+	RequestField  cfg.FieldSelector `json:"request_field" parse:"selector" default:"request"` //*
+	RequestField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The event field which contains the response object.
+	ResponseField  cfg.FieldSelector `json:"response_field" parse:"selector" default:"response"` //*
+	ResponseField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to request fields.
+	RequestPrefix string `json:"request_prefix" default:"request_"` //*
+
+	//> @3@4@5@6
+	//>
+	//> A prefix to add to response fields.
+	ResponsePrefix string `json:"response_prefix" default:"response_"` //*
+
+	//> @3@4@5@6
+	//>
+	//> A separator of nested keys.
+	Separator string `json:"separator" default:"_"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "split_reqresp",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.halves = []*half{
+		{field: p.config.RequestField_, prefix: p.config.RequestPrefix},
+		{field: p.config.ResponseField_, prefix: p.config.ResponsePrefix},
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	for _, h := range p.halves {
+		node := event.Root.Dig(h.field...)
+		if !node.IsObject() {
+			continue
+		}
+
+		node.Suicide()
+
+		p.nameBuf = append(p.nameBuf[:0], h.prefix...)
+		p.flatten(event, node)
+	}
+
+	return pipeline.ActionPass
+}
+
+// flatten adds all leaves of the object to the root, name buffer contains the prefix of leaf names
+func (p *Plugin) flatten(event *pipeline.Event, node *insaneJSON.Node) {
+	prefixEnd := len(p.nameBuf)
+	for _, field := range node.AsFields() {
+		p.nameBuf = append(p.nameBuf[:prefixEnd], field.AsString()...)
+
+		value := field.AsFieldValue()
+		if value.IsObject() && len(value.AsFields()) != 0 {
+			p.nameBuf = append(p.nameBuf, p.config.Separator...)
+			p.flatten(event, value)
+			continue
+		}
+
+		l := len(event.Buf)
+		event.Buf = append(event.Buf, p.nameBuf...)
+		event.Root.AddFieldNoAlloc(event.Root, pipeline.ByteToStringUnsafe(event.Buf[l:])).MutateToNode(value)
+	}
+	p.nameBuf = p.nameBuf[:prefixEnd]
+}
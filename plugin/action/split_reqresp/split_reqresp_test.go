@@ -0,0 +1,60 @@
+package split_reqresp
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitReqResp(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"trace_id":"abc","request":{"method":"POST","url":"/orders","headers":{"host":"example.com","content":{"type":"application/json","length":42}},"tags":["a","b"],"meta":{}},"response":{"status":201,"body":{"id":7},"latency_ms":12.5}}`))
+	input.In(0, "test.log", 0, []byte(`{"request":{"method":"GET","url":"/health"}}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"only response","response":{"status":500,"error":null}}`))
+	input.In(0, "test.log", 0, []byte(`{"request":"GET /","response":null}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 4, len(outEvents), "wrong out events count")
+	assert.JSONEq(t, `{"trace_id":"abc","request_method":"POST","request_url":"/orders","request_headers_host":"example.com","request_headers_content_type":"application/json","request_headers_content_length":42,"request_tags":["a","b"],"request_meta":{},"response_status":201,"response_body_id":7,"response_latency_ms":12.5}`, outEvents[0], "wrong out event")
+	assert.JSONEq(t, `{"request_method":"GET","request_url":"/health"}`, outEvents[1], "wrong out event")
+	assert.JSONEq(t, `{"message":"only response","response_status":500,"response_error":null}`, outEvents[2], "wrong out event")
+	assert.JSONEq(t, `{"request":"GET /","response":null}`, outEvents[3], "wrong out event")
+}
+
+func TestSplitReqRespConfig(t *testing.T) {
+	config := test.NewConfig(&Config{RequestField: "http.req", ResponseField: "http.resp", RequestPrefix: "req.", ResponsePrefix: "resp.", Separator: "."}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"http":{"req":{"headers":{"host":"example.com"}},"resp":{"status":200}}}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 1, len(outEvents), "wrong out events count")
+	assert.JSONEq(t, `{"http":{},"req.headers.host":"example.com","resp.status":200}`, outEvents[0], "wrong out event")
+}
@@ -0,0 +1,375 @@
+package tail_sample
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxTraces      = 10000
+	defaultMaxTraceEvents = 1000
+	maxFlushInterval      = time.Second
+)
+
+var (
+	// buffers should be shared across processors of the action, because events of a trace may come from different streams,
+	// all processors receive the same config, so let's have a map by config
+	buffers   = map[*Config]*buffer{}
+	buffersMu = &sync.Mutex{}
+)
+
+/*{ introduction
+It makes the tail-based sampling decision: events are buffered by `trace_id_field` for `window` since the first event of the trace,
+then all events of the trace are kept if any of them matches `keep_if` conditions.
+Other traces are kept with `sample_rate` probability by the hash of the trace id, so the decision is the same as `sample` plugin's one for the same rate.
+Events without the trace id aren't buffered and pass through the plugin.
+
+Traces with ended windows are decided by the timer every second (or every `window` if it's shorter),
+and kept events are passed to the next actions as new events. Remaining traces are decided when the pipeline stops,
+events which reach the action after that are passed unchanged.
+The original events are discarded once they are buffered, so buffered events are lost if file.d crashes.
+
+To bound the memory, the oldest trace is decided before the end of its window if there are more than `max_traces` buffered traces,
+and only first `max_trace_events` events of a trace are buffered, others are discarded, but still checked with `keep_if` conditions.
+The buffer is shared across processors of the action.
+Decided traces are counted by `tail_sample_traces_total` metric with `decision` label: `kept`, `sampled` or `discarded`.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: tail_sample
+      trace_id_field: trace_id
+      window: 30s
+      keep_if:
+        level: error
+        http.status_code: 5
+      keep_if_mode: or_prefix
+      sample_rate: 0.05
+    ...
+```
+}*/
+type Plugin struct {
+	config     *Config
+	controller pipeline.ActionPluginController
+	logger     *zap.SugaredLogger
+	buffer     *buffer
+	conditions pipeline.MatchConditions
+	keepMode   pipeline.MatchMode
+	threshold  uint64
+	encodeBuf  []byte
+
+	// resolved config values, the config isn't changed since it identifies the shared buffer
+	maxTraces      int
+	maxTraceEvents int
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field which groups events into traces.
+	TraceIDField  cfg.FieldSelector `json:"trace_id_field" default:"trace_id" parse:"selector"` //*
+	TraceIDField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> How long events of a trace are buffered since the first event of the trace.
+	Window  cfg.Duration `json:"window" default:"30s" parse:"duration"` //*
+	Window_ time.Duration
+
+	//> @3@4@5@6
+	//>
+	//> The conditions which make the whole trace kept, see `match_fields` of actions for the values format.
+	KeepIf map[string]string `json:"keep_if" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The way `keep_if` conditions are combined, by default the trace is kept if any condition matches.
+	//> Values match by prefix in `and_prefix` and `or_prefix` modes.
+	KeepIfMode string `json:"keep_if_mode" default:"or" options:"and|or|and_prefix|or_prefix"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The fraction of traces without `keep_if` matches to keep, it should be in `[0, 1]`. Such traces are discarded if not set.
+	SampleRate float64 `json:"sample_rate"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The maximum number of buffered traces. `10000` if not set.
+	MaxTraces int `json:"max_traces"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The maximum number of buffered events of a trace. `1000` if not set.
+	MaxTraceEvents int `json:"max_trace_events"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "tail_sample",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.controller = params.Controller
+	p.logger = params.Logger
+
+	if p.config.Window_ <= 0 {
+		p.logger.Fatalf("window should be positive, got=%s", p.config.Window)
+	}
+	if p.config.SampleRate < 0 || p.config.SampleRate > 1 {
+		p.logger.Fatalf("sample_rate should be in [0, 1], got=%f", p.config.SampleRate)
+	}
+	p.maxTraces = p.config.MaxTraces
+	if p.maxTraces <= 0 {
+		p.maxTraces = defaultMaxTraces
+	}
+	p.maxTraceEvents = p.config.MaxTraceEvents
+	if p.maxTraceEvents <= 0 {
+		p.maxTraceEvents = defaultMaxTraceEvents
+	}
+
+	conditions, err := pipeline.NewMatchConditions(p.config.KeepIf)
+	if err != nil {
+		p.logger.Fatalf("can't parse keep_if: %s", err.Error())
+	}
+	p.conditions = conditions
+
+	p.keepMode = pipeline.MatchModeByName(p.config.KeepIfMode)
+
+	// sample rate 1 keeps all traces, so the threshold is above all hashes
+	p.threshold = math.MaxUint64
+	if p.config.SampleRate < 1 {
+		p.threshold = uint64(p.config.SampleRate * math.MaxUint64)
+	}
+
+	traces := params.MetricsCtl.RegisterCounter("tail_sample_traces_total", "how many traces are decided by tail_sample action", "decision")
+
+	buffersMu.Lock()
+	b, has := buffers[p.config]
+	if !has {
+		b = newBuffer(traces)
+		buffers[p.config] = b
+		go p.maintenance(b)
+	}
+	buffersMu.Unlock()
+
+	p.buffer = b
+}
+
+func (p *Plugin) Stop() {
+	buffersMu.Lock()
+	b, has := buffers[p.config]
+	delete(buffers, p.config)
+	buffersMu.Unlock()
+
+	// the buffer is stopped by the first stopped processor of the action
+	if !has {
+		return
+	}
+
+	close(b.stopCh)
+	<-b.doneCh
+	p.decide(b.stop())
+}
+
+// maintenance decides traces with ended windows, so they don't wait for the next event of the pipeline
+func (p *Plugin) maintenance(b *buffer) {
+	defer close(b.doneCh)
+
+	interval := maxFlushInterval
+	if p.config.Window_ < interval {
+		interval = p.config.Window_
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	decided := make([]*trace, 0)
+	for {
+		select {
+		case <-b.stopCh:
+			return
+		case <-ticker.C:
+			decided = b.popDecided(time.Now(), p.config.Window_, p.maxTraces, decided[:0])
+			p.decide(decided)
+		}
+	}
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.TraceIDField_...)
+	if node == nil {
+		return pipeline.ActionPass
+	}
+
+	p.encodeBuf = event.Root.Encode(p.encodeBuf[:0])
+	keep := p.conditions.Match(event.Root.Node, p.keepMode)
+	evicted, isAdded := p.buffer.add(node.AsString(), p.encodeBuf, keep, time.Now(), p.maxTraceEvents, p.maxTraces)
+	if !isAdded {
+		// processors still work while actions are stopped, the buffer is flushed already, so let's pass the event
+		return pipeline.ActionPass
+	}
+	if evicted != nil {
+		p.decide([]*trace{evicted})
+	}
+
+	return pipeline.ActionDiscard
+}
+
+// decide counts the decisions of the traces and emits events of kept and sampled ones
+func (p *Plugin) decide(traces []*trace) {
+	for _, t := range traces {
+		decision := "kept"
+		if !t.keep {
+			decision = "sampled"
+			if !p.isSampled(t.id) {
+				decision = "discarded"
+			}
+		}
+		p.buffer.traces.WithLabelValues(decision).Inc()
+		if decision == "discarded" {
+			continue
+		}
+
+		for _, json := range t.events {
+			if err := p.controller.Emit(json); err != nil {
+				p.logger.Errorf("can't emit buffered event: %s", err.Error())
+			}
+		}
+	}
+}
+
+// isSampled returns true if the trace without keep_if matches should be kept
+func (p *Plugin) isSampled(id string) bool {
+	hasher := fnv.New64a()
+	_, _ = hasher.Write([]byte(id))
+
+	return mix(hasher.Sum64()) < p.threshold
+}
+
+// mix is the finalizer of splitmix64, it spreads FNV hash of similar values over all bits,
+// because the threshold is compared with the high bits
+func mix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+
+	return h
+}
+
+type trace struct {
+	id        string
+	events    [][]byte
+	keep      bool
+	createdAt time.Time
+}
+
+// buffer keeps traces in the order of their first events, so traces with ended windows are at the head
+type buffer struct {
+	mu     *sync.Mutex
+	byID   map[string]*trace
+	order  []*trace
+	traces *prometheus.CounterVec
+
+	// isStopped is set once the buffer is flushed on stop, events aren't added to it after that
+	isStopped bool
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+}
+
+func newBuffer(traces *prometheus.CounterVec) *buffer {
+	return &buffer{
+		mu:     &sync.Mutex{},
+		byID:   make(map[string]*trace),
+		order:  make([]*trace, 0),
+		traces: traces,
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+}
+
+// add buffers the event of the trace and returns the oldest trace if a new trace exceeds maxTraces,
+// the event isn't added if the buffer is stopped
+func (b *buffer) add(id string, json []byte, keep bool, now time.Time, maxEvents int, maxTraces int) (*trace, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.isStopped {
+		return nil, false
+	}
+
+	var evicted *trace
+
+	t, has := b.byID[id]
+	if !has {
+		// copy the id, because the event memory is reused
+		t = &trace{id: string([]byte(id)), createdAt: now}
+		b.byID[t.id] = t
+		b.order = append(b.order, t)
+
+		if len(b.order) > maxTraces {
+			evicted = b.order[0]
+			b.order[0] = nil
+			b.order = b.order[1:]
+			delete(b.byID, evicted.id)
+		}
+	}
+
+	t.keep = t.keep || keep
+	if len(t.events) < maxEvents {
+		t.events = append(t.events, append([]byte(nil), json...))
+	}
+
+	return evicted, true
+}
+
+// popDecided removes traces with ended windows and the oldest traces above maxTraces from the buffer and appends them to out,
+// zero window and maxTraces remove all traces
+func (b *buffer) popDecided(now time.Time, window time.Duration, maxTraces int, out []*trace) []*trace {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for len(b.order) > 0 {
+		t := b.order[0]
+		if len(b.order) <= maxTraces && now.Sub(t.createdAt) < window {
+			break
+		}
+
+		b.order[0] = nil
+		b.order = b.order[1:]
+		delete(b.byID, t.id)
+		out = append(out, t)
+	}
+
+	return out
+}
+
+// stop removes all traces from the buffer, so they are decided, and rejects events added after that
+func (b *buffer) stop() []*trace {
+	b.mu.Lock()
+	b.isStopped = true
+	b.mu.Unlock()
+
+	return b.popDecided(time.Now(), 0, 0, nil)
+}
@@ -0,0 +1,187 @@
+package tail_sample
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ozonru/file.d/logger"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+)
+
+func TestTailSample(t *testing.T) {
+	config := test.NewConfig(&Config{Window: "100ms", KeepIf: map[string]string{"level": "error"}}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	events := []string{
+		`{"message":"no trace"}`,
+		`{"trace_id":"a","span":1,"level":"info"}`,
+		`{"trace_id":"b","span":1,"level":"info"}`,
+		`{"trace_id":"a","span":2,"level":"error"}`,
+		`{"trace_id":"b","span":2,"level":"info"}`,
+		`{"trace_id":"a","span":3,"level":"info"}`,
+	}
+	for _, event := range events {
+		input.In(0, "test.log", 0, []byte(event))
+	}
+
+	// the windows of the traces end without new events, so they are decided by the timer
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{events[0], events[1], events[3], events[5]}, outEvents, "wrong out events")
+}
+
+func TestTailSampleRate(t *testing.T) {
+	const traces = 20
+	config := test.NewConfig(&Config{Window: "100ms", KeepIf: map[string]string{"level": "error"}, SampleRate: 0.5}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	predictor := &Plugin{threshold: math.MaxUint64 / 2}
+	sampled := 0
+	for i := 0; i < traces; i++ {
+		if predictor.isSampled(fmt.Sprintf("trace_%d", i)) {
+			sampled++
+		}
+	}
+	assert.True(t, sampled > 0 && sampled < traces, "wrong sampled traces count")
+
+	wg := &sync.WaitGroup{}
+	wg.Add(sampled * 2)
+
+	outSpans := make(map[string]int)
+	output.SetOutFn(func(e *pipeline.Event) {
+		if id := e.Root.Dig("trace_id"); id != nil {
+			outSpans[id.AsString()]++
+		}
+		wg.Done()
+	})
+
+	for span := 0; span < 2; span++ {
+		for i := 0; i < traces; i++ {
+			input.In(0, "test.log", 0, []byte(fmt.Sprintf(`{"trace_id":"trace_%d","span":%d}`, i, span)))
+		}
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, sampled, len(outSpans), "wrong sampled traces count")
+	for id, count := range outSpans {
+		assert.True(t, predictor.isSampled(id), "trace %s shouldn't be sampled", id)
+		assert.Equal(t, 2, count, "trace %s isn't kept whole", id)
+	}
+}
+
+func TestTailSampleMaxTraces(t *testing.T) {
+	config := test.NewConfig(&Config{Window: "1h", KeepIf: map[string]string{"level": "error"}, MaxTraces: 1}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	// the first trace is decided before the end of the window, because the second one exceeds the buffer
+	input.In(0, "test.log", 0, []byte(`{"trace_id":"a","level":"error"}`))
+	input.In(0, "test.log", 0, []byte(`{"trace_id":"b","level":"error"}`))
+
+	wg.Wait()
+
+	// the second trace is decided on the stop
+	wg.Add(1)
+	p.Stop()
+	wg.Wait()
+
+	assert.Equal(t, []string{`{"trace_id":"a","level":"error"}`, `{"trace_id":"b","level":"error"}`}, outEvents, "wrong out events")
+}
+
+// gatePlugin holds events until the gate is opened
+type gatePlugin struct {
+	gate chan struct{}
+}
+
+func (p *gatePlugin) Start(_ pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+}
+
+func (p *gatePlugin) Stop() {
+}
+
+func (p *gatePlugin) Do(_ *pipeline.Event) pipeline.ActionResult {
+	<-p.gate
+	return pipeline.ActionPass
+}
+
+func TestTailSampleStopping(t *testing.T) {
+	gate := make(chan struct{})
+	gateFactory := func() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+		return &gatePlugin{gate: gate}, nil
+	}
+
+	config := test.NewConfig(&Config{Window: "1h", KeepIf: map[string]string{"level": "error"}, SampleRate: 1}, nil)
+	actions := test.NewActionPluginStaticInfo(gateFactory, nil, pipeline.MatchModeAnd, nil, false)
+	actions = append(actions, test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false)...)
+	p, input, output := test.NewPipelineMock(actions)
+
+	inEvents := atomic.NewInt32(0)
+	input.SetInFn(func() {
+		inEvents.Inc()
+	})
+
+	outEvents := make(chan string, 2)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents <- e.Root.EncodeToString()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"trace_id":"a"}`))
+	input.In(0, "test.log", 0, []byte(`{"trace_id":"b"}`))
+	for inEvents.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the processor is held by the gate while actions are stopped, so events reach the action after its buffer is flushed
+	p.Stop()
+	close(gate)
+
+	received := make([]string, 0)
+	for len(received) < 2 {
+		select {
+		case event := <-outEvents:
+			received = append(received, event)
+		case <-time.After(time.Second):
+			assert.Fail(t, "events are lost on stop")
+			return
+		}
+	}
+	assert.Equal(t, []string{`{"trace_id":"a"}`, `{"trace_id":"b"}`}, received, "wrong out events")
+}
+
+func TestTailSampleDefaults(t *testing.T) {
+	config := test.NewConfig(&Config{Window: "1h", KeepIf: map[string]string{"level": "error"}}, nil).(*Config)
+	plugin := &Plugin{}
+	plugin.Start(config, &pipeline.ActionPluginParams{PluginDefaultParams: test.NewEmptyPluginDefaultParams(), Logger: logger.Instance})
+	defer plugin.Stop()
+
+	assert.Equal(t, defaultMaxTraces, plugin.maxTraces, "wrong max traces")
+	assert.Equal(t, defaultMaxTraceEvents, plugin.maxTraceEvents, "wrong max trace events")
+	assert.Equal(t, 0, config.MaxTraces, "shared config shouldn't be changed")
+	assert.Equal(t, 0, config.MaxTraceEvents, "shared config shouldn't be changed")
+}
@@ -14,6 +14,7 @@ type limiter struct {
 	buckets     []int64
 	interval    time.Duration // bucket interval
 	minID       int           // minimum bucket id
+	seenAt      time.Time     // current time of the last event, buckets may be by the event time
 	mu          sync.Mutex
 }
 
@@ -28,10 +29,12 @@ func NewLimiter(interval time.Duration, bucketCount int, limit complexLimit) *li
 }
 
 // isAllowed returns TRUE if event is allowed to be processed.
-func (l *limiter) isAllowed(event *pipeline.Event, ts time.Time) bool {
+func (l *limiter) isAllowed(event *pipeline.Event, ts time.Time, now time.Time) bool {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	l.seenAt = now
+
 	if l.minID == 0 {
 		l.minID = l.timeToBucketID(ts) - l.bucketCount + 1
 	}
@@ -72,6 +75,15 @@ func (l *limiter) isAllowed(event *pipeline.Event, ts time.Time) bool {
 	return l.buckets[index] <= l.limit.value
 }
 
+// isExpired returns TRUE if the limiter has no events during the window ending at the current time.
+// Bucket ids aren't compared with the current time, because events may be bucketed by the lagging event time.
+func (l *limiter) isExpired(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return now.Sub(l.seenAt) >= l.interval*time.Duration(l.bucketCount)
+}
+
 // bucketIDToTime converts bucketID to time. This time is start of the bucket.
 func (l *limiter) bucketIDToTime(id int) time.Time {
 	nano := int64(id) * l.interval.Nanoseconds()
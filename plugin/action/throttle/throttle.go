@@ -1,29 +1,44 @@
 package throttle
 
 import (
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/ozonru/file.d/cfg"
 	"github.com/ozonru/file.d/fd"
-	"github.com/ozonru/file.d/logger"
 	"github.com/ozonru/file.d/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+	insaneJSON "github.com/vitkovskii/insane-json"
 )
 
 var (
 	defaultThrottleKey = "default"
 
-	// limiters should be shared across pipeline, so let's have a map by namespace and limiter name
-	limiters   = map[string]map[string]*limiter{} // todo: cleanup this map?
+	// limiters should be shared across pipeline, so let's have a map by pipeline name
+	limiters   = map[string]*limiterSet{}
 	limitersMu = &sync.RWMutex{}
 )
 
+// limiterSet keeps limiters of the pipeline by limiter name, which consists of the rule index and the throttle key
+type limiterSet struct {
+	limiters map[string]*limiter
+	// throttled identifies the pipeline instance, since metrics are created for each instance
+	throttled  *prometheus.CounterVec
+	timeErrors prometheus.Counter
+	cleanedAt  time.Time
+}
+
 /*{ introduction
 It discards the events if pipeline throughput gets higher than a configured threshold.
+Limiters of keys without events during `buckets_count` * `bucket_interval` are removed to save memory.
+Discarded events are counted by `throttle_throttled_events_total` metric with `rule` label, which is the rule index or `default`.
 }*/
 type Plugin struct {
-	config   *Config
-	pipeline string
+	config    *Config
+	pipeline  string
+	set       *limiterSet
+	throttled []prometheus.Counter
 
 	limiterBuff []byte
 	rules       []*rule
@@ -45,13 +60,16 @@ type Config struct {
 	//> The event field which defines the time when event was fired.
 	//> It is used to detect the event throughput in a particular time range.
 	//> If not set, the current time will be taken.
-	TimeField  cfg.FieldSelector `json:"time_field" default:"time"` //*
+	//> It's empty by default, so the current time is taken as in versions which ignored this parameter.
+	//> Events with the time which can't be parsed are counted by `throttle_time_parse_errors_total` metric and the current time is taken for them.
+	TimeField  cfg.FieldSelector `json:"time_field" default:"" parse:"selector"` //*
 	TimeField_ []string
 
 	//> @3@4@5@6
 	//>
 	//> It defines how to parse the time field format.
 	TimeFieldFormat string `json:"time_field_format" default:"rfc3339nano" options:"ansic|unixdate|rubydate|rfc822|rfc822z|rfc850|rfc1123|rfc1123z|rfc3339|rfc3339nano|kitchen|stamp|stampmilli|stampmicro|stampnano"` //*
+	TimeFieldFormat_ string
 
 	//> @3@4@5@6
 	//>
@@ -107,15 +125,33 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginP
 	p.pipeline = params.PipelineName
 	p.limiterBuff = make([]byte, 0)
 
+	format, err := pipeline.ParseFormatName(p.config.TimeFieldFormat)
+	if err != nil {
+		params.Logger.Fatalf("wrong time field format: %s", err.Error())
+	}
+	p.config.TimeFieldFormat_ = format
+
+	throttled := params.MetricsCtl.RegisterCounter("throttle_throttled_events_total", "how many events are discarded by throttle action", "rule")
+	timeErrors := params.MetricsCtl.RegisterCounter("throttle_time_parse_errors_total", "how many events have the time field which can't be parsed by throttle action").WithLabelValues()
+
 	limitersMu.Lock()
-	limiters[p.pipeline] = map[string]*limiter{}
+	set, has := limiters[p.pipeline]
+	// processors are started along with the pipeline and when their count is expanded,
+	// so limiters are recreated only for the new pipeline instance
+	if !has || set.throttled != throttled {
+		set = &limiterSet{limiters: map[string]*limiter{}, throttled: throttled, timeErrors: timeErrors, cleanedAt: time.Now()}
+		limiters[p.pipeline] = set
+	}
 	limitersMu.Unlock()
+	p.set = set
 
-	for _, r := range p.config.Rules {
+	for i, r := range p.config.Rules {
 		p.rules = append(p.rules, NewRule(r.Conditions, complexLimit{r.Limit, r.LimitKind}))
+		p.throttled = append(p.throttled, throttled.WithLabelValues(strconv.Itoa(i)))
 	}
 
 	p.rules = append(p.rules, NewRule(map[string]string{}, complexLimit{p.config.DefaultLimit, p.config.LimitKind}))
+	p.throttled = append(p.throttled, throttled.WithLabelValues("default"))
 }
 
 func (p *Plugin) Stop() {
@@ -130,12 +166,21 @@ func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
 }
 
 func (p *Plugin) isAllowed(event *pipeline.Event) bool {
-	ts := time.Now()
+	now := time.Now()
+	p.tryCleanup(now)
+
+	ts := now
+	// events without the time field are bucketed by the current time
+	var node *insaneJSON.Node
 	if len(p.config.TimeField_) != 0 {
-		tsValue := event.Root.Dig(p.config.TimeField_...).AsString()
-		t, err := time.Parse(p.config.TimeFieldFormat, tsValue)
-		if err != nil || ts.IsZero() {
-			logger.Warnf("can't parse field %q using format %s: %s", p.config.TimeField, p.config.TimeFieldFormat, tsValue)
+		node = event.Root.Dig(p.config.TimeField_...)
+	}
+	if node != nil {
+		tsValue := node.AsString()
+		t, err := time.Parse(p.config.TimeFieldFormat_, tsValue)
+		// logging each event floods the log, because usually all events of the source have the wrong format
+		if err != nil || t.IsZero() {
+			p.set.timeErrors.Inc()
 		} else {
 			ts = t
 		}
@@ -161,25 +206,55 @@ func (p *Plugin) isAllowed(event *pipeline.Event) bool {
 
 		// check if limiter already have been created
 		limitersMu.RLock()
-		limiter, has := limiters[p.pipeline][limiterKey]
+		limiter, has := p.set.limiters[limiterKey]
 		limitersMu.RUnlock()
 
 		// fast check with read lock
 		if !has {
 			limitersMu.Lock()
-			limiter, has = limiters[p.pipeline][limiterKey]
+			limiter, has = p.set.limiters[limiterKey]
 			// we could already write it between `limitersMu.RUnlock()` and `limitersMu.Lock()`, so we need to check again
 			if !has {
 				limiter = NewLimiter(p.config.BucketInterval_, p.config.BucketsCount, rule.limit)
 				// alloc new string before adding new key to map
 				limiterKey = string(p.limiterBuff)
-				limiters[p.pipeline][limiterKey] = limiter
+				p.set.limiters[limiterKey] = limiter
 			}
 			limitersMu.Unlock()
 		}
 
-		return limiter.isAllowed(event, ts)
+		if limiter.isAllowed(event, ts, now) {
+			return true
+		}
+		p.throttled[index].Inc()
+
+		return false
 	}
 
 	return true
 }
+
+// tryCleanup removes limiters without events during the whole window, the check is done once per window
+func (p *Plugin) tryCleanup(now time.Time) {
+	window := p.config.BucketInterval_ * time.Duration(p.config.BucketsCount)
+
+	limitersMu.RLock()
+	isTime := now.Sub(p.set.cleanedAt) >= window
+	limitersMu.RUnlock()
+
+	if !isTime {
+		return
+	}
+
+	limitersMu.Lock()
+	// other processor could clean limiters between `limitersMu.RUnlock()` and `limitersMu.Lock()`
+	if now.Sub(p.set.cleanedAt) >= window {
+		for key, limiter := range p.set.limiters {
+			if limiter.isExpired(now) {
+				delete(p.set.limiters, key)
+			}
+		}
+		p.set.cleanedAt = now
+	}
+	limitersMu.Unlock()
+}
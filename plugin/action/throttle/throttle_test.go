@@ -11,6 +11,7 @@ import (
 	"github.com/ozonru/file.d/logger"
 	"github.com/ozonru/file.d/pipeline"
 	"github.com/ozonru/file.d/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -165,3 +166,191 @@ func TestMixedThrottle(t *testing.T) {
 	tconf := testConfig{t, config, eventsTotal, workTime}
 	tconf.runPipeline()
 }
+
+func runEvents(t *testing.T, config *Config, events []string, outCount int) []string {
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, test.NewConfig(config, nil), pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(outCount)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	for _, event := range events {
+		input.In(0, "test.log", 0, []byte(event))
+	}
+
+	// events are processed sequentially, so the last event is passed after all others are processed
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+func TestThrottleLimitAndRollover(t *testing.T) {
+	config := &Config{
+		ThrottleField:   "service",
+		TimeField:       "time",
+		TimeFieldFormat: "rfc3339",
+		DefaultLimit:    2,
+		BucketsCount:    2,
+		BucketInterval:  "1m",
+		Rules: []RuleConfig{
+			{Limit: 1, Conditions: map[string]string{"level": "debug"}},
+		},
+	}
+
+	events := []string{
+		`{"time":"2021-01-01T10:00:00Z","service":"api","id":1}`,
+		`{"time":"2021-01-01T10:00:10Z","service":"api","id":2}`,
+		`{"time":"2021-01-01T10:00:20Z","service":"api","id":3}`,
+		`{"time":"2021-01-01T10:00:30Z","service":"web","id":4}`,
+		`{"time":"2021-01-01T10:00:40Z","service":"api","level":"debug","id":5}`,
+		`{"time":"2021-01-01T10:00:50Z","service":"api","level":"debug","id":6}`,
+		// the next bucket has its own limit
+		`{"time":"2021-01-01T10:01:00Z","service":"api","id":7}`,
+		`{"time":"2021-01-01T10:01:10Z","service":"api","id":8}`,
+		`{"time":"2021-01-01T10:01:20Z","service":"api","id":9}`,
+		// the window is moved forward, so the first bucket isn't tracked anymore
+		`{"time":"2021-01-01T10:05:00Z","service":"api","id":10}`,
+		`{"time":"2021-01-01T10:00:30Z","service":"api","id":11}`,
+		`{"time":"2021-01-01T10:05:10Z","service":"web","id":12}`,
+	}
+
+	outEvents := runEvents(t, config, events, 8)
+
+	assert.Equal(t, []string{events[0], events[1], events[3], events[4], events[6], events[7], events[9], events[11]}, outEvents, "wrong out events")
+
+	limitersMu.RLock()
+	set := limiters["test_pipeline"]
+	limitersMu.RUnlock()
+	assert.Equal(t, float64(3), testutil.ToFloat64(set.throttled.WithLabelValues("default")), "wrong throttled events count")
+	assert.Equal(t, float64(1), testutil.ToFloat64(set.throttled.WithLabelValues("0")), "wrong throttled events count")
+}
+
+func TestThrottleEviction(t *testing.T) {
+	config := &Config{
+		ThrottleField:  "service",
+		TimeField:      "",
+		DefaultLimit:   100,
+		BucketsCount:   2,
+		BucketInterval: "20ms",
+	}
+
+	events := make([]string, 0)
+	for i := 0; i < 50; i++ {
+		events = append(events, fmt.Sprintf(`{"service":"service_%d"}`, i))
+	}
+	runEvents(t, config, events, len(events))
+
+	limitersMu.RLock()
+	set := limiters["test_pipeline"]
+	count := len(set.limiters)
+	limitersMu.RUnlock()
+	assert.Equal(t, 50, count, "wrong limiters count")
+
+	// keys are quiet during the whole window, so limiters are removed with the next event
+	time.Sleep(config.BucketInterval_ * time.Duration(config.BucketsCount+1))
+	plugin, _ := factory()
+	p := plugin.(*Plugin)
+	p.config = config
+	p.set = set
+	p.tryCleanup(time.Now())
+
+	limitersMu.RLock()
+	count = len(set.limiters)
+	limitersMu.RUnlock()
+	assert.Equal(t, 0, count, "quiet limiters aren't removed")
+}
+
+func TestThrottleLaggingTimeEviction(t *testing.T) {
+	config := &Config{
+		ThrottleField:   "service",
+		TimeField:       "time",
+		TimeFieldFormat: "rfc3339",
+		DefaultLimit:    100,
+		BucketsCount:    2,
+		BucketInterval:  "20ms",
+	}
+
+	// events are replayed, so their time is long before the current time
+	events := []string{
+		`{"time":"2021-01-01T10:00:00Z","service":"api"}`,
+		`{"time":"2021-01-01T10:00:00Z","service":"web"}`,
+	}
+	runEvents(t, config, events, len(events))
+
+	limitersMu.RLock()
+	set := limiters["test_pipeline"]
+	limitersMu.RUnlock()
+
+	plugin, _ := factory()
+	p := plugin.(*Plugin)
+	p.config = config
+	p.set = set
+
+	// limiters have seen events just now, so they aren't removed despite old buckets
+	p.set.cleanedAt = time.Time{}
+	p.tryCleanup(time.Now())
+	limitersMu.RLock()
+	count := len(set.limiters)
+	limitersMu.RUnlock()
+	assert.Equal(t, 2, count, "active limiters are removed")
+
+	time.Sleep(config.BucketInterval_ * time.Duration(config.BucketsCount+1))
+	p.tryCleanup(time.Now())
+	limitersMu.RLock()
+	count = len(set.limiters)
+	limitersMu.RUnlock()
+	assert.Equal(t, 0, count, "quiet limiters aren't removed")
+}
+
+func TestThrottleDefaultTimeField(t *testing.T) {
+	config := &Config{
+		ThrottleField:  "service",
+		DefaultLimit:   2,
+		BucketsCount:   2,
+		BucketInterval: "1m",
+	}
+
+	// the time field isn't set by default, so events are bucketed by the current time
+	events := []string{
+		`{"time":"2021-01-01T10:00:00Z","service":"api","id":1}`,
+		`{"time":"2021-01-01T10:01:00Z","service":"api","id":2}`,
+		`{"time":"2021-01-01T10:02:00Z","service":"api","id":3}`,
+		`{"time":"2021-01-01T10:03:00Z","service":"api","id":4}`,
+		`{"time":"2021-01-01T10:04:00Z","service":"web","id":5}`,
+	}
+
+	outEvents := runEvents(t, config, events, 3)
+
+	assert.Equal(t, []string{events[0], events[1], events[4]}, outEvents, "wrong out events")
+}
+
+func TestThrottleTimeParseErrors(t *testing.T) {
+	config := &Config{
+		ThrottleField:   "service",
+		TimeField:       "time",
+		TimeFieldFormat: "rfc3339",
+		DefaultLimit:    10,
+		BucketsCount:    2,
+		BucketInterval:  "1m",
+	}
+
+	events := []string{
+		`{"time":"2021-01-01T10:00:00Z","service":"api"}`,
+		`{"time":"yesterday","service":"api"}`,
+		`{"time":"today","service":"api"}`,
+	}
+
+	outEvents := runEvents(t, config, events, 3)
+	assert.Equal(t, events, outEvents, "wrong out events")
+
+	limitersMu.RLock()
+	set := limiters["test_pipeline"]
+	limitersMu.RUnlock()
+	assert.Equal(t, float64(2), testutil.ToFloat64(set.timeErrors), "wrong time parse errors count")
+}
@@ -0,0 +1,248 @@
+package time_window_tag
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It sets `field` to `true` if the event time belongs to one of the configured windows, e.g. maintenance windows or business hours.
+Events out of the windows are passed untouched, so alerts can be suppressed by matching the field in the next actions.
+
+Each window has `from` and `to` time of the day in `HH:MM` format and optional `days` of the week.
+The window includes `from` and excludes `to`. If `to` is less than `from`, the window crosses midnight and ends on the next day.
+If `from` equals `to`, the window lasts the whole day.
+`days` is a comma separated list of days or day ranges, e.g. `mon-fri` or `sat,sun`, every day is used if not set.
+For windows crossing midnight `days` define the days on which windows start.
+
+The time is taken from `time_field` and converted to `timezone`, the current time is used if the field is absent or can't be parsed.
+Numeric time is treated as unix timestamp in seconds.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: time_window_tag
+      timezone: Europe/Moscow
+      windows:
+      - days: sat,sun
+        from: "00:00"
+        to: "00:00"
+      - days: mon-fri
+        from: "22:00"
+        to: "06:00"
+    ...
+```
+}*/
+type Plugin struct {
+	config   *Config
+	logger   *zap.SugaredLogger
+	location *time.Location
+	windows  []*window
+}
+
+type window struct {
+	days [7]bool
+	from int
+	to   int
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The list of windows. Each item has the following fields:
+	//> * `days` – days of the week of the window, every day if not set.
+	//> * `from` – the start time of the window in `HH:MM` format.
+	//> * `to` – the end time of the window in `HH:MM` format.
+	Windows []WindowConfig `json:"windows" slice:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The timezone of the windows, e.g. `Europe/Moscow`.
+	Timezone string `json:"timezone" default:"UTC"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field which defines the time when event was fired.
+	TimeField  cfg.FieldSelector `json:"time_field" parse:"selector" default:"time"` //*
+	TimeField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> It defines how to parse the time field. Any of `ansic|unixdate|rubydate|rfc822|rfc822z|rfc850|rfc1123|rfc1123z|rfc3339|rfc3339nano|kitchen|stamp|stampmilli|stampmicro|stampnano` or Go time layout can be used.
+	TimeFieldFormat  string `json:"time_field_format" default:"rfc3339nano"` //*
+	TimeFieldFormat_ string
+
+	//> @3@4@5@6
+	//>
+	//> The event field to which put the tag.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"maintenance"` //*
+	Field_ []string
+}
+
+type WindowConfig struct {
+	Days string `json:"days"`
+	From string `json:"from" required:"true"`
+	To   string `json:"to" required:"true"`
+}
+
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "time_window_tag",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger
+
+	location, err := time.LoadLocation(p.config.Timezone)
+	if err != nil {
+		p.logger.Fatalf("can't load timezone %q: %s", p.config.Timezone, err.Error())
+	}
+	p.location = location
+
+	format, err := pipeline.ParseFormatName(p.config.TimeFieldFormat)
+	if err != nil {
+		format = p.config.TimeFieldFormat
+	}
+	p.config.TimeFieldFormat_ = format
+
+	p.windows = make([]*window, 0, len(p.config.Windows))
+	for i, windowConfig := range p.config.Windows {
+		w, err := parseWindow(windowConfig)
+		if err != nil {
+			p.logger.Fatalf("wrong window #%d: %s", i, err.Error())
+		}
+		p.windows = append(p.windows, w)
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	t := p.eventTime(event).In(p.location)
+	for _, w := range p.windows {
+		if w.contains(t) {
+			pipeline.CreateNestedField(event.Root, p.config.Field_).MutateToBool(true)
+			break
+		}
+	}
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) eventTime(event *pipeline.Event) time.Time {
+	node := event.Root.Dig(p.config.TimeField_...)
+	if node == nil {
+		return time.Now()
+	}
+
+	if node.IsNumber() {
+		return time.Unix(int64(node.AsInt()), 0)
+	}
+
+	t, err := time.Parse(p.config.TimeFieldFormat_, node.AsString())
+	if err != nil {
+		return time.Now()
+	}
+
+	return t
+}
+
+func (w *window) contains(t time.Time) bool {
+	day := t.Weekday()
+	minute := t.Hour()*60 + t.Minute()
+
+	if w.from < w.to {
+		return w.days[day] && minute >= w.from && minute < w.to
+	}
+	if w.from == w.to {
+		return w.days[day]
+	}
+
+	// the window crosses midnight, so it can be started on the previous day
+	prevDay := (day + 6) % 7
+	return (w.days[day] && minute >= w.from) || (w.days[prevDay] && minute < w.to)
+}
+
+func parseWindow(config WindowConfig) (*window, error) {
+	from, err := parseTimeOfDay(config.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseTimeOfDay(config.To)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &window{from: from, to: to}
+	if strings.TrimSpace(config.Days) == "" {
+		for i := range w.days {
+			w.days[i] = true
+		}
+		return w, nil
+	}
+
+	for _, item := range strings.Split(config.Days, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		first, last := item, item
+		if pos := strings.IndexByte(item, '-'); pos != -1 {
+			first, last = strings.TrimSpace(item[:pos]), strings.TrimSpace(item[pos+1:])
+		}
+
+		firstDay, has := dayNames[first]
+		if !has {
+			return nil, fmt.Errorf("unknown day %q", first)
+		}
+		lastDay, has := dayNames[last]
+		if !has {
+			return nil, fmt.Errorf("unknown day %q", last)
+		}
+
+		// ranges like fri-mon wrap around the end of the week
+		for d := firstDay; ; d = (d + 1) % 7 {
+			w.days[d] = true
+			if d == lastDay {
+				break
+			}
+		}
+	}
+
+	return w, nil
+}
+
+func parseTimeOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("wrong time of the day %q, should be in HH:MM format", value)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
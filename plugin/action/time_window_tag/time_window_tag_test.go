@@ -0,0 +1,93 @@
+package time_window_tag
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func runEvents(t *testing.T, config *Config, events []string) []*pipeline.Event {
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, test.NewConfig(config, nil), pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(events))
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	for _, e := range events {
+		input.In(0, "test.log", 0, []byte(e))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+func TestTimeWindowTagBoundaries(t *testing.T) {
+	config := &Config{Windows: []WindowConfig{{Days: "mon-fri", From: "09:00", To: "18:00"}}}
+	outEvents := runEvents(t, config, []string{
+		`{"time":"2021-07-02T08:59:59Z"}`,
+		`{"time":"2021-07-02T09:00:00Z"}`,
+		`{"time":"2021-07-02T17:59:59.999Z"}`,
+		`{"time":"2021-07-02T18:00:00Z"}`,
+		`{"time":"2021-07-03T10:00:00Z"}`,
+	})
+
+	assert.Equal(t, 5, len(outEvents), "wrong out events count")
+	assert.Nil(t, outEvents[0].Root.Dig("maintenance"), "event shouldn't be tagged")
+	assert.True(t, outEvents[1].Root.Dig("maintenance").AsBool(), "event should be tagged")
+	assert.True(t, outEvents[2].Root.Dig("maintenance").AsBool(), "event should be tagged")
+	assert.Nil(t, outEvents[3].Root.Dig("maintenance"), "event shouldn't be tagged")
+	assert.Nil(t, outEvents[4].Root.Dig("maintenance"), "event shouldn't be tagged on saturday")
+}
+
+func TestTimeWindowTagTimezone(t *testing.T) {
+	config := &Config{
+		Timezone:  "Europe/Moscow",
+		TimeField: "ts",
+		Field:     "tags.maintenance",
+		Windows: []WindowConfig{
+			{Days: "fri", From: "22:00", To: "02:00"},
+			{Days: "sun", From: "00:00", To: "00:00"},
+		},
+	}
+	outEvents := runEvents(t, config, []string{
+		`{"ts":"2021-07-02T18:59:00Z"}`,      // fri 21:59 MSK
+		`{"ts":"2021-07-02T19:00:00Z"}`,      // fri 22:00 MSK
+		`{"ts":"2021-07-02T22:30:00Z"}`,      // sat 01:30 MSK
+		`{"ts":"2021-07-02T23:00:00Z"}`,      // sat 02:00 MSK
+		`{"ts":"2021-07-03T21:00:00Z"}`,      // sun 00:00 MSK
+		`{"ts":"2021-07-04T21:00:00Z"}`,      // mon 00:00 MSK
+		`{"ts":"2021-07-02T22:00:00+03:00"}`, // fri 22:00 MSK
+		`{"ts":1625252400}`,                  // fri 22:00 MSK
+	})
+
+	tagged := []bool{false, true, true, false, true, false, true, true}
+	assert.Equal(t, len(tagged), len(outEvents), "wrong out events count")
+	for i, e := range outEvents {
+		node := e.Root.Dig("tags", "maintenance")
+		assert.Equal(t, tagged[i], node != nil && node.AsBool(), "wrong tag for event #%d", i)
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	w, err := parseWindow(WindowConfig{Days: "Fri - mon, wed", From: "10:30", To: "11:00"})
+	assert.NoError(t, err, "window should be parsed")
+	assert.Equal(t, [7]bool{true, true, false, true, false, true, true}, w.days, "wrong days")
+	assert.Equal(t, 630, w.from, "wrong from")
+	assert.Equal(t, 660, w.to, "wrong to")
+
+	_, err = parseWindow(WindowConfig{Days: "weekend", From: "10:00", To: "11:00"})
+	assert.Error(t, err, "unknown day should fail")
+
+	_, err = parseWindow(WindowConfig{From: "25:00", To: "11:00"})
+	assert.Error(t, err, "wrong time should fail")
+}
@@ -0,0 +1,184 @@
+package transcode
+
+import (
+	"bytes"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It transcodes the event field from UTF-16LE, UTF-16BE or Latin-1 (ISO 8859-1) to UTF-8.
+It's useful with `raw` decoder for Windows sources.
+
+In `auto` mode the encoding is detected by BOM. If there is no BOM, valid UTF-8 is left untouched
+and invalid UTF-8 is considered to be Latin-1. BOM is always removed.
+Usually only the first line of a file has BOM, so set `encoding` explicitly for UTF-16 files.
+
+UTF-16 lines split by `\n` byte have a stray NUL byte left from the two-byte newline
+at the beginning (LE) or at the end (BE) of the line, it's removed.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    settings:
+      decoder: raw
+    ...
+    actions:
+    - type: transcode
+      field: message
+      encoding: utf-16le
+    ...
+```
+}*/
+type Plugin struct {
+	config *Config
+	units  []uint16
+	buf    []byte
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field to transcode.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"message"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The source encoding.
+	Encoding string `json:"encoding" default:"auto" options:"auto|utf-16le|utf-16be|latin-1"` //*
+}
+
+const (
+	encodingAuto    = "auto"
+	encodingUTF8    = "utf-8"
+	encodingUTF16LE = "utf-16le"
+	encodingUTF16BE = "utf-16be"
+	encodingLatin1  = "latin-1"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "transcode",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil || !node.IsString() {
+		return pipeline.ActionPass
+	}
+
+	value := node.AsBytes()
+	encoding := p.config.Encoding
+	if encoding == encodingAuto {
+		encoding = detect(value)
+	}
+
+	p.buf = p.buf[:0]
+	switch encoding {
+	case encodingUTF8:
+		if !bytes.HasPrefix(value, bomUTF8) {
+			return pipeline.ActionPass
+		}
+		p.buf = append(p.buf, value[len(bomUTF8):]...)
+	case encodingUTF16LE:
+		p.buf = p.appendUTF16(p.buf, value, false)
+	case encodingUTF16BE:
+		p.buf = p.appendUTF16(p.buf, value, true)
+	case encodingLatin1:
+		p.buf = appendLatin1(p.buf, value)
+	}
+
+	node.MutateToString(string(p.buf))
+
+	return pipeline.ActionPass
+}
+
+func detect(value []byte) string {
+	switch {
+	case bytes.HasPrefix(value, bomUTF8):
+		return encodingUTF8
+	case bytes.HasPrefix(value, bomUTF16LE):
+		return encodingUTF16LE
+	case bytes.HasPrefix(value, bomUTF16BE):
+		return encodingUTF16BE
+	case utf8.Valid(value):
+		return encodingUTF8
+	default:
+		return encodingLatin1
+	}
+}
+
+func (p *Plugin) appendUTF16(out []byte, value []byte, bigEndian bool) []byte {
+	if len(value)%2 == 1 {
+		if bigEndian && value[len(value)-1] == 0 {
+			value = value[:len(value)-1]
+		}
+		if !bigEndian && value[0] == 0 {
+			value = value[1:]
+		}
+	}
+
+	bom := bomUTF16LE
+	if bigEndian {
+		bom = bomUTF16BE
+	}
+	value = bytes.TrimPrefix(value, bom)
+
+	p.units = p.units[:0]
+	for i := 0; i+1 < len(value); i += 2 {
+		if bigEndian {
+			p.units = append(p.units, uint16(value[i])<<8|uint16(value[i+1]))
+		} else {
+			p.units = append(p.units, uint16(value[i+1])<<8|uint16(value[i]))
+		}
+	}
+
+	for _, r := range utf16.Decode(p.units) {
+		out = appendRune(out, r)
+	}
+
+	return out
+}
+
+func appendLatin1(out []byte, value []byte) []byte {
+	for _, c := range value {
+		out = appendRune(out, rune(c))
+	}
+
+	return out
+}
+
+func appendRune(out []byte, r rune) []byte {
+	l := len(out)
+	out = append(out, 0, 0, 0, 0)
+	n := utf8.EncodeRune(out[l:], r)
+
+	return out[:l+n]
+}
@@ -0,0 +1,79 @@
+package transcode
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func runTranscode(encoding string, lines []string) []string {
+	config := test.NewConfig(&Config{Encoding: encoding}, nil)
+	settings := test.NewSettings()
+	settings.Decoder = "raw"
+	p, input, output := test.NewPipelineMockWithSettings(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false), settings)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(lines))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.Dig("message").AsString())
+		wg.Done()
+	})
+
+	for _, line := range lines {
+		input.In(0, "test.log", 0, []byte(line+"\n"))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	return outEvents
+}
+
+func TestTranscodeAuto(t *testing.T) {
+	outEvents := runTranscode("", []string{
+		"\xff\xfeH\x00i\x00 \x00\x16\x04!\x00",
+		"\xfe\xff\x00H\x00i\x00 \x04\x16\x00!",
+		"\xff\xfe=\xd8\x00\xde",
+		"caf\xe9 cr\xe8me",
+		"\xef\xbb\xbfcafé",
+		"plain text",
+	})
+
+	assert.Equal(t, []string{
+		"Hi Ж!",
+		"Hi Ж!",
+		"😀",
+		"café crème",
+		"café",
+		"plain text",
+	}, outEvents, "wrong out events")
+}
+
+func TestTranscodeUTF16(t *testing.T) {
+	// the lines of "Hi\nЖ!\n" split by \n byte
+	outEvents := runTranscode("utf-16le", []string{
+		"\xff\xfeH\x00i\x00",
+		"\x00\x16\x04!\x00",
+	})
+	assert.Equal(t, []string{"Hi", "Ж!"}, outEvents, "wrong out events")
+
+	outEvents = runTranscode("utf-16be", []string{
+		"\xfe\xff\x00H\x00i\x00",
+		"\x04\x16\x00!\x00",
+	})
+	assert.Equal(t, []string{"Hi", "Ж!"}, outEvents, "wrong out events")
+}
+
+func TestTranscodeLatin1(t *testing.T) {
+	outEvents := runTranscode("latin-1", []string{
+		"\xc0 bient\xf4t, \xa9 2021",
+		"ascii",
+	})
+
+	assert.Equal(t, []string{"À bientôt, © 2021", "ascii"}, outEvents, "wrong out events")
+}
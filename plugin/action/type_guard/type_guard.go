@@ -0,0 +1,201 @@
+package type_guard
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It checks that the event fields have the expected JSON types, so malformed events don't break the index mapping.
+Supported types are `string`, `int`, `float`, `bool`, `object` and `array`. Any number matches `float`.
+Missing fields and `null` values match any type.
+
+On mismatch the behaviour depends on `policy`:
+* `coerce` – the value is converted to the expected type if it's possible, otherwise the field is removed.
+* `discard` – the event is discarded.
+
+Possible conversions are:
+* to `string` – numbers and booleans are converted as is, objects and arrays are encoded to JSON.
+* to `int` – numeric strings and floats which have no fractional part, e.g. `"42"` or `42.0`.
+* to `float` – numeric strings.
+* to `bool` – strings `true` and `false`, also `1`, `0`, `t`, `f` in any case.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: type_guard
+      fields:
+        status: int
+        user.id: string
+        tags: array
+    ...
+```
+It transforms `{"status":"200","user":{"id":42},"tags":"a,b"}` into `{"status":200,"user":{"id":"42"}}`.
+}*/
+type Plugin struct {
+	config *Config
+	logger *zap.SugaredLogger
+	fields []*field
+}
+
+type field struct {
+	path []string
+	kind string
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The map of `event field => expected type`. Nested fields can be used.
+	Fields map[string]string `json:"fields" required:"true"` //*
+
+	//> @3@4@5@6
+	//>
+	//> What to do if the field has the wrong type.
+	Policy string `json:"policy" default:"coerce" options:"coerce|discard"` //*
+}
+
+const (
+	typeString = "string"
+	typeInt    = "int"
+	typeFloat  = "float"
+	typeBool   = "bool"
+	typeObject = "object"
+	typeArray  = "array"
+
+	policyDiscard = "discard"
+)
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "type_guard",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger
+
+	selectors := make([]string, 0, len(p.config.Fields))
+	for selector := range p.config.Fields {
+		selectors = append(selectors, selector)
+	}
+	// the order of checks doesn't depend on the map iteration order
+	sort.Strings(selectors)
+
+	p.fields = make([]*field, 0, len(selectors))
+	for _, selector := range selectors {
+		kind := p.config.Fields[selector]
+		switch kind {
+		case typeString, typeInt, typeFloat, typeBool, typeObject, typeArray:
+		default:
+			p.logger.Fatalf("unknown type %q for field %s", kind, selector)
+		}
+
+		p.fields = append(p.fields, &field{path: cfg.ParseFieldSelector(selector), kind: kind})
+	}
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	for _, f := range p.fields {
+		node := event.Root.Dig(f.path...)
+		if node == nil || node.IsNull() || isType(node, f.kind) {
+			continue
+		}
+
+		if p.config.Policy == policyDiscard {
+			return pipeline.ActionDiscard
+		}
+
+		if !coerce(node, f.kind) {
+			node.Suicide()
+		}
+	}
+
+	return pipeline.ActionPass
+}
+
+func isType(node *insaneJSON.Node, kind string) bool {
+	switch kind {
+	case typeString:
+		return node.IsString()
+	case typeInt:
+		if !node.IsNumber() {
+			return false
+		}
+		_, err := strconv.Atoi(node.AsString())
+		return err == nil
+	case typeFloat:
+		return node.IsNumber()
+	case typeBool:
+		return node.IsTrue() || node.IsFalse()
+	case typeObject:
+		return node.IsObject()
+	case typeArray:
+		return node.IsArray()
+	default:
+		return false
+	}
+}
+
+// coerce converts the node to the type and returns false if it isn't possible
+func coerce(node *insaneJSON.Node, kind string) bool {
+	switch kind {
+	case typeString:
+		if node.IsObject() || node.IsArray() {
+			node.MutateToString(node.EncodeToString())
+			return true
+		}
+		node.MutateToString(node.AsString())
+		return true
+	case typeInt:
+		if node.IsNumber() || node.IsString() {
+			value, err := strconv.ParseFloat(node.AsString(), 64)
+			if err != nil || value != math.Trunc(value) || math.Abs(value) > 1<<53 {
+				return false
+			}
+			node.MutateToInt(int(value))
+			return true
+		}
+	case typeFloat:
+		if node.IsString() {
+			value, err := strconv.ParseFloat(node.AsString(), 64)
+			if err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+				return false
+			}
+			node.MutateToFloat(value)
+			return true
+		}
+	case typeBool:
+		if node.IsString() {
+			value, err := strconv.ParseBool(node.AsString())
+			if err != nil {
+				return false
+			}
+			node.MutateToBool(value)
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+package type_guard
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+var fields = map[string]string{
+	"status":  "int",
+	"latency": "float",
+	"user.id": "string",
+	"ok":      "bool",
+	"tags":    "array",
+	"meta":    "object",
+}
+
+func TestTypeGuardCoerce(t *testing.T) {
+	config := test.NewConfig(&Config{Fields: fields}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(5)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	// types match
+	input.In(0, "test.log", 0, []byte(`{"status":200,"latency":1.5,"user":{"id":"42"},"ok":true,"tags":["a"],"meta":{"a":1}}`))
+	input.In(0, "test.log", 0, []byte(`{"status":null,"latency":2,"message":"no fields"}`))
+	// coercible mismatches
+	input.In(0, "test.log", 0, []byte(`{"status":"200","latency":"1.5","user":{"id":42},"ok":"false"}`))
+	input.In(0, "test.log", 0, []byte(`{"status":200.0,"user":{"id":{"name":"bob"}}}`))
+	// hard mismatches
+	input.In(0, "test.log", 0, []byte(`{"status":"OK","latency":"fast","ok":"yes","tags":"a,b","meta":[1],"message":"bad"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"status":200,"latency":1.5,"user":{"id":"42"},"ok":true,"tags":["a"],"meta":{"a":1}}`,
+		`{"status":null,"latency":2,"message":"no fields"}`,
+		`{"status":200,"latency":1.5,"user":{"id":"42"},"ok":false}`,
+		`{"status":200,"user":{"id":"{\"name\":\"bob\"}"}}`,
+		`{"message":"bad"}`,
+	}, outEvents, "wrong out events")
+}
+
+func TestTypeGuardDiscard(t *testing.T) {
+	config := test.NewConfig(&Config{Fields: fields, Policy: "discard"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	// discarded events go first, so they are processed when passed events reach the output
+	input.In(0, "test.log", 0, []byte(`{"status":"200"}`))
+	input.In(0, "test.log", 0, []byte(`{"status":1.5}`))
+	input.In(0, "test.log", 0, []byte(`{"tags":{"a":"b"}}`))
+	input.In(0, "test.log", 0, []byte(`{"status":200,"tags":["a"]}`))
+	input.In(0, "test.log", 0, []byte(`{"message":"no fields"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []string{
+		`{"status":200,"tags":["a"]}`,
+		`{"message":"no fields"}`,
+	}, outEvents, "wrong out events")
+}
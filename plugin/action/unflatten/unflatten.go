@@ -0,0 +1,92 @@
+package unflatten
+
+import (
+	"strings"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ introduction
+It converts keys joined with the separator into nested objects, it's the inverse of the `flatten` plugin with `separator` set.
+If `field` isn't set, keys of the event root are converted. If the field isn't an object, the event is passed as is.
+If the key conflicts with the previous one, e.g. `a` and `a.b`, non-object value is replaced with the object.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: unflatten
+      separator: .
+    ...
+```
+It transforms `{"animal.type":"cat","animal.size.height":30,"name":"Tom"}` into `{"animal":{"type":"cat","size":{"height":30}},"name":"Tom"}`.
+}*/
+type Plugin struct {
+	config *Config
+	fields []*insaneJSON.Node
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> Defines the object field which keys should be converted. If it isn't set, keys of the event root are converted.
+	Field  cfg.FieldSelector `json:"field" parse:"selector"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The separator of nested keys.
+	Separator string `json:"separator" default:"."` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "unflatten",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if !node.IsObject() {
+		return pipeline.ActionPass
+	}
+
+	// object nodes are reused by the new object, so fields should be copied first
+	p.fields = append(p.fields[:0], node.AsFields()...)
+	node.MutateToObject()
+
+	for _, field := range p.fields {
+		// names are the parts of the field names, so they outlive the event
+		path := strings.Split(field.AsString(), p.config.Separator)
+
+		curr := node
+		for i, name := range path {
+			curr = curr.AddFieldNoAlloc(event.Root, name)
+			if i != len(path)-1 && !curr.IsObject() {
+				curr.MutateToObject()
+			}
+		}
+		curr.MutateToNode(field.AsFieldValue())
+	}
+
+	return pipeline.ActionPass
+}
@@ -0,0 +1,93 @@
+package unflatten
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/plugin/action/flatten"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnflatten(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"animal.type":"cat","animal.size.height":30,"animal.size.width":50,"name":"Tom"}`))
+	input.In(0, "test.log", 0, []byte(`{"a":1,"a.b":2}`))
+	input.In(0, "test.log", 0, []byte(`{"a.b":{"c.d":1},"list":[{"x.y":1}]}`))
+	input.In(0, "test.log", 0, []byte(`{"plain":"value"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 4, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"animal":{"type":"cat","size":{"height":30,"width":50}},"name":"Tom"}`, outEvents[0], "wrong out event")
+	assert.Equal(t, `{"a":{"b":2}}`, outEvents[1], "wrong out event")
+	assert.Equal(t, `{"a":{"b":{"c.d":1}},"list":[{"x.y":1}]}`, outEvents[2], "wrong out event")
+	assert.Equal(t, `{"plain":"value"}`, outEvents[3], "wrong out event")
+}
+
+func TestUnflattenField(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "labels", Separator: "/"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"labels":{"app/name":"api","app/version":"1.0"},"a/b":1}`))
+	input.In(0, "test.log", 0, []byte(`{"labels":"app/name"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 2, len(outEvents), "wrong out events count")
+	assert.Equal(t, `{"labels":{"app":{"name":"api","version":"1.0"}},"a/b":1}`, outEvents[0], "wrong out event")
+	assert.Equal(t, `{"labels":"app/name"}`, outEvents[1], "wrong out event")
+}
+
+func TestFlattenUnflattenRoundTrip(t *testing.T) {
+	flattenConfig := test.NewConfig(&flatten.Config{Separator: "."}, nil)
+	unflattenConfig := test.NewConfig(&Config{Separator: "."}, nil)
+
+	flattenFactory := fd.DefaultPluginRegistry.GetActionByType("flatten").Factory
+	actions := test.NewActionPluginStaticInfo(flattenFactory, flattenConfig, pipeline.MatchModeAnd, nil, false)
+	actions = append(actions, test.NewActionPluginStaticInfo(factory, unflattenConfig, pipeline.MatchModeAnd, nil, false)...)
+	p, input, output := test.NewPipelineMock(actions)
+
+	doc := `{"service":{"name":"api","deploy":{"region":"eu","zone":"a","replicas":3}},"tags":["x","y"],"empty":{},"ok":true,"message":"hello"}`
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e.Root.EncodeToString())
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(doc))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 1, len(outEvents), "wrong out events count")
+	assert.JSONEq(t, doc, outEvents[0], "document isn't restored")
+}
@@ -0,0 +1,169 @@
+package url_template
+
+import (
+	"bytes"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+/*{ introduction
+It normalizes the URL path from the event field into a route template, which is useful for grouping requests by endpoint.
+Numeric path segments are replaced with `id_placeholder` and UUID segments are replaced with `uuid_placeholder`.
+Query string, fragment, scheme and host are stripped. If the field is absent, the event will be skipped.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: url_template
+      field: request.path
+    ...
+```
+It transforms `{"request":{"path":"/users/123/orders/5c5d4d1e-6df8-4b8e-9b1a-7d1f0c6a2b3e?full=1"}}`
+into `{"request":{"path":"/users/123/orders/5c5d4d1e-6df8-4b8e-9b1a-7d1f0c6a2b3e?full=1"},"route":"/users/:id/orders/:uuid"}`.
+}*/
+type Plugin struct {
+	config *Config
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field which contains the URL or the URL path.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" default:"path"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The event field to which put the route template.
+	RouteField string `json:"route_field" default:"route"` //*
+
+	//> @3@4@5@6
+	//>
+	//> A placeholder for numeric path segments.
+	IDPlaceholder string `json:"id_placeholder" default:":id"` //*
+
+	//> @3@4@5@6
+	//>
+	//> A placeholder for UUID path segments.
+	UUIDPlaceholder string `json:"uuid_placeholder" default:":uuid"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "url_template",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, _ *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil {
+		return pipeline.ActionPass
+	}
+
+	l := len(event.Buf)
+	event.Buf = p.appendTemplate(event.Buf, urlPath(node.AsBytes()))
+
+	event.Root.AddFieldNoAlloc(event.Root, p.config.RouteField).MutateToString(pipeline.ByteToStringUnsafe(event.Buf[l:]))
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) appendTemplate(out []byte, path []byte) []byte {
+	for len(path) > 0 {
+		end := bytes.IndexByte(path, '/')
+		if end == -1 {
+			end = len(path)
+		}
+
+		segment := path[:end]
+		switch {
+		case isNumeric(segment):
+			out = append(out, p.config.IDPlaceholder...)
+		case isUUID(segment):
+			out = append(out, p.config.UUIDPlaceholder...)
+		default:
+			out = append(out, segment...)
+		}
+
+		if end == len(path) {
+			break
+		}
+		out = append(out, '/')
+		path = path[end+1:]
+	}
+
+	return out
+}
+
+// urlPath strips scheme, host, query string and fragment from the URL.
+func urlPath(url []byte) []byte {
+	if pos := bytes.IndexAny(url, "?#"); pos != -1 {
+		url = url[:pos]
+	}
+
+	if pos := bytes.Index(url, []byte("://")); pos != -1 {
+		url = url[pos+3:]
+		pos = bytes.IndexByte(url, '/')
+		if pos == -1 {
+			return []byte("/")
+		}
+		url = url[pos:]
+	}
+
+	return url
+}
+
+func isNumeric(s []byte) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isUUID checks for the canonical xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx form.
+func isUUID(s []byte) bool {
+	if len(s) != 36 {
+		return false
+	}
+
+	for i, c := range s {
+		if i == 8 || i == 13 || i == 18 || i == 23 {
+			if c != '-' {
+				return false
+			}
+			continue
+		}
+
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+
+	return true
+}
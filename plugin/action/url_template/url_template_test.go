@@ -0,0 +1,75 @@
+package url_template
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLTemplate(t *testing.T) {
+	config := test.NewConfig(&Config{}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	paths := map[string]string{
+		"/users/123":               "/users/:id",
+		"/users/123/orders/456":    "/users/:id/orders/:id",
+		"/v2/users/123/avatar.png": "/v2/users/:id/avatar.png",
+		"/orders/5C5D4D1E-6DF8-4B8E-9B1A-7D1F0C6A2B3E/items/7": "/orders/:uuid/items/:id",
+		"/orders/5c5d4d1e-6df8-4b8e-9b1a-7d1f0c6a2b3e":         "/orders/:uuid",
+		"/orders/5c5d4d1e6df84b8e9b1a7d1f0c6a2b3e":             "/orders/5c5d4d1e6df84b8e9b1a7d1f0c6a2b3e",
+		"/users/123abc/":                       "/users/123abc/",
+		"/search?user=123":                     "/search",
+		"https://example.com:443/users/42#top": "/users/:id",
+		"https://example.com":                  "/",
+		"/":                                    "/",
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(paths) + 1)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	for path := range paths {
+		input.In(0, "test.log", 0, []byte(`{"path":"`+path+`"}`))
+	}
+	input.In(0, "test.log", 0, []byte(`{"message":"no path"}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, len(paths)+1, len(outEvents), "wrong out events count")
+	for _, e := range outEvents[:len(paths)] {
+		path := e.Root.Dig("path").AsString()
+		assert.Equal(t, paths[path], e.Root.Dig("route").AsString(), "wrong route for path %q", path)
+	}
+	assert.Equal(t, `{"message":"no path"}`, outEvents[len(paths)].Root.EncodeToString(), "wrong out event")
+}
+
+func TestURLTemplatePlaceholders(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "request.uri", RouteField: "endpoint", IDPlaceholder: "{id}", UUIDPlaceholder: "{uuid}"}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	outEvents := make([]*pipeline.Event, 0)
+	output.SetOutFn(func(e *pipeline.Event) {
+		outEvents = append(outEvents, e)
+		wg.Done()
+	})
+
+	input.In(0, "test.log", 0, []byte(`{"request":{"uri":"/users/123/sessions/5c5d4d1e-6df8-4b8e-9b1a-7d1f0c6a2b3e"}}`))
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 1, len(outEvents), "wrong out events count")
+	assert.Equal(t, "/users/{id}/sessions/{uuid}", outEvents[0].Root.Dig("endpoint").AsString(), "wrong route")
+}
@@ -0,0 +1,234 @@
+package zscore
+
+import (
+	"container/list"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+)
+
+const (
+	defaultThreshold  = 3
+	defaultMaxKeys    = 10000
+	defaultMinSamples = 10
+)
+
+var (
+	// detectors should be shared across processors of the action, all of them receive the same config, so let's have a map by config
+	detectors   = map[*Config]*detector{}
+	detectorsMu = &sync.Mutex{}
+)
+
+/*{ introduction
+It detects anomalies of a numeric field using z-score.
+The plugin maintains the running mean and standard deviation of the field for each key
+and tags the event with `anomaly: true` if the field value differs from the mean more than `threshold` standard deviations.
+The event value is checked against the statistics collected before the event and then it's added to the statistics.
+
+The number of keys is bounded by `max_keys`, the least recently seen key is evicted when the limit is reached.
+Events without the field or with a non-numeric value are passed as is.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: zscore
+      field: latency_ms
+      key_field: service
+      threshold: 3
+    ...
+```
+}*/
+type Plugin struct {
+	config   *Config
+	detector *detector
+	keyBuf   []byte
+
+	// resolved config values, the config isn't changed since it identifies the shared detector
+	threshold  float64
+	maxKeys    int
+	minSamples int
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The numeric event field to check.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" required:"true"` //*
+	Field_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The event field which is used as a key, statistics are collected separately for each key.
+	//> If not set, all events have the same key.
+	KeyField  cfg.FieldSelector `json:"key_field" parse:"selector"` //*
+	KeyField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> How many standard deviations the value should differ from the mean to be an anomaly. `3` if not set.
+	Threshold float64 `json:"threshold"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The maximum number of keys to keep statistics for. `10000` if not set.
+	MaxKeys int `json:"max_keys"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The minimum number of values collected for the key before anomalies are detected. `10` if not set.
+	MinSamples int `json:"min_samples"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field to put the anomaly flag to.
+	AnomalyField string `json:"anomaly_field" default:"anomaly"` //*
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "zscore",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.threshold = p.config.Threshold
+	if p.threshold <= 0 {
+		p.threshold = defaultThreshold
+	}
+	p.maxKeys = p.config.MaxKeys
+	if p.maxKeys <= 0 {
+		p.maxKeys = defaultMaxKeys
+	}
+	p.minSamples = p.config.MinSamples
+	if p.minSamples <= 0 {
+		p.minSamples = defaultMinSamples
+	}
+
+	detectorsMu.Lock()
+	d, has := detectors[p.config]
+	if !has {
+		d = newDetector(p.maxKeys)
+		detectors[p.config] = d
+	}
+	detectorsMu.Unlock()
+
+	p.detector = d
+}
+
+func (p *Plugin) Stop() {
+	detectorsMu.Lock()
+	delete(detectors, p.config)
+	detectorsMu.Unlock()
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil {
+		return pipeline.ActionPass
+	}
+
+	value, err := strconv.ParseFloat(node.AsString(), 64)
+	if err != nil {
+		return pipeline.ActionPass
+	}
+
+	p.keyBuf = p.keyBuf[:0]
+	if len(p.config.KeyField_) != 0 {
+		p.keyBuf = append(p.keyBuf, event.Root.Dig(p.config.KeyField_...).AsString()...)
+	}
+
+	if p.detector.isAnomaly(p.keyBuf, value, p.threshold, p.minSamples) {
+		event.Root.AddFieldNoAlloc(event.Root, p.config.AnomalyField).MutateToBool(true)
+	}
+
+	return pipeline.ActionPass
+}
+
+type stats struct {
+	key   string
+	count int
+	mean  float64
+	m2    float64 // sum of squares of differences from the mean
+}
+
+// add updates the statistics using Welford's algorithm
+func (s *stats) add(value float64) {
+	s.count++
+	delta := value - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (value - s.mean)
+}
+
+func (s *stats) stddev() float64 {
+	if s.count < 2 {
+		return 0
+	}
+
+	return math.Sqrt(s.m2 / float64(s.count-1))
+}
+
+type detector struct {
+	mu      *sync.Mutex
+	maxKeys int
+	keys    map[string]*list.Element
+	lru     *list.List // the most recently seen key is in front
+}
+
+func newDetector(maxKeys int) *detector {
+	return &detector{
+		mu:      &sync.Mutex{},
+		maxKeys: maxKeys,
+		keys:    make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func (d *detector) isAnomaly(key []byte, value float64, threshold float64, minSamples int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s := d.get(key)
+
+	isAnomaly := false
+	if s.count >= minSamples {
+		stddev := s.stddev()
+		isAnomaly = stddev > 0 && math.Abs(value-s.mean) > threshold*stddev
+	}
+
+	s.add(value)
+
+	return isAnomaly
+}
+
+func (d *detector) get(key []byte) *stats {
+	if el, has := d.keys[string(key)]; has {
+		d.lru.MoveToFront(el)
+		return el.Value.(*stats)
+	}
+
+	if d.lru.Len() >= d.maxKeys {
+		oldest := d.lru.Back()
+		d.lru.Remove(oldest)
+		delete(d.keys, oldest.Value.(*stats).key)
+	}
+
+	s := &stats{key: string(key)}
+	d.keys[s.key] = d.lru.PushFront(s)
+
+	return s
+}
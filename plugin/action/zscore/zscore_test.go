@@ -0,0 +1,103 @@
+package zscore
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/logger"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZScore(t *testing.T) {
+	config := test.NewConfig(&Config{Field: "latency", KeyField: "service", Threshold: 4}, nil)
+	p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+	services := map[string]float64{"api": 100, "db": 10}
+	outliers := map[int]bool{50: true, 200: true, 201: true, 350: true}
+	count := 400
+
+	wg := &sync.WaitGroup{}
+	wg.Add(count * len(services))
+
+	anomalies := make(map[string][]int)
+	output.SetOutFn(func(e *pipeline.Event) {
+		if e.Root.Dig("anomaly").AsBool() {
+			service := e.Root.Dig("service").AsString()
+			anomalies[service] = append(anomalies[service], e.Root.Dig("index").AsInt())
+		}
+		wg.Done()
+	})
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < count; i++ {
+		for service, mean := range services {
+			value := mean + r.NormFloat64()*mean/10
+			if outliers[i] {
+				value = mean * 3
+			}
+			input.In(0, "test.log", 0, []byte(fmt.Sprintf(`{"service":%q,"index":%d,"latency":%f}`, service, i, value)))
+		}
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, []int{50, 200, 201, 350}, anomalies["api"], "wrong anomalies")
+	assert.Equal(t, []int{50, 200, 201, 350}, anomalies["db"], "wrong anomalies")
+}
+
+func TestZScoreMinSamples(t *testing.T) {
+	d := newDetector(10)
+
+	for _, value := range []float64{10, 11, 9} {
+		assert.False(t, d.isAnomaly(nil, value, 3, 3), "anomaly shouldn't be detected")
+	}
+	assert.True(t, d.isAnomaly(nil, 100, 3, 3), "anomaly isn't detected")
+	assert.False(t, d.isAnomaly([]byte("other"), 100, 3, 0), "anomaly shouldn't be detected for a new key")
+}
+
+func TestZScoreMaxKeys(t *testing.T) {
+	d := newDetector(2)
+
+	d.isAnomaly([]byte("a"), 1, 3, 0)
+	d.isAnomaly([]byte("b"), 1, 3, 0)
+	d.isAnomaly([]byte("a"), 1, 3, 0)
+	d.isAnomaly([]byte("c"), 1, 3, 0)
+
+	assert.Equal(t, 2, len(d.keys), "wrong keys count")
+	assert.Equal(t, 2, d.lru.Len(), "wrong keys count")
+	assert.NotNil(t, d.keys["a"], "recently seen key is evicted")
+	assert.NotNil(t, d.keys["c"], "new key isn't added")
+	assert.Nil(t, d.keys["b"], "the oldest key isn't evicted")
+	assert.Equal(t, 2, d.keys["a"].Value.(*stats).count, "wrong values count")
+}
+
+func TestZScoreDetectorPerAction(t *testing.T) {
+	start := func(config *Config) *Plugin {
+		plugin := &Plugin{}
+		plugin.Start(config, &pipeline.ActionPluginParams{PluginDefaultParams: test.NewEmptyPluginDefaultParams(), Logger: logger.Instance})
+		return plugin
+	}
+
+	config := test.NewConfig(&Config{Field: "latency"}, nil).(*Config)
+	first := start(config)
+	second := start(config)
+	other := start(test.NewConfig(&Config{Field: "size"}, nil).(*Config))
+
+	assert.True(t, first.detector == second.detector, "processors of the action should share the detector")
+	assert.True(t, first.detector != other.detector, "actions of the pipeline shouldn't share the detector")
+	assert.Equal(t, 0, config.MaxKeys, "shared config shouldn't be changed")
+	assert.Equal(t, defaultMaxKeys, first.maxKeys, "wrong max keys")
+
+	first.Stop()
+	second.Stop()
+	other.Stop()
+
+	restarted := start(config)
+	assert.True(t, first.detector != restarted.detector, "detector shouldn't survive the action stop")
+	restarted.Stop()
+}
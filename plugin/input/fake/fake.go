@@ -52,6 +52,11 @@ func (p *Plugin) In(sourceID pipeline.SourceID, sourceName string, offset int64,
 	p.controller.In(sourceID, sourceName, offset, bytes, false)
 }
 
+//> It reports an input error to the pipeline.
+func (p *Plugin) Error(err string) { //*
+	p.controller.InputError(err)
+}
+
 //> It sets up a hook to make sure the test event has been successfully committed.
 func (p *Plugin) SetCommitFn(fn func(event *pipeline.Event)) { //*
 	p.commitFn = fn
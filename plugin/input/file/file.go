@@ -154,15 +154,35 @@ type Config struct {
 	//> @maintenance
 	MaintenanceInterval  cfg.Duration `json:"maintenance_interval" default:"10s" parse:"duration"` //*
 	MaintenanceInterval_ time.Duration
+
+	//> @3@4@5@6
+	//>
+	//> If set, the source file metadata is added to each event:
+	//> * `_file_path` – the path of the file (or the symlink) the event is read from
+	//> * `_file_inode` – the inode of the file
+	//> * `_file_offset` – the byte position of the line in the file
+	AddMeta bool `json:"add_meta" default:"false"` //*
+
+	// jobProvider is used by the meta action to find the inode of the event source
+	jobProvider *jobProvider
 }
 
 func init() {
 	fd.DefaultPluginRegistry.RegisterInput(&pipeline.PluginStaticInfo{
-		Type:    "file",
-		Factory: Factory,
+		Type:              "file",
+		Factory:           Factory,
+		AdditionalActions: []string{"file-meta"},
+	})
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "file-meta",
+		Factory: MetaActionFactory,
 	})
 }
 
+func MetaActionFactory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &MetaAction{}, &Config{}
+}
+
 func Factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
 	return &Plugin{}, &Config{}
 }
@@ -175,6 +195,7 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.InputPluginPa
 	p.config.OffsetsFileTmp = p.config.OffsetsFile + ".atomic"
 
 	p.jobProvider = NewJobProvider(p.config, p.params.Controller, p.logger)
+	p.config.jobProvider = p.jobProvider
 	p.startWorkers()
 	p.jobProvider.start()
 }
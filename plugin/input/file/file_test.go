@@ -502,6 +502,39 @@ func TestOffsetsSaveSimple(t *testing.T) {
 	}, eventCount)
 }
 
+// TestOffsetsSaveBatchArray tests if the line which is split into many events is committed once
+func TestOffsetsSaveBatchArray(t *testing.T) {
+	lineCount := 5
+	lines := make([]string, 0, 0)
+	file := ""
+	size := 0
+
+	settings := test.NewSettings()
+	settings.RecordFormat = pipeline.RecordFormatBatchArray
+
+	cleanUp()
+	test.RunCaseWithSettings(&test.Case{
+		Prepare: func() {
+			for i := 0; i < lineCount; i++ {
+				line := fmt.Sprintf(`[{"field":"value_%d_0"},{"field":"value_%d_1"},{"field":"value_%d_2"}]`, i, i, i)
+				lines = append(lines, line)
+				size += len(line) + newLine
+			}
+		},
+		Act: func(p *pipeline.Pipeline) {
+			file = createTempFile()
+			for _, s := range lines {
+				addString(file, s, true, true)
+			}
+		},
+		Assert: func(p *pipeline.Pipeline) {
+			assert.Equal(t, lineCount*3, p.GetEventsTotal(), "wrong event count")
+			assert.Equal(t, `{"field":"value_0_1"}`, p.GetEventLogItem(1), "wrong event")
+			assertOffsetsAreEqual(t, genOffsetsContent(file, size), getContent(getConfigByPipeline(p).OffsetsFile))
+		},
+	}, getInputInfo(), settings, lineCount*3)
+}
+
 // TestOffsetsSaveContinue tests if plugin skips partial data in the case pipeline starts in the middle of the line
 func TestOffsetsSaveContinue(t *testing.T) {
 	leftPart := `["left_part",`
@@ -1082,6 +1115,55 @@ func TestTruncationSeq(t *testing.T) {
 //	assertOffsetsAreEqual(t, genOffsetsContentMultiple(files, 4*19), getContent(input.config.OffsetsFile))
 //}
 
+// TestAddMeta tests if the file metadata is added to events
+func TestAddMeta(t *testing.T) {
+	cleanUp()
+
+	inputInfo := getInputInfo()
+	config := inputInfo.Config.(*Config)
+	config.AddMeta = true
+
+	actions := []*pipeline.ActionPluginStaticInfo{
+		{
+			PluginStaticInfo: &pipeline.PluginStaticInfo{
+				Type:    "file-meta",
+				Factory: MetaActionFactory,
+				Config:  config,
+			},
+			MatchConditions: pipeline.MatchConditions{},
+		},
+	}
+	p, _, output := test.NewPipelineMock(actions, "passive")
+	p.SetInput(inputInfo)
+
+	values := []string{"value_0", "value_long_1", "value_2"}
+	wg := &sync.WaitGroup{}
+	wg.Add(len(values))
+
+	outEvents := make([]string, 0)
+	output.SetOutFn(func(event *pipeline.Event) {
+		outEvents = append(outEvents, event.Root.EncodeToString())
+		wg.Done()
+	})
+
+	p.Start()
+
+	file := createTempFile()
+	for _, value := range values {
+		addString(file, fmt.Sprintf(`{"field":"%s"}`, value), true, true)
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	offset := 0
+	for i, value := range values {
+		expected := fmt.Sprintf(`{"field":"%s","_file_path":"%s","_file_inode":%d,"_file_offset":%d}`, value, file, getInodeByFile(file), offset)
+		assert.Equal(t, expected, outEvents[i], "wrong event")
+		offset += len(fmt.Sprintf(`{"field":"%s"}`, value)) + newLine
+	}
+}
+
 func BenchmarkLightJsonReadPar(b *testing.B) {
 	lines := 128 * 64
 	files := 256
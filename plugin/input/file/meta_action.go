@@ -0,0 +1,38 @@
+package file
+
+import (
+	"github.com/ozonru/file.d/pipeline"
+	"go.uber.org/zap"
+)
+
+// MetaAction adds the source file metadata to events if `add_meta` is set
+type MetaAction struct {
+	config *Config
+	logger *zap.SugaredLogger
+}
+
+func (p *MetaAction) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.logger = params.Logger
+	p.config = config.(*Config)
+}
+
+func (p *MetaAction) Stop() {
+}
+
+func (p *MetaAction) Do(event *pipeline.Event) pipeline.ActionResult {
+	if !p.config.AddMeta {
+		return pipeline.ActionPass
+	}
+
+	event.Root.AddFieldNoAlloc(event.Root, "_file_path").MutateToString(event.SourceName)
+
+	// job may be already removed if the file is deleted, so inode isn't added
+	if ino, has := p.config.jobProvider.inodeBySourceID(event.SourceID); has {
+		event.Root.AddFieldNoAlloc(event.Root, "_file_inode").MutateToInt(int(ino))
+	}
+
+	// event offset points to the end of the line, so the line length is subtracted
+	event.Root.AddFieldNoAlloc(event.Root, "_file_offset").MutateToInt(int(event.Offset) - event.Size)
+
+	return pipeline.ActionPass
+}
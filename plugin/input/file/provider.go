@@ -293,6 +293,18 @@ func (jp *jobProvider) addJob(file *os.File, stat os.FileInfo, filename string,
 	jp.tryResumeJobAndUnlock(job, filename)
 }
 
+func (jp *jobProvider) inodeBySourceID(sourceID pipeline.SourceID) (inode, bool) {
+	jp.jobsMu.RLock()
+	defer jp.jobsMu.RUnlock()
+
+	job, has := jp.jobs[sourceID]
+	if !has {
+		return 0, false
+	}
+
+	return job.inode, true
+}
+
 func sourceIDByStat(s os.FileInfo, symlink string) pipeline.SourceID {
 	inode := int64(s.Sys().(*syscall.Stat_t).Ino)
 
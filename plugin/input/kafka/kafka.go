@@ -2,6 +2,7 @@ package kafka
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"github.com/Shopify/sarama"
@@ -43,6 +44,13 @@ type Config struct {
 	//>
 	//> The name of consumer group to use.
 	ConsumerGroup string `json:"consumer_group" default:"file-d"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The compression of message values to decompress them before processing,
+	//> e.g. values which are compressed with `value_compress` of kafka output plugin.
+	//> Messages which can't be decompressed are reported as input errors and skipped.
+	ValueDecompress string `json:"value_decompress" default:"none" options:"none|zstd|gzip"` //*
 }
 
 func init() {
@@ -78,7 +86,7 @@ func (p *Plugin) consume() {
 	for {
 		err := p.consumerGroup.Consume(p.context, p.config.Topics, p)
 		if err != nil {
-			p.logger.Errorf("can't consume from kafka: %s", err.Error())
+			p.controller.InputError("can't consume from kafka: " + err.Error())
 		}
 
 		if p.context.Err() != nil {
@@ -124,9 +132,21 @@ func (p *Plugin) Cleanup(sarama.ConsumerGroupSession) error {
 }
 
 func (p *Plugin) ConsumeClaim(_ sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	var buf []byte
 	for message := range claim.Messages() {
+		value := message.Value
+		if p.config.ValueDecompress != pipeline.CompressNone {
+			var err error
+			buf, err = pipeline.AppendDecompressed(buf[:0], message.Value, p.config.ValueDecompress)
+			if err != nil {
+				p.controller.InputError(fmt.Sprintf("can't decompress message value, topic=%s, partition=%d, offset=%d: %s", message.Topic, message.Partition, message.Offset, err.Error()))
+				continue
+			}
+			value = buf
+		}
+
 		sourceID := assembleSourceID(p.idByTopic[message.Topic], message.Partition)
-		p.controller.In(sourceID, "kafka", message.Offset, message.Value, true)
+		p.controller.In(sourceID, "kafka", message.Offset, value, true)
 	}
 
 	return nil
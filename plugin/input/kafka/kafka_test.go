@@ -3,6 +3,8 @@ package kafka
 import (
 	"testing"
 
+	"github.com/Shopify/sarama"
+	"github.com/ozonru/file.d/pipeline"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,3 +19,53 @@ func TestAssembleSourceID(t *testing.T) {
 	assert.Equal(t, index, newIndex, "values aren't equal")
 	assert.Equal(t, partition, newPartition, "values aren't equal")
 }
+
+type testClaim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *testClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return c.messages
+}
+
+type testController struct {
+	pipeline.InputPluginController
+	in     []string
+	errors []string
+}
+
+func (c *testController) In(_ pipeline.SourceID, _ string, _ int64, data []byte, _ bool) uint64 {
+	c.in = append(c.in, string(data))
+	return 0
+}
+
+func (c *testController) InputError(err string) {
+	c.errors = append(c.errors, err)
+}
+
+func TestValueDecompress(t *testing.T) {
+	events := []string{`{"message":"first"}`, `{"message":"second","level":"error"}`}
+
+	for _, method := range []string{pipeline.CompressNone, pipeline.CompressZstd, pipeline.CompressGzip} {
+		claim := &testClaim{messages: make(chan *sarama.ConsumerMessage, len(events)+1)}
+		for i, event := range events {
+			value, err := pipeline.AppendCompressed(nil, []byte(event), method)
+			assert.NoError(t, err, "can't compress with %s", method)
+			claim.messages <- &sarama.ConsumerMessage{Topic: "logs", Offset: int64(i), Value: value}
+		}
+		if method != pipeline.CompressNone {
+			claim.messages <- &sarama.ConsumerMessage{Topic: "logs", Offset: 2, Value: []byte(`{"message":"plain"}`)}
+		}
+		close(claim.messages)
+
+		controller := &testController{}
+		p := &Plugin{config: &Config{ValueDecompress: method}, controller: controller, idByTopic: map[string]int{"logs": 0}}
+		assert.NoError(t, p.ConsumeClaim(nil, claim), "wrong consuming")
+
+		assert.Equal(t, events, controller.in, "wrong events with %s", method)
+		if method != pipeline.CompressNone {
+			assert.Equal(t, 1, len(controller.errors), "wrong value isn't reported with %s", method)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,8 +20,14 @@ import (
 /*{ introduction
 It sends events into Elasticsearch. It uses `_bulk` API to send events in batches.
 If a network error occurs, the batch will infinitely try to be delivered to the random endpoint.
+If an event has `id_field`, its value is used as the document `_id` and the field is removed from the document,
+check out `doc_id` action plugin to compute it.
+If `payload_template` is set, the batch is sent as a JSON array of documents wrapped in the template instead,
+so the plugin can be used with HTTP backends which expect a specific envelope.
 }*/
 
+const payloadPlaceholder = "${events}"
+
 type Plugin struct {
 	logger     *zap.SugaredLogger
 	client     *http.Client
@@ -30,6 +37,9 @@ type Plugin struct {
 	batcher    *pipeline.Batcher
 	controller pipeline.OutputPluginController
 	mu         *sync.Mutex
+
+	payloadPrefix []byte
+	payloadSuffix []byte
 }
 
 //! config-params
@@ -84,6 +94,32 @@ type Config struct {
 	//> After this timeout batch will be sent even if batch isn't full.
 	BatchFlushTimeout  cfg.Duration `json:"batch_flush_timeout" default:"200ms"` //*
 	BatchFlushTimeout_ time.Duration
+
+	//> @3@4@5@6
+	//>
+	//> The event field which value is used as the document `_id`. Events without the field get an ID generated by Elasticsearch.
+	IDField  cfg.FieldSelector `json:"id_field" parse:"selector" default:"_id"` //*
+	IDField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The event field with the index name which overrides `index_format` for the event. Nested fields can be used.
+	DestinationField  cfg.FieldSelector `json:"destination_field" parse:"selector"` //*
+	DestinationField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The JSON template of the request body. The `${events}` placeholder is replaced with the JSON array of the batch documents,
+	//> e.g. `{"source":"file.d","records":${events}}`. If it's set, endpoints are used as is instead of the `_bulk` API
+	//> and batches are sent with `application/json` content type.
+	PayloadTemplate string `json:"payload_template"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field to convert to int64 nanoseconds since the Unix epoch before sending, e.g. for `date_nanos` mapping.
+	//> RFC3339 strings and epoch seconds, numbers or numeric strings with an optional fractional part, are converted, other values are sent as is.
+	TimestampNsField  cfg.FieldSelector `json:"timestamp_ns_field" parse:"selector"` //*
+	TimestampNsField_ []string
 }
 
 type data struct {
@@ -112,11 +148,20 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginP
 		p.config.IndexValues = append(p.config.IndexValues, "@time")
 	}
 
-	for i, endpoint := range p.config.Endpoints {
-		if endpoint[len(endpoint)-1] == '/' {
-			endpoint = endpoint[:len(endpoint)-1]
+	if p.config.PayloadTemplate != "" {
+		pos := strings.Index(p.config.PayloadTemplate, payloadPlaceholder)
+		if pos == -1 {
+			p.logger.Fatalf("payload_template must contain %s placeholder", payloadPlaceholder)
+		}
+		p.payloadPrefix = []byte(p.config.PayloadTemplate[:pos])
+		p.payloadSuffix = []byte(p.config.PayloadTemplate[pos+len(payloadPlaceholder):])
+	} else {
+		for i, endpoint := range p.config.Endpoints {
+			if endpoint[len(endpoint)-1] == '/' {
+				endpoint = endpoint[:len(endpoint)-1]
+			}
+			p.config.Endpoints[i] = endpoint + "/_bulk?_source=false"
 		}
-		p.config.Endpoints[i] = endpoint + "/_bulk?_source=false"
 	}
 
 	p.client = &http.Client{
@@ -143,6 +188,7 @@ func (p *Plugin) Stop() {
 }
 
 func (p *Plugin) Out(event *pipeline.Event) {
+	pipeline.TimestampToNanos(event, p.config.TimestampNsField_)
 	p.batcher.Add(event)
 }
 
@@ -160,13 +206,19 @@ func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) {
 	}
 
 	data.outBuf = data.outBuf[:0]
-	for _, event := range batch.Events {
-		data.outBuf = p.appendEvent(data.outBuf, event)
+	contentType := "application/x-ndjson"
+	if p.config.PayloadTemplate != "" {
+		data.outBuf = p.appendPayload(data.outBuf, batch)
+		contentType = "application/json"
+	} else {
+		for _, event := range batch.Events {
+			data.outBuf = p.appendEvent(data.outBuf, event)
+		}
 	}
 
 	for {
 		endpoint := p.config.Endpoints[rand.Int()%len(p.config.Endpoints)]
-		resp, err := p.client.Post(endpoint, "application/x-ndjson", bytes.NewBuffer(data.outBuf))
+		resp, err := p.client.Post(endpoint, contentType, bytes.NewBuffer(data.outBuf))
 		if err != nil {
 			p.logger.Errorf("can't send batch to %s, will try other endpoint: %s", endpoint, err.Error())
 			continue
@@ -184,6 +236,11 @@ func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) {
 			continue
 		}
 
+		// response format of the templated backend is unknown, so only the status is checked
+		if p.config.PayloadTemplate != "" {
+			break
+		}
+
 		root, err := insaneJSON.DecodeBytes(respContent)
 		if err != nil {
 			p.logger.Errorf("wrong response from %s, will try other endpoint: %s", endpoint, err.Error())
@@ -210,7 +267,8 @@ func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) {
 func (p *Plugin) appendEvent(outBuf []byte, event *pipeline.Event) []byte {
 	// index command
 	outBuf = p.appendIndexName(outBuf, event)
-	outBuf = append(outBuf, '\n')
+	outBuf = p.appendID(outBuf, event)
+	outBuf = append(outBuf, "}}\n"...)
 
 	// document
 	outBuf, _ = event.Encode(outBuf)
@@ -219,8 +277,30 @@ func (p *Plugin) appendEvent(outBuf []byte, event *pipeline.Event) []byte {
 	return outBuf
 }
 
+// appendPayload renders the payload template with the batch documents
+func (p *Plugin) appendPayload(outBuf []byte, batch *pipeline.Batch) []byte {
+	outBuf = append(outBuf, p.payloadPrefix...)
+	outBuf = append(outBuf, '[')
+	for i, event := range batch.Events {
+		if i != 0 {
+			outBuf = append(outBuf, ',')
+		}
+		outBuf, _ = event.Encode(outBuf)
+	}
+	outBuf = append(outBuf, ']')
+	outBuf = append(outBuf, p.payloadSuffix...)
+
+	return outBuf
+}
+
 func (p *Plugin) appendIndexName(outBuf []byte, event *pipeline.Event) []byte {
 	outBuf = append(outBuf, `{"index":{"_index":"`...)
+	if index := pipeline.EventDestination(event, p.config.DestinationField_, ""); index != "" {
+		outBuf = append(outBuf, index...)
+		outBuf = append(outBuf, '"')
+		return outBuf
+	}
+
 	replacements := 0
 	for _, c := range pipeline.StringToByteUnsafe(p.config.IndexFormat) {
 		if c != '%' {
@@ -244,7 +324,28 @@ func (p *Plugin) appendIndexName(outBuf []byte, event *pipeline.Event) []byte {
 			outBuf = append(outBuf, value...)
 		}
 	}
-	outBuf = append(outBuf, "\"}}"...)
+	outBuf = append(outBuf, '"')
+	return outBuf
+}
+
+// appendID appends `_id` to the index command and removes the ID field from the document,
+// since Elasticsearch doesn't allow metadata fields in the document
+func (p *Plugin) appendID(outBuf []byte, event *pipeline.Event) []byte {
+	node := event.Root.Dig(p.config.IDField_...)
+	if node == nil || !(node.IsString() || node.IsNumber()) {
+		return outBuf
+	}
+
+	outBuf = append(outBuf, `,"_id":`...)
+	if node.IsString() {
+		outBuf = node.Encode(outBuf)
+	} else {
+		outBuf = append(outBuf, '"')
+		outBuf = append(outBuf, node.AsString()...)
+		outBuf = append(outBuf, '"')
+	}
+	node.Suicide()
+
 	return outBuf
 }
 
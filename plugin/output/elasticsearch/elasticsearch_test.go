@@ -2,6 +2,9 @@ package elasticsearch
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/ozonru/file.d/cfg"
@@ -54,3 +57,134 @@ func TestConfig(t *testing.T) {
 	assert.Equal(t, "http://endpoint_1:9000/_bulk?_source=false", p.config.Endpoints[0], "wrong endpoint")
 	assert.Equal(t, "http://endpoint_2:9000/_bulk?_source=false", p.config.Endpoints[1], "wrong endpoint")
 }
+
+func TestAppendEventID(t *testing.T) {
+	p := &Plugin{}
+	config := &Config{
+		Endpoints:   []string{"test"},
+		IndexFormat: "test-%",
+		IndexValues: []string{"@time"},
+		BatchSize:   "1",
+	}
+
+	err := cfg.Parse(config, map[string]int{"gomaxprocs": 1})
+	if err != nil {
+		logger.Panic(err.Error())
+	}
+
+	p.Start(config, test.NewEmptyOutputPluginParams())
+	p.time = "6666-66-66"
+
+	events := map[string]string{
+		`{"_id":"6f1ed002ab5595859014ebf0951522d9","message":"hello"}`: `{"index":{"_index":"test-6666-66-66","_id":"6f1ed002ab5595859014ebf0951522d9"}}` + "\n" + `{"message":"hello"}` + "\n",
+		`{"_id":42,"message":"hello"}`:                                 `{"index":{"_index":"test-6666-66-66","_id":"42"}}` + "\n" + `{"message":"hello"}` + "\n",
+		`{"_id":"a\"b","message":"hello"}`:                             `{"index":{"_index":"test-6666-66-66","_id":"a\"b"}}` + "\n" + `{"message":"hello"}` + "\n",
+		`{"_id":{"nested":1},"message":"hello"}`:                       `{"index":{"_index":"test-6666-66-66"}}` + "\n" + `{"_id":{"nested":1},"message":"hello"}` + "\n",
+		`{"message":"hello"}`:                                          `{"index":{"_index":"test-6666-66-66"}}` + "\n" + `{"message":"hello"}` + "\n",
+	}
+
+	for event, expected := range events {
+		root, _ := insaneJSON.DecodeString(event)
+		result := p.appendEvent(nil, &pipeline.Event{Root: root})
+		assert.Equal(t, expected, string(result), "wrong request content for event %s", event)
+		insaneJSON.Release(root)
+	}
+}
+
+func TestAppendEventIDField(t *testing.T) {
+	p := &Plugin{}
+	config := &Config{
+		Endpoints:   []string{"test"},
+		IndexFormat: "test",
+		IDField:     "meta.id",
+		BatchSize:   "1",
+	}
+
+	err := cfg.Parse(config, map[string]int{"gomaxprocs": 1})
+	if err != nil {
+		logger.Panic(err.Error())
+	}
+
+	p.Start(config, test.NewEmptyOutputPluginParams())
+
+	root, _ := insaneJSON.DecodeString(`{"_id":"not used","meta":{"id":"doc-1","source":"app"}}`)
+	defer insaneJSON.Release(root)
+	result := p.appendEvent(nil, &pipeline.Event{Root: root})
+
+	expected := fmt.Sprintf("%s\n%s\n", `{"index":{"_index":"test","_id":"doc-1"}}`, `{"_id":"not used","meta":{"source":"app"}}`)
+	assert.Equal(t, expected, string(result), "wrong request content")
+}
+
+func TestAppendEventDestination(t *testing.T) {
+	p := &Plugin{}
+	config := &Config{
+		Endpoints:        []string{"test"},
+		IndexFormat:      "test-%",
+		IndexValues:      []string{"service"},
+		DestinationField: "meta.index",
+		BatchSize:        "1",
+	}
+
+	err := cfg.Parse(config, map[string]int{"gomaxprocs": 1})
+	if err != nil {
+		logger.Panic(err.Error())
+	}
+
+	p.Start(config, test.NewEmptyOutputPluginParams())
+
+	events := map[string]string{
+		`{"service":"app","meta":{"index":"audit-2021"}}`: `{"index":{"_index":"audit-2021"}}` + "\n" + `{"service":"app","meta":{"index":"audit-2021"}}` + "\n",
+		`{"service":"app","meta":{"index":""}}`:           `{"index":{"_index":"test-app"}}` + "\n" + `{"service":"app","meta":{"index":""}}` + "\n",
+		`{"service":"app","meta":{"index":{}}}`:           `{"index":{"_index":"test-app"}}` + "\n" + `{"service":"app","meta":{"index":{}}}` + "\n",
+		`{"service":"app"}`:                               `{"index":{"_index":"test-app"}}` + "\n" + `{"service":"app"}` + "\n",
+	}
+
+	for event, expected := range events {
+		root, _ := insaneJSON.DecodeString(event)
+		result := p.appendEvent(nil, &pipeline.Event{Root: root})
+		assert.Equal(t, expected, string(result), "wrong request content for event %s", event)
+		insaneJSON.Release(root)
+	}
+}
+
+func TestPayloadTemplate(t *testing.T) {
+	var path, contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		path, contentType, body = r.URL.Path, r.Header.Get("Content-Type"), string(content)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	p := &Plugin{}
+	config := &Config{
+		Endpoints:       []string{server.URL + "/ingest"},
+		PayloadTemplate: `{"records":${events},"source":"file.d"}`,
+		BatchSize:       "1",
+	}
+
+	err := cfg.Parse(config, map[string]int{"gomaxprocs": 1})
+	if err != nil {
+		logger.Panic(err.Error())
+	}
+
+	p.Start(config, test.NewEmptyOutputPluginParams())
+
+	events := make([]*pipeline.Event, 0)
+	for _, json := range []string{`{"_id":"1","message":"hello"}`, `{"message":"world","level":"info"}`} {
+		root, _ := insaneJSON.DecodeString(json)
+		defer insaneJSON.Release(root)
+		events = append(events, &pipeline.Event{Root: root})
+	}
+
+	var workerData pipeline.WorkerData
+	p.out(&workerData, &pipeline.Batch{Events: events})
+
+	assert.Equal(t, "/ingest", path, "endpoint shouldn't be changed")
+	assert.Equal(t, "application/json", contentType, "wrong content type")
+	assert.Equal(t, `{"records":[{"_id":"1","message":"hello"},{"message":"world","level":"info"}],"source":"file.d"}`, body, "wrong request body")
+
+	p.out(&workerData, &pipeline.Batch{Events: events[:1]})
+	assert.Equal(t, `{"records":[{"_id":"1","message":"hello"}],"source":"file.d"}`, body, "wrong request body")
+}
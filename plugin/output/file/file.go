@@ -6,6 +6,7 @@ import (
 	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,6 +29,7 @@ type Plugin struct {
 	cancelFunc     context.CancelFunc
 	idx            int
 	nextSealUpTime time.Time
+	sealUpDone     chan struct{}
 
 	targetDir     string
 	fileExtension string
@@ -35,6 +37,11 @@ type Plugin struct {
 	tsFileName    string
 
 	mu *sync.RWMutex
+
+	// destinations write events with the value of destination_field to their own files, they are created on the first event
+	destinations    map[string]*Plugin
+	destinationsMu  *sync.Mutex
+	maxDestinations int
 }
 
 type data struct {
@@ -43,6 +50,8 @@ type data struct {
 
 const (
 	fileNameSeparator = "_"
+
+	defaultMaxDestinations = 100
 )
 
 var (
@@ -75,6 +84,29 @@ type Config struct {
 	//> File mode for log files
 	FileMode  cfg.Base8 `json:"file_mode" default:"0666" parse:"base8"` //*
 	FileMode_ int64
+
+	//> Format of events in the file: `json` or `msgpack`. MessagePack events are written one after another without separators.
+	Format string `json:"format" default:"json" options:"json|msgpack"` //*
+
+	//> The event field to convert to int64 nanoseconds since the Unix epoch before writing.
+	//> RFC3339 strings and epoch seconds, numbers or numeric strings with an optional fractional part, are converted, other values are written as is.
+	TimestampNsField  cfg.FieldSelector `json:"timestamp_ns_field" parse:"selector"` //*
+	TimestampNsField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The event field with the file name which overrides the name of `target_file` for the event.
+	//> The file is created in the directory of `target_file` with the same extension and is sealed up in the same way.
+	//> Values containing path separators are ignored. Outputs have no common config, so each output which
+	//> supports the option resolves the destination by `pipeline.EventDestination`, as kafka and elasticsearch outputs do.
+	DestinationField  cfg.FieldSelector `json:"destination_field" parse:"selector"` //*
+	DestinationField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The maximum number of files of `destination_field` values, each of them is kept open until the stop.
+	//> Events of new values are written to `target_file` once the limit is reached. `100` if not set.
+	MaxDestinations int `json:"max_destinations"` //*
 }
 
 func init() {
@@ -111,6 +143,12 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginP
 		0,
 	)
 	p.mu = &sync.RWMutex{}
+	p.destinations = make(map[string]*Plugin)
+	p.destinationsMu = &sync.Mutex{}
+	p.maxDestinations = p.config.MaxDestinations
+	if p.maxDestinations <= 0 {
+		p.maxDestinations = defaultMaxDestinations
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	p.ctx = ctx
 	p.cancelFunc = cancel
@@ -120,6 +158,12 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginP
 			p.logger.Fatalf("could not create target dir: %s, error: %s", p.targetDir, err.Error())
 		}
 	}
+
+	p.startFile()
+	p.batcher.Start()
+}
+
+func (p *Plugin) startFile() {
 	p.idx = p.getStartIdx()
 	p.createNew()
 	p.setNextSealUpTime()
@@ -131,16 +175,25 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginP
 		p.logger.Panic("next seal up time is nil!")
 	}
 
+	p.sealUpDone = make(chan struct{})
 	go p.fileSealUpTicker()
-	p.batcher.Start()
 }
 
 func (p *Plugin) Stop() {
 	p.cancelFunc()
 	p.batcher.Stop()
+
+	p.destinationsMu.Lock()
+	for _, destination := range p.destinations {
+		destination.close()
+	}
+	// events of late batches are written to the target file
+	p.destinations = nil
+	p.destinationsMu.Unlock()
 }
 
 func (p *Plugin) Out(event *pipeline.Event) {
+	pipeline.TimestampToNanos(event, p.config.TimestampNsField_)
 	p.batcher.Add(event)
 }
 
@@ -158,17 +211,65 @@ func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) {
 	}
 
 	outBuf := data.outBuf[:0]
+	target := p
 
 	for _, event := range batch.Events {
-		outBuf, _ = event.Encode(outBuf)
-		outBuf = append(outBuf, byte('\n'))
+		// events are written to the file of their destination, so the buffer is written once the destination changes
+		destination := p.destination(event)
+		if destination != target {
+			if len(outBuf) != 0 {
+				target.write(outBuf)
+			}
+			outBuf = outBuf[:0]
+			target = destination
+		}
+
+		outBuf, _ = event.EncodeFormat(outBuf, p.config.Format)
+		if p.config.Format != pipeline.FormatMsgpack {
+			outBuf = append(outBuf, byte('\n'))
+		}
 	}
 	data.outBuf = outBuf
 
-	p.write(outBuf)
+	target.write(outBuf)
+}
+
+// destination returns the plugin which writes to the file of the event destination, it's the plugin itself by default
+func (p *Plugin) destination(event *pipeline.Event) *Plugin {
+	name := pipeline.EventDestination(event, p.config.DestinationField_, "")
+	if name == "" || name == p.fileName || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return p
+	}
+
+	p.destinationsMu.Lock()
+	defer p.destinationsMu.Unlock()
+
+	destination, has := p.destinations[name]
+	if !has {
+		if p.destinations == nil || len(p.destinations) >= p.maxDestinations {
+			return p
+		}
+
+		// name points to the event memory, so let's copy it
+		name = string([]byte(name))
+		destination = &Plugin{
+			logger:        p.logger,
+			config:        p.config,
+			ctx:           p.ctx,
+			targetDir:     p.targetDir,
+			fileExtension: p.fileExtension,
+			fileName:      name,
+			mu:            &sync.RWMutex{},
+		}
+		destination.startFile()
+		p.destinations[name] = destination
+	}
+
+	return destination
 }
 
 func (p *Plugin) fileSealUpTicker() {
+	defer close(p.sealUpDone)
 	ticker := time.NewTicker(fileSealUpInterval)
 	defer ticker.Stop()
 	for {
@@ -196,6 +297,10 @@ func (p *Plugin) setNextSealUpTime() {
 func (p *Plugin) write(data []byte) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
+	if p.file == nil {
+		p.logger.Errorf("could not write into the destination file, it's closed on stop: %s%s%s", p.targetDir, p.fileName, p.fileExtension)
+		return
+	}
 	if _, err := p.file.Write(data); err != nil {
 		p.logger.Fatalf("could not write into the file: %s, error: %s", p.file.Name(), err.Error())
 	}
@@ -217,6 +322,19 @@ func (p *Plugin) createNew() {
 	p.file = file
 }
 
+// close closes the file of the destination on stop, it waits for the seal up ticker, so the file isn't sealed up after that
+func (p *Plugin) close() {
+	<-p.sealUpDone
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.file.Close(); err != nil {
+		p.logger.Errorf("could not close file: %s, error: %s", p.file.Name(), err.Error())
+	}
+	p.file = nil
+}
+
 //sealUp manages current file: renames, closes, and creates new.
 func (p *Plugin) sealUp() {
 	info, err := p.file.Stat()
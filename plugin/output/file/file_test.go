@@ -2,6 +2,7 @@ package file
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -323,3 +324,91 @@ func TestStart(t *testing.T) {
 	}
 	p2.Stop()
 }
+
+func TestDestinationField(t *testing.T) {
+	clearDir(t, dir)
+	defer clearDir(t, dir)
+	config := &Config{
+		TargetFile:        targetFile,
+		RetentionInterval: "1h",
+		Layout:            "01",
+		BatchFlushTimeout: "100ms",
+		DestinationField:  "meta.file",
+
+		FileMode_: 0666,
+	}
+	err := cfg.Parse(config, map[string]int{"gomaxprocs": 1, "capacity": 64})
+	assert.NoError(t, err)
+
+	p := newPipeline(t, config)
+	p.Start()
+	sendPack(t, p, []msg{
+		msg(`{"meta":{"file":"api"},"message":"1"}`),
+		msg(`{"message":"2"}`),
+		msg(`{"meta":{"file":"../api"},"message":"3"}`),
+		msg(`{"meta":{"file":"api"},"message":"4"}`),
+		msg(`{"meta":{"file":""},"message":"5"}`),
+	})
+	time.Sleep(300 * time.Millisecond)
+	p.Stop()
+
+	readFile := func(pattern string) string {
+		matches := getMatches(t, pattern)
+		assert.Equal(t, 1, len(matches), "wrong files count")
+		if len(matches) != 1 {
+			return ""
+		}
+
+		content, err := ioutil.ReadFile(matches[0])
+		assert.NoError(t, err)
+		return string(content)
+	}
+
+	assert.Equal(t, "{\"meta\":{\"file\":\"api\"},\"message\":\"1\"}\n{\"meta\":{\"file\":\"api\"},\"message\":\"4\"}\n",
+		readFile(fmt.Sprintf("%s*%sapi%s", dir, fileNameSeparator, extension)), "events with the destination aren't written to its file")
+	assert.Equal(t, "{\"message\":\"2\"}\n{\"meta\":{\"file\":\"../api\"},\"message\":\"3\"}\n{\"meta\":{\"file\":\"\"},\"message\":\"5\"}\n",
+		readFile(fmt.Sprintf("%s*%s%s", dir, fileNameSeparator, file)), "events without the destination aren't written to the target file")
+}
+
+func TestMaxDestinations(t *testing.T) {
+	clearDir(t, dir)
+	defer clearDir(t, dir)
+	config := &Config{
+		TargetFile:        targetFile,
+		RetentionInterval: "1h",
+		Layout:            "01",
+		BatchFlushTimeout: "100ms",
+		DestinationField:  "meta.file",
+		MaxDestinations:   1,
+
+		FileMode_: 0666,
+	}
+	err := cfg.Parse(config, map[string]int{"gomaxprocs": 1, "capacity": 64})
+	assert.NoError(t, err)
+
+	p := newPipeline(t, config)
+	p.Start()
+	sendPack(t, p, []msg{
+		msg(`{"meta":{"file":"api"},"message":"1"}`),
+		msg(`{"meta":{"file":"web"},"message":"2"}`),
+	})
+	time.Sleep(300 * time.Millisecond)
+
+	plugin := p.GetOutput().(*Plugin)
+	plugin.destinationsMu.Lock()
+	assert.Equal(t, 1, len(plugin.destinations), "destinations aren't capped")
+	destination := plugin.destinations["api"]
+	plugin.destinationsMu.Unlock()
+
+	p.Stop()
+	assert.NotNil(t, destination, "destination isn't created")
+	if destination != nil {
+		assert.Nil(t, destination.file, "destination file isn't closed on stop")
+	}
+
+	// events of values above the limit are written to the target file
+	assert.Equal(t, 0, len(getMatches(t, fmt.Sprintf("%s*%sweb%s", dir, fileNameSeparator, extension))), "destination above the limit is created")
+	content, err := ioutil.ReadFile(getMatches(t, fmt.Sprintf("%s*%s%s", dir, fileNameSeparator, file))[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"meta\":{\"file\":\"web\"},\"message\":\"2\"}\n", string(content), "wrong target file content")
+}
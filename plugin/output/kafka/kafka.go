@@ -18,6 +18,7 @@ It sends the event batches to kafka brokers using `sarama` lib.
 type data struct {
 	messages []*sarama.ProducerMessage
 	outBuf   sarama.ByteEncoder
+	valueBuf []byte
 }
 
 type Plugin struct {
@@ -53,6 +54,13 @@ type Config struct {
 	//> Which event field to use as topic name. It works only if `should_use_topic_field` is set.
 	TopicField string `json:"topic_field" default:"topic"` //*
 
+	//> @3@4@5@6
+	//>
+	//> The event field with the topic name which overrides `default_topic` for the event. Nested fields can be used.
+	//> It takes precedence over `use_topic_field`.
+	DestinationField  cfg.FieldSelector `json:"destination_field" parse:"selector"` //*
+	DestinationField_ []string
+
 	//> @3@4@5@6
 	//> 
 	//> How many workers will be instantiated to send batches.
@@ -70,6 +78,25 @@ type Config struct {
 	//> After this timeout the batch will be sent even if batch isn't full.
 	BatchFlushTimeout  cfg.Duration `json:"batch_flush_timeout" default:"200ms" parse:"duration"` //*
 	BatchFlushTimeout_ time.Duration
+
+	//> @3@4@5@6
+	//>
+	//> The format of kafka messages. Use `msgpack` to encode messages in MessagePack, which is more compact than JSON.
+	Format string `json:"format" default:"json" options:"json|msgpack"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The compression of each message value, it's applied to the encoded event before producing.
+	//> Unlike compression codecs of kafka producer, consumers should decompress values by themselves,
+	//> e.g. with `value_decompress` of kafka input plugin.
+	ValueCompress string `json:"value_compress" default:"none" options:"none|zstd|gzip"` //*
+
+	//> @3@4@5@6
+	//>
+	//> The event field to convert to int64 nanoseconds since the Unix epoch before producing, e.g. for time series databases consuming the topic.
+	//> RFC3339 strings and epoch seconds, numbers or numeric strings with an optional fractional part, are converted, other values are sent as is.
+	TimestampNsField  cfg.FieldSelector `json:"timestamp_ns_field" parse:"selector"` //*
+	TimestampNsField_ []string
 }
 
 func init() {
@@ -89,6 +116,8 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginP
 	p.avgLogSize = params.PipelineSettings.AvgLogSize
 	p.controller = params.Controller
 
+	p.config.DestinationField_ = topicField(p.config)
+
 	p.logger.Infof("workers count=%d, batch size=%d", p.config.WorkersCount_, p.config.BatchSize_)
 
 	p.producer = p.newProducer()
@@ -106,7 +135,18 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginP
 	p.batcher.Start()
 }
 
+// topicField returns the event field with the topic name, `topic_field` is used if only `use_topic_field` is set
+func topicField(config *Config) []string {
+	if len(config.DestinationField_) == 0 && config.UseTopicField {
+		return []string{config.TopicField}
+	}
+
+	return config.DestinationField_
+}
+
 func (p *Plugin) Out(event *pipeline.Event) {
+	// it's converted once per event, since batches may be encoded several times
+	pipeline.TimestampToNanos(event, p.config.TimestampNsField_)
 	p.batcher.Add(event)
 }
 
@@ -127,16 +167,20 @@ func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) {
 	outBuf := data.outBuf[:0]
 	start := 0
 	for i, event := range batch.Events {
-		outBuf, start = event.Encode(outBuf)
-
-		topic := p.config.DefaultTopic
-		if p.config.UseTopicField {
-			fieldValue := event.Root.Dig(p.config.TopicField).AsString()
-			if fieldValue != "" {
-				topic = fieldValue
+		if p.config.ValueCompress == pipeline.CompressNone {
+			outBuf, start = event.EncodeFormat(outBuf, p.config.Format)
+		} else {
+			data.valueBuf, _ = event.EncodeFormat(data.valueBuf[:0], p.config.Format)
+			start = len(outBuf)
+			var err error
+			outBuf, err = pipeline.AppendCompressed(outBuf, data.valueBuf, p.config.ValueCompress)
+			if err != nil {
+				p.logger.Fatalf("can't compress event: %s", err.Error())
 			}
 		}
 
+		topic := pipeline.EventDestination(event, p.config.DestinationField_, p.config.DefaultTopic)
+
 		if data.messages[i] == nil {
 			data.messages[i] = &sarama.ProducerMessage{}
 		}
@@ -0,0 +1,102 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/logger"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/stretchr/testify/assert"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+func newTestPlugin(t *testing.T, config *Config, topics []string) (*Plugin, *mocks.SyncProducer) {
+	err := cfg.Parse(config, map[string]int{"gomaxprocs": 1, "capacity": 64})
+	if err != nil {
+		logger.Panic(err.Error())
+	}
+
+	producer := mocks.NewSyncProducer(t, nil)
+	for _, topic := range topics {
+		expected := topic
+		producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(m *sarama.ProducerMessage) error {
+			assert.Equal(t, expected, m.Topic, "wrong topic")
+			return nil
+		})
+	}
+
+	config.DestinationField_ = topicField(config)
+	p := &Plugin{config: config, producer: producer, avgLogSize: 16, logger: logger.Instance}
+
+	return p, producer
+}
+
+func sendBatch(p *Plugin, events []string) {
+	batch := &pipeline.Batch{}
+	for _, e := range events {
+		root, _ := insaneJSON.DecodeString(e)
+		batch.Events = append(batch.Events, &pipeline.Event{Root: root})
+	}
+
+	var workerData pipeline.WorkerData
+	p.out(&workerData, batch)
+
+	for _, e := range batch.Events {
+		insaneJSON.Release(e.Root)
+	}
+}
+
+func TestOutDestinationField(t *testing.T) {
+	config := &Config{Brokers: []string{"test"}, DefaultTopic: "logs", DestinationField: "meta.topic"}
+	p, producer := newTestPlugin(t, config, []string{"audit", "logs", "logs", "42"})
+
+	sendBatch(p, []string{
+		`{"meta":{"topic":"audit"}}`,
+		`{"meta":{"topic":""}}`,
+		`{"message":"no topic"}`,
+		`{"meta":{"topic":42}}`,
+	})
+
+	assert.NoError(t, producer.Close(), "not all messages are sent")
+}
+
+func TestOutTopicField(t *testing.T) {
+	config := &Config{Brokers: []string{"test"}, DefaultTopic: "logs", UseTopicField: true}
+	p, producer := newTestPlugin(t, config, []string{"audit", "logs"})
+
+	sendBatch(p, []string{`{"topic":"audit"}`, `{"message":"no topic"}`})
+
+	assert.NoError(t, producer.Close(), "not all messages are sent")
+}
+
+func TestOutValueCompress(t *testing.T) {
+	events := []string{`{"message":"first"}`, `{"message":"second","level":"error"}`}
+
+	for _, method := range []string{pipeline.CompressZstd, pipeline.CompressGzip} {
+		config := &Config{Brokers: []string{"test"}, DefaultTopic: "logs", ValueCompress: method}
+		err := cfg.Parse(config, map[string]int{"gomaxprocs": 1, "capacity": 64})
+		assert.NoError(t, err, "wrong config")
+
+		values := make([]string, 0)
+		producer := mocks.NewSyncProducer(t, nil)
+		for range events {
+			producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(m *sarama.ProducerMessage) error {
+				value, err := m.Value.Encode()
+				assert.NoError(t, err, "wrong value")
+
+				decompressed, err := pipeline.AppendDecompressed(nil, value, method)
+				assert.NoError(t, err, "can't decompress value with %s", method)
+				values = append(values, string(decompressed))
+				return nil
+			})
+		}
+
+		p := &Plugin{config: config, producer: producer, avgLogSize: 16, logger: logger.Instance}
+		sendBatch(p, events)
+
+		assert.NoError(t, producer.Close(), "not all messages are sent")
+		assert.Equal(t, events, values, "wrong values with %s", method)
+	}
+}
@@ -0,0 +1,221 @@
+package weighted
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"math/rand"
+	"runtime"
+	"sync"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/fd"
+	"github.com/ozonru/file.d/pipeline"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It splits events between several outputs according to their weights. It is useful for canary testing of a new backend.
+Each event goes to exactly one output. If `key_field` is set, events with the same key always go to the same output,
+otherwise the output is chosen randomly.
+
+**Example of sending 5% of events to a new cluster:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    output:
+      type: weighted
+      routes:
+      - name: stable
+        weight: 95
+        output:
+          type: elasticsearch
+          endpoints: ["http://stable:9200"]
+      - name: canary
+        weight: 5
+        output:
+          type: elasticsearch
+          endpoints: ["http://canary:9200"]
+    ...
+```
+}*/
+type Plugin struct {
+	logger      *zap.SugaredLogger
+	config      *Config
+	controller  pipeline.OutputPluginController
+	routes      []*route
+	totalWeight int
+
+	// commits from outputs are passed to the pipeline in the same order as events came to the plugin,
+	// otherwise input will get offsets out of order
+	commitMu  *sync.Mutex
+	queue     []*pipeline.Event
+	committed map[*pipeline.Event]bool
+}
+
+type route struct {
+	name   string
+	weight int
+	output pipeline.OutputPlugin
+}
+
+//! config-params
+//^ config-params
+type Config struct {
+	//> @3@4@5@6
+	//>
+	//> The event field which is used for the consistent output selection. If not set, the output is chosen randomly.
+	KeyField  cfg.FieldSelector `json:"key_field" parse:"selector"` //*
+	KeyField_ []string
+
+	//> @3@4@5@6
+	//>
+	//> The list of outputs. Each item has the following fields:
+	//> * `name` – the name of the route which is used in logs.
+	//> * `weight` – the share of events which will be sent to the output.
+	//> * `output` – the config of the output plugin, it's the same as the pipeline `output` section.
+	Routes []RouteConfig `json:"routes" required:"true" slice:"true"` //*
+}
+
+type RouteConfig struct {
+	Name   string                 `json:"name"`
+	Weight int                    `json:"weight" required:"true"`
+	Output map[string]interface{} `json:"output" required:"true"`
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterOutput(&pipeline.PluginStaticInfo{
+		Type:    "weighted",
+		Factory: Factory,
+	})
+}
+
+func Factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger
+	p.controller = params.Controller
+	p.commitMu = &sync.Mutex{}
+	p.queue = make([]*pipeline.Event, 0, params.PipelineSettings.Capacity)
+	p.committed = make(map[*pipeline.Event]bool, params.PipelineSettings.Capacity)
+
+	values := map[string]int{
+		"capacity":   params.PipelineSettings.Capacity,
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+	}
+
+	for i, routeConfig := range p.config.Routes {
+		if routeConfig.Weight <= 0 {
+			p.logger.Fatalf("weight of route #%d should be positive", i)
+		}
+
+		t, _ := routeConfig.Output["type"].(string)
+		if t == "" {
+			p.logger.Fatalf("output of route #%d doesn't have type", i)
+		}
+
+		name := routeConfig.Name
+		if name == "" {
+			name = t
+		}
+
+		info := fd.DefaultPluginRegistry.Get(pipeline.PluginKindOutput, t)
+		plugin, outputConfig := info.Factory()
+
+		configJSON, err := json.Marshal(routeConfig.Output)
+		if err != nil {
+			p.logger.Fatalf("can't create config json for route %q: %s", name, err.Error())
+		}
+
+		err = json.Unmarshal(configJSON, outputConfig)
+		if err != nil {
+			p.logger.Fatalf("can't unmarshal config for route %q: %s", name, err.Error())
+		}
+
+		err = cfg.Parse(outputConfig, values)
+		if err != nil {
+			p.logger.Fatalf("wrong config for route %q: %s", name, err.Error())
+		}
+
+		r := &route{
+			name:   name,
+			weight: routeConfig.Weight,
+			output: plugin.(pipeline.OutputPlugin),
+		}
+		p.routes = append(p.routes, r)
+		p.totalWeight += r.weight
+
+		p.logger.Infof("starting output %q for route %q with weight %d", t, name, r.weight)
+		r.output.Start(outputConfig, &pipeline.OutputPluginParams{
+			PluginDefaultParams: params.PluginDefaultParams,
+			Controller:          p,
+			Logger:              p.logger.Named(name),
+		})
+	}
+
+	if len(p.routes) == 0 {
+		p.logger.Fatalf("no routes provided")
+	}
+}
+
+func (p *Plugin) Stop() {
+	for _, r := range p.routes {
+		r.output.Stop()
+	}
+}
+
+func (p *Plugin) Out(event *pipeline.Event) {
+	r := p.selectRoute(event)
+
+	p.commitMu.Lock()
+	p.queue = append(p.queue, event)
+	p.commitMu.Unlock()
+
+	r.output.Out(event)
+}
+
+func (p *Plugin) selectRoute(event *pipeline.Event) *route {
+	x := 0
+	if len(p.config.KeyField_) != 0 {
+		hash := fnv.New32a()
+		_, _ = hash.Write(event.Root.Dig(p.config.KeyField_...).AsBytes())
+		x = int(hash.Sum32() % uint32(p.totalWeight))
+	} else {
+		x = rand.Intn(p.totalWeight)
+	}
+
+	for _, r := range p.routes {
+		if x < r.weight {
+			return r
+		}
+		x -= r.weight
+	}
+
+	return p.routes[len(p.routes)-1]
+}
+
+// Commit is called by the route outputs.
+func (p *Plugin) Commit(event *pipeline.Event) {
+	p.commitMu.Lock()
+	p.committed[event] = true
+
+	n := 0
+	for _, e := range p.queue {
+		if !p.committed[e] {
+			break
+		}
+		delete(p.committed, e)
+		p.controller.Commit(e)
+		n++
+	}
+	p.queue = append(p.queue[:0], p.queue[n:]...)
+	p.commitMu.Unlock()
+}
+
+// Error is called by the route outputs.
+func (p *Plugin) Error(err string) {
+	p.controller.Error(err)
+}
@@ -0,0 +1,127 @@
+package weighted
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/ozonru/file.d/cfg"
+	"github.com/ozonru/file.d/pipeline"
+	"github.com/ozonru/file.d/plugin/input/fake"
+	"github.com/ozonru/file.d/plugin/output/devnull"
+	"github.com/ozonru/file.d/test"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+)
+
+func newPipeline(config *Config) (*pipeline.Pipeline, *fake.Plugin, *Plugin) {
+	p := test.NewPipeline(nil, "passive")
+
+	anyPlugin, _ := fake.Factory()
+	input := anyPlugin.(*fake.Plugin)
+	p.SetInput(&pipeline.InputPluginInfo{
+		PluginStaticInfo:  &pipeline.PluginStaticInfo{Type: "fake"},
+		PluginRuntimeInfo: &pipeline.PluginRuntimeInfo{Plugin: input},
+	})
+
+	anyPlugin, _ = Factory()
+	output := anyPlugin.(*Plugin)
+	p.SetOutput(&pipeline.OutputPluginInfo{
+		PluginStaticInfo:  &pipeline.PluginStaticInfo{Type: "weighted", Config: config},
+		PluginRuntimeInfo: &pipeline.PluginRuntimeInfo{Plugin: output},
+	})
+
+	p.Start()
+
+	return p, input, output
+}
+
+func newConfig(keyField string, weights ...int) *Config {
+	config := &Config{KeyField: cfg.FieldSelector(keyField)}
+	for i, weight := range weights {
+		config.Routes = append(config.Routes, RouteConfig{
+			Name:   "route_" + strconv.Itoa(i),
+			Weight: weight,
+			Output: map[string]interface{}{"type": "devnull"},
+		})
+	}
+
+	return test.NewConfig(config, nil).(*Config)
+}
+
+func TestWeightedSplit(t *testing.T) {
+	eventCount := 20000
+	p, input, output := newPipeline(newConfig("", 95, 5))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(eventCount * 2)
+
+	counts := []*atomic.Int32{atomic.NewInt32(0), atomic.NewInt32(0)}
+	for i, r := range output.routes {
+		counter := counts[i]
+		r.output.(*devnull.Plugin).SetOutFn(func(e *pipeline.Event) {
+			counter.Inc()
+			wg.Done()
+		})
+	}
+
+	committed := atomic.NewInt32(0)
+	input.SetCommitFn(func(e *pipeline.Event) {
+		committed.Inc()
+		wg.Done()
+	})
+
+	for i := 0; i < eventCount; i++ {
+		input.In(0, "test.log", int64(i+1), []byte(`{"message":"hello"}`))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, int32(eventCount), committed.Load(), "wrong committed events count")
+	assert.Equal(t, int32(eventCount), counts[0].Load()+counts[1].Load(), "wrong out events count")
+
+	canaryShare := float64(counts[1].Load()) / float64(eventCount)
+	assert.InDelta(t, 0.05, canaryShare, 0.01, "split ratio is out of tolerance")
+}
+
+func TestWeightedSplitConsistent(t *testing.T) {
+	eventCount := 1000
+	p, input, output := newPipeline(newConfig("user", 1, 1, 1))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(eventCount)
+
+	mu := &sync.Mutex{}
+	routesByUser := make(map[string]map[int]bool)
+	for i, r := range output.routes {
+		index := i
+		r.output.(*devnull.Plugin).SetOutFn(func(e *pipeline.Event) {
+			user := e.Root.Dig("user").AsString()
+			mu.Lock()
+			if routesByUser[user] == nil {
+				routesByUser[user] = make(map[int]bool)
+			}
+			routesByUser[user][index] = true
+			mu.Unlock()
+			wg.Done()
+		})
+	}
+
+	for i := 0; i < eventCount; i++ {
+		input.In(0, "test.log", int64(i+1), []byte(`{"user":"user_`+strconv.Itoa(i%50)+`"}`))
+	}
+
+	wg.Wait()
+	p.Stop()
+
+	assert.Equal(t, 50, len(routesByUser), "wrong users count")
+	usedRoutes := make(map[int]bool)
+	for user, routes := range routesByUser {
+		assert.Equal(t, 1, len(routes), "user %s is routed to several outputs", user)
+		for index := range routes {
+			usedRoutes[index] = true
+		}
+	}
+	assert.Equal(t, 3, len(usedRoutes), "not all routes are used")
+}
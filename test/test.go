@@ -28,18 +28,22 @@ type Case struct {
 }
 
 func RunCase(testCase *Case, inputInfo *pipeline.InputPluginInfo, eventCount int, pipelineOpts ...string) {
+	RunCaseWithSettings(testCase, inputInfo, NewSettings(), eventCount, pipelineOpts...)
+}
+
+func RunCaseWithSettings(testCase *Case, inputInfo *pipeline.InputPluginInfo, settings *pipeline.Settings, eventCount int, pipelineOpts ...string) {
 	testCase.Prepare()
 
-	p := startCasePipeline(testCase.Act, testCase.Out, eventCount, inputInfo, pipelineOpts...)
+	p := startCasePipeline(testCase.Act, testCase.Out, eventCount, inputInfo, settings, pipelineOpts...)
 
 	testCase.Assert(p)
 }
 
-func startCasePipeline(act func(pipeline *pipeline.Pipeline), out func(event *pipeline.Event), eventCount int, inputInfo *pipeline.InputPluginInfo, pipelineOpts ...string) *pipeline.Pipeline {
+func startCasePipeline(act func(pipeline *pipeline.Pipeline), out func(event *pipeline.Event), eventCount int, inputInfo *pipeline.InputPluginInfo, settings *pipeline.Settings, pipelineOpts ...string) *pipeline.Pipeline {
 	x := atomic.NewInt32(int32(eventCount))
 
 	pipelineOpts = append(pipelineOpts, "passive")
-	p := NewPipeline(nil, pipelineOpts...)
+	p := NewPipelineWithSettings(nil, settings, pipelineOpts...)
 
 	p.SetInput(inputInfo)
 
@@ -93,16 +97,12 @@ func WaitForEvents(x *atomic.Int32) {
 }
 
 func NewPipeline(actions []*pipeline.ActionPluginStaticInfo, pipelineOpts ...string) *pipeline.Pipeline {
-	parallel := Opts(pipelineOpts).Has("parallel")
-	perf := Opts(pipelineOpts).Has("perf")
-	mock := Opts(pipelineOpts).Has("mock")
-	passive := Opts(pipelineOpts).Has("passive")
-
-	if perf {
-		parallel = true
-	}
+	return NewPipelineWithSettings(actions, NewSettings(), pipelineOpts...)
+}
 
-	settings := &pipeline.Settings{
+// NewSettings returns pipeline settings which are used by the test pipelines.
+func NewSettings() *pipeline.Settings {
+	return &pipeline.Settings{
 		Capacity:            4096,
 		MaintenanceInterval: time.Second * 100000,
 		AntispamThreshold:   0,
@@ -110,6 +110,17 @@ func NewPipeline(actions []*pipeline.ActionPluginStaticInfo, pipelineOpts ...str
 		StreamField:         "stream",
 		Decoder:             "json",
 	}
+}
+
+func NewPipelineWithSettings(actions []*pipeline.ActionPluginStaticInfo, settings *pipeline.Settings, pipelineOpts ...string) *pipeline.Pipeline {
+	parallel := Opts(pipelineOpts).Has("parallel")
+	perf := Opts(pipelineOpts).Has("perf")
+	mock := Opts(pipelineOpts).Has("mock")
+	passive := Opts(pipelineOpts).Has("passive")
+
+	if perf {
+		parallel = true
+	}
 
 	http.DefaultServeMux = &http.ServeMux{}
 	p := pipeline.New("test_pipeline", settings, prometheus.NewRegistry(), http.DefaultServeMux)
@@ -157,8 +168,12 @@ func NewPipeline(actions []*pipeline.ActionPluginStaticInfo, pipelineOpts ...str
 }
 
 func NewPipelineMock(actions []*pipeline.ActionPluginStaticInfo, pipelineOpts ...string) (*pipeline.Pipeline, *fake.Plugin, *devnull.Plugin) {
+	return NewPipelineMockWithSettings(actions, NewSettings(), pipelineOpts...)
+}
+
+func NewPipelineMockWithSettings(actions []*pipeline.ActionPluginStaticInfo, settings *pipeline.Settings, pipelineOpts ...string) (*pipeline.Pipeline, *fake.Plugin, *devnull.Plugin) {
 	pipelineOpts = append(pipelineOpts, "mock")
-	p := NewPipeline(actions, pipelineOpts...)
+	p := NewPipelineWithSettings(actions, settings, pipelineOpts...)
 
 	return p, p.GetInput().(*fake.Plugin), p.GetOutput().(*devnull.Plugin)
 }
@@ -183,7 +198,15 @@ func NewActionPluginStaticInfo(factory pipeline.PluginFactory, config pipeline.A
 }
 
 func NewEmptyOutputPluginParams() *pipeline.OutputPluginParams {
-	return &pipeline.OutputPluginParams{PluginDefaultParams: &pipeline.PluginDefaultParams{PipelineName: "test_pipeline", PipelineSettings: &pipeline.Settings{}}, Controller: nil}
+	return &pipeline.OutputPluginParams{PluginDefaultParams: NewEmptyPluginDefaultParams(), Controller: nil}
+}
+
+func NewEmptyPluginDefaultParams() *pipeline.PluginDefaultParams {
+	return &pipeline.PluginDefaultParams{
+		PipelineName:     "test_pipeline",
+		PipelineSettings: &pipeline.Settings{},
+		MetricsCtl:       pipeline.NewMetricsCtl("test_pipeline", prometheus.NewRegistry()),
+	}
 }
 
 func NewConfig(config interface{}, params map[string]int) interface{} {